@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"healthcheck_service/infrastructure/grpc"
 	"healthcheck_service/infrastructure/healthcheck"
+	"healthcheck_service/infrastructure/redis"
 	grpcclient "healthcheck_service/internal/grpc_client"
+	"healthcheck_service/internal/scheduler"
+	"healthcheck_service/internal/sharding"
 	"healthcheck_service/pb"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
-	"sync"
+	"syscall"
 	"time"
 
 	"github.com/flashhhhh/pkg/env"
@@ -46,6 +52,11 @@ func main() {
 
 	topic := "healthcheck_topic"
 
+	// Instances in different locations all check every server, server_administration_service
+	// then needs a quorum of locations to agree before changing a status
+	location := env.GetEnv("PROBE_LOCATION", "default")
+	logging.LogMessage("healthcheck_service", "Probing from location "+location, "INFO")
+
 	grpcClient, err := grpc.StartGRPCClient()
 	if err != nil {
 		panic(err)
@@ -60,76 +71,149 @@ func main() {
 	}
 	logging.LogMessage("healthcheck_service", "Max concurrency set to "+strconv.Itoa(maxConcurrency), "INFO")
 
+	// Address changes are pulled every DELAY_SECONDS, the full list every FULL_REFRESH_SECONDS
+	// to recover from anything an incremental refresh could miss
 	var delaySecondsStr = env.GetEnv("DELAY_SECONDS", "60")
 	var delaySeconds, delaySecondsErr = strconv.Atoi(delaySecondsStr)
 	if (delaySecondsErr != nil) {
 		delaySeconds = 60
 	}
-	logging.LogMessage("healthcheck_service", "Delay seconds set to "+strconv.Itoa(delaySeconds), "INFO")
+	logging.LogMessage("healthcheck_service", "Addresses refreshed every "+strconv.Itoa(delaySeconds)+" seconds", "INFO")
+
+	var fullRefreshSecondsStr = env.GetEnv("FULL_REFRESH_SECONDS", "600")
+	var fullRefreshSeconds, fullRefreshSecondsErr = strconv.Atoi(fullRefreshSecondsStr)
+	if (fullRefreshSecondsErr != nil) {
+		fullRefreshSeconds = 600
+	}
+	logging.LogMessage("healthcheck_service", "Full address refresh every "+strconv.Itoa(fullRefreshSeconds)+" seconds", "INFO")
+
+	var jitterStr = env.GetEnv("SCHEDULER_JITTER", "0.1")
+	var jitter, jitterErr = strconv.ParseFloat(jitterStr, 64)
+	if (jitterErr != nil) {
+		jitter = 0.1
+	}
+
+	checkScheduler := scheduler.NewScheduler(func(address *pb.AddressInfo) {
+		checkServer(address, location, kafkaProducer, topic)
+	}, maxConcurrency, jitter)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// With sharding on, instances split the servers between them through leases in Redis
+	var owns scheduler.OwnsFunc
+	var shardsChanged <-chan struct{}
+	if env.GetEnv("SHARDING_ENABLED", "false") == "true" {
+		redisAddress := env.GetEnv("HEALTHCHECK_REDIS_HOST", "localhost") +
+			":" + env.GetEnv("HEALTHCHECK_REDIS_PORT", "6379")
+		redisClient := redis.NewRedisClient(redisAddress)
+
+		hostname, _ := os.Hostname()
+		instanceID := env.GetEnv("INSTANCE_ID", hostname+"-"+strconv.Itoa(os.Getpid()))
+
+		var leaseSeconds, leaseSecondsErr = strconv.Atoi(env.GetEnv("SHARD_LEASE_SECONDS", "15"))
+		if (leaseSecondsErr != nil || leaseSeconds < 3) {
+			leaseSeconds = 15
+		}
+
+		membership := sharding.NewMembership(redisClient, location, instanceID, time.Duration(leaseSeconds)*time.Second)
+		if err := membership.Start(ctx); err != nil {
+			logging.LogMessage("healthcheck_service", "Failed to join the healthcheck instances: "+err.Error(), "FATAL")
+			logging.LogMessage("healthcheck_service", "Exiting the program...", "FATAL")
+			os.Exit(1)
+		}
+		defer membership.Leave(context.Background())
+		logging.LogMessage("healthcheck_service", "Sharding enabled, instance "+instanceID+" joined "+strconv.Itoa(len(membership.Members()))+" instance(s)", "INFO")
+
+		owns = membership.Owns
+		shardsChanged = membership.Changed()
+	}
 
-	semaphore := make(chan struct{}, maxConcurrency)
+	refresher := scheduler.NewRefresher(client, checkScheduler, owns)
 
 	/*
 		MAIN LOOP OF HEALTHCHECK SERVICE
 	*/
 
+	go checkScheduler.Run(ctx)
+
+	refresher.FullRefresh()
+	lastFullRefresh := time.Now()
+
+	refreshTicker := time.NewTicker(time.Duration(delaySeconds) * time.Second)
+	defer refreshTicker.Stop()
+
 	for {
-		addressesResponse, err := client.GetAllAddresses()
-		if err != nil {
-			logging.LogMessage("healthcheck_service", "Failed to get addresses from gRPC server: "+err.Error(), "ERROR")
-			logging.LogMessage("healthcheck_service", "Retrying in " + strconv.Itoa(delaySeconds) + " seconds...", "ERROR")
-			
-			time.Sleep(time.Duration(delaySeconds) * time.Second)
+		select {
+		case <-ctx.Done():
+			logging.LogMessage("healthcheck_service", "Shutting down...", "INFO")
+			return
+		case <-shardsChanged:
+			// Rebalance right away instead of waiting for the next tick
+			if refresher.FullRefresh() {
+				lastFullRefresh = time.Now()
+			}
 			continue
+		case <-refreshTicker.C:
 		}
-		logging.LogMessage("healthcheck_service", "Received addresses from gRPC server", "INFO")
-
-		var wg sync.WaitGroup
-
-		for _, address := range addressesResponse.Addresses {
-			wg.Add(1)
-
-			semaphore <- struct{}{} // Acquire a semaphore slot
-
-			go func (address *pb.AddressInfo) {
-				defer wg.Done()
-				defer func() { <-semaphore }() // Release the semaphore slot
-
-				ID := int(address.Id)
-				serverAddress := address.Address
-				
-				// Check if the server is On or Off by pinging the address
-				logging.LogMessage("healthcheck_service", "Pinging server " + strconv.Itoa(ID) + " at address "+serverAddress, "INFO")
-				status := healthcheck.IsHostUp(serverAddress)
-
-				statusText := "OFF"
-				if status {
-					statusText = "ON"
-				}
-				logging.LogMessage("healthcheck_service", "Server " + strconv.Itoa(ID) + " is "+statusText, "INFO")
-				
-				// Send the health check result to Kafka
-				data := map[string]interface{}{
-					"id":   ID,
-					"ipv4": serverAddress,
-					"status":      status,
-				}
-
-				message, _ := json.Marshal(data)
-				err = kafkaProducer.SendMessage(topic, message)
-
-				if err != nil {
-					logging.LogMessage("healthcheck_service", "Failed to send health check result of server "+strconv.Itoa(ID)+" to Kafka topic "+topic+": "+err.Error(), "ERROR")
-					return
-				}
-
-				logging.LogMessage("healthcheck_service", "Sent health check result of server " + strconv.Itoa(ID) + " to Kafka topic "+topic, "INFO")
-			}(address)
+
+		if refresher.NeedsFullRefresh() || time.Since(lastFullRefresh) >= time.Duration(fullRefreshSeconds)*time.Second {
+			if refresher.FullRefresh() {
+				lastFullRefresh = time.Now()
+			}
+		} else {
+			refresher.IncrementalRefresh()
 		}
 
-		logging.LogMessage("healthcheck_service", "Sleep for " + strconv.Itoa(delaySeconds) + " seconds...", "INFO")
-		time.Sleep(time.Duration(delaySeconds) * time.Second)
+		stats := checkScheduler.Metrics().Snapshot()
+		logging.LogMessage("healthcheck_service", fmt.Sprintf("Scheduler: %d servers, %d checks run, lateness p50 %s, p99 %s, max %s",
+			checkScheduler.Len(), stats.Checks, stats.P50Lateness, stats.P99Lateness, stats.MaxLateness), "INFO")
+	}
+}
+
+func checkServer(address *pb.AddressInfo, location string, kafkaProducer *kafka.KafkaProducer, topic string) {
+	ID := int(address.Id)
+	serverAddress := address.Address
+
+	timeout := time.Duration(address.TimeoutMs) * time.Millisecond
+	probe, err := healthcheck.NewProbe(address.ProbeType, address.ProbePath, int(address.ProbeExpectedStatus), address.ProbeExpectedBody, timeout)
+	if err != nil {
+		logging.LogMessage("healthcheck_service", "Invalid probe for server "+strconv.Itoa(ID)+": "+err.Error()+", falling back to TCP", "ERROR")
+		probe, _ = healthcheck.NewProbe(healthcheck.ProbeTCP, "", 0, "", timeout)
+	}
+
+	// Check if the server is On or Off by probing the address
+	logging.LogMessage("healthcheck_service", "Probing server " + strconv.Itoa(ID) + " at address "+serverAddress+" using "+address.ProbeType, "INFO")
+	result := healthcheck.Run(probe, serverAddress, int(address.Retries))
+	status := result.Status
+
+	statusText := "OFF"
+	if status {
+		statusText = "ON"
+	} else {
+		statusText += " (" + result.ErrorClass + ": " + result.Error.Error() + ")"
+	}
+	logging.LogMessage("healthcheck_service", "Server " + strconv.Itoa(ID) + " is "+statusText+" after "+strconv.Itoa(result.Attempts)+" attempt(s), last took "+result.Latency.String(), "INFO")
+
+	// Send the health check result to Kafka
+	data := map[string]interface{}{
+		"id":   ID,
+		"ipv4": serverAddress,
+		"status":      status,
+		"latency_ms":  float64(result.Latency.Microseconds()) / 1000,
+		"error_class": result.ErrorClass,
+		"checked_at":  result.CheckedAt,
+		"location":    location,
+	}
+
+	message, _ := json.Marshal(data)
+	// Keyed by server so every result of a server lands on the same partition, in order
+	err = kafkaProducer.SendMessageWithKey(topic, strconv.Itoa(ID), message)
 
-		wg.Wait() // Wait for all goroutines to finish
+	if err != nil {
+		logging.LogMessage("healthcheck_service", "Failed to send health check result of server "+strconv.Itoa(ID)+" to Kafka topic "+topic+": "+err.Error(), "ERROR")
+		return
 	}
-}
\ No newline at end of file
+
+	logging.LogMessage("healthcheck_service", "Sent health check result of server " + strconv.Itoa(ID) + " to Kafka topic "+topic, "INFO")
+}
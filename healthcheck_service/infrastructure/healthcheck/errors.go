@@ -0,0 +1,67 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// Error classes reported with every failed check
+const (
+	ErrorClassTimeout            = "timeout"
+	ErrorClassConnectionRefused  = "connection_refused"
+	ErrorClassDNSFailure         = "dns_failure"
+	ErrorClassTLSError           = "tls_error"
+	ErrorClassUnexpectedResponse = "unexpected_response"
+	ErrorClassOther              = "other"
+)
+
+// ErrUnexpectedResponse is wrapped by probes that reached the server but did not like its answer
+var ErrUnexpectedResponse = errors.New("unexpected response")
+
+// ErrTLSHandshake is wrapped by TLSProbe when the connection succeeded but the handshake did not
+var ErrTLSHandshake = errors.New("tls handshake failed")
+
+// ClassifyError maps a probe error to one of the ErrorClass* constants, nil maps to ""
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return ErrorClassTimeout
+		}
+		return ErrorClassDNSFailure
+	}
+
+	if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClassTimeout
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrorClassConnectionRefused
+	}
+
+	// Most handshake failures are unexported crypto/tls errors, their messages all start with "tls:"
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	if errors.Is(err, ErrTLSHandshake) || errors.As(err, &recordErr) || errors.As(err, &certErr) || strings.Contains(err.Error(), "tls:") {
+		return ErrorClassTLSError
+	}
+
+	if errors.Is(err, ErrUnexpectedResponse) {
+		return ErrorClassUnexpectedResponse
+	}
+
+	return ErrorClassOther
+}
@@ -0,0 +1,40 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"No error", nil, ""},
+		{"DNS failure", &net.DNSError{Err: "no such host", Name: "db.invalid", IsNotFound: true}, ErrorClassDNSFailure},
+		{"DNS timeout", &net.DNSError{Err: "i/o timeout", Name: "db.invalid", IsTimeout: true}, ErrorClassTimeout},
+		{"Deadline exceeded", fmt.Errorf("read: %w", os.ErrDeadlineExceeded), ErrorClassTimeout},
+		{"Context deadline", context.DeadlineExceeded, ErrorClassTimeout},
+		{"Connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, ErrorClassConnectionRefused},
+		{"TLS handshake", fmt.Errorf("%w: %w", ErrTLSHandshake, errors.New("EOF")), ErrorClassTLSError},
+		{"TLS record header", tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, ErrorClassTLSError},
+		{"TLS alert message", errors.New("remote error: tls: handshake failure"), ErrorClassTLSError},
+		{"Unexpected response", fmt.Errorf("%w: status code 500, expected 200", ErrUnexpectedResponse), ErrorClassUnexpectedResponse},
+		{"Anything else", errors.New("connection reset by peer"), ErrorClassOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifyError(tt.err))
+		})
+	}
+}
@@ -1,16 +1,39 @@
 package healthcheck
 
-import (
-	"net"
-	"time"
-)
+import "time"
 
-func IsHostUp(address string) bool {
-	timeout := 5 * time.Second
-	conn, err := net.DialTimeout("tcp", address, timeout)
-	if err != nil {
-		return false
+// Result is the outcome of a probe, after retries
+type Result struct {
+	Status     bool
+	Latency    time.Duration
+	ErrorClass string
+	Error      error
+	CheckedAt  time.Time
+	Attempts   int
+}
+
+// Run executes the probe, retrying up to retries more times while it fails.
+// Latency is the one of the last attempt.
+func Run(probe Probe, address string, retries int) Result {
+	var result Result
+
+	for attempt := 0; attempt <= retries; attempt++ {
+		checkedAt := time.Now()
+		err := probe.Check(address)
+
+		result = Result{
+			Status:     err == nil,
+			Latency:    time.Since(checkedAt),
+			ErrorClass: ClassifyError(err),
+			Error:      err,
+			CheckedAt:  checkedAt,
+			Attempts:   attempt + 1,
+		}
+
+		if result.Status {
+			break
+		}
 	}
-	conn.Close()
-	return true
-}
\ No newline at end of file
+
+	return result
+}
@@ -0,0 +1,197 @@
+package healthcheck
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ProbeTCP   = "tcp"
+	ProbeHTTP  = "http"
+	ProbeHTTPS = "https"
+	ProbeUDP   = "udp"
+	ProbeTLS   = "tls"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// Probe checks whether the server behind an address is healthy.
+// A nil error means the server is up.
+type Probe interface {
+	Check(address string) error
+}
+
+// NewProbe builds the probe configured for a server.
+// An empty probe type falls back to a TCP dial.
+func NewProbe(probeType, path string, expectedStatus int, expectedBody string, timeout time.Duration) (Probe, error) {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	switch probeType {
+	case "", ProbeTCP:
+		return &TCPProbe{Timeout: timeout}, nil
+	case ProbeHTTP, ProbeHTTPS:
+		return NewHTTPProbe(probeType, path, expectedStatus, expectedBody, timeout), nil
+	case ProbeUDP:
+		return &UDPProbe{Timeout: timeout, ExpectedBody: expectedBody}, nil
+	case ProbeTLS:
+		return &TLSProbe{Timeout: timeout}, nil
+	}
+
+	return nil, fmt.Errorf("unknown probe type %s", probeType)
+}
+
+// TCPProbe only checks that the address accepts TCP connections.
+type TCPProbe struct {
+	Timeout time.Duration
+}
+
+func (p *TCPProbe) Check(address string) error {
+	conn, err := net.DialTimeout("tcp", address, p.Timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// HTTPProbe sends a GET request and checks the status code and, optionally, the body.
+type HTTPProbe struct {
+	Scheme         string
+	Path           string
+	ExpectedStatus int
+	ExpectedBody   string
+	client         *http.Client
+}
+
+func NewHTTPProbe(scheme, path string, expectedStatus int, expectedBody string, timeout time.Duration) *HTTPProbe {
+	if path == "" {
+		path = "/"
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if expectedStatus == 0 {
+		expectedStatus = http.StatusOK
+	}
+
+	return &HTTPProbe{
+		Scheme:         scheme,
+		Path:           path,
+		ExpectedStatus: expectedStatus,
+		ExpectedBody:   expectedBody,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				// Servers are monitored by IP, so certificates rarely match the address
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+				DisableKeepAlives: true,
+			},
+			// Report redirects as they are instead of following them
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (p *HTTPProbe) Check(address string) error {
+	resp, err := p.client.Get(p.Scheme + "://" + address + p.Path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != p.ExpectedStatus {
+		return fmt.Errorf("%w: status code %d, expected %d", ErrUnexpectedResponse, resp.StatusCode, p.ExpectedStatus)
+	}
+
+	if p.ExpectedBody == "" {
+		return nil
+	}
+
+	// Health endpoints are small, 1MB is plenty to find the expected substring
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(body, []byte(p.ExpectedBody)) {
+		return fmt.Errorf("%w: body does not contain %q", ErrUnexpectedResponse, p.ExpectedBody)
+	}
+	return nil
+}
+
+// UDPProbe sends a datagram and waits for a reply.
+// Without an expected body the reply has to echo the payload back.
+type UDPProbe struct {
+	Timeout      time.Duration
+	ExpectedBody string
+}
+
+const udpProbePayload = "healthcheck"
+
+func (p *UDPProbe) Check(address string) error {
+	conn, err := net.DialTimeout("udp", address, p.Timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(p.Timeout)); err != nil {
+		return err
+	}
+
+	if _, err := conn.Write([]byte(udpProbePayload)); err != nil {
+		return err
+	}
+
+	buffer := make([]byte, 2048)
+	n, err := conn.Read(buffer)
+	if err != nil {
+		return err
+	}
+
+	expected := p.ExpectedBody
+	if expected == "" {
+		expected = udpProbePayload
+	}
+	if !bytes.Contains(buffer[:n], []byte(expected)) {
+		return fmt.Errorf("%w: reply does not contain %q", ErrUnexpectedResponse, expected)
+	}
+	return nil
+}
+
+// TLSProbe completes a TLS handshake with the server.
+type TLSProbe struct {
+	Timeout time.Duration
+}
+
+func (p *TLSProbe) Check(address string) error {
+	rawConn, err := net.DialTimeout("tcp", address, p.Timeout)
+	if err != nil {
+		return err
+	}
+	defer rawConn.Close()
+
+	if err := rawConn.SetDeadline(time.Now().Add(p.Timeout)); err != nil {
+		return err
+	}
+
+	// The handshake itself is what we check, certificate validity is out of scope
+	conn := tls.Client(rawConn, &tls.Config{InsecureSkipVerify: true})
+	if err := conn.Handshake(); err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrTLSHandshake, err)
+	}
+	return conn.Close()
+}
@@ -0,0 +1,276 @@
+package healthcheck
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTimeout = 200 * time.Millisecond
+
+func serverAddress(server *httptest.Server) string {
+	return strings.TrimPrefix(strings.TrimPrefix(server.URL, "http://"), "https://")
+}
+
+// closedAddress is a local address nothing listens on
+func closedAddress(t *testing.T, network string) string {
+	t.Helper()
+	if network == "udp" {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		address := conn.LocalAddr().String()
+		conn.Close()
+		return address
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+// silentListener accepts TCP connections and never writes to them
+func silentListener(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// Held until the probe hangs up
+			go func() {
+				io.Copy(io.Discard, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// udpResponder answers every datagram with what reply returns, a nil reply echoes it back
+func udpResponder(t *testing.T, reply func(payload []byte) []byte) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buffer := make([]byte, 2048)
+		for {
+			n, from, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			answer := buffer[:n]
+			if reply != nil {
+				answer = reply(answer)
+			}
+			if answer != nil {
+				conn.WriteTo(answer, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestNewProbe(t *testing.T) {
+	tests := []struct {
+		probeType string
+		want      Probe
+	}{
+		{"", &TCPProbe{}},
+		{ProbeTCP, &TCPProbe{}},
+		{ProbeHTTP, &HTTPProbe{}},
+		{ProbeHTTPS, &HTTPProbe{}},
+		{ProbeUDP, &UDPProbe{}},
+		{ProbeTLS, &TLSProbe{}},
+	}
+
+	for _, tt := range tests {
+		probe, err := NewProbe(tt.probeType, "", 0, "", 0)
+		assert.NoError(t, err)
+		assert.IsType(t, tt.want, probe)
+	}
+
+	_, err := NewProbe("icmp", "", 0, "", 0)
+	assert.Error(t, err)
+
+	httpProbe := NewHTTPProbe(ProbeHTTP, "health", 0, "", time.Second)
+	assert.Equal(t, "/health", httpProbe.Path)
+	assert.Equal(t, http.StatusOK, httpProbe.ExpectedStatus)
+}
+
+func TestTCPProbe(t *testing.T) {
+	probe := &TCPProbe{Timeout: testTimeout}
+
+	assert.NoError(t, probe.Check(silentListener(t)))
+
+	err := probe.Check(closedAddress(t, "tcp"))
+	assert.Error(t, err)
+	assert.Equal(t, ErrorClassConnectionRefused, ClassifyError(err))
+}
+
+func TestHTTPProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.Write([]byte(`{"status":"ok","database":"up"}`))
+		case "/moved":
+			http.Redirect(w, r, "/health", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	address := serverAddress(server)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+		wantClass      string
+	}{
+		{"Status and body match", "/health", 0, `"status":"ok"`, ""},
+		{"Status matches without a body check", "/health", http.StatusOK, "", ""},
+		{"Unexpected status", "/missing", 0, "", ErrorClassUnexpectedResponse},
+		{"Expected 404", "/missing", http.StatusNotFound, "", ""},
+		{"Body mismatch", "/health", 0, `"database":"down"`, ErrorClassUnexpectedResponse},
+		{"Redirect is not followed", "/moved", 0, "", ErrorClassUnexpectedResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewHTTPProbe(ProbeHTTP, tt.path, tt.expectedStatus, tt.expectedBody, testTimeout).Check(address)
+			assert.Equal(t, tt.wantClass, ClassifyError(err))
+		})
+	}
+
+	t.Run("Timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer slow.Close()
+		defer close(release)
+
+		err := NewHTTPProbe(ProbeHTTP, "/", 0, "", testTimeout).Check(serverAddress(slow))
+		assert.Equal(t, ErrorClassTimeout, ClassifyError(err))
+	})
+
+	t.Run("Refused", func(t *testing.T) {
+		err := NewHTTPProbe(ProbeHTTP, "/", 0, "", testTimeout).Check(closedAddress(t, "tcp"))
+		assert.Equal(t, ErrorClassConnectionRefused, ClassifyError(err))
+	})
+}
+
+func TestHTTPSProbe(t *testing.T) {
+	// The certificate of the test server is self-signed, the probe doesn't verify it
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("healthy"))
+	}))
+	defer server.Close()
+
+	assert.NoError(t, NewHTTPProbe(ProbeHTTPS, "/", 0, "healthy", testTimeout).Check(serverAddress(server)))
+
+	err := NewHTTPProbe(ProbeHTTPS, "/", 0, "unhealthy", testTimeout).Check(serverAddress(server))
+	assert.Equal(t, ErrorClassUnexpectedResponse, ClassifyError(err))
+}
+
+func TestUDPProbe(t *testing.T) {
+	t.Run("Echo", func(t *testing.T) {
+		address := udpResponder(t, nil)
+		assert.NoError(t, (&UDPProbe{Timeout: testTimeout}).Check(address))
+	})
+
+	t.Run("Expected reply", func(t *testing.T) {
+		address := udpResponder(t, func([]byte) []byte { return []byte("PONG ready") })
+		assert.NoError(t, (&UDPProbe{Timeout: testTimeout, ExpectedBody: "ready"}).Check(address))
+
+		err := (&UDPProbe{Timeout: testTimeout, ExpectedBody: "busy"}).Check(address)
+		assert.Equal(t, ErrorClassUnexpectedResponse, ClassifyError(err))
+	})
+
+	t.Run("Reply is not an echo", func(t *testing.T) {
+		address := udpResponder(t, func([]byte) []byte { return []byte("PONG") })
+		err := (&UDPProbe{Timeout: testTimeout}).Check(address)
+		assert.Equal(t, ErrorClassUnexpectedResponse, ClassifyError(err))
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		address := udpResponder(t, func([]byte) []byte { return nil })
+		err := (&UDPProbe{Timeout: testTimeout}).Check(address)
+		assert.Equal(t, ErrorClassTimeout, ClassifyError(err))
+	})
+
+	t.Run("Refused", func(t *testing.T) {
+		// The ICMP port unreachable comes back as a refused read
+		err := (&UDPProbe{Timeout: testTimeout}).Check(closedAddress(t, "udp"))
+		assert.Equal(t, ErrorClassConnectionRefused, ClassifyError(err))
+	})
+}
+
+func TestTLSProbe(t *testing.T) {
+	probe := &TLSProbe{Timeout: testTimeout}
+
+	t.Run("Handshake", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		defer server.Close()
+
+		assert.NoError(t, probe.Check(serverAddress(server)))
+	})
+
+	t.Run("Not a TLS server", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		defer server.Close()
+
+		err := probe.Check(serverAddress(server))
+		assert.True(t, errors.Is(err, ErrTLSHandshake))
+		assert.Equal(t, ErrorClassTLSError, ClassifyError(err))
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		err := probe.Check(silentListener(t))
+		assert.Equal(t, ErrorClassTimeout, ClassifyError(err))
+	})
+
+	t.Run("Refused", func(t *testing.T) {
+		err := probe.Check(closedAddress(t, "tcp"))
+		assert.Equal(t, ErrorClassConnectionRefused, ClassifyError(err))
+	})
+}
+
+func TestRun(t *testing.T) {
+	address := closedAddress(t, "tcp")
+
+	result := Run(&TCPProbe{Timeout: testTimeout}, address, 2)
+	assert.False(t, result.Status)
+	assert.Equal(t, 3, result.Attempts)
+	assert.Equal(t, ErrorClassConnectionRefused, result.ErrorClass)
+
+	result = Run(&TCPProbe{Timeout: testTimeout}, silentListener(t), 2)
+	assert.True(t, result.Status)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Empty(t, result.ErrorClass)
+}
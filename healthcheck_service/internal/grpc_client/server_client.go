@@ -7,6 +7,7 @@ import (
 
 type HealthCheckClient interface {
 	GetAllAddresses() (*pb.AddressesResponse, error)
+	GetUpdatedAddresses(updatedSince int64) (*pb.GetUpdatedAddressesResponse, error)
 }
 
 type healthCheckClient struct {
@@ -29,5 +30,18 @@ func (h *healthCheckClient) GetAllAddresses() (*pb.AddressesResponse, error) {
 		return nil, err
 	}
 
+	return resp, nil
+}
+
+func (h *healthCheckClient) GetUpdatedAddresses(updatedSince int64) (*pb.GetUpdatedAddressesResponse, error) {
+	resp, err := h.client.GetUpdatedAddresses(
+		context.Background(),
+		&pb.GetUpdatedAddressesRequest{UpdatedSince: updatedSince},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
 	return resp, nil
 }
\ No newline at end of file
@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"strconv"
+	"time"
+
+	grpcclient "healthcheck_service/internal/grpc_client"
+
+	"github.com/flashhhhh/pkg/logging"
+)
+
+// Overlap between two incremental refreshes, re-applying an unchanged address is harmless
+const refreshOverlap = 5 * time.Second
+
+// OwnsFunc tells whether this instance is responsible for a server
+type OwnsFunc func(serverID int64) bool
+
+// Refresher keeps the scheduler in sync with the servers of server_administration_service
+type Refresher struct {
+	client       grpcclient.HealthCheckClient
+	scheduler    *Scheduler
+	owns         OwnsFunc
+	updatedSince int64 // server time in unix ms, 0 until the first successful refresh
+
+	needsFullRefresh bool
+}
+
+// owns may be nil, the instance then checks every server
+func NewRefresher(client grpcclient.HealthCheckClient, scheduler *Scheduler, owns OwnsFunc) *Refresher {
+	if owns == nil {
+		owns = func(int64) bool { return true }
+	}
+
+	return &Refresher{
+		client:    client,
+		scheduler: scheduler,
+		owns:      owns,
+	}
+}
+
+func (r *Refresher) NeedsFullRefresh() bool {
+	return r.needsFullRefresh
+}
+
+func (r *Refresher) FullRefresh() bool {
+	addressesResponse, err := r.client.GetAllAddresses()
+	if err != nil {
+		logging.LogMessage("healthcheck_service", "Failed to get addresses from gRPC server: "+err.Error(), "ERROR")
+		r.needsFullRefresh = true
+		return false
+	}
+
+	ids := make([]int64, 0, len(addressesResponse.Addresses))
+	for _, address := range addressesResponse.Addresses {
+		if !r.owns(address.Id) {
+			continue
+		}
+		ids = append(ids, address.Id)
+		r.scheduler.Upsert(address)
+	}
+	r.scheduler.Retain(ids)
+	logging.LogMessage("healthcheck_service", "Received "+strconv.Itoa(len(addressesResponse.Addresses))+" addresses from gRPC server, "+strconv.Itoa(len(ids))+" owned by this instance", "INFO")
+
+	r.updatedSince = addressesResponse.ServerTime - refreshOverlap.Milliseconds()
+	r.needsFullRefresh = false
+	return true
+}
+
+func (r *Refresher) IncrementalRefresh() {
+	response, err := r.client.GetUpdatedAddresses(r.updatedSince)
+	if err != nil {
+		logging.LogMessage("healthcheck_service", "Failed to get updated addresses from gRPC server: "+err.Error(), "ERROR")
+		return
+	}
+
+	applied := 0
+	for _, address := range response.Addresses {
+		if r.owns(address.Id) {
+			r.scheduler.Upsert(address)
+			applied++
+		}
+	}
+
+	ownedIDs := make([]int64, 0, len(response.Ids))
+	for _, id := range response.Ids {
+		if r.owns(id) {
+			ownedIDs = append(ownedIDs, id)
+		}
+	}
+	r.scheduler.Retain(ownedIDs)
+
+	// An id we have never seen without its address means a change slipped through
+	for _, id := range ownedIDs {
+		if !r.scheduler.Has(id) {
+			r.needsFullRefresh = true
+			break
+		}
+	}
+
+	r.updatedSince = response.ServerTime - refreshOverlap.Milliseconds()
+	logging.LogMessage("healthcheck_service", "Applied "+strconv.Itoa(applied)+" updated addresses", "INFO")
+}
@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+
+	"healthcheck_service/pb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeHealthCheckClient struct {
+	all          *pb.AddressesResponse
+	allErr       error
+	updated      *pb.GetUpdatedAddressesResponse
+	updatedErr   error
+	updatedSince []int64
+}
+
+func (c *fakeHealthCheckClient) GetAllAddresses() (*pb.AddressesResponse, error) {
+	return c.all, c.allErr
+}
+
+func (c *fakeHealthCheckClient) GetUpdatedAddresses(updatedSince int64) (*pb.GetUpdatedAddressesResponse, error) {
+	c.updatedSince = append(c.updatedSince, updatedSince)
+	return c.updated, c.updatedErr
+}
+
+func ownsOdd(id int64) bool {
+	return id%2 == 1
+}
+
+func TestRefresher_FullRefresh(t *testing.T) {
+	s, _ := newTestScheduler(func(*pb.AddressInfo) {}, 1)
+	s.Upsert(address(5, 60))
+
+	client := &fakeHealthCheckClient{
+		all: &pb.AddressesResponse{
+			Addresses:  []*pb.AddressInfo{address(1, 60), address(2, 60), address(3, 60)},
+			ServerTime: 1700000000000,
+		},
+		updated: &pb.GetUpdatedAddressesResponse{Ids: []int64{1, 3}, ServerTime: 1700000060000},
+	}
+	refresher := NewRefresher(client, s, ownsOdd)
+
+	assert.True(t, refresher.FullRefresh())
+	assert.False(t, refresher.NeedsFullRefresh())
+
+	// Only the owned servers are scheduled, the ones gone from the list are dropped
+	assert.True(t, s.Has(1))
+	assert.False(t, s.Has(2))
+	assert.True(t, s.Has(3))
+	assert.False(t, s.Has(5))
+
+	// The next incremental refresh starts from the server time, not the local clock
+	refresher.IncrementalRefresh()
+	assert.Equal(t, []int64{1700000000000 - refreshOverlap.Milliseconds()}, client.updatedSince)
+}
+
+func TestRefresher_FullRefreshError(t *testing.T) {
+	s, _ := newTestScheduler(func(*pb.AddressInfo) {}, 1)
+	s.Upsert(address(1, 60))
+
+	refresher := NewRefresher(&fakeHealthCheckClient{allErr: errors.New("unavailable")}, s, nil)
+
+	assert.False(t, refresher.FullRefresh())
+	assert.True(t, refresher.NeedsFullRefresh())
+	// Nothing is dropped when the list could not be fetched
+	assert.True(t, s.Has(1))
+}
+
+func TestRefresher_IncrementalRefresh(t *testing.T) {
+	s, _ := newTestScheduler(func(*pb.AddressInfo) {}, 1)
+	client := &fakeHealthCheckClient{
+		all: &pb.AddressesResponse{
+			Addresses:  []*pb.AddressInfo{address(1, 60), address(3, 60)},
+			ServerTime: 1000000,
+		},
+	}
+	refresher := NewRefresher(client, s, ownsOdd)
+	refresher.FullRefresh()
+
+	t.Run("Applies updates and drops deleted servers", func(t *testing.T) {
+		client.updated = &pb.GetUpdatedAddressesResponse{
+			Addresses:  []*pb.AddressInfo{address(1, 30), address(4, 60), address(5, 60)},
+			Ids:        []int64{1, 4, 5},
+			ServerTime: 2000000,
+		}
+		refresher.IncrementalRefresh()
+
+		assert.Equal(t, int32(30), s.entries[1].address.CheckIntervalSeconds)
+		assert.False(t, s.Has(3))
+		assert.False(t, s.Has(4))
+		assert.True(t, s.Has(5))
+		assert.False(t, refresher.NeedsFullRefresh())
+		assert.Equal(t, int64(1000000)-refreshOverlap.Milliseconds(), client.updatedSince[0])
+	})
+
+	t.Run("An unknown id asks for a full refresh", func(t *testing.T) {
+		client.updated = &pb.GetUpdatedAddressesResponse{
+			Ids:        []int64{1, 5, 7},
+			ServerTime: 3000000,
+		}
+		refresher.IncrementalRefresh()
+
+		assert.True(t, refresher.NeedsFullRefresh())
+		assert.Equal(t, int64(2000000)-refreshOverlap.Milliseconds(), client.updatedSince[1])
+	})
+
+	t.Run("An error keeps the servers and the cursor", func(t *testing.T) {
+		client.updatedErr = errors.New("unavailable")
+		refresher.IncrementalRefresh()
+
+		assert.True(t, s.Has(1))
+		assert.True(t, s.Has(5))
+
+		client.updatedErr = nil
+		client.updated = &pb.GetUpdatedAddressesResponse{Ids: []int64{1, 5}, ServerTime: 4000000}
+		refresher.IncrementalRefresh()
+		assert.Equal(t, int64(3000000)-refreshOverlap.Milliseconds(), client.updatedSince[3])
+	})
+}
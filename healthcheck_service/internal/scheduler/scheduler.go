@@ -0,0 +1,373 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"healthcheck_service/pb"
+)
+
+const defaultInterval = 60 * time.Second
+
+// CheckFunc probes one server, it is called from a worker goroutine
+type CheckFunc func(address *pb.AddressInfo)
+
+/*
+Scheduler runs every server on its own check interval.
+
+Servers wait in a min-heap keyed by their next due time, a single loop sleeps until
+the earliest one is due and hands it to a bounded pool of workers. A server is put
+back in the heap only when its check finishes, so checks of one server never overlap.
+Due times stay on a fixed grid (first due + k * interval) so they do not drift,
+a check that fell behind by more than an interval skips the missed slots.
+*/
+type Scheduler struct {
+	check     CheckFunc
+	semaphore chan struct{}
+	jitter    float64
+
+	mu      sync.Mutex
+	queue   entryQueue
+	entries map[int64]*entry
+	wake    chan struct{}
+
+	metrics *Metrics
+
+	// now is time.Now, tests replace it to move the clock forward
+	now func() time.Time
+}
+
+type entry struct {
+	address *pb.AddressInfo
+	// nominal is the slot on the interval grid, due adds the jitter of this run
+	nominal time.Time
+	due     time.Time
+	index   int // position in the heap, -1 while running or removed
+	removed bool
+}
+
+/*
+jitter is the fraction of the interval a check may be delayed by, e.g. 0.1
+delays each run by up to 10% of its interval so that servers sharing an
+interval do not fire in bursts.
+*/
+func NewScheduler(check CheckFunc, maxConcurrency int, jitter float64) *Scheduler {
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+	if jitter < 0 {
+		jitter = 0
+	}
+
+	return &Scheduler{
+		check:     check,
+		semaphore: make(chan struct{}, maxConcurrency),
+		jitter:    jitter,
+		entries:   make(map[int64]*entry),
+		wake:      make(chan struct{}, 1),
+		metrics:   NewMetrics(1024),
+		now:       time.Now,
+	}
+}
+
+// Upsert adds a new server or updates the settings of a known one
+func (s *Scheduler) Upsert(address *pb.AddressInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	interval := intervalOf(address)
+
+	e, existed := s.entries[address.Id]
+	if !existed {
+		// New servers start at a random point of their interval to spread the load
+		e = &entry{address: address, index: -1}
+		e.nominal = now.Add(time.Duration(rand.Int63n(int64(interval))))
+		e.due = e.nominal
+		s.entries[address.Id] = e
+		heap.Push(&s.queue, e)
+		s.notify()
+		return
+	}
+
+	previousInterval := intervalOf(e.address)
+	e.address = address
+
+	// A shorter interval must not wait for the old, longer slot
+	if interval < previousInterval && e.index >= 0 && e.nominal.After(now.Add(interval)) {
+		e.nominal = now.Add(interval)
+		e.due = e.nominal
+		heap.Fix(&s.queue, e.index)
+		s.notify()
+	}
+}
+
+// Remove stops checking a server, a check already running is not interrupted
+func (s *Scheduler) Remove(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(id)
+}
+
+// Retain removes every server whose id is not in ids
+func (s *Scheduler) Retain(ids []int64) {
+	keep := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		keep[id] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.entries {
+		if !keep[id] {
+			s.remove(id)
+		}
+	}
+}
+
+// Has reports whether a server is scheduled
+func (s *Scheduler) Has(id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, existed := s.entries[id]
+	return existed
+}
+
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+func (s *Scheduler) Metrics() *Metrics {
+	return s.metrics
+}
+
+// Run dispatches due checks until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		wait := time.Hour
+		if len(s.queue) > 0 {
+			wait = s.queue[0].due.Sub(s.now())
+		}
+		s.mu.Unlock()
+
+		if wait > 0 {
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(wait)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+				continue
+			case <-timer.C:
+			}
+		}
+
+		s.mu.Lock()
+		if len(s.queue) == 0 || s.now().Before(s.queue[0].due) {
+			s.mu.Unlock()
+			continue
+		}
+		e := heap.Pop(&s.queue).(*entry)
+		address := e.address
+		due := e.due
+		s.mu.Unlock()
+
+		// Blocks while every worker is busy, that wait shows up as lateness
+		select {
+		case <-ctx.Done():
+			return
+		case s.semaphore <- struct{}{}:
+		}
+
+		s.metrics.Observe(s.now().Sub(due))
+
+		go func(e *entry, address *pb.AddressInfo) {
+			defer func() { <-s.semaphore }()
+
+			s.check(address)
+			s.reschedule(e)
+		}(e, address)
+	}
+}
+
+func (s *Scheduler) reschedule(e *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.removed {
+		return
+	}
+
+	now := s.now()
+	interval := intervalOf(e.address)
+
+	e.nominal = e.nominal.Add(interval)
+	if !e.nominal.After(now) {
+		// Fell behind by more than an interval, skip the missed slots
+		missed := now.Sub(e.nominal)/interval + 1
+		e.nominal = e.nominal.Add(missed * interval)
+	}
+
+	e.due = e.nominal
+	if s.jitter > 0 {
+		maxJitter := int64(float64(interval) * s.jitter)
+		if maxJitter > 0 {
+			e.due = e.due.Add(time.Duration(rand.Int63n(maxJitter)))
+		}
+	}
+
+	heap.Push(&s.queue, e)
+	s.notify()
+}
+
+// remove expects s.mu to be held
+func (s *Scheduler) remove(id int64) {
+	e, existed := s.entries[id]
+	if !existed {
+		return
+	}
+
+	e.removed = true
+	if e.index >= 0 {
+		heap.Remove(&s.queue, e.index)
+	}
+	delete(s.entries, id)
+	s.notify()
+}
+
+// notify wakes the dispatch loop up to recompute its wait, it never blocks
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func intervalOf(address *pb.AddressInfo) time.Duration {
+	if address.CheckIntervalSeconds <= 0 {
+		return defaultInterval
+	}
+	return time.Duration(address.CheckIntervalSeconds) * time.Second
+}
+
+// entryQueue implements heap.Interface ordered by due time
+type entryQueue []*entry
+
+func (q entryQueue) Len() int { return len(q) }
+
+func (q entryQueue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+
+func (q entryQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *entryQueue) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *entryQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*q = old[:n-1]
+	return e
+}
+
+/*
+Metrics tracks how late checks start compared to their due time.
+Percentiles are computed over the last window observations.
+*/
+type Metrics struct {
+	mu          sync.Mutex
+	window      []time.Duration
+	next        int
+	filled      bool
+	checks      uint64
+	maxLateness time.Duration
+}
+
+type Stats struct {
+	Checks      uint64
+	P50Lateness time.Duration
+	P99Lateness time.Duration
+	MaxLateness time.Duration
+}
+
+func NewMetrics(window int) *Metrics {
+	return &Metrics{
+		window: make([]time.Duration, window),
+	}
+}
+
+func (m *Metrics) Observe(lateness time.Duration) {
+	if lateness < 0 {
+		lateness = 0
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checks++
+	if lateness > m.maxLateness {
+		m.maxLateness = lateness
+	}
+
+	m.window[m.next] = lateness
+	m.next++
+	if m.next == len(m.window) {
+		m.next = 0
+		m.filled = true
+	}
+}
+
+// Snapshot returns the current stats and resets the maximum
+func (m *Metrics) Snapshot() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	size := m.next
+	if m.filled {
+		size = len(m.window)
+	}
+	samples := make([]time.Duration, size)
+	copy(samples, m.window[:size])
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	stats := Stats{
+		Checks:      m.checks,
+		MaxLateness: m.maxLateness,
+	}
+	if size > 0 {
+		stats.P50Lateness = samples[size*50/100]
+		stats.P99Lateness = samples[size*99/100]
+	}
+
+	m.maxLateness = 0
+	return stats
+}
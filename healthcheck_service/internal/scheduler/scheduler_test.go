@@ -0,0 +1,247 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"healthcheck_service/pb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func newTestScheduler(check CheckFunc, maxConcurrency int) (*Scheduler, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	s := NewScheduler(check, maxConcurrency, 0)
+	s.now = clock.Now
+	return s, clock
+}
+
+// setDue moves a waiting server to the given slot of its interval grid
+func setDue(s *Scheduler, id int64, due time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entries[id]
+	e.nominal = due
+	e.due = due
+	heap.Fix(&s.queue, e.index)
+}
+
+// advance moves the clock and wakes the dispatch loop up like a new server would
+func advance(s *Scheduler, clock *fakeClock, now time.Time) {
+	clock.Set(now)
+	s.mu.Lock()
+	s.notify()
+	s.mu.Unlock()
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func address(id int64, intervalSeconds int32) *pb.AddressInfo {
+	return &pb.AddressInfo{Id: id, Address: "10.0.0.1:80", CheckIntervalSeconds: intervalSeconds}
+}
+
+func TestScheduler_DispatchesInDueOrder(t *testing.T) {
+	var mu sync.Mutex
+	var checked []int64
+	s, clock := newTestScheduler(func(address *pb.AddressInfo) {
+		mu.Lock()
+		defer mu.Unlock()
+		checked = append(checked, address.Id)
+	}, 1)
+
+	start := clock.Now()
+	for _, id := range []int64{1, 2, 3} {
+		s.Upsert(address(id, 60))
+	}
+	setDue(s, 1, start.Add(3*time.Second))
+	setDue(s, 2, start.Add(1*time.Second))
+	setDue(s, 3, start.Add(2*time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	// Nothing is due yet
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	assert.Empty(t, checked)
+	mu.Unlock()
+
+	advance(s, clock, start.Add(5*time.Second))
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(checked) == 3
+	})
+
+	mu.Lock()
+	assert.Equal(t, []int64{2, 3, 1}, checked)
+	mu.Unlock()
+}
+
+func TestScheduler_RescheduleStaysOnTheGrid(t *testing.T) {
+	s, clock := newTestScheduler(func(*pb.AddressInfo) {}, 1)
+	start := clock.Now()
+
+	tests := []struct {
+		name        string
+		finishedAt  time.Duration
+		wantNominal time.Duration
+	}{
+		{"Finished within the interval", 4 * time.Second, 10 * time.Second},
+		{"Finished exactly on the next slot", 10 * time.Second, 20 * time.Second},
+		{"Fell behind by several slots", 35 * time.Second, 40 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.Upsert(address(1, 10))
+			setDue(s, 1, start)
+
+			// What Run does when the server is due
+			s.mu.Lock()
+			e := heap.Pop(&s.queue).(*entry)
+			s.mu.Unlock()
+
+			clock.Set(start.Add(tt.finishedAt))
+			s.reschedule(e)
+
+			assert.Equal(t, start.Add(tt.wantNominal), e.nominal)
+			assert.Equal(t, e.nominal, e.due)
+			assert.GreaterOrEqual(t, e.index, 0)
+
+			s.Remove(1)
+		})
+	}
+}
+
+func TestScheduler_UpsertShorterInterval(t *testing.T) {
+	s, clock := newTestScheduler(func(*pb.AddressInfo) {}, 1)
+	start := clock.Now()
+
+	s.Upsert(address(1, 3600))
+	setDue(s, 1, start.Add(30*time.Minute))
+
+	// A shorter interval does not wait for the old slot
+	s.Upsert(address(1, 60))
+	assert.Equal(t, start.Add(time.Minute), s.entries[1].due)
+
+	// A longer one keeps the current slot
+	s.Upsert(address(1, 600))
+	assert.Equal(t, start.Add(time.Minute), s.entries[1].due)
+	assert.Equal(t, int32(600), s.entries[1].address.CheckIntervalSeconds)
+}
+
+func TestScheduler_ChangesWhileRunning(t *testing.T) {
+	release := make(chan struct{})
+	running := make(chan int64, 10)
+	var mu sync.Mutex
+	checks := make(map[int64]int)
+
+	s, clock := newTestScheduler(func(address *pb.AddressInfo) {
+		mu.Lock()
+		checks[address.Id]++
+		mu.Unlock()
+
+		running <- address.Id
+		if address.Id == 1 {
+			<-release
+		}
+	}, 2)
+
+	start := clock.Now()
+	for _, id := range []int64{1, 2, 3} {
+		s.Upsert(address(id, 60))
+	}
+	setDue(s, 1, start.Add(time.Second))
+	setDue(s, 2, start.Add(2*time.Minute))
+	setDue(s, 3, start.Add(2*time.Minute))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	advance(s, clock, start.Add(time.Second))
+	assert.Equal(t, int64(1), <-running)
+
+	// Server 1 is removed while its check runs, server 3 is dropped by a refresh
+	s.Remove(1)
+	s.Retain([]int64{1, 2})
+	assert.False(t, s.Has(1))
+	assert.False(t, s.Has(3))
+
+	// A new server added to the running loop is picked up
+	s.Upsert(address(4, 60))
+	setDue(s, 4, start.Add(90*time.Second))
+
+	close(release)
+	advance(s, clock, start.Add(3*time.Minute))
+
+	seen := map[int64]bool{}
+	for len(seen) < 2 {
+		seen[<-running] = true
+	}
+	assert.Equal(t, map[int64]bool{2: true, 4: true}, seen)
+
+	// The finished check of server 1 must not put it back
+	waitFor(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.queue) == 2
+	})
+	assert.Equal(t, 2, s.Len())
+
+	mu.Lock()
+	assert.Equal(t, 1, checks[1])
+	assert.Equal(t, 0, checks[3])
+	mu.Unlock()
+}
+
+func TestMetrics_Snapshot(t *testing.T) {
+	metrics := NewMetrics(4)
+	for _, lateness := range []time.Duration{-time.Second, 10 * time.Millisecond, 30 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond} {
+		metrics.Observe(lateness)
+	}
+
+	stats := metrics.Snapshot()
+	assert.Equal(t, uint64(5), stats.Checks)
+	assert.Equal(t, 40*time.Millisecond, stats.MaxLateness)
+	// The negative lateness was the oldest sample and rolled out of the window
+	assert.Equal(t, 30*time.Millisecond, stats.P50Lateness)
+	assert.Equal(t, 40*time.Millisecond, stats.P99Lateness)
+
+	// The maximum resets, the window does not
+	stats = metrics.Snapshot()
+	assert.Equal(t, time.Duration(0), stats.MaxLateness)
+	assert.Equal(t, 30*time.Millisecond, stats.P50Lateness)
+}
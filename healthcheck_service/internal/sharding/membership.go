@@ -0,0 +1,136 @@
+package sharding
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/flashhhhh/pkg/logging"
+	"github.com/redis/go-redis/v9"
+)
+
+const instancesKeyPrefix = "healthcheck_instances:"
+
+/*
+	Membership keeps the set of live healthcheck instances of a probe location in a Redis
+	sorted set. Every location checks all servers, the instances of one location share them.
+
+	Every instance holds a lease, the member's score being the lease expiry in unix ms,
+	and renews it every ttl/3. Expired members are dropped by whoever looks next, so an
+	instance that dies loses its servers after at most ttl. While the members change the
+	instances may briefly disagree on the ring, a server can then be checked twice or
+	skipped for one refresh.
+*/
+type Membership struct {
+	redis      *redis.Client
+	key        string
+	instanceID string
+	ttl        time.Duration
+
+	mu      sync.RWMutex
+	members []string
+	ring    *Ring
+
+	changed chan struct{}
+}
+
+func NewMembership(redisClient *redis.Client, location, instanceID string, ttl time.Duration) *Membership {
+	return &Membership{
+		redis:      redisClient,
+		key:        instancesKeyPrefix + location,
+		instanceID: instanceID,
+		ttl:        ttl,
+		ring:       NewRing([]string{instanceID}, defaultVirtualNodes),
+		members:    []string{instanceID},
+		changed:    make(chan struct{}, 1),
+	}
+}
+
+// Start takes the first lease synchronously, then renews it until ctx is cancelled
+func (m *Membership) Start(ctx context.Context) error {
+	if err := m.renew(ctx); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(m.ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.renew(ctx); err != nil {
+					logging.LogMessage("healthcheck_service", "Failed to renew shard lease: "+err.Error(), "ERROR")
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Leave gives the lease back so the other instances take over right away
+func (m *Membership) Leave(ctx context.Context) error {
+	return m.redis.ZRem(ctx, m.key, m.instanceID).Err()
+}
+
+// Owns reports whether this instance should check a server
+func (m *Membership) Owns(serverID int64) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.ring.Owner(serverID) == m.instanceID
+}
+
+// Changed receives a value every time the set of instances changes
+func (m *Membership) Changed() <-chan struct{} {
+	return m.changed
+}
+
+func (m *Membership) Members() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return slices.Clone(m.members)
+}
+
+func (m *Membership) renew(ctx context.Context) error {
+	now := time.Now()
+	expiry := now.Add(m.ttl).UnixMilli()
+	nowMs := strconv.FormatInt(now.UnixMilli(), 10)
+
+	pipe := m.redis.TxPipeline()
+	pipe.ZAdd(ctx, m.key, redis.Z{Score: float64(expiry), Member: m.instanceID})
+	pipe.ZRemRangeByScore(ctx, m.key, "-inf", nowMs)
+	live := pipe.ZRangeByScore(ctx, m.key, &redis.ZRangeBy{Min: "(" + nowMs, Max: "+inf"})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	members := live.Val()
+	if !slices.Contains(members, m.instanceID) {
+		members = append(members, m.instanceID)
+	}
+	slices.Sort(members)
+
+	m.mu.Lock()
+	if slices.Equal(members, m.members) {
+		m.mu.Unlock()
+		return nil
+	}
+	m.members = members
+	m.ring = NewRing(members, defaultVirtualNodes)
+	m.mu.Unlock()
+
+	logging.LogMessage("healthcheck_service", "Healthcheck instances changed, now "+strconv.Itoa(len(members))+" instance(s)", "INFO")
+
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// The lease scores depend on the clock, only the command and the key are compared
+func matchCommandAndKey(expected, actual []interface{}) error {
+	if len(expected) == 0 || len(actual) == 0 || expected[0] != actual[0] {
+		return fmt.Errorf("expected %v, got %v", expected, actual)
+	}
+	if len(expected) > 1 && (len(actual) < 2 || expected[1] != actual[1]) {
+		return fmt.Errorf("expected %v, got %v", expected, actual)
+	}
+	return nil
+}
+
+func expectRenew(mock redismock.ClientMock, live []string) {
+	m := mock.CustomMatch(matchCommandAndKey)
+	m.ExpectTxPipeline()
+	m.ExpectZAdd("healthcheck_instances:hanoi", redis.Z{}).SetVal(0)
+	m.ExpectZRemRangeByScore("healthcheck_instances:hanoi", "-inf", "").SetVal(0)
+	m.ExpectZRangeByScore("healthcheck_instances:hanoi", &redis.ZRangeBy{}).SetVal(live)
+	m.ExpectTxPipelineExec()
+}
+
+func changed(membership *Membership) bool {
+	select {
+	case <-membership.Changed():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestMembership_Renew(t *testing.T) {
+	redisCli, mock := redismock.NewClientMock()
+	membership := NewMembership(redisCli, "hanoi", "hc-2", time.Hour)
+
+	// Alone until the first lease, the instance owns every server
+	assert.Equal(t, []string{"hc-2"}, membership.Members())
+	assert.True(t, membership.Owns(1))
+
+	t.Run("Joins the live instances", func(t *testing.T) {
+		expectRenew(mock, []string{"hc-1", "hc-2", "hc-3"})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		assert.NoError(t, membership.Start(ctx))
+
+		assert.Equal(t, []string{"hc-1", "hc-2", "hc-3"}, membership.Members())
+		assert.True(t, changed(membership))
+
+		ring := NewRing([]string{"hc-1", "hc-2", "hc-3"}, defaultVirtualNodes)
+		for id := int64(1); id <= 100; id++ {
+			assert.Equal(t, ring.Owner(id) == "hc-2", membership.Owns(id))
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Same members do not signal a change", func(t *testing.T) {
+		expectRenew(mock, []string{"hc-3", "hc-1", "hc-2"})
+
+		assert.NoError(t, membership.renew(context.Background()))
+		assert.False(t, changed(membership))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Expired instance is dropped", func(t *testing.T) {
+		expectRenew(mock, []string{"hc-2", "hc-3"})
+
+		assert.NoError(t, membership.renew(context.Background()))
+		assert.Equal(t, []string{"hc-2", "hc-3"}, membership.Members())
+		assert.True(t, changed(membership))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Keeps itself when its own lease is missing", func(t *testing.T) {
+		expectRenew(mock, []string{"hc-3"})
+
+		assert.NoError(t, membership.renew(context.Background()))
+		assert.Equal(t, []string{"hc-2", "hc-3"}, membership.Members())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Leave gives the lease back", func(t *testing.T) {
+		mock.ExpectZRem("healthcheck_instances:hanoi", "hc-2").SetVal(1)
+
+		assert.NoError(t, membership.Leave(context.Background()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMembership_StartFails(t *testing.T) {
+	redisCli, mock := redismock.NewClientMock()
+	membership := NewMembership(redisCli, "hanoi", "hc-1", time.Hour)
+
+	mock.ExpectTxPipeline()
+	mock.ExpectZAdd("healthcheck_instances:hanoi").SetErr(fmt.Errorf("connection refused"))
+
+	assert.Error(t, membership.Start(context.Background()))
+	assert.Equal(t, []string{"hc-1"}, membership.Members())
+}
@@ -0,0 +1,68 @@
+package sharding
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// Virtual nodes per instance, enough to keep shards within a few percent of each other
+const defaultVirtualNodes = 128
+
+/*
+	Ring is a consistent hash ring of healthcheck instances.
+	Adding or removing an instance only moves the servers of its own arcs.
+*/
+type Ring struct {
+	hashes []uint32
+	owners map[uint32]string
+}
+
+func NewRing(instances []string, virtualNodes int) *Ring {
+	if virtualNodes <= 0 {
+		virtualNodes = defaultVirtualNodes
+	}
+
+	ring := &Ring{
+		hashes: make([]uint32, 0, len(instances)*virtualNodes),
+		owners: make(map[uint32]string, len(instances)*virtualNodes),
+	}
+
+	for _, instance := range instances {
+		for i := 0; i < virtualNodes; i++ {
+			hash := hashKey(instance + "#" + strconv.Itoa(i))
+			// On the rare collision the smallest instance id wins, so every instance agrees
+			if owner, existed := ring.owners[hash]; existed && owner < instance {
+				continue
+			}
+			if _, existed := ring.owners[hash]; !existed {
+				ring.hashes = append(ring.hashes, hash)
+			}
+			ring.owners[hash] = instance
+		}
+	}
+
+	sort.Slice(ring.hashes, func(i, j int) bool { return ring.hashes[i] < ring.hashes[j] })
+	return ring
+}
+
+// Owner returns the instance responsible for a server, "" on an empty ring
+func (r *Ring) Owner(serverID int64) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+
+	hash := hashKey(strconv.FormatInt(serverID, 10))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= hash })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[r.hashes[i]]
+}
+
+// FNV spreads short, similar keys like "1", "2", ... poorly around the ring, SHA-256 does not
+func hashKey(key string) uint32 {
+	sum := sha256.Sum256([]byte(key))
+	return binary.BigEndian.Uint32(sum[:4])
+}
@@ -0,0 +1,131 @@
+package sharding
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const numServers = 10000
+
+func owners(ring *Ring) map[int64]string {
+	owned := make(map[int64]string, numServers)
+	for id := int64(1); id <= numServers; id++ {
+		owned[id] = ring.Owner(id)
+	}
+	return owned
+}
+
+func TestRing_Owner(t *testing.T) {
+	tests := []struct {
+		name      string
+		instances []string
+		serverID  int64
+		want      string
+	}{
+		{"Empty ring", nil, 1, ""},
+		{"Single instance owns every server", []string{"hc-1"}, 42, "hc-1"},
+		{"Single instance owns negative ids too", []string{"hc-1"}, -7, "hc-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewRing(tt.instances, 0).Owner(tt.serverID))
+		})
+	}
+}
+
+func TestRing_SameOwnersOnEveryInstance(t *testing.T) {
+	// Instances build the ring from the member list in any order, they must agree
+	a := NewRing([]string{"hc-1", "hc-2", "hc-3"}, 0)
+	b := NewRing([]string{"hc-3", "hc-1", "hc-2"}, 0)
+
+	assert.Equal(t, owners(a), owners(b))
+}
+
+func TestRing_Balance(t *testing.T) {
+	tests := []struct {
+		name      string
+		instances int
+	}{
+		{"Two instances", 2},
+		{"Three instances", 3},
+		{"Five instances", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instances := make([]string, tt.instances)
+			for i := range instances {
+				instances[i] = "hc-" + strconv.Itoa(i)
+			}
+
+			counts := make(map[string]int)
+			for _, owner := range owners(NewRing(instances, 0)) {
+				counts[owner]++
+			}
+
+			assert.Len(t, counts, tt.instances)
+			fair := numServers / tt.instances
+			for instance, count := range counts {
+				assert.InDelta(t, fair, count, float64(fair)*0.25, "instance %s owns %d servers", instance, count)
+			}
+		})
+	}
+}
+
+func TestRing_Rebalance(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []string
+		after  []string
+	}{
+		{"Instance joins", []string{"hc-1", "hc-2", "hc-3"}, []string{"hc-1", "hc-2", "hc-3", "hc-4"}},
+		{"Instance leaves", []string{"hc-1", "hc-2", "hc-3", "hc-4"}, []string{"hc-1", "hc-2", "hc-4"}},
+		{"Single instance scales out", []string{"hc-1"}, []string{"hc-1", "hc-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := owners(NewRing(tt.before, 0))
+			after := owners(NewRing(tt.after, 0))
+
+			joined := difference(tt.after, tt.before)
+			left := difference(tt.before, tt.after)
+
+			moved := 0
+			for id, owner := range before {
+				if after[id] == owner {
+					continue
+				}
+				moved++
+				// Only the servers of the leaving instance move, and only to the joining one
+				assert.True(t, left[owner] || joined[after[id]], "server %d moved from %s to %s", id, owner, after[id])
+			}
+
+			// About one instance worth of servers moves, never the whole fleet
+			larger := len(tt.before)
+			if len(tt.after) > larger {
+				larger = len(tt.after)
+			}
+			assert.Less(t, moved, numServers/larger*3/2)
+			assert.Greater(t, moved, 0)
+		})
+	}
+}
+
+func difference(a, b []string) map[string]bool {
+	inB := make(map[string]bool, len(b))
+	for _, instance := range b {
+		inB[instance] = true
+	}
+
+	diff := make(map[string]bool)
+	for _, instance := range a {
+		if !inB[instance] {
+			diff[instance] = true
+		}
+	}
+	return diff
+}
@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.5
+// 	protoc-gen-go v1.36.6
 // 	protoc        v3.21.12
 // source: proto/server.proto
 
@@ -57,9 +57,11 @@ func (*EmptyRequest) Descriptor() ([]byte, []int) {
 	return file_proto_server_proto_rawDescGZIP(), []int{0}
 }
 
+// serverTime (unix milliseconds) is the updatedSince of the first GetUpdatedAddresses call
 type AddressesResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Addresses     []*AddressInfo         `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	ServerTime    int64                  `protobuf:"varint,2,opt,name=serverTime,proto3" json:"serverTime,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -101,17 +103,137 @@ func (x *AddressesResponse) GetAddresses() []*AddressInfo {
 	return nil
 }
 
-type AddressInfo struct {
+func (x *AddressesResponse) GetServerTime() int64 {
+	if x != nil {
+		return x.ServerTime
+	}
+	return 0
+}
+
+// Servers created or updated after updatedSince (unix milliseconds), plus the ids of every
+// current server so callers can drop deleted ones. serverTime is the next updatedSince.
+type GetUpdatedAddressesRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	UpdatedSince  int64                  `protobuf:"varint,1,opt,name=updatedSince,proto3" json:"updatedSince,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *GetUpdatedAddressesRequest) Reset() {
+	*x = GetUpdatedAddressesRequest{}
+	mi := &file_proto_server_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUpdatedAddressesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUpdatedAddressesRequest) ProtoMessage() {}
+
+func (x *GetUpdatedAddressesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_server_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUpdatedAddressesRequest.ProtoReflect.Descriptor instead.
+func (*GetUpdatedAddressesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_server_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetUpdatedAddressesRequest) GetUpdatedSince() int64 {
+	if x != nil {
+		return x.UpdatedSince
+	}
+	return 0
+}
+
+type GetUpdatedAddressesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Addresses     []*AddressInfo         `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	Ids           []int64                `protobuf:"varint,2,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	ServerTime    int64                  `protobuf:"varint,3,opt,name=serverTime,proto3" json:"serverTime,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUpdatedAddressesResponse) Reset() {
+	*x = GetUpdatedAddressesResponse{}
+	mi := &file_proto_server_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUpdatedAddressesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUpdatedAddressesResponse) ProtoMessage() {}
+
+func (x *GetUpdatedAddressesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_server_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUpdatedAddressesResponse.ProtoReflect.Descriptor instead.
+func (*GetUpdatedAddressesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_server_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetUpdatedAddressesResponse) GetAddresses() []*AddressInfo {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+func (x *GetUpdatedAddressesResponse) GetIds() []int64 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *GetUpdatedAddressesResponse) GetServerTime() int64 {
+	if x != nil {
+		return x.ServerTime
+	}
+	return 0
+}
+
+type AddressInfo struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Id                   int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Address              string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	ProbeType            string                 `protobuf:"bytes,3,opt,name=probeType,proto3" json:"probeType,omitempty"` // tcp, http, https, udp or tls
+	ProbePath            string                 `protobuf:"bytes,4,opt,name=probePath,proto3" json:"probePath,omitempty"`
+	ProbeExpectedStatus  int32                  `protobuf:"varint,5,opt,name=probeExpectedStatus,proto3" json:"probeExpectedStatus,omitempty"`
+	ProbeExpectedBody    string                 `protobuf:"bytes,6,opt,name=probeExpectedBody,proto3" json:"probeExpectedBody,omitempty"`
+	CheckIntervalSeconds int32                  `protobuf:"varint,7,opt,name=checkIntervalSeconds,proto3" json:"checkIntervalSeconds,omitempty"`
+	TimeoutMs            int32                  `protobuf:"varint,8,opt,name=timeoutMs,proto3" json:"timeoutMs,omitempty"`
+	Retries              int32                  `protobuf:"varint,9,opt,name=retries,proto3" json:"retries,omitempty"` // extra attempts before reporting the server Off
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
 func (x *AddressInfo) Reset() {
 	*x = AddressInfo{}
-	mi := &file_proto_server_proto_msgTypes[2]
+	mi := &file_proto_server_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -123,7 +245,7 @@ func (x *AddressInfo) String() string {
 func (*AddressInfo) ProtoMessage() {}
 
 func (x *AddressInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_server_proto_msgTypes[2]
+	mi := &file_proto_server_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -136,7 +258,7 @@ func (x *AddressInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AddressInfo.ProtoReflect.Descriptor instead.
 func (*AddressInfo) Descriptor() ([]byte, []int) {
-	return file_proto_server_proto_rawDescGZIP(), []int{2}
+	return file_proto_server_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *AddressInfo) GetId() int64 {
@@ -153,34 +275,87 @@ func (x *AddressInfo) GetAddress() string {
 	return ""
 }
 
+func (x *AddressInfo) GetProbeType() string {
+	if x != nil {
+		return x.ProbeType
+	}
+	return ""
+}
+
+func (x *AddressInfo) GetProbePath() string {
+	if x != nil {
+		return x.ProbePath
+	}
+	return ""
+}
+
+func (x *AddressInfo) GetProbeExpectedStatus() int32 {
+	if x != nil {
+		return x.ProbeExpectedStatus
+	}
+	return 0
+}
+
+func (x *AddressInfo) GetProbeExpectedBody() string {
+	if x != nil {
+		return x.ProbeExpectedBody
+	}
+	return ""
+}
+
+func (x *AddressInfo) GetCheckIntervalSeconds() int32 {
+	if x != nil {
+		return x.CheckIntervalSeconds
+	}
+	return 0
+}
+
+func (x *AddressInfo) GetTimeoutMs() int32 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+func (x *AddressInfo) GetRetries() int32 {
+	if x != nil {
+		return x.Retries
+	}
+	return 0
+}
+
 var File_proto_server_proto protoreflect.FileDescriptor
 
-var file_proto_server_proto_rawDesc = string([]byte{
-	0x0a, 0x12, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x1d, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x61, 0x64, 0x6d,
-	0x69, 0x6e, 0x69, 0x73, 0x74, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x73, 0x65, 0x72, 0x76,
-	0x69, 0x63, 0x65, 0x22, 0x0e, 0x0a, 0x0c, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x22, 0x5d, 0x0a, 0x11, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x48, 0x0a, 0x09, 0x61, 0x64, 0x64, 0x72,
-	0x65, 0x73, 0x73, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2a, 0x2e, 0x73, 0x65,
-	0x72, 0x76, 0x65, 0x72, 0x5f, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x69, 0x73, 0x74, 0x72, 0x61, 0x74,
-	0x69, 0x6f, 0x6e, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x41, 0x64, 0x64, 0x72,
-	0x65, 0x73, 0x73, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x09, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
-	0x65, 0x73, 0x22, 0x37, 0x0a, 0x0b, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x49, 0x6e, 0x66,
-	0x6f, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x69,
-	0x64, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x32, 0x8f, 0x01, 0x0a, 0x1b,
-	0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x69, 0x73, 0x74, 0x72, 0x61,
-	0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x70, 0x0a, 0x0f, 0x47,
-	0x65, 0x74, 0x41, 0x6c, 0x6c, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x12, 0x2b,
-	0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x69, 0x73, 0x74,
-	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x45,
-	0x6d, 0x70, 0x74, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x30, 0x2e, 0x73, 0x65,
-	0x72, 0x76, 0x65, 0x72, 0x5f, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x69, 0x73, 0x74, 0x72, 0x61, 0x74,
-	0x69, 0x6f, 0x6e, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x41, 0x64, 0x64, 0x72,
-	0x65, 0x73, 0x73, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x06, 0x5a,
-	0x04, 0x2e, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
-})
+const file_proto_server_proto_rawDesc = "" +
+	"\n" +
+	"\x12proto/server.proto\x12\x1dserver_administration_service\"\x0e\n" +
+	"\fEmptyRequest\"}\n" +
+	"\x11AddressesResponse\x12H\n" +
+	"\taddresses\x18\x01 \x03(\v2*.server_administration_service.AddressInfoR\taddresses\x12\x1e\n" +
+	"\n" +
+	"serverTime\x18\x02 \x01(\x03R\n" +
+	"serverTime\"@\n" +
+	"\x1aGetUpdatedAddressesRequest\x12\"\n" +
+	"\fupdatedSince\x18\x01 \x01(\x03R\fupdatedSince\"\x99\x01\n" +
+	"\x1bGetUpdatedAddressesResponse\x12H\n" +
+	"\taddresses\x18\x01 \x03(\v2*.server_administration_service.AddressInfoR\taddresses\x12\x10\n" +
+	"\x03ids\x18\x02 \x03(\x03R\x03ids\x12\x1e\n" +
+	"\n" +
+	"serverTime\x18\x03 \x01(\x03R\n" +
+	"serverTime\"\xbf\x02\n" +
+	"\vAddressInfo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x1c\n" +
+	"\tprobeType\x18\x03 \x01(\tR\tprobeType\x12\x1c\n" +
+	"\tprobePath\x18\x04 \x01(\tR\tprobePath\x120\n" +
+	"\x13probeExpectedStatus\x18\x05 \x01(\x05R\x13probeExpectedStatus\x12,\n" +
+	"\x11probeExpectedBody\x18\x06 \x01(\tR\x11probeExpectedBody\x122\n" +
+	"\x14checkIntervalSeconds\x18\a \x01(\x05R\x14checkIntervalSeconds\x12\x1c\n" +
+	"\ttimeoutMs\x18\b \x01(\x05R\ttimeoutMs\x12\x18\n" +
+	"\aretries\x18\t \x01(\x05R\aretries2\x9e\x02\n" +
+	"\x1bServerAdministrationService\x12p\n" +
+	"\x0fGetAllAddresses\x12+.server_administration_service.EmptyRequest\x1a0.server_administration_service.AddressesResponse\x12\x8c\x01\n" +
+	"\x13GetUpdatedAddresses\x129.server_administration_service.GetUpdatedAddressesRequest\x1a:.server_administration_service.GetUpdatedAddressesResponseB\x06Z\x04./pbb\x06proto3"
 
 var (
 	file_proto_server_proto_rawDescOnce sync.Once
@@ -194,21 +369,26 @@ func file_proto_server_proto_rawDescGZIP() []byte {
 	return file_proto_server_proto_rawDescData
 }
 
-var file_proto_server_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_proto_server_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
 var file_proto_server_proto_goTypes = []any{
-	(*EmptyRequest)(nil),      // 0: server_administration_service.EmptyRequest
-	(*AddressesResponse)(nil), // 1: server_administration_service.AddressesResponse
-	(*AddressInfo)(nil),       // 2: server_administration_service.AddressInfo
+	(*EmptyRequest)(nil),                // 0: server_administration_service.EmptyRequest
+	(*AddressesResponse)(nil),           // 1: server_administration_service.AddressesResponse
+	(*GetUpdatedAddressesRequest)(nil),  // 2: server_administration_service.GetUpdatedAddressesRequest
+	(*GetUpdatedAddressesResponse)(nil), // 3: server_administration_service.GetUpdatedAddressesResponse
+	(*AddressInfo)(nil),                 // 4: server_administration_service.AddressInfo
 }
 var file_proto_server_proto_depIdxs = []int32{
-	2, // 0: server_administration_service.AddressesResponse.addresses:type_name -> server_administration_service.AddressInfo
-	0, // 1: server_administration_service.ServerAdministrationService.GetAllAddresses:input_type -> server_administration_service.EmptyRequest
-	1, // 2: server_administration_service.ServerAdministrationService.GetAllAddresses:output_type -> server_administration_service.AddressesResponse
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	4, // 0: server_administration_service.AddressesResponse.addresses:type_name -> server_administration_service.AddressInfo
+	4, // 1: server_administration_service.GetUpdatedAddressesResponse.addresses:type_name -> server_administration_service.AddressInfo
+	0, // 2: server_administration_service.ServerAdministrationService.GetAllAddresses:input_type -> server_administration_service.EmptyRequest
+	2, // 3: server_administration_service.ServerAdministrationService.GetUpdatedAddresses:input_type -> server_administration_service.GetUpdatedAddressesRequest
+	1, // 4: server_administration_service.ServerAdministrationService.GetAllAddresses:output_type -> server_administration_service.AddressesResponse
+	3, // 5: server_administration_service.ServerAdministrationService.GetUpdatedAddresses:output_type -> server_administration_service.GetUpdatedAddressesResponse
+	4, // [4:6] is the sub-list for method output_type
+	2, // [2:4] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_proto_server_proto_init() }
@@ -222,7 +402,7 @@ func file_proto_server_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_server_proto_rawDesc), len(file_proto_server_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   5,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
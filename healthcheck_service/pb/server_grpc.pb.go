@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ServerAdministrationService_GetAllAddresses_FullMethodName = "/server_administration_service.ServerAdministrationService/GetAllAddresses"
+	ServerAdministrationService_GetAllAddresses_FullMethodName     = "/server_administration_service.ServerAdministrationService/GetAllAddresses"
+	ServerAdministrationService_GetUpdatedAddresses_FullMethodName = "/server_administration_service.ServerAdministrationService/GetUpdatedAddresses"
 )
 
 // ServerAdministrationServiceClient is the client API for ServerAdministrationService service.
@@ -27,6 +28,7 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ServerAdministrationServiceClient interface {
 	GetAllAddresses(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*AddressesResponse, error)
+	GetUpdatedAddresses(ctx context.Context, in *GetUpdatedAddressesRequest, opts ...grpc.CallOption) (*GetUpdatedAddressesResponse, error)
 }
 
 type serverAdministrationServiceClient struct {
@@ -47,11 +49,22 @@ func (c *serverAdministrationServiceClient) GetAllAddresses(ctx context.Context,
 	return out, nil
 }
 
+func (c *serverAdministrationServiceClient) GetUpdatedAddresses(ctx context.Context, in *GetUpdatedAddressesRequest, opts ...grpc.CallOption) (*GetUpdatedAddressesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUpdatedAddressesResponse)
+	err := c.cc.Invoke(ctx, ServerAdministrationService_GetUpdatedAddresses_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ServerAdministrationServiceServer is the server API for ServerAdministrationService service.
 // All implementations must embed UnimplementedServerAdministrationServiceServer
 // for forward compatibility.
 type ServerAdministrationServiceServer interface {
 	GetAllAddresses(context.Context, *EmptyRequest) (*AddressesResponse, error)
+	GetUpdatedAddresses(context.Context, *GetUpdatedAddressesRequest) (*GetUpdatedAddressesResponse, error)
 	mustEmbedUnimplementedServerAdministrationServiceServer()
 }
 
@@ -65,6 +78,9 @@ type UnimplementedServerAdministrationServiceServer struct{}
 func (UnimplementedServerAdministrationServiceServer) GetAllAddresses(context.Context, *EmptyRequest) (*AddressesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetAllAddresses not implemented")
 }
+func (UnimplementedServerAdministrationServiceServer) GetUpdatedAddresses(context.Context, *GetUpdatedAddressesRequest) (*GetUpdatedAddressesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUpdatedAddresses not implemented")
+}
 func (UnimplementedServerAdministrationServiceServer) mustEmbedUnimplementedServerAdministrationServiceServer() {
 }
 func (UnimplementedServerAdministrationServiceServer) testEmbeddedByValue() {}
@@ -105,6 +121,24 @@ func _ServerAdministrationService_GetAllAddresses_Handler(srv interface{}, ctx c
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ServerAdministrationService_GetUpdatedAddresses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUpdatedAddressesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServerAdministrationServiceServer).GetUpdatedAddresses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ServerAdministrationService_GetUpdatedAddresses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServerAdministrationServiceServer).GetUpdatedAddresses(ctx, req.(*GetUpdatedAddressesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ServerAdministrationService_ServiceDesc is the grpc.ServiceDesc for ServerAdministrationService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -116,6 +150,10 @@ var ServerAdministrationService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetAllAddresses",
 			Handler:    _ServerAdministrationService_GetAllAddresses_Handler,
 		},
+		{
+			MethodName: "GetUpdatedAddresses",
+			Handler:    _ServerAdministrationService_GetUpdatedAddresses_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/server.proto",
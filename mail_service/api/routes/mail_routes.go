@@ -8,6 +8,12 @@ import (
 	"github.com/gorilla/mux"
 )
 
-func RegisterRoutes(r *mux.Router, mailHandler handler.MailHandler) {
+func RegisterRoutes(r *mux.Router, mailHandler handler.MailHandler, alertHandler handler.AlertHandler) {
 	r.Handle("/manual_send", middleware.AdminMiddleware(http.HandlerFunc(mailHandler.ManualSendEmail))).Methods("POST")
-}
\ No newline at end of file
+
+	r.Handle("/alerts/rules", middleware.AdminMiddleware(http.HandlerFunc(alertHandler.CreateRule))).Methods("POST")
+	r.Handle("/alerts/rules", middleware.AdminMiddleware(http.HandlerFunc(alertHandler.GetRules))).Methods("GET")
+	r.Handle("/alerts/rules", middleware.AdminMiddleware(http.HandlerFunc(alertHandler.UpdateRule))).Methods("PUT")
+	r.Handle("/alerts/rules", middleware.AdminMiddleware(http.HandlerFunc(alertHandler.DeleteRule))).Methods("DELETE")
+	r.Handle("/alerts/active", middleware.AdminMiddleware(http.HandlerFunc(alertHandler.GetActiveAlerts))).Methods("GET")
+}
@@ -5,10 +5,12 @@ import (
 	"mail_service/infrastructure/grpc"
 	grpcclient "mail_service/internal/grpc_client"
 	"mail_service/internal/handler"
+	"mail_service/internal/repository"
 	"mail_service/internal/service"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/flashhhhh/pkg/env"
@@ -47,6 +49,7 @@ func main() {
 	resp, err := client.GetServerInformation(
 		time.Now().Add(-24*time.Hour).Unix(),
 		time.Now().Unix(),
+		nil,
 	)
 
 	if err != nil {
@@ -56,9 +59,37 @@ func main() {
 
 	println("Server Information:", resp)
 
-	mailService := service.NewMailService(client)
+	reportSubscriptionsPath := env.GetEnv("REPORT_SUBSCRIPTIONS_PATH", filepath.Join(currentPath, "data", "report_subscriptions.json"))
+	reportSubscriptions, err := repository.LoadReportSubscriptions(reportSubscriptionsPath)
+	if err != nil {
+		logging.LogMessage("mail_service", "Failed to load report subscriptions from "+reportSubscriptionsPath+": "+err.Error(), "FATAL")
+		logging.LogMessage("mail_service", "Exiting the program...", "FATAL")
+		os.Exit(1)
+	}
+	logging.LogMessage("mail_service", "Loaded "+strconv.Itoa(len(reportSubscriptions))+" report subscriptions", "INFO")
+
+	mailService := service.NewMailService(client, reportSubscriptions)
 	mailHandler := handler.NewMailHandler(mailService)
 
+	// Initialize alerting
+	alertRulesPath := env.GetEnv("ALERT_RULES_PATH", filepath.Join(currentPath, "data", "alert_rules.json"))
+	alertRuleRepository, err := repository.NewAlertRuleRepository(alertRulesPath)
+	if err != nil {
+		logging.LogMessage("mail_service", "Failed to load alert rules from "+alertRulesPath+": "+err.Error(), "FATAL")
+		logging.LogMessage("mail_service", "Exiting the program...", "FATAL")
+		os.Exit(1)
+	}
+
+	alertService := service.NewAlertService(alertRuleRepository, client, mailService)
+	alertHandler := handler.NewAlertHandler(alertService)
+
+	alertIntervalSeconds, err := strconv.Atoi(env.GetEnv("ALERT_EVALUATION_INTERVAL_SECONDS", "60"))
+	if err != nil || alertIntervalSeconds <= 0 {
+		alertIntervalSeconds = 60
+	}
+	logging.LogMessage("mail_service", "Evaluating alert rules every "+strconv.Itoa(alertIntervalSeconds)+" seconds", "INFO")
+	go alertService.StartEvaluating(time.Duration(alertIntervalSeconds) * time.Second)
+
 	// Send email report every 24 hours
 	go func () {
 		mailService.StartEmailReport(time.Now().Add(-24*time.Hour).Unix(), time.Now().Unix())
@@ -70,7 +101,7 @@ func main() {
 	serverPort := env.GetEnv("MAIL_SERVICE_PORT", "10003")
 
 	r := mux.NewRouter()
-	routes.RegisterRoutes(r, mailHandler)
+	routes.RegisterRoutes(r, mailHandler, alertHandler)
 
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"}, // Allow all origins, change this for security
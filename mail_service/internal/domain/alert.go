@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+const (
+	// Fires for every server whose latest Threshold checks are all Off
+	RuleServerDown = "server_down"
+	// Fires when the fleet uptime of the last WindowMinutes is below Threshold percent
+	RuleFleetUptimeBelow = "fleet_uptime_below"
+)
+
+type AlertRule struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Type            string   `json:"type"`
+	Threshold       float64  `json:"threshold"`
+	WindowMinutes   int      `json:"window_minutes"`
+	CooldownMinutes int      `json:"cooldown_minutes"`
+	Recipients      []string `json:"recipients"`
+	Enabled         bool     `json:"enabled"`
+}
+
+/*
+	An alert is one firing rule for one subject,
+	either a single server ("server:<id>") or the whole fleet ("fleet")
+*/
+type Alert struct {
+	RuleID   string    `json:"rule_id"`
+	RuleName string    `json:"rule_name"`
+	Subject  string    `json:"subject"`
+	Message  string    `json:"message"`
+	FiredAt  time.Time `json:"fired_at"`
+	// False when the firing email was suppressed by the rule cooldown
+	Notified bool `json:"notified"`
+}
@@ -0,0 +1,11 @@
+package domain
+
+/*
+	A report subscription sends the daily report of the servers having all of
+	the labels, e.g. {"team": "payments"}, to its recipients
+*/
+type ReportSubscription struct {
+	Name       string            `json:"name"`
+	Recipients []string          `json:"recipients"`
+	Labels     map[string]string `json:"labels"`
+}
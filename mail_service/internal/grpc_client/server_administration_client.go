@@ -6,7 +6,9 @@ import (
 )
 
 type ServerAdministrationServiceClient interface {
-	GetServerInformation(startTime, endTime int64) (*pb.GetServerInformationResponse, error)
+	// labels scope the information to the servers having all of them, nil for every server
+	GetServerInformation(startTime, endTime int64, labels map[string]string) (*pb.GetServerInformationResponse, error)
+	GetDownServers(minConsecutiveChecks int64) (*pb.GetDownServersResponse, error)
 }
 
 type serverAdministrationServiceClient struct {
@@ -19,17 +21,33 @@ func NewServerAdministrationServiceClient(client pb.ServerAdministrationServiceC
 	}
 }
 
-func (s *serverAdministrationServiceClient) GetServerInformation(startTime, endTime int64) (*pb.GetServerInformationResponse, error) {
+func (s *serverAdministrationServiceClient) GetServerInformation(startTime, endTime int64, labels map[string]string) (*pb.GetServerInformationResponse, error) {
 	resp, err := s.client.GetServerInformation(
 		context.Background(),
 		&pb.GetServerInformationRequest{
 			StartTime: startTime,
 			EndTime:   endTime,
+			Labels:    labels,
 		},
 	)
 
 	if err != nil {
-		panic("Failed to get server information: " + err.Error())
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (s *serverAdministrationServiceClient) GetDownServers(minConsecutiveChecks int64) (*pb.GetDownServersResponse, error) {
+	resp, err := s.client.GetDownServers(
+		context.Background(),
+		&pb.GetDownServersRequest{
+			MinConsecutiveChecks: minConsecutiveChecks,
+		},
+	)
+
+	if err != nil {
+		return nil, err
 	}
 
 	return resp, nil
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"mail_service/internal/domain"
+	"mail_service/internal/repository"
+	"mail_service/internal/service"
+	"net/http"
+)
+
+type AlertHandler interface {
+	CreateRule(w http.ResponseWriter, r *http.Request)
+	GetRules(w http.ResponseWriter, r *http.Request)
+	UpdateRule(w http.ResponseWriter, r *http.Request)
+	DeleteRule(w http.ResponseWriter, r *http.Request)
+	GetActiveAlerts(w http.ResponseWriter, r *http.Request)
+}
+
+type alertHandler struct {
+	alertService service.AlertService
+}
+
+func NewAlertHandler(alertService service.AlertService) AlertHandler {
+	return &alertHandler{
+		alertService: alertService,
+	}
+}
+
+func (h *alertHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var rule domain.AlertRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	createdRule, err := h.alertService.CreateRule(rule)
+	if errors.Is(err, service.ErrInvalidRule) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create alert rule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, createdRule)
+}
+
+func (h *alertHandler) GetRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.alertService.GetRules()
+	if err != nil {
+		http.Error(w, "Failed to get alert rules: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rules)
+}
+
+func (h *alertHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	var rule domain.AlertRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	rule.ID = id
+
+	err := h.alertService.UpdateRule(rule)
+	if errors.Is(err, service.ErrInvalidRule) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, repository.ErrRuleNotFound) {
+		http.Error(w, "Alert rule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update alert rule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rule)
+}
+
+func (h *alertHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	err := h.alertService.DeleteRule(id)
+	if errors.Is(err, repository.ErrRuleNotFound) {
+		http.Error(w, "Alert rule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete alert rule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Alert rule deleted successfully"))
+}
+
+func (h *alertHandler) GetActiveAlerts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.alertService.GetActiveAlerts())
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	response, err := json.Marshal(data)
+	if err != nil {
+		http.Error(w, "Failed to process response data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(response)
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"mail_service/internal/domain"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var ErrRuleNotFound = errors.New("alert rule not found")
+
+type AlertRuleRepository interface {
+	CreateRule(rule *domain.AlertRule) error
+	GetRules() ([]domain.AlertRule, error)
+	GetRule(id string) (*domain.AlertRule, error)
+	UpdateRule(rule *domain.AlertRule) error
+	DeleteRule(id string) error
+}
+
+/*
+	mail_service has no database, so the rules are kept in memory
+	and written back to a JSON file after every change
+*/
+type alertRuleRepository struct {
+	path  string
+	mu    sync.RWMutex
+	rules map[string]domain.AlertRule
+}
+
+func NewAlertRuleRepository(path string) (AlertRuleRepository, error) {
+	repo := &alertRuleRepository{
+		path:  path,
+		rules: make(map[string]domain.AlertRule),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return repo, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []domain.AlertRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range rules {
+		repo.rules[rule.ID] = rule
+	}
+
+	return repo, nil
+}
+
+func (r *alertRuleRepository) CreateRule(rule *domain.AlertRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rules[rule.ID] = *rule
+	return r.save()
+}
+
+func (r *alertRuleRepository) GetRules() ([]domain.AlertRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sortedRules(), nil
+}
+
+func (r *alertRuleRepository) GetRule(id string) (*domain.AlertRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rule, existed := r.rules[id]
+	if !existed {
+		return nil, ErrRuleNotFound
+	}
+	return &rule, nil
+}
+
+func (r *alertRuleRepository) UpdateRule(rule *domain.AlertRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, existed := r.rules[rule.ID]; !existed {
+		return ErrRuleNotFound
+	}
+
+	r.rules[rule.ID] = *rule
+	return r.save()
+}
+
+func (r *alertRuleRepository) DeleteRule(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, existed := r.rules[id]; !existed {
+		return ErrRuleNotFound
+	}
+
+	delete(r.rules, id)
+	return r.save()
+}
+
+func (r *alertRuleRepository) sortedRules() []domain.AlertRule {
+	rules := make([]domain.AlertRule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		rules = append(rules, rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+// save must be called with the write lock held
+func (r *alertRuleRepository) save() error {
+	data, err := json.MarshalIndent(r.sortedRules(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a half written rules file
+	tmpPath := r.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, r.path)
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mail_service/internal/domain"
+	"os"
+)
+
+/*
+	Subscriptions are edited by hand, so they are read once at startup.
+	A missing file means that only the administrator gets the report.
+*/
+func LoadReportSubscriptions(path string) ([]domain.ReportSubscription, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []domain.ReportSubscription{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var subscriptions []domain.ReportSubscription
+	if err := json.Unmarshal(data, &subscriptions); err != nil {
+		return nil, err
+	}
+
+	for _, subscription := range subscriptions {
+		if len(subscription.Recipients) == 0 {
+			return nil, fmt.Errorf("report subscription %q has no recipients", subscription.Name)
+		}
+		// Without labels it would just be a copy of the administrator report
+		if len(subscription.Labels) == 0 {
+			return nil, fmt.Errorf("report subscription %q has no labels", subscription.Name)
+		}
+	}
+
+	return subscriptions, nil
+}
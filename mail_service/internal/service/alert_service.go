@@ -0,0 +1,323 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	grpcclient "mail_service/internal/grpc_client"
+	"mail_service/internal/domain"
+	"mail_service/internal/repository"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/flashhhhh/pkg/env"
+	"github.com/flashhhhh/pkg/logging"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidRule is returned when an alert rule fails validation
+var ErrInvalidRule = errors.New("invalid alert rule")
+
+type AlertService interface {
+	CreateRule(rule domain.AlertRule) (*domain.AlertRule, error)
+	GetRules() ([]domain.AlertRule, error)
+	UpdateRule(rule domain.AlertRule) error
+	DeleteRule(id string) error
+	GetActiveAlerts() []domain.Alert
+
+	EvaluateRules() error
+	StartEvaluating(interval time.Duration)
+}
+
+type alertService struct {
+	ruleRepository repository.AlertRuleRepository
+	grpcClient     grpcclient.ServerAdministrationServiceClient
+	mailService    MailService
+
+	mu           sync.Mutex
+	activeAlerts map[string]*domain.Alert
+	// Kept after an alert resolves so the cooldown also covers flapping servers
+	lastNotified map[string]time.Time
+}
+
+func NewAlertService(ruleRepository repository.AlertRuleRepository, grpcClient grpcclient.ServerAdministrationServiceClient, mailService MailService) AlertService {
+	return &alertService{
+		ruleRepository: ruleRepository,
+		grpcClient:     grpcClient,
+		mailService:    mailService,
+		activeAlerts:   make(map[string]*domain.Alert),
+		lastNotified:   make(map[string]time.Time),
+	}
+}
+
+func (a *alertService) CreateRule(rule domain.AlertRule) (*domain.AlertRule, error) {
+	rule.ID = uuid.New().String()
+	if err := validateRule(&rule); err != nil {
+		return nil, err
+	}
+
+	if err := a.ruleRepository.CreateRule(&rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (a *alertService) GetRules() ([]domain.AlertRule, error) {
+	return a.ruleRepository.GetRules()
+}
+
+func (a *alertService) UpdateRule(rule domain.AlertRule) error {
+	if err := validateRule(&rule); err != nil {
+		return err
+	}
+
+	return a.ruleRepository.UpdateRule(&rule)
+}
+
+func (a *alertService) DeleteRule(id string) error {
+	if err := a.ruleRepository.DeleteRule(id); err != nil {
+		return err
+	}
+
+	// Drop the alerts of the deleted rule without sending a resolution
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key, alert := range a.activeAlerts {
+		if alert.RuleID == id {
+			delete(a.activeAlerts, key)
+		}
+	}
+	return nil
+}
+
+func (a *alertService) GetActiveAlerts() []domain.Alert {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	alerts := make([]domain.Alert, 0, len(a.activeAlerts))
+	for _, alert := range a.activeAlerts {
+		alerts = append(alerts, *alert)
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].FiredAt.Before(alerts[j].FiredAt)
+	})
+	return alerts
+}
+
+func (a *alertService) StartEvaluating(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := a.EvaluateRules(); err != nil {
+			logging.LogMessage("mail_service", "Failed to evaluate alert rules: "+err.Error(), "ERROR")
+		}
+	}
+}
+
+/*
+	Evaluates every enabled rule once. An alert sends one email when it fires
+	and one when it resolves; while it keeps firing nothing is sent again.
+*/
+func (a *alertService) EvaluateRules() error {
+	rules, err := a.ruleRepository.GetRules()
+	if err != nil {
+		return err
+	}
+
+	// Query server_administration_service before taking the lock
+	enabledRules := make(map[string]bool)
+	firingByRule := make(map[string]map[string]string)
+	var evaluationErr error
+
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		enabledRules[rule.ID] = true
+
+		firing, err := a.firingSubjects(rule)
+		if err != nil {
+			// Keep the current alerts of the rule untouched, we do not know if they resolved
+			logging.LogMessage("mail_service", "Failed to evaluate alert rule "+rule.ID+": "+err.Error(), "ERROR")
+			evaluationErr = err
+			continue
+		}
+		firingByRule[rule.ID] = firing
+	}
+
+	a.mu.Lock()
+	var notifications []alertNotification
+	now := time.Now()
+	for _, rule := range rules {
+		if firing, evaluated := firingByRule[rule.ID]; evaluated {
+			notifications = append(notifications, a.applyRule(rule, firing, now)...)
+		}
+	}
+
+	// Alerts of disabled or deleted rules disappear silently
+	for key, alert := range a.activeAlerts {
+		if !enabledRules[alert.RuleID] {
+			delete(a.activeAlerts, key)
+		}
+	}
+	a.mu.Unlock()
+
+	for _, notification := range notifications {
+		a.notify(notification)
+	}
+
+	return evaluationErr
+}
+
+// firingSubjects returns the message of every subject for which the rule currently fires
+func (a *alertService) firingSubjects(rule domain.AlertRule) (map[string]string, error) {
+	firing := make(map[string]string)
+
+	switch rule.Type {
+	case domain.RuleServerDown:
+		resp, err := a.grpcClient.GetDownServers(int64(rule.Threshold))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, server := range resp.Servers {
+			subject := "server:" + strconv.FormatInt(server.Id, 10)
+			firing[subject] = fmt.Sprintf("Server %s (id %d) has been Off for the last %d checks, latest check at %s.",
+				server.ServerName, server.Id, server.ConsecutiveDownChecks,
+				time.Unix(server.LastCheck, 0).Format(time.RFC1123))
+		}
+
+	case domain.RuleFleetUptimeBelow:
+		endTime := time.Now()
+		startTime := endTime.Add(-time.Duration(rule.WindowMinutes) * time.Minute)
+
+		resp, err := a.grpcClient.GetServerInformation(startTime.Unix(), endTime.Unix(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		uptimePercent := float64(resp.MeanUptimeRatio) * 100
+		if uptimePercent < rule.Threshold {
+			firing["fleet"] = fmt.Sprintf("Fleet uptime over the last %d minutes is %.2f%%, below the %.2f%% threshold. %d of %d servers are currently off.",
+				rule.WindowMinutes, uptimePercent, rule.Threshold, resp.NumOffServers, resp.NumServers)
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: unknown rule type %s", ErrInvalidRule, rule.Type)
+	}
+
+	return firing, nil
+}
+
+type alertNotification struct {
+	recipients []string
+	subject    string
+	message    string
+}
+
+// applyRule must be called with a.mu held, it returns the emails to send
+func (a *alertService) applyRule(rule domain.AlertRule, firing map[string]string, now time.Time) []alertNotification {
+	cooldown := time.Duration(rule.CooldownMinutes) * time.Minute
+	var notifications []alertNotification
+
+	for subject, message := range firing {
+		key := rule.ID + "|" + subject
+
+		alert, existed := a.activeAlerts[key]
+		if existed {
+			alert.Message = message
+			if alert.Notified {
+				continue
+			}
+		} else {
+			alert = &domain.Alert{
+				RuleID:   rule.ID,
+				RuleName: rule.Name,
+				Subject:  subject,
+				Message:  message,
+				FiredAt:  now,
+			}
+			a.activeAlerts[key] = alert
+		}
+
+		// A suppressed alert that keeps firing is sent once its cooldown is over
+		if lastNotified, notified := a.lastNotified[key]; notified && now.Sub(lastNotified) < cooldown {
+			if !existed {
+				logging.LogMessage("mail_service", "Alert "+key+" fired during its cooldown, email suppressed", "INFO")
+			}
+			continue
+		}
+
+		notifications = append(notifications, alertNotification{rule.Recipients, "[FIRING] " + rule.Name, message})
+		alert.Notified = true
+		a.lastNotified[key] = now
+	}
+
+	for key, alert := range a.activeAlerts {
+		if alert.RuleID != rule.ID {
+			continue
+		}
+		if _, stillFiring := firing[alert.Subject]; stillFiring {
+			continue
+		}
+
+		delete(a.activeAlerts, key)
+
+		// Nobody heard about a suppressed alert, so its resolution is not sent either
+		if alert.Notified {
+			message := fmt.Sprintf("Resolved after %s: %s", now.Sub(alert.FiredAt).Round(time.Second), alert.Message)
+			notifications = append(notifications, alertNotification{rule.Recipients, "[RESOLVED] " + rule.Name, message})
+			a.lastNotified[key] = now
+		}
+	}
+
+	return notifications
+}
+
+func (a *alertService) notify(notification alertNotification) {
+	recipients := notification.recipients
+	if len(recipients) == 0 {
+		recipients = []string{env.GetEnv("SERVER_ADMINISTRATOR_EMAIL", "")}
+	}
+
+	body := "Dear server administrator,\n\n" + notification.message + "\n\nBest regards,\nYour Server Monitoring System"
+	for _, to := range recipients {
+		if err := a.mailService.SendEmail(to, notification.subject, body); err != nil {
+			logging.LogMessage("mail_service", "Failed to send alert email to "+to+": "+err.Error(), "ERROR")
+		}
+	}
+}
+
+func validateRule(rule *domain.AlertRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+
+	switch rule.Type {
+	case domain.RuleServerDown:
+		if rule.Threshold < 1 || rule.Threshold > 100 || rule.Threshold != float64(int(rule.Threshold)) {
+			return fmt.Errorf("%w: threshold of %s must be a whole number of checks between 1 and 100", ErrInvalidRule, rule.Type)
+		}
+	case domain.RuleFleetUptimeBelow:
+		if rule.Threshold <= 0 || rule.Threshold > 100 {
+			return fmt.Errorf("%w: threshold of %s must be a percentage between 0 and 100", ErrInvalidRule, rule.Type)
+		}
+		if rule.WindowMinutes == 0 {
+			rule.WindowMinutes = 60
+		}
+		if rule.WindowMinutes < 0 {
+			return fmt.Errorf("%w: window_minutes must be positive", ErrInvalidRule)
+		}
+	default:
+		return fmt.Errorf("%w: unknown rule type %s", ErrInvalidRule, rule.Type)
+	}
+
+	if rule.CooldownMinutes < 0 {
+		return fmt.Errorf("%w: cooldown_minutes must not be negative", ErrInvalidRule)
+	}
+
+	return nil
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"errors"
+	"mail_service/internal/domain"
+	"mail_service/pb"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockRuleRepository struct {
+	mock.Mock
+}
+
+func (m *mockRuleRepository) CreateRule(rule *domain.AlertRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+
+func (m *mockRuleRepository) GetRules() ([]domain.AlertRule, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.AlertRule), args.Error(1)
+}
+
+func (m *mockRuleRepository) GetRule(id string) (*domain.AlertRule, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AlertRule), args.Error(1)
+}
+
+func (m *mockRuleRepository) UpdateRule(rule *domain.AlertRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+
+func (m *mockRuleRepository) DeleteRule(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+type mockGrpcClient struct {
+	mock.Mock
+}
+
+func (m *mockGrpcClient) GetServerInformation(startTime, endTime int64, labels map[string]string) (*pb.GetServerInformationResponse, error) {
+	args := m.Called(startTime, endTime, labels)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*pb.GetServerInformationResponse), args.Error(1)
+}
+
+func (m *mockGrpcClient) GetDownServers(minConsecutiveChecks int64) (*pb.GetDownServersResponse, error) {
+	args := m.Called(minConsecutiveChecks)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*pb.GetDownServersResponse), args.Error(1)
+}
+
+type mockMailService struct {
+	mock.Mock
+}
+
+func (m *mockMailService) StartEmailReport(startTime int64, endTime int64) error {
+	args := m.Called(startTime, endTime)
+	return args.Error(0)
+}
+
+func (m *mockMailService) PrepareEmail(to string, subject string, report ServerReport) error {
+	args := m.Called(to, subject, report)
+	return args.Error(0)
+}
+
+func (m *mockMailService) SendEmail(to string, subject string, body string) error {
+	args := m.Called(to, subject, body)
+	return args.Error(0)
+}
+
+func newTestAlertService() (*alertService, *mockRuleRepository, *mockGrpcClient, *mockMailService) {
+	ruleRepository := new(mockRuleRepository)
+	grpcClient := new(mockGrpcClient)
+	mailService := new(mockMailService)
+	return NewAlertService(ruleRepository, grpcClient, mailService).(*alertService), ruleRepository, grpcClient, mailService
+}
+
+func serverDownRule() domain.AlertRule {
+	return domain.AlertRule{
+		ID:              "rule-1",
+		Name:            "Server down",
+		Type:            domain.RuleServerDown,
+		Threshold:       3,
+		CooldownMinutes: 10,
+		Recipients:      []string{"admin@example.com"},
+		Enabled:         true,
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    domain.AlertRule
+		wantErr bool
+	}{
+		{"Valid server down rule", domain.AlertRule{Name: "down", Type: domain.RuleServerDown, Threshold: 3}, false},
+		{"Missing name", domain.AlertRule{Type: domain.RuleServerDown, Threshold: 3}, true},
+		{"Fractional number of checks", domain.AlertRule{Name: "down", Type: domain.RuleServerDown, Threshold: 2.5}, true},
+		{"Too many checks", domain.AlertRule{Name: "down", Type: domain.RuleServerDown, Threshold: 101}, true},
+		{"Valid fleet uptime rule", domain.AlertRule{Name: "uptime", Type: domain.RuleFleetUptimeBelow, Threshold: 99.5}, false},
+		{"Uptime above 100 percent", domain.AlertRule{Name: "uptime", Type: domain.RuleFleetUptimeBelow, Threshold: 101}, true},
+		{"Negative window", domain.AlertRule{Name: "uptime", Type: domain.RuleFleetUptimeBelow, Threshold: 90, WindowMinutes: -1}, true},
+		{"Negative cooldown", domain.AlertRule{Name: "down", Type: domain.RuleServerDown, Threshold: 3, CooldownMinutes: -1}, true},
+		{"Unknown type", domain.AlertRule{Name: "down", Type: "cpu_high", Threshold: 3}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRule(&tt.rule)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRule)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("Default window", func(t *testing.T) {
+		rule := domain.AlertRule{Name: "uptime", Type: domain.RuleFleetUptimeBelow, Threshold: 90}
+		assert.NoError(t, validateRule(&rule))
+		assert.Equal(t, 60, rule.WindowMinutes)
+	})
+}
+
+func TestApplyRule(t *testing.T) {
+	rule := serverDownRule()
+	now := time.Now()
+	firing := map[string]string{"server:1": "Server 1 is down"}
+
+	t.Run("Fires and resolves once", func(t *testing.T) {
+		a, _, _, _ := newTestAlertService()
+
+		notifications := a.applyRule(rule, firing, now)
+		assert.Len(t, notifications, 1)
+		assert.Equal(t, "[FIRING] Server down", notifications[0].subject)
+		assert.True(t, a.activeAlerts["rule-1|server:1"].Notified)
+
+		// Still firing, nothing is sent again
+		notifications = a.applyRule(rule, firing, now.Add(time.Minute))
+		assert.Empty(t, notifications)
+
+		notifications = a.applyRule(rule, map[string]string{}, now.Add(2*time.Minute))
+		assert.Len(t, notifications, 1)
+		assert.Equal(t, "[RESOLVED] Server down", notifications[0].subject)
+		assert.Empty(t, a.activeAlerts)
+	})
+
+	t.Run("Suppressed during the cooldown", func(t *testing.T) {
+		a, _, _, _ := newTestAlertService()
+		a.lastNotified["rule-1|server:1"] = now.Add(-time.Minute)
+
+		notifications := a.applyRule(rule, firing, now)
+		assert.Empty(t, notifications)
+		assert.False(t, a.activeAlerts["rule-1|server:1"].Notified)
+
+		// Nobody heard about it, so its resolution is not sent either
+		notifications = a.applyRule(rule, map[string]string{}, now.Add(time.Minute))
+		assert.Empty(t, notifications)
+	})
+
+	t.Run("Suppressed alert is sent once the cooldown expires", func(t *testing.T) {
+		a, _, _, _ := newTestAlertService()
+		a.lastNotified["rule-1|server:1"] = now.Add(-time.Minute)
+
+		notifications := a.applyRule(rule, firing, now)
+		assert.Empty(t, notifications)
+
+		notifications = a.applyRule(rule, firing, now.Add(5*time.Minute))
+		assert.Empty(t, notifications)
+
+		notifications = a.applyRule(rule, firing, now.Add(9*time.Minute))
+		assert.Len(t, notifications, 1)
+		assert.Equal(t, "[FIRING] Server down", notifications[0].subject)
+		assert.True(t, a.activeAlerts["rule-1|server:1"].Notified)
+		assert.Equal(t, now.Add(9*time.Minute), a.lastNotified["rule-1|server:1"])
+
+		// Sent only once while it keeps firing
+		notifications = a.applyRule(rule, firing, now.Add(30*time.Minute))
+		assert.Empty(t, notifications)
+	})
+
+	t.Run("Other rules are left alone", func(t *testing.T) {
+		a, _, _, _ := newTestAlertService()
+		a.activeAlerts["rule-2|fleet"] = &domain.Alert{RuleID: "rule-2", Subject: "fleet", Notified: true}
+
+		notifications := a.applyRule(rule, map[string]string{}, now)
+		assert.Empty(t, notifications)
+		assert.Contains(t, a.activeAlerts, "rule-2|fleet")
+	})
+}
+
+func TestEvaluateRules(t *testing.T) {
+	t.Run("Sends an email per recipient for a down server", func(t *testing.T) {
+		a, ruleRepository, grpcClient, mailService := newTestAlertService()
+
+		rule := serverDownRule()
+		rule.Recipients = []string{"a@example.com", "b@example.com"}
+		disabled := serverDownRule()
+		disabled.ID = "rule-2"
+		disabled.Enabled = false
+
+		ruleRepository.On("GetRules").Return([]domain.AlertRule{rule, disabled}, nil)
+		grpcClient.On("GetDownServers", int64(3)).Return(&pb.GetDownServersResponse{
+			Servers: []*pb.DownServer{
+				{Id: 1, ServerName: "web-1", ConsecutiveDownChecks: 3, LastCheck: time.Now().Unix()},
+			},
+		}, nil).Once()
+		mailService.On("SendEmail", "a@example.com", "[FIRING] Server down", mock.Anything).Return(nil).Once()
+		mailService.On("SendEmail", "b@example.com", "[FIRING] Server down", mock.Anything).Return(nil).Once()
+
+		assert.NoError(t, a.EvaluateRules())
+		assert.Len(t, a.GetActiveAlerts(), 1)
+
+		// The server recovered
+		grpcClient.On("GetDownServers", int64(3)).Return(&pb.GetDownServersResponse{}, nil).Once()
+		mailService.On("SendEmail", "a@example.com", "[RESOLVED] Server down", mock.Anything).Return(nil).Once()
+		mailService.On("SendEmail", "b@example.com", "[RESOLVED] Server down", mock.Anything).Return(nil).Once()
+
+		assert.NoError(t, a.EvaluateRules())
+		assert.Empty(t, a.GetActiveAlerts())
+
+		grpcClient.AssertExpectations(t)
+		mailService.AssertExpectations(t)
+	})
+
+	t.Run("Fleet uptime below the threshold", func(t *testing.T) {
+		a, ruleRepository, grpcClient, mailService := newTestAlertService()
+
+		rule := domain.AlertRule{
+			ID:            "rule-3",
+			Name:          "Fleet uptime",
+			Type:          domain.RuleFleetUptimeBelow,
+			Threshold:     99,
+			WindowMinutes: 60,
+			Recipients:    []string{"admin@example.com"},
+			Enabled:       true,
+		}
+
+		ruleRepository.On("GetRules").Return([]domain.AlertRule{rule}, nil)
+		grpcClient.On("GetServerInformation", mock.Anything, mock.Anything, map[string]string(nil)).Return(&pb.GetServerInformationResponse{
+			NumServers:      10,
+			NumOffServers:   2,
+			MeanUptimeRatio: 0.8,
+		}, nil)
+		mailService.On("SendEmail", "admin@example.com", "[FIRING] Fleet uptime", mock.Anything).Return(nil).Once()
+
+		assert.NoError(t, a.EvaluateRules())
+
+		alerts := a.GetActiveAlerts()
+		assert.Len(t, alerts, 1)
+		assert.Equal(t, "fleet", alerts[0].Subject)
+		mailService.AssertExpectations(t)
+	})
+
+	t.Run("Keeps the alerts of a rule that failed to evaluate", func(t *testing.T) {
+		a, ruleRepository, grpcClient, mailService := newTestAlertService()
+
+		rule := serverDownRule()
+		a.activeAlerts["rule-1|server:1"] = &domain.Alert{RuleID: "rule-1", Subject: "server:1", Notified: true}
+
+		ruleRepository.On("GetRules").Return([]domain.AlertRule{rule}, nil)
+		grpcClient.On("GetDownServers", int64(3)).Return(nil, errors.New("unavailable"))
+
+		assert.Error(t, a.EvaluateRules())
+		assert.Len(t, a.GetActiveAlerts(), 1)
+		mailService.AssertNotCalled(t, "SendEmail", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Drops the alerts of disabled rules silently", func(t *testing.T) {
+		a, ruleRepository, _, mailService := newTestAlertService()
+
+		rule := serverDownRule()
+		rule.Enabled = false
+		a.activeAlerts["rule-1|server:1"] = &domain.Alert{RuleID: "rule-1", Subject: "server:1", Notified: true}
+
+		ruleRepository.On("GetRules").Return([]domain.AlertRule{rule}, nil)
+
+		assert.NoError(t, a.EvaluateRules())
+		assert.Empty(t, a.GetActiveAlerts())
+		mailService.AssertNotCalled(t, "SendEmail", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -1,8 +1,12 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	grpcclient "mail_service/internal/grpc_client"
+	"mail_service/internal/domain"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/flashhhhh/pkg/env"
@@ -11,39 +15,102 @@ import (
 
 type MailService interface {
 	StartEmailReport(startTime int64, endTime int64) (error)
-	PrepareEmail(to string, subject string, numServers, numOnServers, numOffServers int, meanUptimeRate float64) (error)
+	PrepareEmail(to string, subject string, report ServerReport) (error)
 	SendEmail(to string, subject string, body string) error
 }
 
+// ServerReport is the content of the daily report, On servers include the Degraded ones
+type ServerReport struct {
+	NumServers               int
+	NumOnServers             int
+	NumOffServers            int
+	NumDegradedServers       int
+	NumMaintenanceServers    int
+	NumUnknownServers        int
+	NumDecommissionedServers int
+	MeanUptimeRate           float64
+}
+
 type mailService struct{
 	grpcClient grpcclient.ServerAdministrationServiceClient
+	subscriptions []domain.ReportSubscription
 }
 
-func NewMailService(grpcClient grpcclient.ServerAdministrationServiceClient) MailService {
+func NewMailService(grpcClient grpcclient.ServerAdministrationServiceClient, subscriptions []domain.ReportSubscription) MailService {
 	return &mailService{
 		grpcClient: grpcClient,
+		subscriptions: subscriptions,
 	}
 }
 
+/*
+	The administrator gets the report of every server,
+	then each subscription gets the report of its own servers only
+*/
 func (mail *mailService) StartEmailReport(startTime int64, endTime int64) (error) {
-	resp, err := mail.grpcClient.GetServerInformation(startTime, endTime)
+	subject := "Daily Server Status Report for " + time.Now().Format("2006-01-02")
+
+	report, err := mail.getReport(startTime, endTime, nil)
 	if err != nil {
 		return err
 	}
 
-	numServers := int(resp.NumServers)
-	numOnServers := int(resp.NumOnServers)
-	numOffServers := int(resp.NumOffServers)
-	meanUptimeRate := float64(resp.MeanUptimeRatio)
-
 	to := env.GetEnv("SERVER_ADMINISTRATOR_EMAIL", "")
-	subject := "Daily Server Status Report for " + time.Now().Format("2006-01-02")
+	errs := []error{mail.PrepareEmail(to, subject, report)}
+
+	// A failing subscription must not prevent the others from getting their report
+	for _, subscription := range mail.subscriptions {
+		report, err := mail.getReport(startTime, endTime, subscription.Labels)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("report %s: %w", subscription.Name, err))
+			continue
+		}
+
+		subscriptionSubject := subject + " (" + formatLabels(subscription.Labels) + ")"
+		for _, recipient := range subscription.Recipients {
+			errs = append(errs, mail.PrepareEmail(recipient, subscriptionSubject, report))
+		}
+	}
+
+	return errors.Join(errs...)
+}
 
-	return mail.PrepareEmail(to, subject, numServers, numOnServers, numOffServers, meanUptimeRate)
+func (mail *mailService) getReport(startTime, endTime int64, labels map[string]string) (ServerReport, error) {
+	resp, err := mail.grpcClient.GetServerInformation(startTime, endTime, labels)
+	if err != nil {
+		return ServerReport{}, err
+	}
+
+	report := ServerReport{
+		NumServers:               int(resp.NumServers),
+		NumOnServers:             int(resp.NumOnServers),
+		NumOffServers:            int(resp.NumOffServers),
+		NumDegradedServers:       int(resp.NumDegradedServers),
+		NumMaintenanceServers:    int(resp.NumMaintenanceServers),
+		NumUnknownServers:        int(resp.NumUnknownServers),
+		NumDecommissionedServers: int(resp.NumDecommissionedServers),
+		MeanUptimeRate:           float64(resp.MeanUptimeRatio),
+	}
+
+	return report, nil
+}
+
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
 }
 
-func (mail *mailService) PrepareEmail(to string, subject string, numServers, numOnServers, numOffServers int, meanUptimeRate float64) (error) {
-	body := fmt.Sprintf("Dear server administrator,\n\nThe server status is as follows:\n\nTotal servers: %d\nServers on: %d\nServers off: %d\nMean uptime rate: %.2f%%\n\nBest regards,\nYour Server Monitoring System", numServers, numOnServers, numOffServers, meanUptimeRate * 100)
+/*
+	Servers in maintenance are counted apart and their planned downtime is left out of the uptime rate
+*/
+func (mail *mailService) PrepareEmail(to string, subject string, report ServerReport) (error) {
+	body := fmt.Sprintf("Dear server administrator,\n\nThe server status is as follows:\n\nTotal servers: %d\nServers on: %d (degraded: %d)\nServers off: %d\nServers in maintenance: %d\nServers not checked yet: %d\nDecommissioned servers: %d\nMean uptime rate: %.2f%%\n\nBest regards,\nYour Server Monitoring System",
+		report.NumServers, report.NumOnServers, report.NumDegradedServers, report.NumOffServers, report.NumMaintenanceServers,
+		report.NumUnknownServers, report.NumDecommissionedServers, report.MeanUptimeRate * 100)
 	return mail.SendEmail(to, subject, body)
 }
 
@@ -1,7 +1,7 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
 // 	protoc-gen-go v1.36.6
-// 	protoc        v3.12.4
+// 	protoc        v3.21.12
 // source: proto/server.proto
 
 package pb
@@ -57,9 +57,11 @@ func (*EmptyRequest) Descriptor() ([]byte, []int) {
 	return file_proto_server_proto_rawDescGZIP(), []int{0}
 }
 
+// serverTime (unix milliseconds) is the updatedSince of the first GetUpdatedAddresses call
 type AddressesResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Addresses     []*AddressInfo         `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	ServerTime    int64                  `protobuf:"varint,2,opt,name=serverTime,proto3" json:"serverTime,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -101,6 +103,13 @@ func (x *AddressesResponse) GetAddresses() []*AddressInfo {
 	return nil
 }
 
+func (x *AddressesResponse) GetServerTime() int64 {
+	if x != nil {
+		return x.ServerTime
+	}
+	return 0
+}
+
 type AddressInfo struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -155,8 +164,9 @@ func (x *AddressInfo) GetAddress() string {
 
 type GetServerInformationRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	StartTime     int64                  `protobuf:"varint,1,opt,name=startTime,proto3" json:"startTime,omitempty"` // timestamp in unix format
-	EndTime       int64                  `protobuf:"varint,2,opt,name=endTime,proto3" json:"endTime,omitempty"`     // timestamp in unix format
+	StartTime     int64                  `protobuf:"varint,1,opt,name=startTime,proto3" json:"startTime,omitempty"`                                                                    // timestamp in unix format
+	EndTime       int64                  `protobuf:"varint,2,opt,name=endTime,proto3" json:"endTime,omitempty"`                                                                        // timestamp in unix format
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // only servers having all of these labels, empty for every server
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -205,14 +215,25 @@ func (x *GetServerInformationRequest) GetEndTime() int64 {
 	return 0
 }
 
+func (x *GetServerInformationRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
 type GetServerInformationResponse struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	NumServers      int64                  `protobuf:"varint,1,opt,name=numServers,proto3" json:"numServers,omitempty"`
-	NumOnServers    int64                  `protobuf:"varint,2,opt,name=numOnServers,proto3" json:"numOnServers,omitempty"`
-	NumOffServers   int64                  `protobuf:"varint,3,opt,name=numOffServers,proto3" json:"numOffServers,omitempty"`
-	MeanUptimeRatio float32                `protobuf:"fixed32,4,opt,name=meanUptimeRatio,proto3" json:"meanUptimeRatio,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	state                    protoimpl.MessageState `protogen:"open.v1"`
+	NumServers               int64                  `protobuf:"varint,1,opt,name=numServers,proto3" json:"numServers,omitempty"`
+	NumOnServers             int64                  `protobuf:"varint,2,opt,name=numOnServers,proto3" json:"numOnServers,omitempty"`   // Up or Degraded
+	NumOffServers            int64                  `protobuf:"varint,3,opt,name=numOffServers,proto3" json:"numOffServers,omitempty"` // Down
+	MeanUptimeRatio          float32                `protobuf:"fixed32,4,opt,name=meanUptimeRatio,proto3" json:"meanUptimeRatio,omitempty"`
+	NumMaintenanceServers    int64                  `protobuf:"varint,5,opt,name=numMaintenanceServers,proto3" json:"numMaintenanceServers,omitempty"` // not counted in numOnServers / numOffServers
+	NumUnknownServers        int64                  `protobuf:"varint,6,opt,name=numUnknownServers,proto3" json:"numUnknownServers,omitempty"`         // never checked yet
+	NumDegradedServers       int64                  `protobuf:"varint,7,opt,name=numDegradedServers,proto3" json:"numDegradedServers,omitempty"`       // included in numOnServers
+	NumDecommissionedServers int64                  `protobuf:"varint,8,opt,name=numDecommissionedServers,proto3" json:"numDecommissionedServers,omitempty"`
+	unknownFields            protoimpl.UnknownFields
+	sizeCache                protoimpl.SizeCache
 }
 
 func (x *GetServerInformationResponse) Reset() {
@@ -273,30 +294,238 @@ func (x *GetServerInformationResponse) GetMeanUptimeRatio() float32 {
 	return 0
 }
 
+func (x *GetServerInformationResponse) GetNumMaintenanceServers() int64 {
+	if x != nil {
+		return x.NumMaintenanceServers
+	}
+	return 0
+}
+
+func (x *GetServerInformationResponse) GetNumUnknownServers() int64 {
+	if x != nil {
+		return x.NumUnknownServers
+	}
+	return 0
+}
+
+func (x *GetServerInformationResponse) GetNumDegradedServers() int64 {
+	if x != nil {
+		return x.NumDegradedServers
+	}
+	return 0
+}
+
+func (x *GetServerInformationResponse) GetNumDecommissionedServers() int64 {
+	if x != nil {
+		return x.NumDecommissionedServers
+	}
+	return 0
+}
+
+type GetDownServersRequest struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	MinConsecutiveChecks int64                  `protobuf:"varint,1,opt,name=minConsecutiveChecks,proto3" json:"minConsecutiveChecks,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *GetDownServersRequest) Reset() {
+	*x = GetDownServersRequest{}
+	mi := &file_proto_server_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDownServersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDownServersRequest) ProtoMessage() {}
+
+func (x *GetDownServersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_server_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDownServersRequest.ProtoReflect.Descriptor instead.
+func (*GetDownServersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_server_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetDownServersRequest) GetMinConsecutiveChecks() int64 {
+	if x != nil {
+		return x.MinConsecutiveChecks
+	}
+	return 0
+}
+
+type DownServer struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	Id                    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ServerName            string                 `protobuf:"bytes,2,opt,name=serverName,proto3" json:"serverName,omitempty"`
+	ConsecutiveDownChecks int64                  `protobuf:"varint,3,opt,name=consecutiveDownChecks,proto3" json:"consecutiveDownChecks,omitempty"`
+	LastCheck             int64                  `protobuf:"varint,4,opt,name=lastCheck,proto3" json:"lastCheck,omitempty"` // timestamp in unix format
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *DownServer) Reset() {
+	*x = DownServer{}
+	mi := &file_proto_server_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownServer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownServer) ProtoMessage() {}
+
+func (x *DownServer) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_server_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownServer.ProtoReflect.Descriptor instead.
+func (*DownServer) Descriptor() ([]byte, []int) {
+	return file_proto_server_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DownServer) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *DownServer) GetServerName() string {
+	if x != nil {
+		return x.ServerName
+	}
+	return ""
+}
+
+func (x *DownServer) GetConsecutiveDownChecks() int64 {
+	if x != nil {
+		return x.ConsecutiveDownChecks
+	}
+	return 0
+}
+
+func (x *DownServer) GetLastCheck() int64 {
+	if x != nil {
+		return x.LastCheck
+	}
+	return 0
+}
+
+type GetDownServersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Servers       []*DownServer          `protobuf:"bytes,1,rep,name=servers,proto3" json:"servers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDownServersResponse) Reset() {
+	*x = GetDownServersResponse{}
+	mi := &file_proto_server_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDownServersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDownServersResponse) ProtoMessage() {}
+
+func (x *GetDownServersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_server_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDownServersResponse.ProtoReflect.Descriptor instead.
+func (*GetDownServersResponse) Descriptor() ([]byte, []int) {
+	return file_proto_server_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetDownServersResponse) GetServers() []*DownServer {
+	if x != nil {
+		return x.Servers
+	}
+	return nil
+}
+
 var File_proto_server_proto protoreflect.FileDescriptor
 
 const file_proto_server_proto_rawDesc = "" +
 	"\n" +
 	"\x12proto/server.proto\x12\x1dserver_administration_service\"\x0e\n" +
-	"\fEmptyRequest\"]\n" +
+	"\fEmptyRequest\"}\n" +
 	"\x11AddressesResponse\x12H\n" +
-	"\taddresses\x18\x01 \x03(\v2*.server_administration_service.AddressInfoR\taddresses\"7\n" +
+	"\taddresses\x18\x01 \x03(\v2*.server_administration_service.AddressInfoR\taddresses\x12\x1e\n" +
+	"\n" +
+	"serverTime\x18\x02 \x01(\x03R\n" +
+	"serverTime\"7\n" +
 	"\vAddressInfo\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x18\n" +
-	"\aaddress\x18\x02 \x01(\tR\aaddress\"U\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\"\xf0\x01\n" +
 	"\x1bGetServerInformationRequest\x12\x1c\n" +
 	"\tstartTime\x18\x01 \x01(\x03R\tstartTime\x12\x18\n" +
-	"\aendTime\x18\x02 \x01(\x03R\aendTime\"\xb2\x01\n" +
+	"\aendTime\x18\x02 \x01(\x03R\aendTime\x12^\n" +
+	"\x06labels\x18\x03 \x03(\v2F.server_administration_service.GetServerInformationRequest.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x82\x03\n" +
 	"\x1cGetServerInformationResponse\x12\x1e\n" +
 	"\n" +
 	"numServers\x18\x01 \x01(\x03R\n" +
 	"numServers\x12\"\n" +
 	"\fnumOnServers\x18\x02 \x01(\x03R\fnumOnServers\x12$\n" +
 	"\rnumOffServers\x18\x03 \x01(\x03R\rnumOffServers\x12(\n" +
-	"\x0fmeanUptimeRatio\x18\x04 \x01(\x02R\x0fmeanUptimeRatio2\xa1\x02\n" +
+	"\x0fmeanUptimeRatio\x18\x04 \x01(\x02R\x0fmeanUptimeRatio\x124\n" +
+	"\x15numMaintenanceServers\x18\x05 \x01(\x03R\x15numMaintenanceServers\x12,\n" +
+	"\x11numUnknownServers\x18\x06 \x01(\x03R\x11numUnknownServers\x12.\n" +
+	"\x12numDegradedServers\x18\a \x01(\x03R\x12numDegradedServers\x12:\n" +
+	"\x18numDecommissionedServers\x18\b \x01(\x03R\x18numDecommissionedServers\"K\n" +
+	"\x15GetDownServersRequest\x122\n" +
+	"\x14minConsecutiveChecks\x18\x01 \x01(\x03R\x14minConsecutiveChecks\"\x90\x01\n" +
+	"\n" +
+	"DownServer\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1e\n" +
+	"\n" +
+	"serverName\x18\x02 \x01(\tR\n" +
+	"serverName\x124\n" +
+	"\x15consecutiveDownChecks\x18\x03 \x01(\x03R\x15consecutiveDownChecks\x12\x1c\n" +
+	"\tlastCheck\x18\x04 \x01(\x03R\tlastCheck\"]\n" +
+	"\x16GetDownServersResponse\x12C\n" +
+	"\aservers\x18\x01 \x03(\v2).server_administration_service.DownServerR\aservers2\xa0\x03\n" +
 	"\x1bServerAdministrationService\x12p\n" +
 	"\x0fGetAllAddresses\x12+.server_administration_service.EmptyRequest\x1a0.server_administration_service.AddressesResponse\x12\x8f\x01\n" +
-	"\x14GetServerInformation\x12:.server_administration_service.GetServerInformationRequest\x1a;.server_administration_service.GetServerInformationResponseB\x06Z\x04./pbb\x06proto3"
+	"\x14GetServerInformation\x12:.server_administration_service.GetServerInformationRequest\x1a;.server_administration_service.GetServerInformationResponse\x12}\n" +
+	"\x0eGetDownServers\x124.server_administration_service.GetDownServersRequest\x1a5.server_administration_service.GetDownServersResponseB\x06Z\x04./pbb\x06proto3"
 
 var (
 	file_proto_server_proto_rawDescOnce sync.Once
@@ -310,25 +539,33 @@ func file_proto_server_proto_rawDescGZIP() []byte {
 	return file_proto_server_proto_rawDescData
 }
 
-var file_proto_server_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_proto_server_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
 var file_proto_server_proto_goTypes = []any{
 	(*EmptyRequest)(nil),                 // 0: server_administration_service.EmptyRequest
 	(*AddressesResponse)(nil),            // 1: server_administration_service.AddressesResponse
 	(*AddressInfo)(nil),                  // 2: server_administration_service.AddressInfo
 	(*GetServerInformationRequest)(nil),  // 3: server_administration_service.GetServerInformationRequest
 	(*GetServerInformationResponse)(nil), // 4: server_administration_service.GetServerInformationResponse
+	(*GetDownServersRequest)(nil),        // 5: server_administration_service.GetDownServersRequest
+	(*DownServer)(nil),                   // 6: server_administration_service.DownServer
+	(*GetDownServersResponse)(nil),       // 7: server_administration_service.GetDownServersResponse
+	nil,                                  // 8: server_administration_service.GetServerInformationRequest.LabelsEntry
 }
 var file_proto_server_proto_depIdxs = []int32{
 	2, // 0: server_administration_service.AddressesResponse.addresses:type_name -> server_administration_service.AddressInfo
-	0, // 1: server_administration_service.ServerAdministrationService.GetAllAddresses:input_type -> server_administration_service.EmptyRequest
-	3, // 2: server_administration_service.ServerAdministrationService.GetServerInformation:input_type -> server_administration_service.GetServerInformationRequest
-	1, // 3: server_administration_service.ServerAdministrationService.GetAllAddresses:output_type -> server_administration_service.AddressesResponse
-	4, // 4: server_administration_service.ServerAdministrationService.GetServerInformation:output_type -> server_administration_service.GetServerInformationResponse
-	3, // [3:5] is the sub-list for method output_type
-	1, // [1:3] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	8, // 1: server_administration_service.GetServerInformationRequest.labels:type_name -> server_administration_service.GetServerInformationRequest.LabelsEntry
+	6, // 2: server_administration_service.GetDownServersResponse.servers:type_name -> server_administration_service.DownServer
+	0, // 3: server_administration_service.ServerAdministrationService.GetAllAddresses:input_type -> server_administration_service.EmptyRequest
+	3, // 4: server_administration_service.ServerAdministrationService.GetServerInformation:input_type -> server_administration_service.GetServerInformationRequest
+	5, // 5: server_administration_service.ServerAdministrationService.GetDownServers:input_type -> server_administration_service.GetDownServersRequest
+	1, // 6: server_administration_service.ServerAdministrationService.GetAllAddresses:output_type -> server_administration_service.AddressesResponse
+	4, // 7: server_administration_service.ServerAdministrationService.GetServerInformation:output_type -> server_administration_service.GetServerInformationResponse
+	7, // 8: server_administration_service.ServerAdministrationService.GetDownServers:output_type -> server_administration_service.GetDownServersResponse
+	6, // [6:9] is the sub-list for method output_type
+	3, // [3:6] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_proto_server_proto_init() }
@@ -342,7 +579,7 @@ func file_proto_server_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_server_proto_rawDesc), len(file_proto_server_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   9,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -21,6 +21,7 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	ServerAdministrationService_GetAllAddresses_FullMethodName      = "/server_administration_service.ServerAdministrationService/GetAllAddresses"
 	ServerAdministrationService_GetServerInformation_FullMethodName = "/server_administration_service.ServerAdministrationService/GetServerInformation"
+	ServerAdministrationService_GetDownServers_FullMethodName       = "/server_administration_service.ServerAdministrationService/GetDownServers"
 )
 
 // ServerAdministrationServiceClient is the client API for ServerAdministrationService service.
@@ -29,6 +30,7 @@ const (
 type ServerAdministrationServiceClient interface {
 	GetAllAddresses(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*AddressesResponse, error)
 	GetServerInformation(ctx context.Context, in *GetServerInformationRequest, opts ...grpc.CallOption) (*GetServerInformationResponse, error)
+	GetDownServers(ctx context.Context, in *GetDownServersRequest, opts ...grpc.CallOption) (*GetDownServersResponse, error)
 }
 
 type serverAdministrationServiceClient struct {
@@ -59,12 +61,23 @@ func (c *serverAdministrationServiceClient) GetServerInformation(ctx context.Con
 	return out, nil
 }
 
+func (c *serverAdministrationServiceClient) GetDownServers(ctx context.Context, in *GetDownServersRequest, opts ...grpc.CallOption) (*GetDownServersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDownServersResponse)
+	err := c.cc.Invoke(ctx, ServerAdministrationService_GetDownServers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ServerAdministrationServiceServer is the server API for ServerAdministrationService service.
 // All implementations must embed UnimplementedServerAdministrationServiceServer
 // for forward compatibility.
 type ServerAdministrationServiceServer interface {
 	GetAllAddresses(context.Context, *EmptyRequest) (*AddressesResponse, error)
 	GetServerInformation(context.Context, *GetServerInformationRequest) (*GetServerInformationResponse, error)
+	GetDownServers(context.Context, *GetDownServersRequest) (*GetDownServersResponse, error)
 	mustEmbedUnimplementedServerAdministrationServiceServer()
 }
 
@@ -81,6 +94,9 @@ func (UnimplementedServerAdministrationServiceServer) GetAllAddresses(context.Co
 func (UnimplementedServerAdministrationServiceServer) GetServerInformation(context.Context, *GetServerInformationRequest) (*GetServerInformationResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetServerInformation not implemented")
 }
+func (UnimplementedServerAdministrationServiceServer) GetDownServers(context.Context, *GetDownServersRequest) (*GetDownServersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDownServers not implemented")
+}
 func (UnimplementedServerAdministrationServiceServer) mustEmbedUnimplementedServerAdministrationServiceServer() {
 }
 func (UnimplementedServerAdministrationServiceServer) testEmbeddedByValue() {}
@@ -139,6 +155,24 @@ func _ServerAdministrationService_GetServerInformation_Handler(srv interface{},
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ServerAdministrationService_GetDownServers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDownServersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServerAdministrationServiceServer).GetDownServers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ServerAdministrationService_GetDownServers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServerAdministrationServiceServer).GetDownServers(ctx, req.(*GetDownServersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ServerAdministrationService_ServiceDesc is the grpc.ServiceDesc for ServerAdministrationService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -154,6 +188,10 @@ var ServerAdministrationService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetServerInformation",
 			Handler:    _ServerAdministrationService_GetServerInformation_Handler,
 		},
+		{
+			MethodName: "GetDownServers",
+			Handler:    _ServerAdministrationService_GetDownServers_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/server.proto",
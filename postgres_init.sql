@@ -22,9 +22,100 @@ CREATE TABLE IF NOT EXISTS servers (
     id SERIAL,
     server_id VARCHAR(255) PRIMARY KEY,
     server_name VARCHAR(255) UNIQUE NOT NULL,
-    status VARCHAR(255) NOT NULL,
+    status VARCHAR(255) NOT NULL DEFAULT 'Unknown',
     created_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
     ipv4 VARCHAR(255) NOT NULL,
-    port INTEGER NOT NULL
+    port INTEGER NOT NULL,
+    probe_type VARCHAR(255) NOT NULL DEFAULT 'tcp',
+    probe_path VARCHAR(255),
+    probe_expected_status INTEGER,
+    probe_expected_body TEXT,
+    check_interval_seconds INTEGER NOT NULL DEFAULT 60,
+    timeout_ms INTEGER NOT NULL DEFAULT 5000,
+    retries INTEGER NOT NULL DEFAULT 0,
+    labels JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS idx_servers_status ON servers (status);
+CREATE INDEX IF NOT EXISTS idx_servers_deleted_at ON servers (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_servers_labels ON servers USING GIN (labels);
+
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id SERIAL PRIMARY KEY,
+    server_ids JSONB,
+    server_name_filter VARCHAR(255),
+    ipv4_prefix VARCHAR(255),
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP NOT NULL,
+    reason TEXT NOT NULL,
+    created_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_start_time ON maintenance_windows (start_time);
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_end_time ON maintenance_windows (end_time);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id VARCHAR(255) PRIMARY KEY,
+    type VARCHAR(255) NOT NULL,
+    status VARCHAR(255) NOT NULL,
+    parameters JSONB NOT NULL,
+    processed INTEGER NOT NULL DEFAULT 0,
+    total INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    actor_id VARCHAR(255),
+    actor_name VARCHAR(255),
+    actor_role VARCHAR(255),
+    request_id VARCHAR(255),
+    result_name VARCHAR(255),
+    result_type VARCHAR(255),
+    created_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_time TIMESTAMP,
+    finished_time TIMESTAMP,
+    last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs (status);
+CREATE INDEX IF NOT EXISTS idx_jobs_created_time ON jobs (created_time);
+CREATE INDEX IF NOT EXISTS idx_jobs_finished_time ON jobs (finished_time);
+
+CREATE TABLE IF NOT EXISTS job_chunks (
+    job_id VARCHAR(255) NOT NULL,
+    kind VARCHAR(255) NOT NULL,
+    sequence INTEGER NOT NULL,
+    data BYTEA NOT NULL,
+    PRIMARY KEY (job_id, kind, sequence)
+);
+
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id BIGSERIAL PRIMARY KEY,
+    time TIMESTAMP NOT NULL,
+    actor_id VARCHAR(255) NOT NULL,
+    actor_name VARCHAR(255) NOT NULL,
+    actor_role VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255),
+    action VARCHAR(255) NOT NULL,
+    source VARCHAR(255) NOT NULL,
+    server_id VARCHAR(255) NOT NULL,
+    changes JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_entries_time ON audit_entries (time);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_actor_id ON audit_entries (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_request_id ON audit_entries (request_id);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_action ON audit_entries (action);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_server_id ON audit_entries (server_id);
+
+-- The audit log is append-only, even for a client going around the service
+CREATE OR REPLACE FUNCTION audit_entries_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_entries is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_entries_no_change ON audit_entries;
+CREATE TRIGGER audit_entries_no_change BEFORE UPDATE OR DELETE ON audit_entries FOR EACH ROW EXECUTE FUNCTION audit_entries_append_only();
+DROP TRIGGER IF EXISTS audit_entries_no_truncate ON audit_entries;
+CREATE TRIGGER audit_entries_no_truncate BEFORE TRUNCATE ON audit_entries FOR EACH STATEMENT EXECUTE FUNCTION audit_entries_append_only();
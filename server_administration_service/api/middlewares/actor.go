@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"server_administration_service/internal/domain"
+)
+
+/*
+	withActor keeps the user of a validated token in the request context, so that the changes
+	made by the request are recorded on their behalf. user_service issues the id, name and role claims.
+*/
+func withActor(r *http.Request, claims map[string]interface{}) *http.Request {
+	actor := domain.Actor{
+		ID:   claimString(claims["id"]),
+		Name: claimString(claims["name"]),
+		Role: claimString(claims["role"]),
+	}
+	return r.WithContext(domain.ContextWithActor(r.Context(), actor))
+}
+
+func claimString(claim interface{}) string {
+	if claim == nil {
+		return ""
+	}
+	return fmt.Sprint(claim)
+}
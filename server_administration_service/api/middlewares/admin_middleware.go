@@ -33,6 +33,6 @@ func AdminMiddleware(next http.Handler) http.Handler {
 		
 		logging.LogMessage("server_administration_service", "User is an admin", "INFO")
 		
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, withActor(r, data))
 	})
 }
\ No newline at end of file
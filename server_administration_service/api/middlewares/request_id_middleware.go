@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"net/http"
+	"server_administration_service/internal/domain"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// IDs sent by the client end up in logs and in the audit log, longer ones are replaced
+const maxRequestIDLength = 128
+
+/*
+	RequestIDMiddleware gives every request an ID, the one sent by the client or a gateway in X-Request-ID
+	or a new one. The ID is sent back in the response and recorded with the changes made by the request.
+*/
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = rand.Text()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(domain.ContextWithRequestID(r.Context(), requestID)))
+	})
+}
+
+// Printable ASCII without spaces, so that an ID can't forge a log line
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] <= ' ' || requestID[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -32,6 +32,6 @@ func UserMiddleware(next http.Handler) http.Handler {
 		}
 		
 		logging.LogMessage("server_administration_service", "User is an admin or user", "INFO")
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, withActor(r, data))
 	})
 }
\ No newline at end of file
@@ -8,11 +8,36 @@ import (
 	"github.com/gorilla/mux"
 )
 
-func RegisterRoutes(r *mux.Router, serverHandler handler.ServerHandler) {
+func RegisterRoutes(r *mux.Router, serverHandler handler.ServerHandler, maintenanceHandler handler.MaintenanceHandler, jobHandler handler.JobHandler, auditHandler handler.AuditHandler) {
+	// Every response carries the ID of its request, the audit log records it with the changes
+	r.Use(middlewares.RequestIDMiddleware)
+
 	r.Handle("/create", middlewares.UserMiddleware(http.HandlerFunc(serverHandler.CreateServer))).Methods("POST")
 	r.Handle("/view", middlewares.GuestMiddleware(http.HandlerFunc(serverHandler.ViewServers))).Methods("GET")
 	r.Handle("/update", middlewares.AdminMiddleware(http.HandlerFunc(serverHandler.UpdateServer))).Methods("PUT")
 	r.Handle("/delete", middlewares.AdminMiddleware(http.HandlerFunc(serverHandler.DeleteServer))).Methods("DELETE")
+	r.Handle("/trash", middlewares.AdminMiddleware(http.HandlerFunc(serverHandler.GetDeletedServers))).Methods("GET")
+	r.Handle("/restore", middlewares.AdminMiddleware(http.HandlerFunc(serverHandler.RestoreServer))).Methods("POST")
 	r.Handle("/import", middlewares.UserMiddleware(http.HandlerFunc(serverHandler.ImportServers))).Methods("POST")
 	r.Handle("/export", middlewares.AdminMiddleware(http.HandlerFunc(serverHandler.ExportServers))).Methods("GET")
+	r.Handle("/search", middlewares.GuestMiddleware(http.HandlerFunc(serverHandler.SearchServers))).Methods("GET")
+	r.Handle("/uptime", middlewares.GuestMiddleware(http.HandlerFunc(serverHandler.GetServersUptime))).Methods("GET")
+	r.Handle("/uptime/timeline", middlewares.GuestMiddleware(http.HandlerFunc(serverHandler.GetUptimeTimeline))).Methods("GET")
+	r.Handle("/latency", middlewares.GuestMiddleware(http.HandlerFunc(serverHandler.GetLatencyPercentiles))).Methods("GET")
+	r.Handle("/locations", middlewares.GuestMiddleware(http.HandlerFunc(serverHandler.GetLocationStatuses))).Methods("GET")
+	r.Handle("/transitions", middlewares.GuestMiddleware(http.HandlerFunc(serverHandler.GetStatusTransitions))).Methods("GET")
+
+	r.Handle("/maintenance", middlewares.AdminMiddleware(http.HandlerFunc(maintenanceHandler.CreateWindow))).Methods("POST")
+	r.Handle("/maintenance", middlewares.GuestMiddleware(http.HandlerFunc(maintenanceHandler.GetWindows))).Methods("GET")
+	r.Handle("/maintenance", middlewares.AdminMiddleware(http.HandlerFunc(maintenanceHandler.UpdateWindow))).Methods("PUT")
+	r.Handle("/maintenance", middlewares.AdminMiddleware(http.HandlerFunc(maintenanceHandler.DeleteWindow))).Methods("DELETE")
+
+	r.Handle("/import/jobs", middlewares.UserMiddleware(http.HandlerFunc(jobHandler.SubmitImport))).Methods("POST")
+	r.Handle("/export/jobs", middlewares.AdminMiddleware(http.HandlerFunc(jobHandler.SubmitExport))).Methods("POST")
+	r.Handle("/jobs", middlewares.UserMiddleware(http.HandlerFunc(jobHandler.GetJob))).Methods("GET")
+	r.Handle("/jobs", middlewares.UserMiddleware(http.HandlerFunc(jobHandler.CancelJob))).Methods("DELETE")
+	r.Handle("/jobs/result", middlewares.UserMiddleware(http.HandlerFunc(jobHandler.GetJobResult))).Methods("GET")
+
+	r.Handle("/audit", middlewares.AdminMiddleware(http.HandlerFunc(auditHandler.GetAuditEntries))).Methods("GET")
+	r.Handle("/audit/export", middlewares.AdminMiddleware(http.HandlerFunc(auditHandler.ExportAuditEntries))).Methods("GET")
 }
\ No newline at end of file
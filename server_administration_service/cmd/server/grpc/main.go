@@ -10,6 +10,7 @@ import (
 	"server_administration_service/internal/handler"
 	"server_administration_service/internal/repository"
 	"server_administration_service/internal/service"
+	"time"
 
 	"github.com/flashhhhh/pkg/env"
 	"github.com/flashhhhh/pkg/logging"
@@ -63,11 +64,18 @@ func main() {
 	// Initialize internal services
 	serverRepository := repository.NewServerRepository(db, redis, es)
 	serverService := service.NewServerService(serverRepository)
-	serverHandler := handler.NewGrpcServerHandler(serverService)
+	maintenanceRepository := repository.NewMaintenanceRepository(db)
+	maintenanceService := service.NewMaintenanceService(maintenanceRepository, 30*time.Second)
+	serverHandler := handler.NewGrpcServerHandler(serverService, maintenanceService)
 
 	// Synchronize Redis with DB
 	serverRepository.SyncServerStatus()
 
+	// Mirror the servers to the search index, the service still works without it
+	if err := serverRepository.SyncServerIndex(); err != nil {
+		logging.LogMessage("server_administration_service", "Failed to synchronize the servers index: "+err.Error(), "ERROR")
+	}
+
 	// Start gRPC server
 	grpcPort := env.GetEnv("SERVER_GRPC_ADMINISTRATION_PORT", "50051")
 	
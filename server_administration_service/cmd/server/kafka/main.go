@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -10,7 +11,9 @@ import (
 	"server_administration_service/internal/handler"
 	"server_administration_service/internal/repository"
 	"server_administration_service/internal/service"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/flashhhhh/pkg/env"
 	"github.com/flashhhhh/pkg/kafka"
@@ -76,19 +79,73 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Second consumer group turning raw heartbeats into server_down / server_recovered events
+	eventsGroupID := "server_events_group"
+	eventsConsumerGroup, err := kafka.NewKafkaConsumerGroup(brokers, eventsGroupID, topics)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to connect to Kafka: " + err.Error(), "FATAL")
+		logging.LogMessage("server_administration_service", "Exiting the program...", "FATAL")
+		os.Exit(1)
+	}
+
+	eventsProducer, err := kafka.NewKafkaProducer(brokers)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to create Kafka producer: " + err.Error(), "FATAL")
+		logging.LogMessage("server_administration_service", "Exiting the program...", "FATAL")
+		os.Exit(1)
+	}
+
+	windowSize := getEnvInt("EVENT_WINDOW_SIZE", 5)
+	downThreshold := getEnvInt("EVENT_DOWN_THRESHOLD", 3)
+	upThreshold := getEnvInt("EVENT_UP_THRESHOLD", 4)
+	if err := service.ValidateEventWindow(windowSize, downThreshold, upThreshold); err != nil {
+		logging.LogMessage("server_administration_service", "Invalid server events window: "+err.Error(), "FATAL")
+		logging.LogMessage("server_administration_service", "Exiting the program...", "FATAL")
+		os.Exit(1)
+	}
+	logging.LogMessage("server_administration_service", "Server events window: "+strconv.Itoa(windowSize)+" checks, down after "+strconv.Itoa(downThreshold)+" failures, recovered after "+strconv.Itoa(upThreshold)+" successes", "INFO")
+
+	// Windows of servers without a result for EVENT_WINDOW_IDLE_SECONDS are dropped
+	windowIdleSeconds := getEnvInt("EVENT_WINDOW_IDLE_SECONDS", 3600)
+
+	// Results from several probe locations only flip a status once QUORUM_SIZE of them agree
+	quorum := getEnvInt("QUORUM_SIZE", 1)
+	locationMaxAgeSeconds := getEnvInt("LOCATION_RESULT_MAX_AGE_SECONDS", 300)
+	logging.LogMessage("server_administration_service", "Status quorum: "+strconv.Itoa(quorum)+" location(s), results expire after "+strconv.Itoa(locationMaxAgeSeconds)+" seconds", "INFO")
+
+	// Servers under maintenance are looked up at most every MAINTENANCE_CACHE_SECONDS
+	maintenanceCacheSeconds := getEnvInt("MAINTENANCE_CACHE_SECONDS", 30)
+
 	// Initialize internal services
 	serverRepository := repository.NewServerRepository(db, redis, es)
 	serverService := service.NewServerService(serverRepository)
+	quorumService := service.NewQuorumService(serverRepository, quorum, time.Duration(locationMaxAgeSeconds)*time.Second)
+	maintenanceService := service.NewMaintenanceService(repository.NewMaintenanceRepository(db), time.Duration(maintenanceCacheSeconds)*time.Second)
+	serverEventService := service.NewServerEventService(eventsProducer, serverRepository, "server_events_topic", windowSize, downThreshold, upThreshold)
+	go serverEventService.EvictStaleWindows(context.Background(), time.Duration(windowIdleSeconds)*time.Second)
 
-	// Start Kafka consumer
-	kafkaHandler := handler.NewServerConsumerHandler(serverService)
+	// Start Kafka consumers
+	kafkaHandler := handler.NewServerConsumerHandler(serverService, quorumService, maintenanceService)
 	consumerGroup.StartConsuming(kafkaHandler)
 
+	eventsHandler := handler.NewServerEventsConsumerHandler(serverEventService, quorumService, maintenanceService)
+	eventsConsumerGroup.StartConsuming(eventsHandler)
+
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
 	<-sigs // Wait for interrupt
 	logging.LogMessage("server_administration_service", "Shutting down server...", "INFO")
 	consumerGroup.Stop()
+	eventsConsumerGroup.Stop()
+	eventsProducer.Close()
 	redis.Close()
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(env.GetEnv(key, strconv.Itoa(defaultValue)))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
 }
\ No newline at end of file
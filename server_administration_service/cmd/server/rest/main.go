@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"path/filepath"
+	"server_administration_service/api/middlewares"
 	"server_administration_service/api/routes"
+	"server_administration_service/infrastructure/elasticsearch"
 	"server_administration_service/infrastructure/postgres"
 	"server_administration_service/infrastructure/redis"
 	"server_administration_service/internal/handler"
 	"server_administration_service/internal/repository"
 	"server_administration_service/internal/service"
+	"strconv"
+	"time"
 
 	"github.com/flashhhhh/pkg/env"
 	"github.com/flashhhhh/pkg/logging"
@@ -59,21 +64,45 @@ func main() {
 				":" + env.GetEnv("SERVER_REDIS_PORT", "6379")
 	redis := redis.NewRedisClient(redisAddress)
 
+	elasticSearchAddress := env.GetEnv("SERVER_ELASTICSEARCH_HOST", "localhost") +
+			 ":" + env.GetEnv("SERVER_ELASTICSEARCH_PORT", "9200")
+	es := elasticsearch.ConnectES(elasticSearchAddress)
+
 	// Initialize the server
-	serverRepository := repository.NewServerRepository(db, redis, nil)
+	serverRepository := repository.NewServerRepository(db, redis, es)
 	serverService := service.NewServerService(serverRepository)
 	serverHandler := handler.NewServerHandler(serverService)
 
+	// Deleted servers can be restored from the trash for TRASH_RETENTION_DAYS, then they are purged
+	trashRetentionDays := getEnvInt("TRASH_RETENTION_DAYS", 30)
+	logging.LogMessage("server_administration_service", "Deleted servers are purged after "+strconv.Itoa(trashRetentionDays)+" days", "INFO")
+	go serverService.PurgeDeletedServers(context.Background(), time.Duration(trashRetentionDays)*24*time.Hour)
+
+	maintenanceRepository := repository.NewMaintenanceRepository(db)
+	maintenanceService := service.NewMaintenanceService(maintenanceRepository, 0)
+	maintenanceHandler := handler.NewMaintenanceHandler(maintenanceService)
+
+	// Imports and exports submitted as jobs run in the background of this process
+	jobRepository := repository.NewJobRepository(db)
+	jobService := service.NewJobService(jobRepository, serverService)
+	jobHandler := handler.NewJobHandler(jobService)
+	go jobService.Run(context.Background())
+
+	auditRepository := repository.NewAuditRepository(db)
+	auditService := service.NewAuditService(auditRepository)
+	auditHandler := handler.NewAuditHandler(auditService)
+
 	// Initialize the HTTP server
 	serverPort := env.GetEnv("SERVER_ADMINISTRATION_PORT", "10002")
 	
 	r := mux.NewRouter()
-	routes.RegisterRoutes(r, serverHandler)
+	routes.RegisterRoutes(r, serverHandler, maintenanceHandler, jobHandler, auditHandler)
 
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"}, // Allow all origins, change this for security
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", middlewares.RequestIDHeader},
+		ExposedHeaders:   []string{middlewares.RequestIDHeader},
 		AllowCredentials: true,
 	}).Handler(r)
 
@@ -86,4 +115,12 @@ func main() {
 	logging.LogMessage("user_service", "HTTP server stopped", "INFO")
 	logging.LogMessage("user_service", "Exiting the program...", "INFO")
 	os.Exit(0)
-}
\ No newline at end of file
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(env.GetEnv(key, strconv.Itoa(defaultValue)))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -27,20 +27,57 @@ func ConnectDB(dsn string) *gorm.DB {
 func Migrate(db *gorm.DB) {
 	logging.LogMessage("server_administration_service", "Migrating the database...", "INFO")
 
-	// Check if the table exists
+	// AutoMigrate creates missing tables and adds missing columns, it never drops anything
 	tableExists := db.Migrator().HasTable(&domain.Server{})
 	if !tableExists {
 		logging.LogMessage("server_administration_service", "Tables don't exist, migrating...", "INFO")
-		
-		err := db.AutoMigrate(&domain.Server{})
-		if err != nil {
-			logging.LogMessage("server_administration_service", "Failed to migrate the database: "+err.Error(), "FATAL")
+	} else {
+		logging.LogMessage("server_administration_service", "Tables already exist, adding missing columns", "INFO")
+	}
+
+	err := db.AutoMigrate(&domain.Server{}, &domain.MaintenanceWindow{}, &domain.Job{}, &domain.JobChunk{}, &domain.AuditEntry{})
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to migrate the database: "+err.Error(), "FATAL")
+		logging.LogMessage("server_administration_service", "Exiting the program...", "FATAL")
+		os.Exit(1)
+	}
+
+	// Rows written before the status enum still use On / Off
+	legacyStatuses := map[string]domain.ServerStatus{"On": domain.StatusUp, "Off": domain.StatusDown}
+	for legacyStatus, status := range legacyStatuses {
+		if err := db.Model(&domain.Server{}).Where("status = ?", legacyStatus).Update("status", status).Error; err != nil {
+			logging.LogMessage("server_administration_service", "Failed to migrate "+legacyStatus+" statuses: "+err.Error(), "FATAL")
 			logging.LogMessage("server_administration_service", "Exiting the program...", "FATAL")
 			os.Exit(1)
 		}
-		
-		logging.LogMessage("server_administration_service", "Database migrated successfully", "INFO")
-	} else {
-		logging.LogMessage("server_administration_service", "Tables already exist, skipping migration", "INFO")
 	}
+
+	// GORM can't declare a GIN index, it is needed by the labels @> filter
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_servers_labels ON servers USING GIN (labels)").Error; err != nil {
+		logging.LogMessage("server_administration_service", "Failed to create the labels index: "+err.Error(), "FATAL")
+		logging.LogMessage("server_administration_service", "Exiting the program...", "FATAL")
+		os.Exit(1)
+	}
+
+	// The audit log is append-only, even for a client going around the service
+	auditStatements := []string{
+		`CREATE OR REPLACE FUNCTION audit_entries_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_entries is append-only';
+		END;
+		$$ LANGUAGE plpgsql`,
+		"DROP TRIGGER IF EXISTS audit_entries_no_change ON audit_entries",
+		"CREATE TRIGGER audit_entries_no_change BEFORE UPDATE OR DELETE ON audit_entries FOR EACH ROW EXECUTE FUNCTION audit_entries_append_only()",
+		"DROP TRIGGER IF EXISTS audit_entries_no_truncate ON audit_entries",
+		"CREATE TRIGGER audit_entries_no_truncate BEFORE TRUNCATE ON audit_entries FOR EACH STATEMENT EXECUTE FUNCTION audit_entries_append_only()",
+	}
+	for _, statement := range auditStatements {
+		if err := db.Exec(statement).Error; err != nil {
+			logging.LogMessage("server_administration_service", "Failed to protect the audit log: "+err.Error(), "FATAL")
+			logging.LogMessage("server_administration_service", "Exiting the program...", "FATAL")
+			os.Exit(1)
+		}
+	}
+
+	logging.LogMessage("server_administration_service", "Database migrated successfully", "INFO")
 }
\ No newline at end of file
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	// Moved to the trash, see AuditPurge for the removal of the row
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditPurge   AuditAction = "purge"
+)
+
+func (a AuditAction) IsValid() bool {
+	switch a {
+	case AuditCreate, AuditUpdate, AuditDelete, AuditRestore, AuditPurge:
+		return true
+	}
+	return false
+}
+
+// Where a change came from, an import records one entry per server it changed
+type AuditSource string
+
+const (
+	AuditSourceAPI    AuditSource = "api"
+	AuditSourceImport AuditSource = "import"
+	// Changes the service makes on its own, e.g. purging the trash
+	AuditSourceSystem AuditSource = "system"
+)
+
+func (s AuditSource) IsValid() bool {
+	return s == AuditSourceAPI || s == AuditSourceImport || s == AuditSourceSystem
+}
+
+// Actor is the user behind a request, taken from the claims of its JWT
+type Actor struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+func (a Actor) IsAdmin() bool {
+	return a.Role == "admin"
+}
+
+// SystemActor stands for changes made without a user, e.g. by a job submitted before actors were recorded
+var SystemActor = Actor{ID: "system", Name: "system", Role: "system"}
+
+// A FieldChange is the value of a field before and after a change, nil when the server didn't exist
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After interface{} `json:"after"`
+}
+
+/*
+	An AuditEntry records a change of a server: who made it, through which request and what changed.
+	Entries are written in the transaction of the change and are never updated nor deleted.
+*/
+type AuditEntry struct {
+	ID int64 `json:"id" gorm:"primaryKey"`
+	Time time.Time `json:"time" gorm:"not null;index"`
+
+	ActorID string `json:"actor_id" gorm:"not null;index"`
+	ActorName string `json:"actor_name" gorm:"not null"`
+	ActorRole string `json:"actor_role" gorm:"not null"`
+	RequestID string `json:"request_id" gorm:"index"`
+
+	Action AuditAction `json:"action" gorm:"not null;index"`
+	Source AuditSource `json:"source" gorm:"not null"`
+	ServerID string `json:"server_id" gorm:"not null;index"`
+	// The changed fields by their JSON name, every field for a create, a delete, a restore or a purge
+	Changes map[string]FieldChange `json:"changes" gorm:"serializer:json;type:jsonb;not null"`
+}
+
+// Managed by the database, they change on every write, the action tells when deleted_at does
+var unauditedFields = map[string]bool{"created_time": true, "last_updated": true, "deleted_at": true}
+
+/*
+	DiffServers returns the fields differing between two versions of a server,
+	before is nil for a created server and after is nil for a deleted one
+*/
+func DiffServers(before, after *Server) map[string]FieldChange {
+	beforeFields := serverFields(before)
+	afterFields := serverFields(after)
+
+	changes := make(map[string]FieldChange)
+	for _, fields := range []map[string]interface{}{beforeFields, afterFields} {
+		for field := range fields {
+			if unauditedFields[field] {
+				continue
+			}
+			if reflect.DeepEqual(beforeFields[field], afterFields[field]) {
+				continue
+			}
+			changes[field] = FieldChange{Before: beforeFields[field], After: afterFields[field]}
+		}
+	}
+	return changes
+}
+
+// serverFields decodes the JSON of a server, so that the values are recorded as the API shows them
+func serverFields(server *Server) map[string]interface{} {
+	if server == nil {
+		return nil
+	}
+
+	encoded, _ := json.Marshal(server)
+	var fields map[string]interface{}
+	json.Unmarshal(encoded, &fields)
+	return fields
+}
+
+type contextKey int
+
+const (
+	actorKey contextKey = iota
+	requestIDKey
+)
+
+func ContextWithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFromContext returns SystemActor when the context carries no actor
+func ActorFromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey).(Actor); ok {
+		return actor
+	}
+	return SystemActor
+}
+
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
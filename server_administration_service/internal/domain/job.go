@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type JobType string
+
+const (
+	JobImport JobType = "import"
+	JobExport JobType = "export"
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+func (s JobStatus) IsFinished() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCanceled
+}
+
+/*
+	A Job is an import or an export run in the background by a worker of the REST service.
+	Its files are stored in JobChunk rows, so that a job survives a restart and can be followed
+	from any replica.
+*/
+type Job struct {
+	ID string `json:"id" gorm:"primaryKey"`
+	Type JobType `json:"type" gorm:"not null"`
+	Status JobStatus `json:"status" gorm:"not null;index"`
+	// The dto.ImportOptions or the dto.ExportRequest of the job, as JSON
+	Parameters string `json:"-" gorm:"type:jsonb;not null"`
+
+	// Rows done so far, Total stays 0 until the number of rows is known
+	Processed int `json:"processed" gorm:"not null;default:0"`
+	Total int `json:"total" gorm:"not null;default:0"`
+	Error string `json:"error,omitempty"`
+
+	// Who submitted the job and through which request, the changes of an import are recorded on their behalf
+	ActorID string `json:"actor_id"`
+	ActorName string `json:"actor_name"`
+	ActorRole string `json:"actor_role"`
+	RequestID string `json:"request_id"`
+
+	// File name and Content-Type of the result, set once the job succeeded
+	ResultName string `json:"result_name,omitempty"`
+	ResultType string `json:"result_type,omitempty"`
+
+	CreatedTime time.Time `json:"created_time" gorm:"autoCreateTime;index"`
+	StartedTime *time.Time `json:"started_time,omitempty"`
+	FinishedTime *time.Time `json:"finished_time,omitempty" gorm:"index"`
+	// Touched by the worker while the job runs, a running job left untouched is requeued
+	LastUpdated time.Time `json:"last_updated" gorm:"autoUpdateTime"`
+}
+
+// The files of a job
+const (
+	JobInput  = "input"
+	JobResult = "result"
+)
+
+// A JobChunk is a piece of a file of a job, the file is the chunks of the job and kind by sequence
+type JobChunk struct {
+	JobID string `gorm:"primaryKey"`
+	Kind string `gorm:"primaryKey"`
+	Sequence int `gorm:"primaryKey;autoIncrement:false"`
+	Data []byte `gorm:"not null"`
+}
+
+// Context carries the submitter of the job, jobs submitted before submitters were recorded run as SystemActor
+func (j *Job) Context(ctx context.Context) context.Context {
+	if j.ActorID != "" {
+		ctx = ContextWithActor(ctx, Actor{ID: j.ActorID, Name: j.ActorName, Role: j.ActorRole})
+	}
+	return ContextWithRequestID(ctx, j.RequestID)
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+/*
+	Label keys and values share the same charset so that labels can be written
+	as "env=prod,team=payments" in query parameters and import files
+*/
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_.-]{0,61}[A-Za-z0-9])?$`)
+
+const MaxLabelsPerServer = 32
+
+func ValidateLabels(labels map[string]string) error {
+	if len(labels) > MaxLabelsPerServer {
+		return fmt.Errorf("a server can have at most %d labels, got %d", MaxLabelsPerServer, len(labels))
+	}
+
+	for key, value := range labels {
+		if !labelPattern.MatchString(key) {
+			return fmt.Errorf("invalid label key %q", key)
+		}
+		if !labelPattern.MatchString(value) {
+			return fmt.Errorf("invalid value %q for label %s", value, key)
+		}
+	}
+	return nil
+}
+
+/*
+	ParseLabels reads labels written as "env=prod,team=payments", an empty string gives no labels
+*/
+func ParseLabels(value string) (map[string]string, error) {
+	labels := make(map[string]string)
+	if strings.TrimSpace(value) == "" {
+		return labels, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		key, labelValue, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+
+		key = strings.TrimSpace(key)
+		if _, existed := labels[key]; existed {
+			return nil, fmt.Errorf("duplicated label %s", key)
+		}
+		labels[key] = strings.TrimSpace(labelValue)
+	}
+
+	if err := ValidateLabels(labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// FormatLabels is the inverse of ParseLabels, keys are sorted to keep the output stable
+func FormatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + labels[key]
+	}
+	return strings.Join(pairs, ",")
+}
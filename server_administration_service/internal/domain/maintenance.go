@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+/*
+	A MaintenanceWindow covers the listed servers plus every server matching its filter
+	(name substring and/or IPv4 prefix) between StartTime and EndTime
+*/
+type MaintenanceWindow struct {
+	ID int `json:"id" gorm:"primaryKey;autoIncrement"`
+	ServerIDs []int `json:"server_ids" gorm:"serializer:json;type:jsonb"`
+	ServerNameFilter string `json:"server_name_filter"`
+	IPv4Prefix string `json:"ipv4_prefix"`
+	StartTime time.Time `json:"start_time" gorm:"not null;index"`
+	EndTime time.Time `json:"end_time" gorm:"not null;index"`
+	Reason string `json:"reason" gorm:"not null"`
+	CreatedTime time.Time `json:"created_time" gorm:"autoCreateTime"`
+	LastUpdated time.Time `json:"last_updated" gorm:"autoUpdateTime"`
+}
+
+func (w *MaintenanceWindow) HasFilter() bool {
+	return w.ServerNameFilter != "" || w.IPv4Prefix != ""
+}
+
+func (w *MaintenanceWindow) IsActive(at time.Time) bool {
+	return !at.Before(w.StartTime) && at.Before(w.EndTime)
+}
@@ -1,14 +1,71 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Server struct {
 	ID int `json:"id" gorm:"autoIncrement"`
 	ServerID string `json:"server_id" gorm:"primary_key;unique"`
 	ServerName string `json:"server_name" gorm:"unique;not null"`
-	Status string `json:"status" gorm:"not null"`
+	Status ServerStatus `json:"status" gorm:"not null;default:Unknown;index"`
 	CreatedTime time.Time `json:"created_time" gorm:"autoCreateTime"`
 	LastUpdated time.Time `json:"last_updated" gorm:"autoUpdateTime"`
+	// Set while the server is in the trash, queries skip it until it is restored or purged
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 	IPv4 string `json:"ipv4" gorm:"not null"`
 	Port int `json:"port" gorm:"not null"`
+
+	// Free key/value labels such as env=prod or team=payments, see ValidateLabels
+	Labels map[string]string `json:"labels" gorm:"serializer:json;type:jsonb;not null;default:'{}'"`
+
+	// How healthcheck_service probes the server, see the Probe* constants
+	ProbeType string `json:"probe_type" gorm:"not null;default:tcp"`
+	// HTTP(S) only: request path, expected status code and optional body substring
+	ProbePath string `json:"probe_path"`
+	ProbeExpectedStatus int `json:"probe_expected_status"`
+	ProbeExpectedBody string `json:"probe_expected_body"`
+
+	// Check cadence of the server, retries are extra attempts before reporting it Off
+	CheckIntervalSeconds int `json:"check_interval_seconds" gorm:"not null;default:60"`
+	TimeoutMs int `json:"timeout_ms" gorm:"not null;default:5000"`
+	Retries int `json:"retries" gorm:"not null;default:0"`
+}
+
+const (
+	DefaultCheckIntervalSeconds = 60
+	DefaultTimeoutMs            = 5000
+)
+
+const (
+	ProbeTCP   = "tcp"
+	ProbeHTTP  = "http"
+	ProbeHTTPS = "https"
+	ProbeUDP   = "udp"
+	ProbeTLS   = "tls"
+)
+
+// ValidateCheckConfig checks the cadence fields, zero values are expected to be defaulted beforehand
+func ValidateCheckConfig(checkIntervalSeconds, timeoutMs, retries int) error {
+	if checkIntervalSeconds < 5 || checkIntervalSeconds > 86400 {
+		return fmt.Errorf("check interval must be between 5 and 86400 seconds, got %d", checkIntervalSeconds)
+	}
+	if timeoutMs < 100 || timeoutMs > 60000 {
+		return fmt.Errorf("timeout must be between 100 and 60000 ms, got %d", timeoutMs)
+	}
+	if retries < 0 || retries > 10 {
+		return fmt.Errorf("retries must be between 0 and 10, got %d", retries)
+	}
+	return nil
+}
+
+func IsValidProbeType(probeType string) bool {
+	switch probeType {
+	case ProbeTCP, ProbeHTTP, ProbeHTTPS, ProbeUDP, ProbeTLS:
+		return true
+	}
+	return false
 }
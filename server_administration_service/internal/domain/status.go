@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ServerStatus string
+
+const (
+	// Never checked yet, it counts neither as up nor as down
+	StatusUnknown ServerStatus = "Unknown"
+	StatusUp      ServerStatus = "Up"
+	StatusDown    ServerStatus = "Down"
+	// Reachable but not healthy, it still counts as up in the uptime
+	StatusDegraded       ServerStatus = "Degraded"
+	StatusMaintenance    ServerStatus = "Maintenance"
+	// Retired but kept with its history, healthcheck_service stops probing it
+	StatusDecommissioned ServerStatus = "Decommissioned"
+)
+
+var ServerStatuses = []ServerStatus{
+	StatusUnknown,
+	StatusUp,
+	StatusDown,
+	StatusDegraded,
+	StatusMaintenance,
+	StatusDecommissioned,
+}
+
+/*
+ParseServerStatus is case insensitive and still accepts the "On" / "Off"
+values used before the enum, e.g. in old import files
+*/
+func ParseServerStatus(value string) (ServerStatus, error) {
+	value = strings.TrimSpace(value)
+
+	switch strings.ToLower(value) {
+	case "on":
+		return StatusUp, nil
+	case "off":
+		return StatusDown, nil
+	}
+
+	for _, status := range ServerStatuses {
+		if strings.EqualFold(value, string(status)) {
+			return status, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid status %q", value)
+}
+
+// IsAvailable tells whether the status counts towards uptime
+func (s ServerStatus) IsAvailable() bool {
+	return s == StatusUp || s == StatusDegraded
+}
+
+// IsManual statuses are set by an administrator, health check results do not override them
+func (s ServerStatus) IsManual() bool {
+	return s == StatusMaintenance || s == StatusDecommissioned
+}
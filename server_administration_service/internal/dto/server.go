@@ -1,15 +1,248 @@
 package dto
 
+import (
+	"server_administration_service/internal/domain"
+	"time"
+)
+
 type ServerAddress struct {
 	ID int `json:"id" gorm:"primary_key"`
 	IPv4      string `json:"ip_address" gorm:"not null"`
 	Port      int    `json:"port" gorm:"not null"`
+
+	ProbeType           string `json:"probe_type"`
+	ProbePath           string `json:"probe_path"`
+	ProbeExpectedStatus int    `json:"probe_expected_status"`
+	ProbeExpectedBody   string `json:"probe_expected_body"`
+
+	CheckIntervalSeconds int `json:"check_interval_seconds"`
+	TimeoutMs            int `json:"timeout_ms"`
+	Retries              int `json:"retries"`
 }
 
 type ServerFilter struct {
 	ServerID string `json:"server_id"`
 	ServerName string `json:"server_name"`
-	Status	 string `json:"status"`
+	// Servers having any of these statuses
+	Statuses []string `json:"statuses"`
 	IPv4	  string `json:"ipv4"`
+	// Servers whose ipv4 is inside this range, e.g. 10.2.0.0/16
+	IPv4CIDR string `json:"ipv4_cidr"`
 	Port	  int    `json:"port"`
-}
\ No newline at end of file
+	PortRange   IntRange  `json:"port_range"`
+	CreatedTime TimeRange `json:"created_time"`
+	LastUpdated TimeRange `json:"last_updated"`
+	// Only servers having all of these labels
+	Labels map[string]string `json:"labels"`
+}
+
+// IntRange bounds are inclusive, a nil bound is open
+type IntRange struct {
+	From *int `json:"from,omitempty"`
+	To   *int `json:"to,omitempty"`
+}
+
+// TimeRange includes From but not To, a nil bound is open
+type TimeRange struct {
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+}
+
+// SortField is one column of a multi column sort, Order is "asc" or "desc"
+type SortField struct {
+	Column string `json:"column"`
+	Order  string `json:"order"`
+}
+
+// ServerCursor is the position after which the next page starts, it holds the value of every sort field of the last server
+type ServerCursor struct {
+	Values []interface{}
+}
+
+// ServerPage is one page of ViewServers, NextCursor is empty on the last page
+type ServerPage struct {
+	Servers    []domain.Server `json:"servers"`
+	NextCursor string          `json:"next_cursor"`
+	Total      int             `json:"total"`
+}
+
+// ServerSearchQuery is a full-text search over the servers index, Port -1 means any port
+type ServerSearchQuery struct {
+	Text     string
+	Statuses []string
+	Port     int
+	Offset   int
+	Limit    int
+}
+
+type ServerSearchHit struct {
+	Server domain.Server `json:"server"`
+	Score  float64       `json:"score"`
+	// Matched fragments per field, the matches are wrapped in <em> tags
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
+
+// ServerSearchResult holds one page of hits and the facet counts of every match
+type ServerSearchResult struct {
+	Hits         []ServerSearchHit `json:"hits"`
+	Total        int               `json:"total"`
+	StatusCounts map[string]int    `json:"status_counts"`
+	PortCounts   map[int]int       `json:"port_counts"`
+}
+
+// RejectedServer is a server an import could not insert and why
+type RejectedServer struct {
+	Server domain.Server `json:"server"`
+	Reason string        `json:"reason"`
+}
+
+// ServerImportPlan is what an import changes, Update and Delete hold the servers with their id
+type ServerImportPlan struct {
+	Create []domain.Server
+	Update []domain.Server
+	Delete []domain.Server
+}
+
+type ServerImportResult struct {
+	Created  []domain.Server
+	Updated  []domain.Server
+	Deleted  []domain.Server
+	Rejected []RejectedServer
+}
+
+// ImportError is one reason a row of an import file was rejected, Column and Cell are empty when it is about the whole row
+type ImportError struct {
+	Row    int    `json:"row"`
+	Column string `json:"column,omitempty"`
+	Cell   string `json:"cell,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// RejectedRow is a row of an import file that was not imported, with the cells as they were in the file
+type RejectedRow struct {
+	Row    int           `json:"row"`
+	Values []string      `json:"values"`
+	Errors []ImportError `json:"errors"`
+}
+
+// ProgressFunc is told how many rows of an import or an export are done, an error stops it
+type ProgressFunc func(processed, total int) error
+
+// ImportOptions Mode is "insert", "upsert" or "replace", a dry run only reports what the import would change
+type ImportOptions struct {
+	Mode     string       `json:"mode"`
+	DryRun   bool         `json:"dry_run"`
+	Progress ProgressFunc `json:"-"`
+}
+
+// ExportOptions Format is one of the formats of the service, xlsx by default
+type ExportOptions struct {
+	Format   string
+	Progress ProgressFunc
+}
+
+// ExportRequest is an export run as a job
+type ExportRequest struct {
+	Filter     *ServerFilter `json:"filter"`
+	SortFields []SortField   `json:"sort_fields"`
+	Format     string        `json:"format"`
+}
+
+// AuditFilter empty fields match every entry
+type AuditFilter struct {
+	ActorID   string
+	Action    string
+	Source    string
+	ServerID  string
+	RequestID string
+	Time      TimeRange
+}
+
+// AuditPage is one page of audit entries, newest first, NextCursor is empty on the last page
+type AuditPage struct {
+	Entries    []domain.AuditEntry `json:"entries"`
+	NextCursor string              `json:"next_cursor"`
+}
+
+type ImportReport struct {
+	Mode      string          `json:"mode"`
+	DryRun    bool            `json:"dry_run"`
+	Created   []domain.Server `json:"created_servers"`
+	Updated   []domain.Server `json:"updated_servers"`
+	Unchanged []domain.Server `json:"unchanged_servers"`
+	Deleted   []domain.Server `json:"deleted_servers"`
+	Rejected  []RejectedRow   `json:"rejected_rows"`
+}
+
+type ServerUptime struct {
+	ID               int     `json:"id"`
+	UptimeRatio      float64 `json:"uptime_ratio"`
+	NumChecks        int     `json:"num_checks"`
+	NumStatusChanges int     `json:"num_status_changes"`
+}
+
+type UptimeBucket struct {
+	Timestamp   time.Time `json:"timestamp"`
+	UptimeRatio float64   `json:"uptime_ratio"`
+	NumChecks   int       `json:"num_checks"`
+}
+
+
+type StatusTransition struct {
+	ID             int       `json:"id"`
+	PreviousStatus string    `json:"previous_status"`
+	Status         string    `json:"status"`
+	Timestamp      time.Time `json:"timestamp"`
+	// Nil when the server had no recorded transition before this one
+	PreviousDurationSeconds *int64 `json:"previous_duration_seconds,omitempty"`
+}
+
+type DownServer struct {
+	ID                    int       `json:"id"`
+	ServerName            string    `json:"server_name"`
+	ConsecutiveDownChecks int       `json:"consecutive_down_checks"`
+	LastCheck             time.Time `json:"last_check"`
+}
+
+// HealthCheckResult is the message healthcheck_service publishes to healthcheck_topic
+type HealthCheckResult struct {
+	ID     int    `json:"id"`
+	IPv4   string `json:"ipv4"`
+	Status bool   `json:"status"`
+
+	// Older healthcheck_service versions send none of the fields below
+	LatencyMs  float64   `json:"latency_ms"`
+	ErrorClass string    `json:"error_class"`
+	CheckedAt  time.Time `json:"checked_at"`
+	// Where the probe ran, empty for single location setups
+	Location string `json:"location"`
+}
+
+// LocationStatus is the latest result of one probe location for a server
+type LocationStatus struct {
+	Location  string    `json:"location"`
+	Status    string    `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// LatencyPercentiles summarizes the probe latency of a server, in milliseconds
+type LatencyPercentiles struct {
+	ID        int     `json:"id"`
+	NumChecks int     `json:"num_checks"`
+	P50       float64 `json:"p50_ms"`
+	P90       float64 `json:"p90_ms"`
+	P95       float64 `json:"p95_ms"`
+	P99       float64 `json:"p99_ms"`
+	Avg       float64 `json:"avg_ms"`
+	Max       float64 `json:"max_ms"`
+}
+
+// ServerEvent is the message published to server_events_topic
+type ServerEvent struct {
+	Type         string    `json:"type"`
+	ID           int       `json:"id"`
+	IPv4         string    `json:"ipv4"`
+	Timestamp    time.Time `json:"timestamp"`
+	FailedChecks int       `json:"failed_checks"`
+	WindowSize   int       `json:"window_size"`
+}
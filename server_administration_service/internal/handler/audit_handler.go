@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/flashhhhh/pkg/logging"
+)
+
+// AuditHandler serves the audit log of the server changes
+type AuditHandler interface {
+	GetAuditEntries(w http.ResponseWriter, r *http.Request)
+	ExportAuditEntries(w http.ResponseWriter, r *http.Request)
+}
+
+type auditHandler struct {
+	service service.AuditService
+}
+
+func NewAuditHandler(service service.AuditService) AuditHandler {
+	return &auditHandler{
+		service: service,
+	}
+}
+
+// The audit export formats, CSV is served when the client accepts anything
+var auditExportFormats = []exportFormat{
+	{"text/csv", service.FormatCSV, "csv"},
+	{"application/json", service.FormatJSON, "json"},
+	{"application/x-ndjson", service.FormatNDJSON, "ndjson"},
+	{"application/ndjson", service.FormatNDJSON, "ndjson"},
+}
+
+/*
+	parseAuditFilter reads the filters shared by the audit endpoints, e.g.
+
+		?server_id=srv-001&action=update&time=now-24h..
+
+	actor_id, action, source, server_id and request_id match exactly, time is a range like
+	created_time in parseServerQuery
+*/
+func parseAuditFilter(query url.Values) (*dto.AuditFilter, error) {
+	auditFilter := &dto.AuditFilter{
+		ActorID:   query.Get("actor_id"),
+		Action:    query.Get("action"),
+		Source:    query.Get("source"),
+		ServerID:  query.Get("server_id"),
+		RequestID: query.Get("request_id"),
+	}
+
+	if value := query.Get("time"); value != "" {
+		timeRange, err := parseTimeRange(value, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("Invalid 'time' query parameter: %s", err.Error())
+		}
+		auditFilter.Time = timeRange
+	}
+
+	return auditFilter, nil
+}
+
+// Pages like /view: the cursor comes from the next_cursor of the previous page
+func (h *auditHandler) GetAuditEntries(w http.ResponseWriter, r *http.Request) {
+	cursor := r.URL.Query().Get("cursor")
+
+	pageSize := 0
+	if pageSizeStr := r.URL.Query().Get("page_size"); pageSizeStr != "" {
+		var err error
+		pageSize, err = strconv.Atoi(pageSizeStr)
+		if err != nil {
+			logging.LogMessage("server_administration_service", "Invalid 'page_size' query parameter: "+pageSizeStr, "ERROR")
+			http.Error(w, "Invalid 'page_size' query parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	auditFilter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid audit query: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.service.GetAuditEntries(auditFilter, cursor, pageSize)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid audit query: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get audit entries: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to get audit entries", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := json.Marshal(page)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to marshal audit entries response: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to process audit entries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// Takes the filters of /audit, the format is negotiated from the Accept header like /export
+func (h *auditHandler) ExportAuditEntries(w http.ResponseWriter, r *http.Request) {
+	auditFilter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid audit query: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format, ok := negotiateFormat(r.Header.Get("Accept"), auditExportFormats)
+	if !ok {
+		logging.LogMessage("server_administration_service", "Unsupported audit export format: "+r.Header.Get("Accept"), "ERROR")
+		http.Error(w, "Audit export is available as text/csv, application/json or application/x-ndjson", http.StatusNotAcceptable)
+		return
+	}
+
+	filename := "audit_" + time.Now().Format("2006-01-02_15-04-05") + "." + format.extension
+	export := &downloadWriter{w: w, mediaType: format.mediaType, filename: filename}
+	w.Header().Set("Vary", "Accept")
+
+	err = h.service.ExportAuditEntries(export, auditFilter, format.format)
+	if err != nil && export.started {
+		export.abort("Audit export interrupted", err)
+	}
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid audit query: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to export audit entries: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to export audit entries", http.StatusInternalServerError)
+		return
+	}
+
+	// An empty NDJSON export writes nothing
+	export.start()
+}
@@ -0,0 +1,151 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/handler"
+	"server_administration_service/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuditService is a mock implementation of service.AuditService
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) GetAuditEntries(auditFilter *dto.AuditFilter, cursor string, pageSize int) (*dto.AuditPage, error) {
+	args := m.Called(auditFilter, cursor, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.AuditPage), args.Error(1)
+}
+
+// ExportAuditEntries writes the bytes it is given, then returns its error
+func (m *MockAuditService) ExportAuditEntries(w io.Writer, auditFilter *dto.AuditFilter, format string) error {
+	args := m.Called(auditFilter, format)
+	if data, ok := args.Get(0).([]byte); ok {
+		w.Write(data)
+	}
+	return args.Error(1)
+}
+
+func TestGetAuditEntries(t *testing.T) {
+	mockService := new(MockAuditService)
+	handler := handler.NewAuditHandler(mockService)
+
+	page := &dto.AuditPage{
+		Entries:    []domain.AuditEntry{{ID: 5, ServerID: "srv-001", Action: domain.AuditDelete}},
+		NextCursor: "5",
+	}
+	mockService.On("GetAuditEntries", &dto.AuditFilter{ServerID: "srv-001", Action: "delete", ActorID: "user-1"}, "9", 1).Return(page, nil)
+
+	req := httptest.NewRequest("GET", "/audit?server_id=srv-001&action=delete&actor_id=user-1&cursor=9&page_size=1", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetAuditEntries(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response dto.AuditPage
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "5", response.NextCursor)
+	assert.Len(t, response.Entries, 1)
+	assert.Equal(t, "srv-001", response.Entries[0].ServerID)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetAuditEntries_InvalidQuery(t *testing.T) {
+	tests := []string{
+		"/audit?page_size=abc",
+		"/audit?time=yesterday",
+	}
+
+	for _, target := range tests {
+		t.Run(target, func(t *testing.T) {
+			mockService := new(MockAuditService)
+			handler := handler.NewAuditHandler(mockService)
+
+			req := httptest.NewRequest("GET", target, nil)
+			rec := httptest.NewRecorder()
+
+			handler.GetAuditEntries(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			mockService.AssertNotCalled(t, "GetAuditEntries", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGetAuditEntries_InvalidInput(t *testing.T) {
+	mockService := new(MockAuditService)
+	handler := handler.NewAuditHandler(mockService)
+
+	mockService.On("GetAuditEntries", &dto.AuditFilter{Action: "rename"}, "", 0).Return(nil, service.ErrInvalidInput)
+
+	req := httptest.NewRequest("GET", "/audit?action=rename", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetAuditEntries(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestExportAuditEntries_ContentNegotiation(t *testing.T) {
+	tests := []struct {
+		accept      string
+		format      string
+		contentType string
+		extension   string
+	}{
+		{"", service.FormatCSV, "text/csv", ".csv"},
+		{"*/*", service.FormatCSV, "text/csv", ".csv"},
+		{"application/json", service.FormatJSON, "application/json", ".json"},
+		{"application/x-ndjson", service.FormatNDJSON, "application/x-ndjson", ".ndjson"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.accept, func(t *testing.T) {
+			mockService := new(MockAuditService)
+			handler := handler.NewAuditHandler(mockService)
+
+			mockService.On("ExportAuditEntries", &dto.AuditFilter{ServerID: "srv-001"}, test.format).Return([]byte("entries"), nil)
+
+			req := httptest.NewRequest("GET", "/audit/export?server_id=srv-001", nil)
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ExportAuditEntries(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, test.contentType, rec.Header().Get("Content-Type"))
+			assert.Contains(t, rec.Header().Get("Content-Disposition"), test.extension)
+			assert.Equal(t, "entries", rec.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestExportAuditEntries_NotAcceptable(t *testing.T) {
+	mockService := new(MockAuditService)
+	handler := handler.NewAuditHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/audit/export", nil)
+	req.Header.Set("Accept", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	rec := httptest.NewRecorder()
+
+	handler.ExportAuditEntries(rec, req)
+
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+	mockService.AssertNotCalled(t, "ExportAuditEntries", mock.Anything, mock.Anything)
+}
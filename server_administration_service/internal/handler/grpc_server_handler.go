@@ -2,6 +2,8 @@ package handler
 
 import (
 	"context"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
 	"server_administration_service/internal/service"
 	"server_administration_service/pb"
 	"strconv"
@@ -10,21 +12,58 @@ import (
 
 type GRPCServerHandler struct {
 	serverService service.ServerService
+	maintenanceService service.MaintenanceService
 	pb.UnimplementedServerAdministrationServiceServer
 }
 
-func NewGrpcServerHandler(serverService service.ServerService) *GRPCServerHandler {
+func NewGrpcServerHandler(serverService service.ServerService, maintenanceService service.MaintenanceService) *GRPCServerHandler {
 	return &GRPCServerHandler{
 		serverService: serverService,
+		maintenanceService: maintenanceService,
 	}
 }
 
 func (grpcHandler *GRPCServerHandler) GetAllAddresses(ctx context.Context, req *pb.EmptyRequest) (*pb.AddressesResponse, error) {
+	// Taken before querying, like in GetUpdatedAddresses
+	serverTime := time.Now()
+
 	addresses, err := grpcHandler.serverService.GetAllAddresses()
 	if err != nil {
 		return nil, err
 	}
 
+	response := &pb.AddressesResponse{
+		Addresses:  toAddressInfos(addresses),
+		ServerTime: serverTime.UnixMilli(),
+	}
+
+	return response, nil
+}
+
+func (grpcHandler *GRPCServerHandler) GetUpdatedAddresses(ctx context.Context, req *pb.GetUpdatedAddressesRequest) (*pb.GetUpdatedAddressesResponse, error) {
+	// Taken before querying so that updates made during the query are returned again next time
+	serverTime := time.Now()
+
+	addresses, ids, err := grpcHandler.serverService.GetUpdatedAddresses(time.UnixMilli(req.GetUpdatedSince()))
+	if err != nil {
+		return nil, err
+	}
+
+	serverIDs := make([]int64, len(ids))
+	for i, id := range ids {
+		serverIDs[i] = int64(id)
+	}
+
+	response := &pb.GetUpdatedAddressesResponse{
+		Addresses:  toAddressInfos(addresses),
+		Ids:        serverIDs,
+		ServerTime: serverTime.UnixMilli(),
+	}
+
+	return response, nil
+}
+
+func toAddressInfos(addresses []dto.ServerAddress) []*pb.AddressInfo {
 	addressInfo := make([]*pb.AddressInfo, len(addresses))
 	for i, address := range addresses {
 		addressLink := address.IPv4
@@ -35,20 +74,58 @@ func (grpcHandler *GRPCServerHandler) GetAllAddresses(ctx context.Context, req *
 		addressInfo[i] = &pb.AddressInfo{
 			Id:  int64(address.ID),
 			Address: addressLink,
+			ProbeType: address.ProbeType,
+			ProbePath: address.ProbePath,
+			ProbeExpectedStatus: int32(address.ProbeExpectedStatus),
+			ProbeExpectedBody: address.ProbeExpectedBody,
+			CheckIntervalSeconds: int32(address.CheckIntervalSeconds),
+			TimeoutMs: int32(address.TimeoutMs),
+			Retries: int32(address.Retries),
 		}
 	}
 
-	response := &pb.AddressesResponse{
-		Addresses: addressInfo,
-	}
-
-	return response, nil
+	return addressInfo
 }
 
 func (grpcHandler *GRPCServerHandler) GetServerInformation(ctx context.Context, req *pb.GetServerInformationRequest) (*pb.GetServerInformationResponse, error) {
-	numOnServers, _ := grpcHandler.serverService.GetNumOnServers()
-	numServers, _ := grpcHandler.serverService.GetNumServers()
-	numOffServers := numServers - numOnServers
+	// nil when the report is not scoped by labels
+	scopedIDs, err := grpcHandler.serverService.GetServerIDsByLabels(req.GetLabels())
+	if err != nil {
+		return nil, err
+	}
+
+	statusCounts, err := grpcHandler.serverService.GetStatusCounts(scopedIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Servers in an active maintenance window are reported as Maintenance, whatever their last status
+	maintenanceIDs, err := grpcHandler.maintenanceService.GetServersInMaintenance()
+	if err != nil {
+		return nil, err
+	}
+	if scopedIDs != nil {
+		maintenanceIDs = intersectIDs(maintenanceIDs, scopedIDs)
+	}
+	if len(maintenanceIDs) > 0 {
+		maintenanceCounts, err := grpcHandler.serverService.GetStatusCounts(maintenanceIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		for status, count := range maintenanceCounts {
+			if status == domain.StatusMaintenance || status == domain.StatusDecommissioned {
+				continue
+			}
+			statusCounts[status] -= count
+			statusCounts[domain.StatusMaintenance] += count
+		}
+	}
+
+	numServers := 0
+	for _, count := range statusCounts {
+		numServers += count
+	}
 
 	startTime := req.GetStartTime()
 	endTime := req.GetEndTime()
@@ -58,17 +135,90 @@ func (grpcHandler *GRPCServerHandler) GetServerInformation(ctx context.Context,
 	endTimeObj := time.Unix(endTime, 0)
 
 	// Call the service method to get the server uptime ratio
-	uptimeRatio, err := grpcHandler.serverService.GetServerUptimeRatio(startTimeObj, endTimeObj)
+	uptimeRatio, err := grpcHandler.serverService.GetServerUptimeRatio(startTimeObj, endTimeObj, req.GetLabels())
 	if err != nil {
 		return nil, err
 	}
 
 	response := &pb.GetServerInformationResponse{
 		NumServers: int64(numServers),
-		NumOnServers: int64(numOnServers),
-		NumOffServers: int64(numOffServers),
+		NumOnServers: int64(statusCounts[domain.StatusUp] + statusCounts[domain.StatusDegraded]),
+		NumOffServers: int64(statusCounts[domain.StatusDown]),
 		MeanUptimeRatio: float32(uptimeRatio),
+		NumMaintenanceServers: int64(statusCounts[domain.StatusMaintenance]),
+		NumUnknownServers: int64(statusCounts[domain.StatusUnknown]),
+		NumDegradedServers: int64(statusCounts[domain.StatusDegraded]),
+		NumDecommissionedServers: int64(statusCounts[domain.StatusDecommissioned]),
 	}
 	
+	return response, nil
+}
+
+func intersectIDs(ids, allowedIDs []int) []int {
+	allowed := make(map[int]bool, len(allowedIDs))
+	for _, id := range allowedIDs {
+		allowed[id] = true
+	}
+
+	intersection := make([]int, 0)
+	for _, id := range ids {
+		if allowed[id] {
+			intersection = append(intersection, id)
+		}
+	}
+	return intersection
+}
+
+func (grpcHandler *GRPCServerHandler) GetServersUptime(ctx context.Context, req *pb.GetServersUptimeRequest) (*pb.GetServersUptimeResponse, error) {
+	startTimeObj := time.Unix(req.GetStartTime(), 0)
+	endTimeObj := time.Unix(req.GetEndTime(), 0)
+
+	uptimes, total, err := grpcHandler.serverService.GetServersUptime(
+		startTimeObj, endTimeObj, req.GetLabels(),
+		int(req.GetOffset()), int(req.GetLimit()),
+		req.GetSortColumn(), req.GetSortOrder(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]*pb.ServerUptime, len(uptimes))
+	for i, uptime := range uptimes {
+		servers[i] = &pb.ServerUptime{
+			Id:               int64(uptime.ID),
+			UptimeRatio:      float32(uptime.UptimeRatio),
+			NumChecks:        int64(uptime.NumChecks),
+			NumStatusChanges: int64(uptime.NumStatusChanges),
+		}
+	}
+
+	response := &pb.GetServersUptimeResponse{
+		Servers: servers,
+		Total:   int64(total),
+	}
+
+	return response, nil
+}
+
+func (grpcHandler *GRPCServerHandler) GetDownServers(ctx context.Context, req *pb.GetDownServersRequest) (*pb.GetDownServersResponse, error) {
+	downServers, err := grpcHandler.serverService.GetDownServers(int(req.GetMinConsecutiveChecks()))
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]*pb.DownServer, len(downServers))
+	for i, downServer := range downServers {
+		servers[i] = &pb.DownServer{
+			Id:                    int64(downServer.ID),
+			ServerName:            downServer.ServerName,
+			ConsecutiveDownChecks: int64(downServer.ConsecutiveDownChecks),
+			LastCheck:             downServer.LastCheck.Unix(),
+		}
+	}
+
+	response := &pb.GetDownServersResponse{
+		Servers: servers,
+	}
+
 	return response, nil
 }
\ No newline at end of file
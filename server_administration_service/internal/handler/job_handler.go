@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/repository"
+	"server_administration_service/internal/service"
+
+	"github.com/flashhhhh/pkg/logging"
+)
+
+/*
+	JobHandler runs imports and exports in the background: a submission returns the job at once,
+	the client then polls /jobs until it finished and downloads its result from /jobs/result
+*/
+type JobHandler interface {
+	SubmitImport(w http.ResponseWriter, r *http.Request)
+	SubmitExport(w http.ResponseWriter, r *http.Request)
+	GetJob(w http.ResponseWriter, r *http.Request)
+	CancelJob(w http.ResponseWriter, r *http.Request)
+	GetJobResult(w http.ResponseWriter, r *http.Request)
+}
+
+type jobHandler struct {
+	service service.JobService
+}
+
+func NewJobHandler(service service.JobService) JobHandler {
+	return &jobHandler{
+		service: service,
+	}
+}
+
+// Takes the same form and query parameters as /import, the result is the JSON import report
+func (h *jobHandler) SubmitImport(w http.ResponseWriter, r *http.Request) {
+	buf, options, ok := readImportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.service.SubmitImport(r.Context(), buf, options)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid import job: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to submit import job: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to submit import job", http.StatusInternalServerError)
+		return
+	}
+
+	writeSubmittedJob(w, job)
+}
+
+/*
+	Takes the same filter and sort query parameters as /export, the format is given by
+	?format=xlsx|csv|json|ndjson since the response itself is the job
+*/
+func (h *jobHandler) SubmitExport(w http.ResponseWriter, r *http.Request) {
+	serverFilter, sortFields, err := parseServerQuery(r.URL.Query())
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid server query: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.service.SubmitExport(r.Context(), dto.ExportRequest{
+		Filter:     serverFilter,
+		SortFields: sortFields,
+		Format:     r.URL.Query().Get("format"),
+	})
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid export job: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to submit export job: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to submit export job", http.StatusInternalServerError)
+		return
+	}
+
+	writeSubmittedJob(w, job)
+}
+
+func (h *jobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+
+	writeJob(w, http.StatusOK, job)
+}
+
+// A running import can still be canceled until its servers are being written
+func (h *jobHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.service.CancelJob(job.ID)
+	if errors.Is(err, repository.ErrJobNotFound) {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repository.ErrJobFinished) {
+		http.Error(w, "Job already "+string(job.Status), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to cancel job: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to cancel job", http.StatusInternalServerError)
+		return
+	}
+
+	writeJob(w, http.StatusOK, job)
+}
+
+func (h *jobHandler) GetJobResult(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+	if job.Status != domain.JobSucceeded {
+		http.Error(w, "Job is "+string(job.Status)+", it has no result", http.StatusConflict)
+		return
+	}
+
+	download := &downloadWriter{w: w, mediaType: job.ResultType, filename: job.ResultName}
+	err := h.service.WriteJobResult(download, job.ID)
+	if err != nil && download.started {
+		download.abort("Job result download interrupted", err)
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get job result: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to get job result", http.StatusInternalServerError)
+		return
+	}
+
+	// An empty export writes nothing
+	download.start()
+}
+
+/*
+	loadJob reads the job of the id query parameter. A job belongs to the user who submitted it,
+	anybody else but an admin gets a 404 so that an ID gives access neither to the job nor to its result.
+*/
+func (h *jobHandler) loadJob(w http.ResponseWriter, r *http.Request) (*domain.Job, bool) {
+	id, ok := parseJobID(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	job, err := h.service.GetJob(id)
+	if errors.Is(err, repository.ErrJobNotFound) {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get job: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to get job", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	actor := domain.ActorFromContext(r.Context())
+	owner := actor.ID != "" && actor.ID == job.ActorID
+	if !owner && !actor.IsAdmin() {
+		logging.LogMessage("server_administration_service", "User "+actor.ID+" denied access to job "+id, "ERROR")
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return nil, false
+	}
+	return job, true
+}
+
+func parseJobID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		logging.LogMessage("server_administration_service", "Missing 'id' query parameter", "ERROR")
+		http.Error(w, "Missing 'id' query parameter", http.StatusBadRequest)
+		return "", false
+	}
+	return id, true
+}
+
+// writeSubmittedJob answers 202 Accepted, Location is where the job can be followed
+func writeSubmittedJob(w http.ResponseWriter, job *domain.Job) {
+	w.Header().Set("Location", "/jobs?id="+job.ID)
+	writeJob(w, http.StatusAccepted, job)
+}
+
+func writeJob(w http.ResponseWriter, statusCode int, job *domain.Job) {
+	response, err := json.Marshal(job)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to marshal job response: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to process job data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(response)
+}
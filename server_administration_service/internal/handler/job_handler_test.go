@@ -0,0 +1,290 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/handler"
+	"server_administration_service/internal/repository"
+	"server_administration_service/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockJobService is a mock implementation of service.JobService
+type MockJobService struct {
+	mock.Mock
+}
+
+func (m *MockJobService) SubmitImport(ctx context.Context, buf []byte, options dto.ImportOptions) (*domain.Job, error) {
+	args := m.Called(buf, options)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Job), args.Error(1)
+}
+
+func (m *MockJobService) SubmitExport(ctx context.Context, request dto.ExportRequest) (*domain.Job, error) {
+	args := m.Called(request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Job), args.Error(1)
+}
+
+func (m *MockJobService) GetJob(id string) (*domain.Job, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Job), args.Error(1)
+}
+
+func (m *MockJobService) CancelJob(id string) (*domain.Job, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Job), args.Error(1)
+}
+
+// WriteJobResult writes the bytes it is given before returning its error
+func (m *MockJobService) WriteJobResult(w io.Writer, id string) error {
+	args := m.Called(id)
+	if data := args.Get(0).([]byte); len(data) > 0 {
+		w.Write(data)
+	}
+	return args.Error(1)
+}
+
+func (m *MockJobService) Run(ctx context.Context) {
+	m.Called(ctx)
+}
+
+var (
+	jobOwner = domain.Actor{ID: "user-1", Name: "alice", Role: "user"}
+	jobAdmin = domain.Actor{ID: "admin-1", Name: "root", Role: "admin"}
+)
+
+func newJobRequest(method, target string, actor domain.Actor) *http.Request {
+	return withActor(httptest.NewRequest(method, target, nil), actor)
+}
+
+func TestSubmitImportJob(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := handler.NewJobHandler(mockService)
+
+	content := []byte("server_id,server_name,ipv4\n")
+	job := &domain.Job{ID: "job-1", Type: domain.JobImport, Status: domain.JobQueued}
+	mockService.On("SubmitImport", content, dto.ImportOptions{Mode: "replace", DryRun: true}).Return(job, nil)
+
+	req := withActor(newImportRequest("/import/jobs?mode=replace&dry_run=true", content), jobAdmin)
+	rec := httptest.NewRecorder()
+
+	handler.SubmitImport(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "/jobs?id=job-1", rec.Header().Get("Location"))
+
+	var response domain.Job
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "job-1", response.ID)
+	assert.Equal(t, domain.JobQueued, response.Status)
+	mockService.AssertExpectations(t)
+}
+
+func TestSubmitImportJob_InvalidMode(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := handler.NewJobHandler(mockService)
+
+	mockService.On("SubmitImport", mock.Anything, mock.Anything).Return(nil, service.ErrInvalidInput)
+
+	req := newImportRequest("/import/jobs?mode=merge", []byte("data"))
+	rec := httptest.NewRecorder()
+
+	handler.SubmitImport(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSubmitImportJob_ModeForbidden(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := handler.NewJobHandler(mockService)
+
+	rec := httptest.NewRecorder()
+	handler.SubmitImport(rec, withActor(newImportRequest("/import/jobs?mode=upsert", []byte("data")), jobOwner))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	mockService.AssertNotCalled(t, "SubmitImport", mock.Anything, mock.Anything)
+}
+
+func TestSubmitExportJob(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := handler.NewJobHandler(mockService)
+
+	job := &domain.Job{ID: "job-2", Type: domain.JobExport, Status: domain.JobQueued}
+	mockService.On("SubmitExport", dto.ExportRequest{
+		Filter:     &dto.ServerFilter{Statuses: []string{"Up"}, Port: -1},
+		SortFields: []dto.SortField{{Column: "server_name", Order: "desc"}},
+		Format:     "csv",
+	}).Return(job, nil)
+
+	req := httptest.NewRequest("POST", "/export/jobs?status=Up&sort=-server_name&format=csv", nil)
+	rec := httptest.NewRecorder()
+
+	handler.SubmitExport(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "/jobs?id=job-2", rec.Header().Get("Location"))
+	mockService.AssertExpectations(t)
+}
+
+func TestGetJob(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := handler.NewJobHandler(mockService)
+
+	job := &domain.Job{ID: "job-1", ActorID: "user-1", Type: domain.JobExport, Status: domain.JobRunning, Processed: 500, Total: 2000}
+	mockService.On("GetJob", "job-1").Return(job, nil)
+	mockService.On("GetJob", "missing").Return(nil, repository.ErrJobNotFound)
+
+	rec := httptest.NewRecorder()
+	handler.GetJob(rec, newJobRequest("GET", "/jobs?id=job-1", jobOwner))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"processed":500,"total":2000`)
+
+	rec = httptest.NewRecorder()
+	handler.GetJob(rec, newJobRequest("GET", "/jobs?id=missing", jobOwner))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.GetJob(rec, newJobRequest("GET", "/jobs", jobOwner))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCancelJob(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := handler.NewJobHandler(mockService)
+
+	mockService.On("GetJob", "job-1").Return(&domain.Job{ID: "job-1", ActorID: "user-1", Status: domain.JobRunning}, nil)
+	mockService.On("GetJob", "job-2").Return(&domain.Job{ID: "job-2", ActorID: "user-1", Status: domain.JobSucceeded}, nil)
+	mockService.On("CancelJob", "job-1").Return(&domain.Job{ID: "job-1", ActorID: "user-1", Status: domain.JobCanceled}, nil)
+	mockService.On("CancelJob", "job-2").Return(&domain.Job{ID: "job-2", ActorID: "user-1", Status: domain.JobSucceeded}, repository.ErrJobFinished)
+
+	rec := httptest.NewRecorder()
+	handler.CancelJob(rec, newJobRequest("DELETE", "/jobs?id=job-1", jobOwner))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"canceled"`)
+
+	rec = httptest.NewRecorder()
+	handler.CancelJob(rec, newJobRequest("DELETE", "/jobs?id=job-2", jobOwner))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "Job already succeeded\n", rec.Body.String())
+}
+
+func TestGetJobResult(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := handler.NewJobHandler(mockService)
+
+	mockService.On("GetJob", "job-1").Return(&domain.Job{
+		ID:         "job-1",
+		ActorID:    "user-1",
+		Status:     domain.JobSucceeded,
+		ResultName: "servers_2025-01-02_03-04-05.csv",
+		ResultType: "text/csv",
+	}, nil)
+	mockService.On("WriteJobResult", "job-1").Return([]byte("server_id\nsrv-1\n"), nil)
+
+	rec := httptest.NewRecorder()
+	handler.GetJobResult(rec, newJobRequest("GET", "/jobs/result?id=job-1", jobOwner))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=servers_2025-01-02_03-04-05.csv", rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "server_id\nsrv-1\n", rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestGetJobResult_NotReady(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := handler.NewJobHandler(mockService)
+
+	mockService.On("GetJob", "job-1").Return(&domain.Job{ID: "job-1", ActorID: "user-1", Status: domain.JobRunning}, nil)
+
+	rec := httptest.NewRecorder()
+	handler.GetJobResult(rec, newJobRequest("GET", "/jobs/result?id=job-1", jobOwner))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "Job is running, it has no result\n", rec.Body.String())
+	mockService.AssertNotCalled(t, "WriteJobResult", mock.Anything)
+}
+
+func TestGetJobResult_ServiceError(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := handler.NewJobHandler(mockService)
+
+	mockService.On("GetJob", "job-1").Return(&domain.Job{ID: "job-1", ActorID: "user-1", Status: domain.JobSucceeded, ResultType: "text/csv"}, nil)
+	mockService.On("WriteJobResult", "job-1").Return([]byte{}, assert.AnError)
+
+	rec := httptest.NewRecorder()
+	handler.GetJobResult(rec, newJobRequest("GET", "/jobs/result?id=job-1", jobOwner))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Disposition"))
+}
+
+func TestJobs_OtherUser(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := handler.NewJobHandler(mockService)
+
+	// An admin-only export submitted by an admin
+	mockService.On("GetJob", "job-1").Return(&domain.Job{ID: "job-1", ActorID: "admin-1", Type: domain.JobExport, Status: domain.JobSucceeded}, nil)
+
+	rec := httptest.NewRecorder()
+	handler.GetJob(rec, newJobRequest("GET", "/jobs?id=job-1", jobOwner))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.GetJobResult(rec, newJobRequest("GET", "/jobs/result?id=job-1", jobOwner))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.CancelJob(rec, newJobRequest("DELETE", "/jobs?id=job-1", jobOwner))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Jobs submitted before submitters were recorded belong to nobody
+	mockService.On("GetJob", "job-2").Return(&domain.Job{ID: "job-2", Status: domain.JobRunning}, nil)
+
+	rec = httptest.NewRecorder()
+	handler.GetJob(rec, newJobRequest("GET", "/jobs?id=job-2", domain.Actor{Role: "user"}))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	mockService.AssertNotCalled(t, "WriteJobResult", mock.Anything)
+	mockService.AssertNotCalled(t, "CancelJob", mock.Anything)
+}
+
+func TestJobs_Admin(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := handler.NewJobHandler(mockService)
+
+	mockService.On("GetJob", "job-1").Return(&domain.Job{ID: "job-1", ActorID: "user-1", Type: domain.JobImport, Status: domain.JobRunning}, nil)
+	mockService.On("CancelJob", "job-1").Return(&domain.Job{ID: "job-1", ActorID: "user-1", Status: domain.JobCanceled}, nil)
+
+	rec := httptest.NewRecorder()
+	handler.GetJob(rec, newJobRequest("GET", "/jobs?id=job-1", jobAdmin))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.CancelJob(rec, newJobRequest("DELETE", "/jobs?id=job-1", jobAdmin))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"canceled"`)
+	mockService.AssertExpectations(t)
+}
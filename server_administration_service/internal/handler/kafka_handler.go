@@ -2,19 +2,26 @@ package handler
 
 import (
 	"encoding/json"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
 	"server_administration_service/internal/service"
+	"strconv"
 
 	"github.com/IBM/sarama"
 	"github.com/flashhhhh/pkg/logging"
 )
 
 type ServerConsumerHandler struct {
-	serverService service.ServerService
+	serverService      service.ServerService
+	quorumService      service.QuorumService
+	maintenanceService service.MaintenanceService
 }
 
-func NewServerConsumerHandler(serverService service.ServerService) *ServerConsumerHandler {
+func NewServerConsumerHandler(serverService service.ServerService, quorumService service.QuorumService, maintenanceService service.MaintenanceService) *ServerConsumerHandler {
 	return &ServerConsumerHandler{
-		serverService: serverService,
+		serverService:      serverService,
+		quorumService:      quorumService,
+		maintenanceService: maintenanceService,
 	}
 }
 
@@ -43,11 +50,7 @@ func (h ServerConsumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 			session.MarkMessage(message, "")
 
 			// Parse the message
-			var serverMessage struct {
-				IPv4     string `json:"ipv4"`
-				ID int `json:"id"`
-				Status   bool   `json:"status"`
-			}
+			var serverMessage dto.HealthCheckResult
 			
 			if err := json.Unmarshal(message.Value, &serverMessage); err != nil {
 				logging.LogMessage("server_administration_service", "Error parsing message: "+err.Error(), "ERROR")
@@ -57,14 +60,27 @@ func (h ServerConsumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 			// Now you can use the parsed message
 			logging.LogMessage("server_administration_service", "Updating server status: "+serverMessage.IPv4, "INFO")
 
-			status := "Off"
+			status := domain.StatusDown
 			if serverMessage.Status {
-				status = "On"
+				status = domain.StatusUp
+			}
+
+			inMaintenance, err := h.maintenanceService.IsInMaintenance(serverMessage.ID)
+			if err != nil {
+				logging.LogMessage("server_administration_service", "Error checking maintenance windows: "+err.Error(), "ERROR")
+			}
+
+			var decidedStatus domain.ServerStatus
+			if inMaintenance && !serverMessage.Status {
+				// Planned downtime: the server keeps its status and the check does not count as Off
+				logging.LogMessage("server_administration_service", "Server "+strconv.Itoa(serverMessage.ID)+" is in maintenance, keeping its status", "INFO")
+				status = domain.StatusMaintenance
+			} else {
+				decidedStatus = h.decideStatus(serverMessage, status)
 			}
-			h.serverService.UpdateServerStatus(serverMessage.ID, status)
 
 			logging.LogMessage("server_administration_service", "Write to ES: "+serverMessage.IPv4, "INFO")
-			err := h.serverService.AddServerStatus(serverMessage.ID, status)
+			err = h.serverService.AddServerStatus(serverMessage, status, decidedStatus)
 			if err != nil {
 				logging.LogMessage("server_administration_service", "Error writing to ES: "+err.Error(), "ERROR")
 			}
@@ -74,4 +90,24 @@ func (h ServerConsumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// decideStatus returns the status decided by the quorum, empty when there is no quorum
+func (h ServerConsumerHandler) decideStatus(result dto.HealthCheckResult, status domain.ServerStatus) domain.ServerStatus {
+	// Without a quorum of locations the status stays as it is
+	decidedStatus, decided, err := h.quorumService.Decide(result)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Error deciding quorum status, using the single result: "+err.Error(), "ERROR")
+		decidedStatus, decided = status, true
+	}
+
+	if !decided {
+		logging.LogMessage("server_administration_service", "No quorum yet for server "+strconv.Itoa(result.ID)+", keeping its status", "INFO")
+		return ""
+	}
+
+	if err := h.serverService.UpdateServerStatus(result.ID, decidedStatus); err != nil {
+		logging.LogMessage("server_administration_service", "Error updating server status: "+err.Error(), "ERROR")
+	}
+	return decidedStatus
+}
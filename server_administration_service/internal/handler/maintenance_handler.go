@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/repository"
+	"server_administration_service/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/flashhhhh/pkg/logging"
+)
+
+type MaintenanceHandler interface {
+	CreateWindow(w http.ResponseWriter, r *http.Request)
+	GetWindows(w http.ResponseWriter, r *http.Request)
+	UpdateWindow(w http.ResponseWriter, r *http.Request)
+	DeleteWindow(w http.ResponseWriter, r *http.Request)
+}
+
+type maintenanceHandler struct {
+	service service.MaintenanceService
+}
+
+func NewMaintenanceHandler(service service.MaintenanceService) MaintenanceHandler {
+	return &maintenanceHandler{
+		service: service,
+	}
+}
+
+// Times are unix timestamps like in the other endpoints
+type maintenanceWindowRequest struct {
+	ServerIDs        []int  `json:"server_ids"`
+	ServerNameFilter string `json:"server_name_filter"`
+	IPv4Prefix       string `json:"ipv4_prefix"`
+	StartTime        int64  `json:"start_time"`
+	EndTime          int64  `json:"end_time"`
+	Reason           string `json:"reason"`
+}
+
+func (request *maintenanceWindowRequest) toWindow() *domain.MaintenanceWindow {
+	window := &domain.MaintenanceWindow{
+		ServerIDs:        request.ServerIDs,
+		ServerNameFilter: request.ServerNameFilter,
+		IPv4Prefix:       request.IPv4Prefix,
+		Reason:           request.Reason,
+	}
+	if request.StartTime > 0 {
+		window.StartTime = time.Unix(request.StartTime, 0)
+	}
+	if request.EndTime > 0 {
+		window.EndTime = time.Unix(request.EndTime, 0)
+	}
+	return window
+}
+
+func (h *maintenanceHandler) CreateWindow(w http.ResponseWriter, r *http.Request) {
+	var request maintenanceWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logging.LogMessage("server_administration_service", "Failed to decode request body for request CreateWindow: "+err.Error(), "ERROR")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	window := request.toWindow()
+	err := h.service.CreateWindow(window)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid maintenance window: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to create maintenance window: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to create maintenance window", http.StatusInternalServerError)
+		return
+	}
+
+	writeWindow(w, http.StatusCreated, window)
+}
+
+/*
+	?active=true only returns the windows in effect right now
+*/
+func (h *maintenanceHandler) GetWindows(w http.ResponseWriter, r *http.Request) {
+	activeOnly := false
+	if activeStr := r.URL.Query().Get("active"); activeStr != "" {
+		var err error
+		activeOnly, err = strconv.ParseBool(activeStr)
+		if err != nil {
+			logging.LogMessage("server_administration_service", "Invalid 'active' query parameter: "+activeStr, "ERROR")
+			http.Error(w, "Invalid 'active' query parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	windows, err := h.service.GetWindows(activeOnly)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get maintenance windows: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to get maintenance windows", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := json.Marshal(windows)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to marshal maintenance windows response: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to process maintenance windows data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (h *maintenanceHandler) UpdateWindow(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseWindowID(w, r)
+	if !ok {
+		return
+	}
+
+	var request maintenanceWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logging.LogMessage("server_administration_service", "Failed to decode request body for request UpdateWindow: "+err.Error(), "ERROR")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	window := request.toWindow()
+	window.ID = id
+
+	err := h.service.UpdateWindow(window)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid maintenance window: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, repository.ErrMaintenanceWindowNotFound) {
+		http.Error(w, "Maintenance window not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to update maintenance window: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to update maintenance window", http.StatusInternalServerError)
+		return
+	}
+
+	writeWindow(w, http.StatusOK, window)
+}
+
+func (h *maintenanceHandler) DeleteWindow(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseWindowID(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.service.DeleteWindow(id)
+	if errors.Is(err, repository.ErrMaintenanceWindowNotFound) {
+		http.Error(w, "Maintenance window not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to delete maintenance window: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to delete maintenance window", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Maintenance window deleted successfully"))
+}
+
+func parseWindowID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	idStr := r.URL.Query().Get("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		logging.LogMessage("server_administration_service", "Invalid 'id' query parameter: "+idStr, "ERROR")
+		http.Error(w, "Invalid 'id' query parameter", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeWindow(w http.ResponseWriter, statusCode int, window *domain.MaintenanceWindow) {
+	response, err := json.Marshal(window)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to marshal maintenance window response: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to process maintenance window data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(response)
+}
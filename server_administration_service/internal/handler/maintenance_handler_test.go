@@ -0,0 +1,212 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/handler"
+	"server_administration_service/internal/repository"
+	"server_administration_service/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockMaintenanceService is a mock implementation of service.MaintenanceService
+type MockMaintenanceService struct {
+	mock.Mock
+}
+
+func (m *MockMaintenanceService) CreateWindow(window *domain.MaintenanceWindow) error {
+	args := m.Called(window)
+	return args.Error(0)
+}
+
+func (m *MockMaintenanceService) GetWindows(activeOnly bool) ([]domain.MaintenanceWindow, error) {
+	args := m.Called(activeOnly)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.MaintenanceWindow), args.Error(1)
+}
+
+func (m *MockMaintenanceService) GetWindow(id int) (*domain.MaintenanceWindow, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.MaintenanceWindow), args.Error(1)
+}
+
+func (m *MockMaintenanceService) UpdateWindow(window *domain.MaintenanceWindow) error {
+	args := m.Called(window)
+	return args.Error(0)
+}
+
+func (m *MockMaintenanceService) DeleteWindow(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockMaintenanceService) IsInMaintenance(id int) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMaintenanceService) GetServersInMaintenance() ([]int, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func TestCreateMaintenanceWindow_Success(t *testing.T) {
+	mockService := new(MockMaintenanceService)
+	handler := handler.NewMaintenanceHandler(mockService)
+
+	mockService.On("CreateWindow", mock.MatchedBy(func(window *domain.MaintenanceWindow) bool {
+		return assert.ObjectsAreEqual([]int{1, 2}, window.ServerIDs) &&
+			window.ServerNameFilter == "db-" &&
+			window.StartTime.Equal(time.Unix(1000, 0)) &&
+			window.EndTime.Equal(time.Unix(4600, 0)) &&
+			window.Reason == "Kernel upgrade"
+	})).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.MaintenanceWindow).ID = 3
+	}).Return(nil)
+
+	body := `{"server_ids": [1, 2], "server_name_filter": "db-", "start_time": 1000, "end_time": 4600, "reason": "Kernel upgrade"}`
+	req := httptest.NewRequest("POST", "/maintenance", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	handler.CreateWindow(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	var window domain.MaintenanceWindow
+	err := json.NewDecoder(res.Body).Decode(&window)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, window.ID)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateMaintenanceWindow_InvalidInput(t *testing.T) {
+	mockService := new(MockMaintenanceService)
+	handler := handler.NewMaintenanceHandler(mockService)
+
+	mockService.On("CreateWindow", mock.Anything).Return(fmt.Errorf("%w: reason is required", service.ErrInvalidInput))
+
+	req := httptest.NewRequest("POST", "/maintenance", bytes.NewBufferString(`{"server_ids": [1], "start_time": 1000, "end_time": 2000}`))
+	rec := httptest.NewRecorder()
+
+	handler.CreateWindow(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateMaintenanceWindow_InvalidBody(t *testing.T) {
+	mockService := new(MockMaintenanceService)
+	handler := handler.NewMaintenanceHandler(mockService)
+
+	req := httptest.NewRequest("POST", "/maintenance", bytes.NewBufferString("not json"))
+	rec := httptest.NewRecorder()
+
+	handler.CreateWindow(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "CreateWindow", mock.Anything)
+}
+
+func TestGetMaintenanceWindows_ActiveOnly(t *testing.T) {
+	mockService := new(MockMaintenanceService)
+	handler := handler.NewMaintenanceHandler(mockService)
+
+	windows := []domain.MaintenanceWindow{
+		{ID: 1, ServerIDs: []int{4}, StartTime: time.Unix(1000, 0).UTC(), EndTime: time.Unix(2000, 0).UTC(), Reason: "Reboot"},
+	}
+	mockService.On("GetWindows", true).Return(windows, nil)
+
+	req := httptest.NewRequest("GET", "/maintenance?active=true", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetWindows(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var response []domain.MaintenanceWindow
+	err := json.NewDecoder(res.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, windows, response)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetMaintenanceWindows_InvalidActive(t *testing.T) {
+	mockService := new(MockMaintenanceService)
+	handler := handler.NewMaintenanceHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/maintenance?active=maybe", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetWindows(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "GetWindows", mock.Anything)
+}
+
+func TestUpdateMaintenanceWindow_NotFound(t *testing.T) {
+	mockService := new(MockMaintenanceService)
+	handler := handler.NewMaintenanceHandler(mockService)
+
+	mockService.On("UpdateWindow", mock.MatchedBy(func(window *domain.MaintenanceWindow) bool {
+		return window.ID == 9
+	})).Return(repository.ErrMaintenanceWindowNotFound)
+
+	body := `{"server_ids": [1], "start_time": 1000, "end_time": 2000, "reason": "Reboot"}`
+	req := httptest.NewRequest("PUT", "/maintenance?id=9", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	handler.UpdateWindow(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeleteMaintenanceWindow(t *testing.T) {
+	mockService := new(MockMaintenanceService)
+	handler := handler.NewMaintenanceHandler(mockService)
+
+	mockService.On("DeleteWindow", 5).Return(nil)
+
+	req := httptest.NewRequest("DELETE", "/maintenance?id=5", nil)
+	rec := httptest.NewRecorder()
+
+	handler.DeleteWindow(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeleteMaintenanceWindow_InvalidID(t *testing.T) {
+	mockService := new(MockMaintenanceService)
+	handler := handler.NewMaintenanceHandler(mockService)
+
+	req := httptest.NewRequest("DELETE", "/maintenance?id=abc", nil)
+	rec := httptest.NewRecorder()
+
+	handler.DeleteWindow(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "DeleteWindow", mock.Anything)
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/flashhhhh/pkg/logging"
+)
+
+type ServerEventsConsumerHandler struct {
+	serverEventService service.ServerEventService
+	quorumService      service.QuorumService
+	maintenanceService service.MaintenanceService
+}
+
+func NewServerEventsConsumerHandler(serverEventService service.ServerEventService, quorumService service.QuorumService, maintenanceService service.MaintenanceService) *ServerEventsConsumerHandler {
+	return &ServerEventsConsumerHandler{
+		serverEventService: serverEventService,
+		quorumService:      quorumService,
+		maintenanceService: maintenanceService,
+	}
+}
+
+func (h ServerEventsConsumerHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h ServerEventsConsumerHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+/*
+	Unlike ServerConsumerHandler the messages are processed one by one,
+	the sliding windows need the check results in order. The windows see the
+	status decided by the quorum of locations, not the result of a single location
+*/
+func (h ServerEventsConsumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for message := range claim.Messages() {
+		var result dto.HealthCheckResult
+		if err := json.Unmarshal(message.Value, &result); err != nil {
+			logging.LogMessage("server_administration_service", "Error parsing message: "+err.Error(), "ERROR")
+			session.MarkMessage(message, "")
+			continue
+		}
+
+		// Failures during a maintenance window must not raise server_down alerts
+		if !result.Status {
+			inMaintenance, err := h.maintenanceService.IsInMaintenance(result.ID)
+			if err != nil {
+				logging.LogMessage("server_administration_service", "Error checking maintenance windows: "+err.Error(), "ERROR")
+			}
+			if inMaintenance {
+				session.MarkMessage(message, "")
+				continue
+			}
+		}
+
+		// Recording the location status again is harmless, ServerConsumerHandler stores the same value
+		decidedStatus, decided, err := h.quorumService.Decide(result)
+		if err != nil {
+			logging.LogMessage("server_administration_service", "Error deciding quorum status, using the single result: "+err.Error(), "ERROR")
+		} else if !decided {
+			logging.LogMessage("server_administration_service", "No quorum yet for server "+strconv.Itoa(result.ID)+", skipping the event window", "INFO")
+			session.MarkMessage(message, "")
+			continue
+		} else {
+			result.Status = decidedStatus == domain.StatusUp
+		}
+
+		checkedAt := message.Timestamp
+		if checkedAt.IsZero() {
+			checkedAt = time.Now()
+		}
+
+		if err := h.serverEventService.ProcessCheckResult(result, checkedAt); err != nil {
+			logging.LogMessage("server_administration_service", "Error publishing server event: "+err.Error(), "ERROR")
+		}
+
+		session.MarkMessage(message, "")
+	}
+
+	return nil
+}
@@ -2,9 +2,12 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"server_administration_service/internal/domain"
 	"server_administration_service/internal/dto"
+	"server_administration_service/internal/repository"
 	"server_administration_service/internal/service"
 	"strconv"
 	"time"
@@ -17,8 +20,16 @@ type ServerHandler interface {
 	ViewServers(w http.ResponseWriter, r *http.Request)
 	UpdateServer(w http.ResponseWriter, r *http.Request)
 	DeleteServer(w http.ResponseWriter, r *http.Request)
+	GetDeletedServers(w http.ResponseWriter, r *http.Request)
+	RestoreServer(w http.ResponseWriter, r *http.Request)
 	ImportServers(w http.ResponseWriter, r *http.Request)
 	ExportServers(w http.ResponseWriter, r *http.Request)
+	SearchServers(w http.ResponseWriter, r *http.Request)
+	GetServersUptime(w http.ResponseWriter, r *http.Request)
+	GetUptimeTimeline(w http.ResponseWriter, r *http.Request)
+	GetStatusTransitions(w http.ResponseWriter, r *http.Request)
+	GetLatencyPercentiles(w http.ResponseWriter, r *http.Request)
+	GetLocationStatuses(w http.ResponseWriter, r *http.Request)
 }
 
 type serverHandler struct {
@@ -52,7 +63,51 @@ func (h *serverHandler) CreateServer(w http.ResponseWriter, r *http.Request) {
 		port = int(portFloat)
 	}
 	
-	id, err := h.service.CreateServer(serverID, serverName, status, ipAddress, port)
+	probeType, _ := requestBody["probe_type"].(string)
+	probePath, _ := requestBody["probe_path"].(string)
+	probeExpectedStatus, _ := requestBody["probe_expected_status"].(float64)
+	probeExpectedBody, _ := requestBody["probe_expected_body"].(string)
+	checkIntervalSeconds, _ := requestBody["check_interval_seconds"].(float64)
+	timeoutMs, _ := requestBody["timeout_ms"].(float64)
+	retries, _ := requestBody["retries"].(float64)
+
+	var labels map[string]string
+	if value, existed := requestBody["labels"]; existed {
+		labels, ok = toLabels(value)
+		if !ok {
+			logging.LogMessage("server_administration_service", "Invalid labels in request CreateServer", "ERROR")
+			http.Error(w, "Labels must be an object of strings", http.StatusBadRequest)
+			return
+		}
+	}
+
+	server := &domain.Server{
+		ServerID:             serverID,
+		ServerName:           serverName,
+		Status:               domain.ServerStatus(status),
+		IPv4:                 ipAddress,
+		Port:                 port,
+		ProbeType:            probeType,
+		ProbePath:            probePath,
+		ProbeExpectedStatus:  int(probeExpectedStatus),
+		ProbeExpectedBody:    probeExpectedBody,
+		CheckIntervalSeconds: int(checkIntervalSeconds),
+		TimeoutMs:            int(timeoutMs),
+		Retries:              int(retries),
+		Labels:               labels,
+	}
+
+	id, err := h.service.CreateServer(r.Context(), server)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid server: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, repository.ErrServerConflict) {
+		logging.LogMessage("server_administration_service", "Server conflict: "+serverID+" - "+serverName, "ERROR")
+		http.Error(w, "A server with this server_id or server_name already exists, possibly in the trash where it can be restored", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		logging.LogMessage("server_administration_service", "Failed to create server: "+err.Error(), "ERROR")
 		http.Error(w, "Failed to create server", http.StatusInternalServerError)
@@ -65,60 +120,33 @@ func (h *serverHandler) CreateServer(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *serverHandler) ViewServers(w http.ResponseWriter, r *http.Request) {
-	fromStr := r.URL.Query().Get("from")
-	from, err := strconv.Atoi(fromStr)
-	if err != nil {
-		logging.LogMessage("server_administration_service", "Invalid 'from' query parameter: "+err.Error(), "ERROR")
-		logging.LogMessage("server_administration_service", "from: "+fromStr, "DEBUG")
-		http.Error(w, "Invalid 'from' query parameter", http.StatusBadRequest)
-		return
+	// The cursor comes from the next_cursor of the previous page, none for the first page
+	cursor := r.URL.Query().Get("cursor")
+
+	pageSize := 0
+	if pageSizeStr := r.URL.Query().Get("page_size"); pageSizeStr != "" {
+		var err error
+		pageSize, err = strconv.Atoi(pageSizeStr)
+		if err != nil {
+			logging.LogMessage("server_administration_service", "Invalid 'page_size' query parameter: "+pageSizeStr, "ERROR")
+			http.Error(w, "Invalid 'page_size' query parameter", http.StatusBadRequest)
+			return
+		}
 	}
 
-	toStr := r.URL.Query().Get("to")
-	to, err := strconv.Atoi(toStr)
+	serverFilter, sortFields, err := parseServerQuery(r.URL.Query())
 	if err != nil {
-		logging.LogMessage("server_administration_service", "Invalid 'to' query parameter: "+err.Error(), "ERROR")
-		logging.LogMessage("server_administration_service", "to: "+toStr, "DEBUG")
-		http.Error(w, "Invalid 'to' query parameter", http.StatusBadRequest)
+		logging.LogMessage("server_administration_service", "Invalid server query: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	sortedColumn := r.URL.Query().Get("sort_column")
-	order := r.URL.Query().Get("sort_order")
-
-	serverID := r.URL.Query().Get("server_id")
-	serverName := r.URL.Query().Get("server_name")
-	status := r.URL.Query().Get("status")
-	ipv4 := r.URL.Query().Get("ipv4")
-	portStr := r.URL.Query().Get("port")
-
-	serverFilter := dto.ServerFilter{}
-
-	if serverID != "" {
-		serverFilter.ServerID = serverID
-	}
-	if serverName != "" {
-		serverFilter.ServerName = serverName
-	}
-	if status != "" {
-		serverFilter.Status = status
-	}
-	if ipv4 != "" {
-		serverFilter.IPv4 = ipv4
-	}
-	if portStr != "" {
-		port, err := strconv.Atoi(portStr)
-		if err != nil {
-			logging.LogMessage("server_administration_service", "Invalid port in query params", "ERROR")
-			http.Error(w, "Invalid port", http.StatusBadRequest)
-			return
-		}
-		serverFilter.Port = port
-	} else {
-		serverFilter.Port = -1
+	page, err := h.service.ViewServers(serverFilter, sortFields, cursor, pageSize)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid server filter: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-
-	servers, err := h.service.ViewServers(&serverFilter, from, to, sortedColumn, order)
 	if err != nil {
 		logging.LogMessage("server_administration_service", "Failed to view servers: "+err.Error(), "ERROR")
 		http.Error(w, "Failed to view servers", http.StatusInternalServerError)
@@ -129,7 +157,7 @@ func (h *serverHandler) ViewServers(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	response, err := json.Marshal(servers)
+	response, err := json.Marshal(page)
 	if err != nil {
 		logging.LogMessage("server_administration_service", "Failed to marshal servers response: "+err.Error(), "ERROR")
 		http.Error(w, "Failed to process servers data", http.StatusInternalServerError)
@@ -176,7 +204,37 @@ func (h *serverHandler) UpdateServer(w http.ResponseWriter, r *http.Request) {
 		updatedData["port"] = int(portFloat)
 	}
 
-	err = h.service.UpdateServer(serverID, updatedData)
+	for _, field := range []string{"probe_type", "probe_path", "probe_expected_body"} {
+		value, existed := requestBody[field].(string)
+		if existed {
+			updatedData[field] = value
+		}
+	}
+
+	for _, field := range []string{"probe_expected_status", "check_interval_seconds", "timeout_ms", "retries"} {
+		value, existed := requestBody[field].(float64)
+		if existed {
+			updatedData[field] = int(value)
+		}
+	}
+
+	// The labels given replace the stored ones
+	if value, existed := requestBody["labels"]; existed {
+		labels, ok := toLabels(value)
+		if !ok {
+			logging.LogMessage("server_administration_service", "Invalid labels in request UpdateServer", "ERROR")
+			http.Error(w, "Labels must be an object of strings", http.StatusBadRequest)
+			return
+		}
+		updatedData["labels"] = labels
+	}
+
+	err = h.service.UpdateServer(r.Context(), serverID, updatedData)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid server update: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		logging.LogMessage("server_administration_service", "Failed to update server: "+err.Error(), "ERROR")
 		http.Error(w, "Failed to update server", http.StatusInternalServerError)
@@ -196,7 +254,7 @@ func (h *serverHandler) DeleteServer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.service.DeleteServer(serverID)
+	err := h.service.DeleteServer(r.Context(), serverID)
 	if err != nil {
 		logging.LogMessage("server_administration_service", "Invalid server ID: "+serverID+" - "+err.Error(), "ERROR")
 		http.Error(w, "Invalid server ID", http.StatusNotFound)
@@ -208,25 +266,137 @@ func (h *serverHandler) DeleteServer(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Server deleted successfully"))
 }
 
-func (h *serverHandler) ImportServers(w http.ResponseWriter, r *http.Request) {
+// Lists the trash, the servers deleted most recently first
+func (h *serverHandler) GetDeletedServers(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	offset := 0
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		offset, err = strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			logging.LogMessage("server_administration_service", "Invalid 'offset' query parameter: "+offsetStr, "ERROR")
+			http.Error(w, "Invalid 'offset' query parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			logging.LogMessage("server_administration_service", "Invalid 'limit' query parameter: "+limitStr, "ERROR")
+			http.Error(w, "Invalid 'limit' query parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	servers, total, err := h.service.GetDeletedServers(offset, limit)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid trash request: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get deleted servers: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to get deleted servers", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := json.Marshal(map[string]interface{}{
+		"servers": servers,
+		"total":   total,
+	})
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to marshal deleted servers response: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to process deleted servers", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (h *serverHandler) RestoreServer(w http.ResponseWriter, r *http.Request) {
+	serverID := r.URL.Query().Get("server_id")
+	if serverID == "" {
+		logging.LogMessage("server_administration_service", "Server ID is required for restoring", "ERROR")
+		http.Error(w, "Server ID is required", http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.RestoreServer(r.Context(), serverID)
+	if errors.Is(err, repository.ErrServerNotInTrash) {
+		logging.LogMessage("server_administration_service", "Server ID: "+serverID+" is not in the trash", "ERROR")
+		http.Error(w, "Server not found in the trash", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to restore server: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to restore server", http.StatusInternalServerError)
+		return
+	}
+
+	logging.LogMessage("server_administration_service", "Server restored successfully with ID: "+serverID, "INFO")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Server restored successfully"))
+}
+
+/*
+	readImportRequest reads the servers_file of a multipart form and the mode and dry_run query
+	parameters, it answers the request itself when they are invalid or the mode is not allowed to the user
+*/
+func readImportRequest(w http.ResponseWriter, r *http.Request) ([]byte, dto.ImportOptions, bool) {
+	// The mode is validated by the service
+	options := dto.ImportOptions{Mode: r.URL.Query().Get("mode")}
+
+	// Like /update and /delete, changing or deleting the existing servers is left to the admins
+	if (options.Mode == service.ImportModeUpsert || options.Mode == service.ImportModeReplace) && !domain.ActorFromContext(r.Context()).IsAdmin() {
+		logging.LogMessage("server_administration_service", "Import mode "+options.Mode+" denied to a non-admin user", "ERROR")
+		http.Error(w, "Only admins can import with mode "+options.Mode, http.StatusForbidden)
+		return nil, options, false
+	}
+	if dryRunStr := r.URL.Query().Get("dry_run"); dryRunStr != "" {
+		dryRun, err := strconv.ParseBool(dryRunStr)
+		if err != nil {
+			logging.LogMessage("server_administration_service", "Invalid 'dry_run' query parameter: "+dryRunStr, "ERROR")
+			http.Error(w, "Invalid 'dry_run' query parameter", http.StatusBadRequest)
+			return nil, options, false
+		}
+		options.DryRun = dryRun
+	}
+
 	serversFile, _, err := r.FormFile("servers_file")
 
 	if err != nil {
 		logging.LogMessage("server_administration_service", "Failed to get file from request: "+err.Error(), "ERROR")
 		http.Error(w, "Failed to get file from request", http.StatusBadRequest)
-		return
+		return nil, options, false
 	}
 	defer serversFile.Close()
 
-	var buf []byte
-	buf, err = io.ReadAll(serversFile)
+	buf, err := io.ReadAll(serversFile)
 	if err != nil {
 		logging.LogMessage("server_administration_service", "Failed to read file: "+err.Error(), "ERROR")
 		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return nil, options, false
+	}
+
+	return buf, options, true
+}
+
+func (h *serverHandler) ImportServers(w http.ResponseWriter, r *http.Request) {
+	buf, options, ok := readImportRequest(w, r)
+	if !ok {
 		return
 	}
 
-	importedServer, nonImportedServer, err := h.service.ImportServers(buf)
+	report, err := h.service.ImportServers(r.Context(), buf, options)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid import file: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		logging.LogMessage("server_administration_service", "Failed to import servers: "+err.Error(), "ERROR")
 		http.Error(w, "Failed to import servers", http.StatusInternalServerError)
@@ -234,91 +404,448 @@ func (h *serverHandler) ImportServers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logging.LogMessage("server_administration_service", "Servers imported successfully", "INFO")
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/json")
 
-	response := map[string]interface{}{
-		"imported_servers":    importedServer,
-		"non_imported_servers": nonImportedServer,
+	// ?report=xlsx downloads the rejected rows with their errors instead, to fix them and import them again
+	if r.URL.Query().Get("report") == "xlsx" {
+		reportBuf, err := h.service.ExportRejectedRows(report)
+		if err != nil {
+			logging.LogMessage("server_administration_service", "Failed to export rejected rows: "+err.Error(), "ERROR")
+			http.Error(w, "Failed to export rejected rows", http.StatusInternalServerError)
+			return
+		}
+
+		filename := "rejected_servers_" + time.Now().Format("2006-01-02_15-04-05") + ".xlsx"
+
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition, Created-Count, Updated-Count, Deleted-Count, Rejected-Count")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+		w.Header().Set("File-Name", filename)
+		w.Header().Set("Created-Count", strconv.Itoa(len(report.Created)))
+		w.Header().Set("Updated-Count", strconv.Itoa(len(report.Updated)))
+		w.Header().Set("Deleted-Count", strconv.Itoa(len(report.Deleted)))
+		w.Header().Set("Rejected-Count", strconv.Itoa(len(report.Rejected)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(reportBuf)
+		return
 	}
-	
-	responseJSON, err := json.Marshal(response)
+
+	responseJSON, err := json.Marshal(report)
 	if err != nil {
 		logging.LogMessage("server_administration_service", "Failed to marshal response: "+err.Error(), "ERROR")
 		http.Error(w, "Failed to process servers data", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 	w.Write(responseJSON)
 }
 
 func (h *serverHandler) ExportServers(w http.ResponseWriter, r *http.Request) {
-	fromStr := r.URL.Query().Get("from")
-	from, err := strconv.Atoi(fromStr)
+	serverFilter, sortFields, err := parseServerQuery(r.URL.Query())
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid server query: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format, ok := negotiateFormat(r.Header.Get("Accept"), exportFormats)
+	if !ok {
+		logging.LogMessage("server_administration_service", "Unsupported export format: "+r.Header.Get("Accept"), "ERROR")
+		http.Error(w, "Export is available as xlsx, text/csv, application/json or application/x-ndjson", http.StatusNotAcceptable)
+		return
+	}
+
+	filename := "servers_" + time.Now().Format("2006-01-02_15-04-05") + "." + format.extension
+	export := &downloadWriter{w: w, mediaType: format.mediaType, filename: filename}
+	w.Header().Set("Vary", "Accept")
+
+	err = h.service.ExportServers(export, serverFilter, sortFields, dto.ExportOptions{Format: format.format})
+	if err != nil && export.started {
+		export.abort("Export interrupted", err)
+	}
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid server filter: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		logging.LogMessage("server_administration_service", "Invalid 'from' query parameter: "+err.Error(), "ERROR")
-		logging.LogMessage("server_administration_service", "from: "+fromStr, "DEBUG")
-		http.Error(w, "Invalid 'from' query parameter", http.StatusBadRequest)
+		logging.LogMessage("server_administration_service", "Failed to export servers: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to export servers", http.StatusInternalServerError)
 		return
 	}
 
-	toStr := r.URL.Query().Get("to")
-	to, err := strconv.Atoi(toStr)
+	// An empty NDJSON export writes nothing
+	export.start()
+}
+
+func (h *serverHandler) SearchServers(w http.ResponseWriter, r *http.Request) {
+	searchQuery := dto.ServerSearchQuery{
+		Text: r.URL.Query().Get("q"),
+		Port: -1,
+	}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		searchQuery.Statuses = splitList(status)
+	}
+
+	for name, target := range map[string]*int{
+		"port":   &searchQuery.Port,
+		"offset": &searchQuery.Offset,
+		"limit":  &searchQuery.Limit,
+	} {
+		valueStr := r.URL.Query().Get(name)
+		if valueStr == "" {
+			continue
+		}
+
+		value, err := strconv.Atoi(valueStr)
+		if err != nil {
+			logging.LogMessage("server_administration_service", "Invalid '"+name+"' query parameter: "+valueStr, "ERROR")
+			http.Error(w, "Invalid '"+name+"' query parameter", http.StatusBadRequest)
+			return
+		}
+		*target = value
+	}
+
+	result, err := h.service.SearchServers(searchQuery)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid search query: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		logging.LogMessage("server_administration_service", "Invalid 'to' query parameter: "+err.Error(), "ERROR")
-		logging.LogMessage("server_administration_service", "to: "+toStr, "DEBUG")
-		http.Error(w, "Invalid 'to' query parameter", http.StatusBadRequest)
+		logging.LogMessage("server_administration_service", "Failed to search servers: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to search servers", http.StatusInternalServerError)
 		return
 	}
 
+	response, err := json.Marshal(result)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to marshal search response: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to process search results", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (h *serverHandler) GetServersUptime(w http.ResponseWriter, r *http.Request) {
+	startTimeStr := r.URL.Query().Get("start_time")
+	startTime, err := strconv.ParseInt(startTimeStr, 10, 64)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid 'start_time' query parameter: "+err.Error(), "ERROR")
+		http.Error(w, "Invalid 'start_time' query parameter", http.StatusBadRequest)
+		return
+	}
+
+	endTimeStr := r.URL.Query().Get("end_time")
+	endTime, err := strconv.ParseInt(endTimeStr, 10, 64)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid 'end_time' query parameter: "+err.Error(), "ERROR")
+		http.Error(w, "Invalid 'end_time' query parameter", http.StatusBadRequest)
+		return
+	}
+
+	offset := 0
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		offset, err = strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			logging.LogMessage("server_administration_service", "Invalid 'offset' query parameter: "+offsetStr, "ERROR")
+			http.Error(w, "Invalid 'offset' query parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			logging.LogMessage("server_administration_service", "Invalid 'limit' query parameter: "+limitStr, "ERROR")
+			http.Error(w, "Invalid 'limit' query parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
 	sortedColumn := r.URL.Query().Get("sort_column")
 	order := r.URL.Query().Get("sort_order")
 
-	serverID := r.URL.Query().Get("server_id")
-	serverName := r.URL.Query().Get("server_name")
-	status := r.URL.Query().Get("status")
-	ipv4 := r.URL.Query().Get("ipv4")
-	portStr := r.URL.Query().Get("port")
+	labels, err := domain.ParseLabels(r.URL.Query().Get("labels"))
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid labels in query params: "+err.Error(), "ERROR")
+		http.Error(w, "Invalid labels: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	uptimes, total, err := h.service.GetServersUptime(time.Unix(startTime, 0), time.Unix(endTime, 0), labels, offset, limit, sortedColumn, order)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid servers uptime request: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get servers uptime: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to get servers uptime", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := json.Marshal(map[string]interface{}{
+		"servers": uptimes,
+		"total":   total,
+	})
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to marshal servers uptime response: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to process servers uptime data", http.StatusInternalServerError)
+		return
+	}
+
+	logging.LogMessage("server_administration_service", "Servers uptime retrieved successfully", "INFO")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (h *serverHandler) GetUptimeTimeline(w http.ResponseWriter, r *http.Request) {
+	startTimeStr := r.URL.Query().Get("start_time")
+	startTime, err := strconv.ParseInt(startTimeStr, 10, 64)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid 'start_time' query parameter: "+err.Error(), "ERROR")
+		http.Error(w, "Invalid 'start_time' query parameter", http.StatusBadRequest)
+		return
+	}
+
+	endTimeStr := r.URL.Query().Get("end_time")
+	endTime, err := strconv.ParseInt(endTimeStr, 10, 64)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid 'end_time' query parameter: "+err.Error(), "ERROR")
+		http.Error(w, "Invalid 'end_time' query parameter", http.StatusBadRequest)
+		return
+	}
+
+	// Without an id the timeline covers the whole fleet
+	id := 0
+	if idStr := r.URL.Query().Get("id"); idStr != "" {
+		id, err = strconv.Atoi(idStr)
+		if err != nil || id <= 0 {
+			logging.LogMessage("server_administration_service", "Invalid 'id' query parameter: "+idStr, "ERROR")
+			http.Error(w, "Invalid 'id' query parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	interval := r.URL.Query().Get("interval")
+	if interval == "" {
+		interval = "hour"
+	}
+
+	timeline, err := h.service.GetUptimeTimeline(id, time.Unix(startTime, 0), time.Unix(endTime, 0), interval)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid uptime timeline request: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get uptime timeline: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to get uptime timeline", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := json.Marshal(timeline)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to marshal uptime timeline response: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to process uptime timeline data", http.StatusInternalServerError)
+		return
+	}
+
+	logging.LogMessage("server_administration_service", "Uptime timeline retrieved successfully", "INFO")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (h *serverHandler) GetLatencyPercentiles(w http.ResponseWriter, r *http.Request) {
+	startTimeStr := r.URL.Query().Get("start_time")
+	startTime, err := strconv.ParseInt(startTimeStr, 10, 64)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid 'start_time' query parameter: "+err.Error(), "ERROR")
+		http.Error(w, "Invalid 'start_time' query parameter", http.StatusBadRequest)
+		return
+	}
+
+	endTimeStr := r.URL.Query().Get("end_time")
+	endTime, err := strconv.ParseInt(endTimeStr, 10, 64)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid 'end_time' query parameter: "+err.Error(), "ERROR")
+		http.Error(w, "Invalid 'end_time' query parameter", http.StatusBadRequest)
+		return
+	}
+
+	// Without an id every server is returned
+	id := 0
+	if idStr := r.URL.Query().Get("id"); idStr != "" {
+		id, err = strconv.Atoi(idStr)
+		if err != nil || id <= 0 {
+			logging.LogMessage("server_administration_service", "Invalid 'id' query parameter: "+idStr, "ERROR")
+			http.Error(w, "Invalid 'id' query parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	latencies, err := h.service.GetLatencyPercentiles(id, time.Unix(startTime, 0), time.Unix(endTime, 0))
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid latency percentiles request: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get latency percentiles: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to get latency percentiles", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := json.Marshal(latencies)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to marshal latency percentiles response: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to process latency percentiles data", http.StatusInternalServerError)
+		return
+	}
+
+	logging.LogMessage("server_administration_service", "Latency percentiles retrieved successfully", "INFO")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (h *serverHandler) GetLocationStatuses(w http.ResponseWriter, r *http.Request) {
+	idStr := r.URL.Query().Get("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid 'id' query parameter: "+idStr, "ERROR")
+		http.Error(w, "Invalid 'id' query parameter", http.StatusBadRequest)
+		return
+	}
+
+	statuses, err := h.service.GetLocationStatuses(id)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid location statuses request: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get location statuses: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to get location statuses", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := json.Marshal(statuses)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to marshal location statuses response: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to process location statuses data", http.StatusInternalServerError)
+		return
+	}
 
-	serverFilter := dto.ServerFilter{}
+	logging.LogMessage("server_administration_service", "Location statuses retrieved successfully", "INFO")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
 
-	if serverID != "" {
-		serverFilter.ServerID = serverID
+func (h *serverHandler) GetStatusTransitions(w http.ResponseWriter, r *http.Request) {
+	startTimeStr := r.URL.Query().Get("start_time")
+	startTime, err := strconv.ParseInt(startTimeStr, 10, 64)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid 'start_time' query parameter: "+err.Error(), "ERROR")
+		http.Error(w, "Invalid 'start_time' query parameter", http.StatusBadRequest)
+		return
 	}
-	if serverName != "" {
-		serverFilter.ServerName = serverName
+
+	endTimeStr := r.URL.Query().Get("end_time")
+	endTime, err := strconv.ParseInt(endTimeStr, 10, 64)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Invalid 'end_time' query parameter: "+err.Error(), "ERROR")
+		http.Error(w, "Invalid 'end_time' query parameter", http.StatusBadRequest)
+		return
 	}
-	if status != "" {
-		serverFilter.Status = status
+
+	// Without an id the transitions of every server are listed
+	id := 0
+	if idStr := r.URL.Query().Get("id"); idStr != "" {
+		id, err = strconv.Atoi(idStr)
+		if err != nil || id <= 0 {
+			logging.LogMessage("server_administration_service", "Invalid 'id' query parameter: "+idStr, "ERROR")
+			http.Error(w, "Invalid 'id' query parameter", http.StatusBadRequest)
+			return
+		}
 	}
-	if ipv4 != "" {
-		serverFilter.IPv4 = ipv4
+
+	offset := 0
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		offset, err = strconv.Atoi(offsetStr)
+		if err != nil {
+			logging.LogMessage("server_administration_service", "Invalid 'offset' query parameter: "+offsetStr, "ERROR")
+			http.Error(w, "Invalid 'offset' query parameter", http.StatusBadRequest)
+			return
+		}
 	}
-	if portStr != "" {
-		port, err := strconv.Atoi(portStr)
+
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
 		if err != nil {
-			logging.LogMessage("server_administration_service", "Invalid port in query params", "ERROR")
-			http.Error(w, "Invalid port", http.StatusBadRequest)
+			logging.LogMessage("server_administration_service", "Invalid 'limit' query parameter: "+limitStr, "ERROR")
+			http.Error(w, "Invalid 'limit' query parameter", http.StatusBadRequest)
 			return
 		}
-		serverFilter.Port = port
-	} else {
-		serverFilter.Port = -1
 	}
 
-	serverBuf, err := h.service.ExportServers(&serverFilter, from, to, sortedColumn, order)
+	transitions, total, err := h.service.GetStatusTransitions(id, time.Unix(startTime, 0), time.Unix(endTime, 0), offset, limit)
+	if errors.Is(err, service.ErrInvalidInput) {
+		logging.LogMessage("server_administration_service", "Invalid status transitions request: "+err.Error(), "ERROR")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		logging.LogMessage("server_administration_service", "Failed to export servers: "+err.Error(), "ERROR")
-		http.Error(w, "Failed to export servers", http.StatusInternalServerError)
+		logging.LogMessage("server_administration_service", "Failed to get status transitions: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to get status transitions", http.StatusInternalServerError)
 		return
 	}
 
-	filename := "servers_" + time.Now().Format("2006-01-02_15-04-05") + ".xlsx"
+	response, err := json.Marshal(map[string]interface{}{
+		"transitions": transitions,
+		"total":       total,
+	})
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to marshal status transitions response: "+err.Error(), "ERROR")
+		http.Error(w, "Failed to process status transitions data", http.StatusInternalServerError)
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition")
-	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
-	w.Header().Set("File-Name", filename)
+	logging.LogMessage("server_administration_service", "Status transitions retrieved successfully", "INFO")
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write(serverBuf)
-}
\ No newline at end of file
+	w.Write(response)
+}
+
+// toLabels converts a decoded JSON object, null clears the labels
+func toLabels(value interface{}) (map[string]string, bool) {
+	labels := make(map[string]string)
+	if value == nil {
+		return labels, true
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	for key, labelValue := range object {
+		labelStr, ok := labelValue.(string)
+		if !ok {
+			return nil, false
+		}
+		labels[key] = labelStr
+	}
+	return labels, true
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -11,6 +12,8 @@ import (
 	"server_administration_service/internal/domain"
 	"server_administration_service/internal/dto"
 	"server_administration_service/internal/handler"
+	"server_administration_service/internal/repository"
+	"server_administration_service/internal/service"
 	"server_administration_service/pb"
 	"testing"
 	"time"
@@ -24,37 +27,80 @@ type MockServerService struct {
 	mock.Mock
 }
 
-func (m *MockServerService) CreateServer(serverID, serverName, status, ipv4 string, port int) (int, error) {
-	args := m.Called(serverID, serverName, status, ipv4, port)
+func (m *MockServerService) CreateServer(ctx context.Context, server *domain.Server) (int, error) {
+	args := m.Called(server)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *MockServerService) ViewServers(serverFilter *dto.ServerFilter, from, to int, sortedColumn, order string) ([]domain.Server, error) {
-	args := m.Called(serverFilter, from, to, sortedColumn, order)
-	return args.Get(0).([]domain.Server), args.Error(1)
+func (m *MockServerService) ViewServers(serverFilter *dto.ServerFilter, sortFields []dto.SortField, cursor string, pageSize int) (*dto.ServerPage, error) {
+	args := m.Called(serverFilter, sortFields, cursor, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ServerPage), args.Error(1)
 }
 
-func (m *MockServerService) UpdateServer(serverID string, updatedData map[string]interface{}) error {
+func (m *MockServerService) UpdateServer(ctx context.Context, serverID string, updatedData map[string]interface{}) error {
 	args := m.Called(serverID, updatedData)
 	return args.Error(0)
 }
 
-func (m *MockServerService) DeleteServer(serverID string) error {
+func (m *MockServerService) DeleteServer(ctx context.Context, serverID string) error {
+	args := m.Called(serverID)
+	return args.Error(0)
+}
+
+func (m *MockServerService) GetDeletedServers(offset, limit int) ([]domain.Server, int, error) {
+	args := m.Called(offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]domain.Server), args.Int(1), args.Error(2)
+}
+
+func (m *MockServerService) RestoreServer(ctx context.Context, serverID string) error {
 	args := m.Called(serverID)
 	return args.Error(0)
 }
 
-func (m *MockServerService) ImportServers(data []byte) ([]domain.Server, []domain.Server, error) {
-	args := m.Called(data)
-	return args.Get(0).([]domain.Server), args.Get(1).([]domain.Server), args.Error(2)
+func (m *MockServerService) PurgeDeletedServers(ctx context.Context, retention time.Duration) {
+	m.Called(retention)
+}
+
+func (m *MockServerService) ImportServers(ctx context.Context, data []byte, options dto.ImportOptions) (*dto.ImportReport, error) {
+	args := m.Called(data, options)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ImportReport), args.Error(1)
 }
 
-func (m *MockServerService) ExportServers(serverFilter *dto.ServerFilter, from, to int, sortedColumn, order string) ([]byte, error) {
-	args := m.Called(serverFilter, from, to, sortedColumn, order)
+func (m *MockServerService) ExportRejectedRows(report *dto.ImportReport) ([]byte, error) {
+	args := m.Called(report)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
 	return args.Get(0).([]byte), args.Error(1)
 }
 
-func (m *MockServerService) UpdateServerStatus(id int, status string) error {
+// ExportServers writes the bytes it is given before returning its error, like an export failing midway
+func (m *MockServerService) ExportServers(w io.Writer, serverFilter *dto.ServerFilter, sortFields []dto.SortField, options dto.ExportOptions) error {
+	args := m.Called(serverFilter, sortFields, options.Format)
+	if data := args.Get(0).([]byte); len(data) > 0 {
+		w.Write(data)
+	}
+	return args.Error(1)
+}
+
+func (m *MockServerService) SearchServers(searchQuery dto.ServerSearchQuery) (*dto.ServerSearchResult, error) {
+	args := m.Called(searchQuery)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ServerSearchResult), args.Error(1)
+}
+
+func (m *MockServerService) UpdateServerStatus(id int, status domain.ServerStatus) error {
 	args := m.Called(id, status)
 	return args.Error(0)
 }
@@ -67,8 +113,24 @@ func (m *MockServerService) GetAllAddresses() ([]dto.ServerAddress, error) {
 	return args.Get(0).([]dto.ServerAddress), args.Error(1)
 }
 
-func (m *MockServerService) AddServerStatus(id int, status string) error {
-	args := m.Called(id, status)
+func (m *MockServerService) GetUpdatedAddresses(since time.Time) ([]dto.ServerAddress, []int, error) {
+	args := m.Called(since)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).([]dto.ServerAddress), args.Get(1).([]int), args.Error(2)
+}
+
+func (m *MockServerService) GetLocationStatuses(id int) ([]dto.LocationStatus, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dto.LocationStatus), args.Error(1)
+}
+
+func (m *MockServerService) AddServerStatus(result dto.HealthCheckResult, status, decidedStatus domain.ServerStatus) error {
+	args := m.Called(result, status, decidedStatus)
 	return args.Error(0)
 }
 
@@ -77,21 +139,77 @@ func (m *MockServerService) GetNumOnServers() (int, error) {
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockServerService) GetStatusCounts(ids []int) (map[domain.ServerStatus]int, error) {
+	args := m.Called(ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[domain.ServerStatus]int), args.Error(1)
+}
+
 func (m *MockServerService) GetNumServers() (int, error) {
 	args := m.Called()
 	return args.Int(0), args.Error(1)
 }
 
-func (m *MockServerService) GetServerUptimeRatio(startTime, endTime time.Time) (float64, error) {
-	args := m.Called(startTime, endTime)
+func (m *MockServerService) GetServerIDsByLabels(labels map[string]string) ([]int, error) {
+	args := m.Called(labels)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockServerService) GetServerUptimeRatio(startTime, endTime time.Time, labels map[string]string) (float64, error) {
+	args := m.Called(startTime, endTime, labels)
 	return args.Get(0).(float64), args.Error(1)
 }
 
+func (m *MockServerService) GetServersUptime(startTime, endTime time.Time, labels map[string]string, offset, limit int, sortedColumn, order string) ([]dto.ServerUptime, int, error) {
+	args := m.Called(startTime, endTime, labels, offset, limit, sortedColumn, order)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]dto.ServerUptime), args.Int(1), args.Error(2)
+}
+
+func (m *MockServerService) GetUptimeTimeline(id int, startTime, endTime time.Time, interval string) ([]dto.UptimeBucket, error) {
+	args := m.Called(id, startTime, endTime, interval)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dto.UptimeBucket), args.Error(1)
+}
+
+func (m *MockServerService) GetLatencyPercentiles(id int, startTime, endTime time.Time) ([]dto.LatencyPercentiles, error) {
+	args := m.Called(id, startTime, endTime)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dto.LatencyPercentiles), args.Error(1)
+}
+
+func (m *MockServerService) GetStatusTransitions(id int, startTime, endTime time.Time, offset, limit int) ([]dto.StatusTransition, int, error) {
+	args := m.Called(id, startTime, endTime, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]dto.StatusTransition), args.Int(1), args.Error(2)
+}
+
+func (m *MockServerService) GetDownServers(minConsecutiveChecks int) ([]dto.DownServer, error) {
+	args := m.Called(minConsecutiveChecks)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dto.DownServer), args.Error(1)
+}
+
 func TestCreateServer_Success(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
 	
-	mockService.On("CreateServer", "server123", "Test Server", "On", "192.168.1.1", 8080).
+	mockService.On("CreateServer", &domain.Server{ServerID: "server123", ServerName: "Test Server", Status: "On", IPv4: "192.168.1.1", Port: 8080}).
 		Return(201, nil)
 
 	body := map[string]interface{}{
@@ -124,7 +242,7 @@ func TestCreateServer_Failure(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
 
-	mockService.On("CreateServer", "server123", "Test Server", "On", "192.168.1.1", 8080).
+	mockService.On("CreateServer", &domain.Server{ServerID: "server123", ServerName: "Test Server", Status: "On", IPv4: "192.168.1.1", Port: 8080}).
 		Return(0, assert.AnError)
 
 	body := map[string]interface{}{
@@ -152,6 +270,86 @@ func TestCreateServer_Failure(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestCreateServer_Conflict(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	mockService.On("CreateServer", mock.Anything).Return(0, repository.ErrServerConflict)
+
+	body := map[string]interface{}{
+		"server_id":   "server123",
+		"server_name": "Test Server",
+		"ipv4":        "192.168.1.1",
+	}
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/create", bytes.NewBuffer(jsonBody))
+	rec := httptest.NewRecorder()
+
+	handler.CreateServer(rec, req)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "possibly in the trash")
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateServer_InvalidProbeType(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	expectedServer := &domain.Server{ServerID: "server123", ServerName: "Test Server", Status: "On", IPv4: "192.168.1.1", Port: 8080, ProbeType: "icmp"}
+	mockService.On("CreateServer", expectedServer).
+		Return(0, fmt.Errorf("%w: invalid probe type icmp", service.ErrInvalidInput))
+
+	body := map[string]interface{}{
+		"server_id":   "server123",
+		"server_name": "Test Server",
+		"status":     "On",
+		"ipv4":       "192.168.1.1",
+		"port":       8080,
+		"probe_type": "icmp",
+	}
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/create", bytes.NewBuffer(jsonBody))
+	rec := httptest.NewRecorder()
+
+	handler.CreateServer(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "Expected status code 400")
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateServer_Labels(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	expectedServer := &domain.Server{ServerID: "server123", ServerName: "Test Server", IPv4: "192.168.1.1", Port: 8080,
+		Labels: map[string]string{"env": "prod", "team": "payments"}}
+	mockService.On("CreateServer", expectedServer).Return(1, nil)
+
+	body := `{"server_id": "server123", "server_name": "Test Server", "ipv4": "192.168.1.1", "port": 8080, "labels": {"env": "prod", "team": "payments"}}`
+	req := httptest.NewRequest("POST", "/create", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	handler.CreateServer(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateServer_InvalidLabels(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	body := `{"server_id": "server123", "server_name": "Test Server", "ipv4": "192.168.1.1", "labels": {"replicas": 3}}`
+	req := httptest.NewRequest("POST", "/create", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	handler.CreateServer(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "CreateServer", mock.Anything)
+}
+
 func TestViewServers_Success(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
@@ -164,14 +362,15 @@ func TestViewServers_Success(t *testing.T) {
 	expectedFilter := &dto.ServerFilter{
 		ServerID:   "server123",
 		ServerName: "Test",
-		Status:     "On",
+		Statuses:   []string{"On"},
 		IPv4:       "192.168.1.1",
 		Port:       8080,
 	}
 	
-	mockService.On("ViewServers", expectedFilter, 0, 10, "server_name", "asc").Return(servers, nil)
+	page := &dto.ServerPage{Servers: servers, NextCursor: "next", Total: 5}
+	mockService.On("ViewServers", expectedFilter, []dto.SortField{{Column: "server_name", Order: "asc"}}, "prev", 2).Return(page, nil)
 
-	req := httptest.NewRequest("GET", "/servers?from=0&to=10&sort_column=server_name&sort_order=asc&server_id=server123&server_name=Test&status=On&ipv4=192.168.1.1&port=8080", nil)
+	req := httptest.NewRequest("GET", "/servers?cursor=prev&page_size=2&sort_column=server_name&sort_order=asc&server_id=server123&server_name=Test&status=On&ipv4=192.168.1.1&port=8080", nil)
 	rec := httptest.NewRecorder()
 
 	handler.ViewServers(rec, req)
@@ -185,23 +384,23 @@ func TestViewServers_Success(t *testing.T) {
 		t.Errorf("Failed to read response body: %v", err)
 	}
 	
-	var responseServers []domain.Server
-	err = json.Unmarshal(responseBody, &responseServers)
+	var responsePage dto.ServerPage
+	err = json.Unmarshal(responseBody, &responsePage)
 	if err != nil {
 		t.Errorf("Failed to unmarshal response body: %v", err)
 	}
 	
-	assert.Equal(t, servers, responseServers)
+	assert.Equal(t, *page, responsePage)
 	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
 	
 	mockService.AssertExpectations(t)
 }
 
-func TestViewServers_InvalidFrom(t *testing.T) {
+func TestViewServers_InvalidPageSize(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
 
-	req := httptest.NewRequest("GET", "/servers?from=invalid&to=10", nil)
+	req := httptest.NewRequest("GET", "/servers?page_size=invalid", nil)
 	rec := httptest.NewRecorder()
 
 	handler.ViewServers(rec, req)
@@ -214,34 +413,31 @@ func TestViewServers_InvalidFrom(t *testing.T) {
 	if err != nil {
 		t.Errorf("Failed to read response body: %v", err)
 	}
-	assert.Equal(t, "Invalid 'from' query parameter\n", string(responseBody))
+	assert.Equal(t, "Invalid 'page_size' query parameter\n", string(responseBody))
+	mockService.AssertNotCalled(t, "ViewServers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
-func TestViewServers_InvalidTo(t *testing.T) {
+func TestViewServers_InvalidCursor(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
 
-	req := httptest.NewRequest("GET", "/servers?from=0&to=invalid", nil)
+	expectedFilter := &dto.ServerFilter{Port: -1}
+	mockService.On("ViewServers", expectedFilter, []dto.SortField(nil), "garbage", 0).Return(nil, fmt.Errorf("%w: invalid cursor", service.ErrInvalidInput))
+
+	req := httptest.NewRequest("GET", "/servers?cursor=garbage", nil)
 	rec := httptest.NewRecorder()
 
 	handler.ViewServers(rec, req)
 
-	res := rec.Result()
-	defer res.Body.Close()
-	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-	
-	responseBody, err := io.ReadAll(res.Body)
-	if err != nil {
-		t.Errorf("Failed to read response body: %v", err)
-	}
-	assert.Equal(t, "Invalid 'to' query parameter\n", string(responseBody))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertExpectations(t)
 }
 
 func TestViewServers_InvalidPort(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
 
-	req := httptest.NewRequest("GET", "/servers?from=0&to=10&port=invalid", nil)
+	req := httptest.NewRequest("GET", "/servers?port=invalid", nil)
 	rec := httptest.NewRecorder()
 
 	handler.ViewServers(rec, req)
@@ -257,14 +453,46 @@ func TestViewServers_InvalidPort(t *testing.T) {
 	assert.Equal(t, "Invalid port\n", string(responseBody))
 }
 
+func TestViewServers_LabelsFilter(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	expectedFilter := &dto.ServerFilter{
+		Port:   -1,
+		Labels: map[string]string{"env": "prod", "team": "payments"},
+	}
+	mockService.On("ViewServers", expectedFilter, []dto.SortField{{Column: "id", Order: "asc"}}, "", 0).Return(&dto.ServerPage{Servers: []domain.Server{}}, nil)
+
+	req := httptest.NewRequest("GET", "/servers?sort_column=id&sort_order=asc&labels=env=prod,team=payments", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ViewServers(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestViewServers_InvalidLabels(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/servers?labels=env", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ViewServers(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "ViewServers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestViewServers_ServiceError(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
 	
 	expectedFilter := &dto.ServerFilter{Port: -1}
-	mockService.On("ViewServers", expectedFilter, 0, 10, "", "").Return([]domain.Server{}, assert.AnError)
+	mockService.On("ViewServers", expectedFilter, []dto.SortField(nil), "", 0).Return(nil, assert.AnError)
 
-	req := httptest.NewRequest("GET", "/servers?from=0&to=10", nil)
+	req := httptest.NewRequest("GET", "/servers", nil)
 	rec := httptest.NewRecorder()
 
 	handler.ViewServers(rec, req)
@@ -282,6 +510,55 @@ func TestViewServers_ServiceError(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+
+func TestViewServers_FilterExpressions(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	var captured *dto.ServerFilter
+	mockService.On("ViewServers", mock.Anything, []dto.SortField{{Column: "status", Order: "asc"}, {Column: "last_updated", Order: "desc"}}, "", 0).
+		Run(func(args mock.Arguments) { captured = args.Get(0).(*dto.ServerFilter) }).
+		Return(&dto.ServerPage{Servers: []domain.Server{}}, nil)
+
+	before := time.Now()
+	req := httptest.NewRequest("GET", "/servers?status=Off,Down&ipv4=10.2.0.0/16&port=8000..9000&last_updated=now-1h..&created_time=2024-01-01T00:00:00Z..2024-02-01T00:00:00Z&sort=status,-last_updated", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ViewServers(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"Off", "Down"}, captured.Statuses)
+	assert.Equal(t, "10.2.0.0/16", captured.IPv4CIDR)
+	assert.Equal(t, "", captured.IPv4)
+	assert.Equal(t, -1, captured.Port)
+	assert.Equal(t, 8000, *captured.PortRange.From)
+	assert.Equal(t, 9000, *captured.PortRange.To)
+	assert.WithinDuration(t, before.Add(-time.Hour), *captured.LastUpdated.From, time.Minute)
+	assert.Nil(t, captured.LastUpdated.To)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), *captured.CreatedTime.From)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), *captured.CreatedTime.To)
+	mockService.AssertExpectations(t)
+}
+
+func TestViewServers_InvalidFilterExpressions(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	for _, query := range []string{
+		"port=80..http",
+		"last_updated=now-1h",
+		"last_updated=now-soon..",
+		"created_time=yesterday..",
+	} {
+		req := httptest.NewRequest("GET", "/servers?"+query, nil)
+		rec := httptest.NewRecorder()
+
+		handler.ViewServers(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+	mockService.AssertNotCalled(t, "ViewServers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
 func TestUpdateServer_Success(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
@@ -467,6 +744,100 @@ func TestDeleteServer_ServiceError(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestGetDeletedServers(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	servers := []domain.Server{{ID: 4, ServerID: "srv-004", ServerName: "Server 4"}}
+	mockService.On("GetDeletedServers", 10, 5).Return(servers, 11, nil)
+
+	req := httptest.NewRequest("GET", "/trash?offset=10&limit=5", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetDeletedServers(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Servers []domain.Server `json:"servers"`
+		Total   int             `json:"total"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 11, response.Total)
+	assert.Equal(t, "srv-004", response.Servers[0].ServerID)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetDeletedServers_InvalidLimit(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/trash?limit=ten", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetDeletedServers(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "GetDeletedServers", mock.Anything, mock.Anything)
+}
+
+func TestGetDeletedServers_NegativeOffset(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/trash?offset=-5", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetDeletedServers(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "GetDeletedServers", mock.Anything, mock.Anything)
+}
+
+func TestRestoreServer(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	mockService.On("RestoreServer", "srv-001").Return(nil)
+
+	req := httptest.NewRequest("POST", "/restore?server_id=srv-001", nil)
+	rec := httptest.NewRecorder()
+
+	handler.RestoreServer(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Server restored successfully", rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestRestoreServer_NotInTrash(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	mockService.On("RestoreServer", "srv-001").Return(repository.ErrServerNotInTrash)
+
+	req := httptest.NewRequest("POST", "/restore?server_id=srv-001", nil)
+	rec := httptest.NewRecorder()
+
+	handler.RestoreServer(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestRestoreServer_MissingID(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	req := httptest.NewRequest("POST", "/restore", nil)
+	rec := httptest.NewRecorder()
+
+	handler.RestoreServer(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "RestoreServer", mock.Anything)
+}
+
 func TestExportServers_Success(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
@@ -475,14 +846,14 @@ func TestExportServers_Success(t *testing.T) {
 	expectedFilter := &dto.ServerFilter{
 		ServerID:   "server123",
 		ServerName: "TestServer",
-		Status:     "On",
+		Statuses:   []string{"On"},
 		IPv4:       "192.168.1.1",
 		Port:       8080,
 	}
 	
-	mockService.On("ExportServers", expectedFilter, 0, 10, "server_name", "asc").Return(excelBytes, nil)
+	mockService.On("ExportServers", expectedFilter, []dto.SortField{{Column: "server_name", Order: "asc"}}, service.FormatXLSX).Return(excelBytes, nil)
 
-	req := httptest.NewRequest("GET", "/export?from=0&to=10&sort_column=server_name&sort_order=asc&server_id=server123&server_name=TestServer&status=On&ipv4=192.168.1.1&port=8080", nil)
+	req := httptest.NewRequest("GET", "/export?sort_column=server_name&sort_order=asc&server_id=server123&server_name=TestServer&status=On&ipv4=192.168.1.1&port=8080", nil)
 	rec := httptest.NewRecorder()
 
 	handler.ExportServers(rec, req)
@@ -506,51 +877,112 @@ func TestExportServers_Success(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
-func TestExportServers_InvalidFrom(t *testing.T) {
+func TestExportServers_ContentNegotiation(t *testing.T) {
+	tests := []struct {
+		accept      string
+		format      string
+		contentType string
+		extension   string
+	}{
+		{"text/csv", service.FormatCSV, "text/csv", ".csv"},
+		{"application/json", service.FormatJSON, "application/json", ".json"},
+		{"application/x-ndjson", service.FormatNDJSON, "application/x-ndjson", ".ndjson"},
+		{"application/json;q=0.5, text/csv", service.FormatCSV, "text/csv", ".csv"},
+		{"text/html, */*;q=0.8", service.FormatXLSX, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", ".xlsx"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.accept, func(t *testing.T) {
+			mockService := new(MockServerService)
+			handler := handler.NewServerHandler(mockService)
+
+			mockService.On("ExportServers", &dto.ServerFilter{Port: -1}, []dto.SortField(nil), test.format).Return([]byte("servers"), nil)
+
+			req := httptest.NewRequest("GET", "/export", nil)
+			req.Header.Set("Accept", test.accept)
+			rec := httptest.NewRecorder()
+
+			handler.ExportServers(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, test.contentType, rec.Header().Get("Content-Type"))
+			assert.Contains(t, rec.Header().Get("Content-Disposition"), test.extension)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestExportServers_NotAcceptable(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
 
-	req := httptest.NewRequest("GET", "/export?from=invalid&to=10", nil)
+	req := httptest.NewRequest("GET", "/export", nil)
+	req.Header.Set("Accept", "application/pdf")
 	rec := httptest.NewRecorder()
 
 	handler.ExportServers(rec, req)
 
-	res := rec.Result()
-	defer res.Body.Close()
-	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-	
-	responseBody, err := io.ReadAll(res.Body)
-	if err != nil {
-		t.Errorf("Failed to read response body: %v", err)
-	}
-	assert.Equal(t, "Invalid 'from' query parameter\n", string(responseBody))
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+	mockService.AssertNotCalled(t, "ExportServers", mock.Anything, mock.Anything, mock.Anything)
 }
 
-func TestExportServers_InvalidTo(t *testing.T) {
+func TestExportServers_Empty(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
 
-	req := httptest.NewRequest("GET", "/export?from=0&to=invalid", nil)
+	mockService.On("ExportServers", &dto.ServerFilter{Port: -1}, []dto.SortField(nil), service.FormatNDJSON).Return([]byte{}, nil)
+
+	req := httptest.NewRequest("GET", "/export", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
 	rec := httptest.NewRecorder()
 
 	handler.ExportServers(rec, req)
 
-	res := rec.Result()
-	defer res.Body.Close()
-	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-	
-	responseBody, err := io.ReadAll(res.Body)
-	if err != nil {
-		t.Errorf("Failed to read response body: %v", err)
-	}
-	assert.Equal(t, "Invalid 'to' query parameter\n", string(responseBody))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), ".ndjson")
+	assert.Empty(t, rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestExportServers_Interrupted(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	mockService.On("ExportServers", &dto.ServerFilter{Port: -1}, []dto.SortField(nil), service.FormatCSV).Return([]byte("server_id\n"), assert.AnError)
+
+	req := httptest.NewRequest("GET", "/export", nil)
+	req.Header.Set("Accept", "text/csv")
+	rec := httptest.NewRecorder()
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ExportServers(rec, req)
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestExportServers_InvalidSortColumn(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	expectedFilter := &dto.ServerFilter{Port: -1}
+	mockService.On("ExportServers", expectedFilter, []dto.SortField{{Column: "password", Order: "asc"}}, service.FormatXLSX).Return([]byte{}, fmt.Errorf("%w: invalid sort column password", service.ErrInvalidInput))
+
+	req := httptest.NewRequest("GET", "/export?sort_column=password&sort_order=asc", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ExportServers(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertExpectations(t)
 }
 
 func TestExportServers_InvalidPort(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
 
-	req := httptest.NewRequest("GET", "/export?from=0&to=10&port=invalid", nil)
+	req := httptest.NewRequest("GET", "/export?port=invalid", nil)
 	rec := httptest.NewRecorder()
 
 	handler.ExportServers(rec, req)
@@ -571,9 +1003,9 @@ func TestExportServers_ServiceError(t *testing.T) {
 	handler := handler.NewServerHandler(mockService)
 	
 	expectedFilter := &dto.ServerFilter{Port: -1}
-	mockService.On("ExportServers", expectedFilter, 0, 10, "", "").Return([]byte{}, assert.AnError)
+	mockService.On("ExportServers", expectedFilter, []dto.SortField(nil), service.FormatXLSX).Return([]byte{}, assert.AnError)
 
-	req := httptest.NewRequest("GET", "/export?from=0&to=10", nil)
+	req := httptest.NewRequest("GET", "/export", nil)
 	rec := httptest.NewRecorder()
 
 	handler.ExportServers(rec, req)
@@ -591,9 +1023,72 @@ func TestExportServers_ServiceError(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestSearchServers_Success(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	result := &dto.ServerSearchResult{
+		Hits: []dto.ServerSearchHit{{
+			Server:     domain.Server{ID: 1, ServerName: "payments-api", IPv4: "10.2.0.1"},
+			Score:      1.5,
+			Highlights: map[string][]string{"server_name": {"<em>payments</em>-api"}},
+		}},
+		Total:        1,
+		StatusCounts: map[string]int{"Up": 1},
+		PortCounts:   map[int]int{443: 1},
+	}
+	mockService.On("SearchServers", dto.ServerSearchQuery{Text: "paymnts", Statuses: []string{"Up", "Down"}, Port: 443, Offset: 20, Limit: 10}).Return(result, nil)
+
+	req := httptest.NewRequest("GET", "/search?q=paymnts&status=Up,Down&port=443&offset=20&limit=10", nil)
+	rec := httptest.NewRecorder()
+
+	handler.SearchServers(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var response dto.ServerSearchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Errorf("Failed to unmarshal response body: %v", err)
+	}
+	assert.Equal(t, *result, response)
+	mockService.AssertExpectations(t)
+}
+
+func TestSearchServers_InvalidLimit(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/search?q=x&limit=ten", nil)
+	rec := httptest.NewRecorder()
+
+	handler.SearchServers(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "Invalid 'limit' query parameter\n", rec.Body.String())
+	mockService.AssertNotCalled(t, "SearchServers", mock.Anything)
+}
+
+func TestSearchServers_ServiceError(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	mockService.On("SearchServers", dto.ServerSearchQuery{Port: -1}).Return(nil, assert.AnError)
+
+	req := httptest.NewRequest("GET", "/search", nil)
+	rec := httptest.NewRecorder()
+
+	handler.SearchServers(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "Failed to search servers\n", rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
 func TestGetAllAddresses_Success(t *testing.T) {
 	mockService := new(MockServerService)
-	grpcHandler := handler.NewGrpcServerHandler(mockService)
+	mockMaintenance := new(MockMaintenanceService)
+	grpcHandler := handler.NewGrpcServerHandler(mockService, mockMaintenance)
 
 	addresses := []dto.ServerAddress{
 		{ID: 1, IPv4: "192.168.1.1", Port: 8080},
@@ -602,17 +1097,20 @@ func TestGetAllAddresses_Success(t *testing.T) {
 	mockService.On("GetAllAddresses").Return(addresses, nil)
 
 	req := &pb.EmptyRequest{}
+	before := time.Now().UnixMilli()
 	response, err := grpcHandler.GetAllAddresses(context.Background(), req)
 	assert.NoError(t, err)
 	assert.NotNil(t, response)
 	assert.Equal(t, 2, len(response.Addresses))
+	assert.GreaterOrEqual(t, response.ServerTime, before)
 	
 	mockService.AssertExpectations(t)
 }
 
 func TestGetAllAddresses_ServiceError(t *testing.T) {
 	mockService := new(MockServerService)
-	grpcHandler := handler.NewGrpcServerHandler(mockService)
+	mockMaintenance := new(MockMaintenanceService)
+	grpcHandler := handler.NewGrpcServerHandler(mockService, mockMaintenance)
 
 	mockService.On("GetAllAddresses").Return(nil, assert.AnError)
 
@@ -626,20 +1124,23 @@ func TestGetAllAddresses_ServiceError(t *testing.T) {
 
 func TestGetServerInformation_Success(t *testing.T) {
 	mockService := new(MockServerService)
-	grpcHandler := handler.NewGrpcServerHandler(mockService)
+	mockMaintenance := new(MockMaintenanceService)
+	grpcHandler := handler.NewGrpcServerHandler(mockService, mockMaintenance)
 	
 	startTime := time.Now().Add(-24 * time.Hour)
 	endTime := time.Now()
 	
-	mockService.On("GetNumOnServers").Return(3, nil)
-	mockService.On("GetNumServers").Return(5, nil)
+	mockService.On("GetServerIDsByLabels", mock.Anything).Return(nil, nil)
+	mockService.On("GetStatusCounts", []int(nil)).Return(map[domain.ServerStatus]int{domain.StatusUp: 3, domain.StatusDown: 2}, nil)
+	mockMaintenance.On("GetServersInMaintenance").Return([]int{}, nil)
 	mockService.On("GetServerUptimeRatio", 
 		mock.MatchedBy(func(st time.Time) bool { 
 			return st.Unix() == startTime.Unix() 
 		}),
 		mock.MatchedBy(func(et time.Time) bool { 
 			return et.Unix() == endTime.Unix() 
-		})).Return(0.75, nil)
+		}),
+		mock.Anything).Return(0.75, nil)
 	
 	req := &pb.GetServerInformationRequest{
 		StartTime: startTime.Unix(),
@@ -660,20 +1161,23 @@ func TestGetServerInformation_Success(t *testing.T) {
 
 func TestGetServerInformation_UptimeRatioError(t *testing.T) {
 	mockService := new(MockServerService)
-	grpcHandler := handler.NewGrpcServerHandler(mockService)
+	mockMaintenance := new(MockMaintenanceService)
+	grpcHandler := handler.NewGrpcServerHandler(mockService, mockMaintenance)
 	
 	startTime := time.Now().Add(-24 * time.Hour)
 	endTime := time.Now()
 	
-	mockService.On("GetNumOnServers").Return(3, nil)
-	mockService.On("GetNumServers").Return(5, nil)
+	mockService.On("GetServerIDsByLabels", mock.Anything).Return(nil, nil)
+	mockService.On("GetStatusCounts", []int(nil)).Return(map[domain.ServerStatus]int{domain.StatusUp: 3, domain.StatusDown: 2}, nil)
+	mockMaintenance.On("GetServersInMaintenance").Return([]int{}, nil)
 	mockService.On("GetServerUptimeRatio", 
 		mock.MatchedBy(func(st time.Time) bool { 
 			return st.Unix() == startTime.Unix() 
 		}),
 		mock.MatchedBy(func(et time.Time) bool { 
 			return et.Unix() == endTime.Unix() 
-		})).Return(0.0, assert.AnError)
+		}),
+		mock.Anything).Return(0.0, assert.AnError)
 	
 	req := &pb.GetServerInformationRequest{
 		StartTime: startTime.Unix(),
@@ -690,17 +1194,20 @@ func TestGetServerInformation_UptimeRatioError(t *testing.T) {
 
 func TestGetServerInformation_EmptyTimestamps(t *testing.T) {
 	mockService := new(MockServerService)
-	grpcHandler := handler.NewGrpcServerHandler(mockService)
+	mockMaintenance := new(MockMaintenanceService)
+	grpcHandler := handler.NewGrpcServerHandler(mockService, mockMaintenance)
 	
-	mockService.On("GetNumOnServers").Return(3, nil)
-	mockService.On("GetNumServers").Return(5, nil)
+	mockService.On("GetServerIDsByLabels", mock.Anything).Return(nil, nil)
+	mockService.On("GetStatusCounts", []int(nil)).Return(map[domain.ServerStatus]int{domain.StatusUp: 3, domain.StatusDown: 2}, nil)
+	mockMaintenance.On("GetServersInMaintenance").Return([]int{}, nil)
 	mockService.On("GetServerUptimeRatio", 
 		mock.MatchedBy(func(st time.Time) bool { 
 			return st.Unix() == 0 
 		}),
 		mock.MatchedBy(func(et time.Time) bool { 
 			return et.Unix() == 0 
-		})).Return(0.8, nil)
+		}),
+		mock.Anything).Return(0.8, nil)
 	
 	req := &pb.GetServerInformationRequest{
 		StartTime: 0,
@@ -719,56 +1226,253 @@ func TestGetServerInformation_EmptyTimestamps(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestGetServerInformation_MaintenanceServers(t *testing.T) {
+	mockService := new(MockServerService)
+	mockMaintenance := new(MockMaintenanceService)
+	grpcHandler := handler.NewGrpcServerHandler(mockService, mockMaintenance)
+
+	// Servers 2 (Up) and 9 (Down) are in a maintenance window
+	mockService.On("GetServerIDsByLabels", mock.Anything).Return(nil, nil)
+	mockService.On("GetStatusCounts", []int(nil)).Return(map[domain.ServerStatus]int{
+		domain.StatusUp:       5,
+		domain.StatusDegraded: 1,
+		domain.StatusDown:     3,
+		domain.StatusUnknown:  1,
+	}, nil)
+	mockMaintenance.On("GetServersInMaintenance").Return([]int{2, 9}, nil)
+	mockService.On("GetStatusCounts", []int{2, 9}).Return(map[domain.ServerStatus]int{domain.StatusUp: 1, domain.StatusDown: 1}, nil)
+	mockService.On("GetServerUptimeRatio", mock.Anything, mock.Anything, mock.Anything).Return(0.9, nil)
+
+	response, err := grpcHandler.GetServerInformation(context.Background(), &pb.GetServerInformationRequest{
+		StartTime: time.Now().Add(-24 * time.Hour).Unix(),
+		EndTime:   time.Now().Unix(),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), response.NumServers)
+	assert.Equal(t, int64(5), response.NumOnServers)
+	assert.Equal(t, int64(1), response.NumDegradedServers)
+	assert.Equal(t, int64(2), response.NumOffServers)
+	assert.Equal(t, int64(2), response.NumMaintenanceServers)
+	assert.Equal(t, int64(1), response.NumUnknownServers)
+
+	mockService.AssertExpectations(t)
+	mockMaintenance.AssertExpectations(t)
+}
+
+func TestGetServerInformation_ScopedByLabels(t *testing.T) {
+	mockService := new(MockServerService)
+	mockMaintenance := new(MockMaintenanceService)
+	grpcHandler := handler.NewGrpcServerHandler(mockService, mockMaintenance)
+
+	labels := map[string]string{"team": "payments"}
+
+	// Servers 3 and 8 belong to the team, only 8 of the servers in maintenance does
+	mockService.On("GetServerIDsByLabels", labels).Return([]int{3, 8}, nil)
+	mockService.On("GetStatusCounts", []int{3, 8}).Return(map[domain.ServerStatus]int{domain.StatusUp: 2}, nil)
+	mockMaintenance.On("GetServersInMaintenance").Return([]int{5, 8}, nil)
+	mockService.On("GetStatusCounts", []int{8}).Return(map[domain.ServerStatus]int{domain.StatusUp: 1}, nil)
+	mockService.On("GetServerUptimeRatio", mock.Anything, mock.Anything, labels).Return(1.0, nil)
+
+	response, err := grpcHandler.GetServerInformation(context.Background(), &pb.GetServerInformationRequest{
+		StartTime: time.Now().Add(-24 * time.Hour).Unix(),
+		EndTime:   time.Now().Unix(),
+		Labels:    labels,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), response.NumServers)
+	assert.Equal(t, int64(1), response.NumOnServers)
+	assert.Equal(t, int64(1), response.NumMaintenanceServers)
+
+	mockService.AssertExpectations(t)
+	mockMaintenance.AssertExpectations(t)
+}
+
+func newImportRequest(target string, fileContent []byte) *http.Request {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("servers_file", "test.xlsx")
+	part.Write(fileContent)
+	writer.Close()
+
+	req := httptest.NewRequest("POST", target, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// withActor makes the request on behalf of the actor, as the auth middlewares would
+func withActor(req *http.Request, actor domain.Actor) *http.Request {
+	return req.WithContext(domain.ContextWithActor(req.Context(), actor))
+}
+
 func TestImportServers_Success(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
 	
-	importedServers := []domain.Server{
-		{ID: 1, ServerID: "server123", ServerName: "Test Server", Status: "On", IPv4: "192.168.1.1", Port: 8080},
-	}
-	nonImportedServers := []domain.Server{
-		{ID: 0, ServerID: "server456", ServerName: "Invalid Server", Status: "Unknown", IPv4: "invalid", Port: 0},
+	report := &dto.ImportReport{
+		Mode: "insert",
+		Created: []domain.Server{
+			{ID: 1, ServerID: "server123", ServerName: "Test Server", Status: domain.StatusUp, IPv4: "192.168.1.1", Port: 8080},
+		},
+		Rejected: []dto.RejectedRow{
+			{
+				Row:    3,
+				Values: []string{"server456", "Invalid Server", "Up", "invalid"},
+				Errors: []dto.ImportError{{Row: 3, Column: "IPv4", Cell: "D3", Reason: `invalid IPv4 address "invalid"`}},
+			},
+		},
 	}
 	
 	// Mock file content
 	fileContent := []byte("mock excel data")
 	
-	mockService.On("ImportServers", fileContent).Return(importedServers, nonImportedServers, nil)
-
-	// Create multipart form with file
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	part, _ := writer.CreateFormFile("servers_file", "test.xlsx")
-	part.Write(fileContent)
-	writer.Close()
+	mockService.On("ImportServers", fileContent, dto.ImportOptions{}).Return(report, nil)
 
-	req := httptest.NewRequest("POST", "/import", body)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
 	rec := httptest.NewRecorder()
-
-	handler.ImportServers(rec, req)
+	handler.ImportServers(rec, newImportRequest("/import", fileContent))
 
 	res := rec.Result()
 	defer res.Body.Close()
 	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
 	
-	responseBody, err := io.ReadAll(res.Body)
-	if err != nil {
-		t.Errorf("Failed to read response body: %v", err)
-	}
-	
-	var response map[string]interface{}
-	err = json.Unmarshal(responseBody, &response)
-	if err != nil {
+	var response dto.ImportReport
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
 		t.Errorf("Failed to unmarshal response body: %v", err)
 	}
 	
-	assert.Contains(t, response, "imported_servers")
-	assert.Contains(t, response, "non_imported_servers")
+	assert.Equal(t, *report, response)
 	
 	mockService.AssertExpectations(t)
 }
 
+func TestImportServers_RejectedRowsReport(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	fileContent := []byte("mock excel data")
+	report := &dto.ImportReport{
+		Created:  []domain.Server{{ID: 1, ServerID: "server123"}},
+		Updated:  []domain.Server{{ID: 2, ServerID: "server456"}, {ID: 3, ServerID: "server789"}},
+		Rejected: []dto.RejectedRow{{Row: 3}, {Row: 4}},
+	}
+	mockService.On("ImportServers", fileContent, dto.ImportOptions{}).Return(report, nil)
+	mockService.On("ExportRejectedRows", report).Return([]byte("rejected rows"), nil)
+
+	rec := httptest.NewRecorder()
+	handler.ImportServers(rec, newImportRequest("/import?report=xlsx", fileContent))
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", res.Header.Get("Content-Type"))
+	assert.Contains(t, res.Header.Get("Content-Disposition"), "attachment; filename=rejected_servers_")
+	assert.Equal(t, "1", res.Header.Get("Created-Count"))
+	assert.Equal(t, "2", res.Header.Get("Updated-Count"))
+	assert.Equal(t, "0", res.Header.Get("Deleted-Count"))
+	assert.Equal(t, "2", res.Header.Get("Rejected-Count"))
+
+	body, _ := io.ReadAll(res.Body)
+	assert.Equal(t, "rejected rows", string(body))
+
+	mockService.AssertExpectations(t)
+}
+
+func TestImportServers_Options(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	fileContent := []byte("mock excel data")
+	report := &dto.ImportReport{Mode: "replace", DryRun: true}
+	mockService.On("ImportServers", fileContent, dto.ImportOptions{Mode: "replace", DryRun: true}).Return(report, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ImportServers(rec, withActor(newImportRequest("/import?mode=replace&dry_run=true", fileContent), domain.Actor{ID: "admin-1", Role: "admin"}))
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var response dto.ImportReport
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		t.Errorf("Failed to unmarshal response body: %v", err)
+	}
+	assert.Equal(t, "replace", response.Mode)
+	assert.True(t, response.DryRun)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestImportServers_ModeForbidden(t *testing.T) {
+	user := domain.Actor{ID: "user-1", Role: "user"}
+
+	for _, mode := range []string{"upsert", "replace"} {
+		t.Run(mode, func(t *testing.T) {
+			mockService := new(MockServerService)
+			handler := handler.NewServerHandler(mockService)
+
+			rec := httptest.NewRecorder()
+			handler.ImportServers(rec, withActor(newImportRequest("/import?mode="+mode, []byte("mock excel data")), user))
+
+			assert.Equal(t, http.StatusForbidden, rec.Code)
+			assert.Equal(t, "Only admins can import with mode "+mode+"\n", rec.Body.String())
+			mockService.AssertNotCalled(t, "ImportServers", mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("insert", func(t *testing.T) {
+		mockService := new(MockServerService)
+		handler := handler.NewServerHandler(mockService)
+
+		fileContent := []byte("mock excel data")
+		mockService.On("ImportServers", fileContent, dto.ImportOptions{Mode: "insert"}).Return(&dto.ImportReport{Mode: "insert"}, nil)
+
+		rec := httptest.NewRecorder()
+		handler.ImportServers(rec, withActor(newImportRequest("/import?mode=insert", fileContent), user))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestImportServers_InvalidDryRun(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	rec := httptest.NewRecorder()
+	handler.ImportServers(rec, newImportRequest("/import?dry_run=maybe", []byte("mock excel data")))
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	body, _ := io.ReadAll(res.Body)
+	assert.Equal(t, "Invalid 'dry_run' query parameter\n", string(body))
+
+	mockService.AssertNotCalled(t, "ImportServers", mock.Anything, mock.Anything)
+}
+
+func TestImportServers_InvalidFile(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	fileContent := []byte("not a spreadsheet")
+	mockService.On("ImportServers", fileContent, dto.ImportOptions{}).Return(nil, fmt.Errorf("%w: not an Excel file", service.ErrInvalidInput))
+
+	rec := httptest.NewRecorder()
+	handler.ImportServers(rec, newImportRequest("/import", fileContent))
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	body, _ := io.ReadAll(res.Body)
+	assert.Equal(t, "invalid input: not an Excel file\n", string(body))
+
+	mockService.AssertExpectations(t)
+}
+
 func TestImportServers_NoFile(t *testing.T) {
 	mockService := new(MockServerService)
 	handler := handler.NewServerHandler(mockService)
@@ -796,17 +1500,9 @@ func TestImportServers_ServiceError(t *testing.T) {
 	// Mock file content
 	fileContent := []byte("mock excel data")
 	
-	mockService.On("ImportServers", fileContent).Return([]domain.Server{}, []domain.Server{}, assert.AnError)
-
-	// Create multipart form with file
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	part, _ := writer.CreateFormFile("servers_file", "test.xlsx")
-	part.Write(fileContent)
-	writer.Close()
+	mockService.On("ImportServers", fileContent, dto.ImportOptions{}).Return(nil, assert.AnError)
 
-	req := httptest.NewRequest("POST", "/import", body)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req := newImportRequest("/import", fileContent)
 	rec := httptest.NewRecorder()
 
 	handler.ImportServers(rec, req)
@@ -821,5 +1517,296 @@ func TestImportServers_ServiceError(t *testing.T) {
 	}
 	assert.Equal(t, "Failed to import servers\n", string(responseBody))
 	
+	mockService.AssertExpectations(t)
+}
+
+func TestGetServersUptime_Success(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	uptimes := []dto.ServerUptime{
+		{ID: 2, UptimeRatio: 0.5, NumChecks: 10, NumStatusChanges: 6},
+	}
+	mockService.On("GetServersUptime", time.Unix(100, 0), time.Unix(200, 0), map[string]string{}, 0, 1, "uptime_ratio", "asc").
+		Return(uptimes, 3, nil)
+
+	req := httptest.NewRequest("GET", "/uptime?start_time=100&end_time=200&limit=1&sort_column=uptime_ratio&sort_order=asc", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetServersUptime(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var response struct {
+		Servers []dto.ServerUptime `json:"servers"`
+		Total   int                `json:"total"`
+	}
+	err := json.NewDecoder(res.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, uptimes, response.Servers)
+	assert.Equal(t, 3, response.Total)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetServersUptime_InvalidStartTime(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/uptime?start_time=abc&end_time=200", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetServersUptime(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestGetServersUptime_InvalidSortColumn(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	mockService.On("GetServersUptime", time.Unix(100, 0), time.Unix(200, 0), map[string]string{}, 0, 0, "server_name", "").
+		Return(nil, 0, fmt.Errorf("%w: invalid sort column server_name", service.ErrInvalidInput))
+
+	req := httptest.NewRequest("GET", "/uptime?start_time=100&end_time=200&sort_column=server_name", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetServersUptime(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGRPCGetServersUptime_Success(t *testing.T) {
+	mockService := new(MockServerService)
+	mockMaintenance := new(MockMaintenanceService)
+	grpcHandler := handler.NewGrpcServerHandler(mockService, mockMaintenance)
+
+	mockService.On("GetServersUptime", time.Unix(100, 0), time.Unix(200, 0), map[string]string(nil), 0, 10, "id", "desc").
+		Return([]dto.ServerUptime{
+			{ID: 2, UptimeRatio: 0.5, NumChecks: 10, NumStatusChanges: 6},
+			{ID: 1, UptimeRatio: 1, NumChecks: 10, NumStatusChanges: 0},
+		}, 2, nil)
+
+	response, err := grpcHandler.GetServersUptime(context.Background(), &pb.GetServersUptimeRequest{
+		StartTime:  100,
+		EndTime:    200,
+		Limit:      10,
+		SortColumn: "id",
+		SortOrder:  "desc",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), response.Total)
+	assert.Equal(t, 2, len(response.Servers))
+	assert.Equal(t, int64(2), response.Servers[0].Id)
+	assert.Equal(t, float32(0.5), response.Servers[0].UptimeRatio)
+	assert.Equal(t, int64(6), response.Servers[0].NumStatusChanges)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetUptimeTimeline_Success(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	timeline := []dto.UptimeBucket{
+		{Timestamp: time.Unix(0, 0).UTC(), UptimeRatio: 0.75, NumChecks: 4},
+	}
+	mockService.On("GetUptimeTimeline", 0, time.Unix(0, 0), time.Unix(3600, 0), "hour").Return(timeline, nil)
+
+	req := httptest.NewRequest("GET", "/uptime/timeline?start_time=0&end_time=3600", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetUptimeTimeline(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var response []dto.UptimeBucket
+	err := json.NewDecoder(res.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, timeline, response)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetUptimeTimeline_InvalidInterval(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	mockService.On("GetUptimeTimeline", 5, time.Unix(0, 0), time.Unix(3600, 0), "week").
+		Return(nil, fmt.Errorf("%w: invalid interval week", service.ErrInvalidInput))
+
+	req := httptest.NewRequest("GET", "/uptime/timeline?id=5&start_time=0&end_time=3600&interval=week", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetUptimeTimeline(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetLatencyPercentiles_Success(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	latencies := []dto.LatencyPercentiles{
+		{ID: 2, NumChecks: 60, P50: 12, P90: 30, P95: 45, P99: 120, Avg: 18.5, Max: 150},
+	}
+	mockService.On("GetLatencyPercentiles", 2, time.Unix(0, 0), time.Unix(3600, 0)).Return(latencies, nil)
+
+	req := httptest.NewRequest("GET", "/latency?id=2&start_time=0&end_time=3600", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetLatencyPercentiles(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var response []dto.LatencyPercentiles
+	err := json.NewDecoder(res.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, latencies, response)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetLatencyPercentiles_InvalidID(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/latency?id=abc&start_time=0&end_time=3600", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetLatencyPercentiles(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "GetLatencyPercentiles", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetLocationStatuses_Success(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	statuses := []dto.LocationStatus{
+		{Location: "hanoi", Status: "Off", CheckedAt: time.Unix(1000, 0).UTC()},
+		{Location: "singapore", Status: "On", CheckedAt: time.Unix(1001, 0).UTC()},
+	}
+	mockService.On("GetLocationStatuses", 7).Return(statuses, nil)
+
+	req := httptest.NewRequest("GET", "/locations?id=7", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetLocationStatuses(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var response []dto.LocationStatus
+	err := json.NewDecoder(res.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, statuses, response)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetStatusTransitions_Success(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	transitions := []dto.StatusTransition{
+		{ID: 7, PreviousStatus: "On", Status: "Off", Timestamp: time.Unix(500, 0).UTC()},
+	}
+	mockService.On("GetStatusTransitions", 7, time.Unix(0, 0), time.Unix(1000, 0), 0, 20).Return(transitions, 1, nil)
+
+	req := httptest.NewRequest("GET", "/transitions?id=7&start_time=0&end_time=1000&limit=20", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetStatusTransitions(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var response struct {
+		Transitions []dto.StatusTransition `json:"transitions"`
+		Total       int                    `json:"total"`
+	}
+	err := json.NewDecoder(res.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, transitions, response.Transitions)
+	assert.Equal(t, 1, response.Total)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetStatusTransitions_InvalidID(t *testing.T) {
+	mockService := new(MockServerService)
+	handler := handler.NewServerHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/transitions?id=abc&start_time=0&end_time=1000", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetStatusTransitions(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestGRPCGetUpdatedAddresses_Success(t *testing.T) {
+	mockService := new(MockServerService)
+	mockMaintenance := new(MockMaintenanceService)
+	grpcHandler := handler.NewGrpcServerHandler(mockService, mockMaintenance)
+
+	mockService.On("GetUpdatedAddresses", time.UnixMilli(1700000000000)).Return([]dto.ServerAddress{
+		{ID: 2, IPv4: "192.168.1.2", Port: 8081, ProbeType: "http", CheckIntervalSeconds: 10, TimeoutMs: 2000, Retries: 1},
+	}, []int{1, 2}, nil)
+
+	before := time.Now().UnixMilli()
+	response, err := grpcHandler.GetUpdatedAddresses(context.Background(), &pb.GetUpdatedAddressesRequest{UpdatedSince: 1700000000000})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(response.Addresses))
+	assert.Equal(t, "192.168.1.2:8081", response.Addresses[0].Address)
+	assert.Equal(t, int32(10), response.Addresses[0].CheckIntervalSeconds)
+	assert.Equal(t, []int64{1, 2}, response.Ids)
+	assert.GreaterOrEqual(t, response.ServerTime, before)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGRPCGetDownServers_Success(t *testing.T) {
+	mockService := new(MockServerService)
+	mockMaintenance := new(MockMaintenanceService)
+	grpcHandler := handler.NewGrpcServerHandler(mockService, mockMaintenance)
+
+	mockService.On("GetDownServers", 3).Return([]dto.DownServer{
+		{ID: 4, ServerName: "Server 4", ConsecutiveDownChecks: 3, LastCheck: time.Unix(1000, 0)},
+	}, nil)
+
+	response, err := grpcHandler.GetDownServers(context.Background(), &pb.GetDownServersRequest{MinConsecutiveChecks: 3})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(response.Servers))
+	assert.Equal(t, int64(4), response.Servers[0].Id)
+	assert.Equal(t, "Server 4", response.Servers[0].ServerName)
+	assert.Equal(t, int64(3), response.Servers[0].ConsecutiveDownChecks)
+	assert.Equal(t, int64(1000), response.Servers[0].LastCheck)
+
 	mockService.AssertExpectations(t)
 }
\ No newline at end of file
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/service"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flashhhhh/pkg/logging"
+)
+
+/*
+	parseServerQuery reads the filters and the sort shared by the view and export endpoints, e.g.
+
+		?status=Down,Off&ipv4=10.2.0.0/16&last_updated=now-1h..&sort=status,-last_updated
+
+	- status is a comma separated set
+	- ipv4 is an address or a CIDR range
+	- port is a port or a range "from..to", either bound can be left out
+	- created_time and last_updated are ranges "from..to" of RFC3339 times or "now-<duration>"
+	- sort is a comma separated list of columns, "-" in front sorts in descending order.
+	  The older sort_column / sort_order pair is still read when sort is missing.
+
+	Columns, statuses and bounds are validated by the service, only the syntax is checked here.
+*/
+func parseServerQuery(query url.Values) (*dto.ServerFilter, []dto.SortField, error) {
+	serverFilter := &dto.ServerFilter{
+		ServerID:   query.Get("server_id"),
+		ServerName: query.Get("server_name"),
+		Port:       -1,
+	}
+
+	if status := query.Get("status"); status != "" {
+		serverFilter.Statuses = splitList(status)
+	}
+
+	if ipv4 := query.Get("ipv4"); strings.Contains(ipv4, "/") {
+		serverFilter.IPv4CIDR = ipv4
+	} else {
+		serverFilter.IPv4 = ipv4
+	}
+
+	if portStr := query.Get("port"); portStr != "" {
+		if strings.Contains(portStr, "..") {
+			portRange, err := parseIntRange(portStr)
+			if err != nil {
+				return nil, nil, errors.New("Invalid port")
+			}
+			serverFilter.PortRange = portRange
+		} else {
+			port, err := strconv.Atoi(portStr)
+			if err != nil {
+				return nil, nil, errors.New("Invalid port")
+			}
+			serverFilter.Port = port
+		}
+	}
+
+	now := time.Now()
+	for name, timeRange := range map[string]*dto.TimeRange{
+		"created_time": &serverFilter.CreatedTime,
+		"last_updated": &serverFilter.LastUpdated,
+	} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := parseTimeRange(value, now)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid '%s' query parameter: %s", name, err.Error())
+		}
+		*timeRange = parsed
+	}
+
+	if labelsStr := query.Get("labels"); labelsStr != "" {
+		labels, err := domain.ParseLabels(labelsStr)
+		if err != nil {
+			return nil, nil, errors.New("Invalid labels: " + err.Error())
+		}
+		serverFilter.Labels = labels
+	}
+
+	return serverFilter, parseSort(query), nil
+}
+
+func parseSort(query url.Values) []dto.SortField {
+	if sort := query.Get("sort"); sort != "" {
+		var sortFields []dto.SortField
+		for _, column := range splitList(sort) {
+			if strings.HasPrefix(column, "-") {
+				sortFields = append(sortFields, dto.SortField{Column: column[1:], Order: "desc"})
+			} else {
+				sortFields = append(sortFields, dto.SortField{Column: column, Order: "asc"})
+			}
+		}
+		return sortFields
+	}
+
+	if column := query.Get("sort_column"); column != "" {
+		return []dto.SortField{{Column: column, Order: query.Get("sort_order")}}
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseIntRange(value string) (dto.IntRange, error) {
+	fromStr, toStr, _ := strings.Cut(value, "..")
+
+	var intRange dto.IntRange
+	if fromStr != "" {
+		from, err := strconv.Atoi(fromStr)
+		if err != nil {
+			return dto.IntRange{}, err
+		}
+		intRange.From = &from
+	}
+	if toStr != "" {
+		to, err := strconv.Atoi(toStr)
+		if err != nil {
+			return dto.IntRange{}, err
+		}
+		intRange.To = &to
+	}
+	return intRange, nil
+}
+
+func parseTimeRange(value string, now time.Time) (dto.TimeRange, error) {
+	fromStr, toStr, found := strings.Cut(value, "..")
+	if !found {
+		return dto.TimeRange{}, errors.New("expected a range from..to")
+	}
+
+	var timeRange dto.TimeRange
+	if fromStr != "" {
+		from, err := parseTimeBound(fromStr, now)
+		if err != nil {
+			return dto.TimeRange{}, err
+		}
+		timeRange.From = &from
+	}
+	if toStr != "" {
+		to, err := parseTimeBound(toStr, now)
+		if err != nil {
+			return dto.TimeRange{}, err
+		}
+		timeRange.To = &to
+	}
+	return timeRange, nil
+}
+
+// parseTimeBound reads a RFC3339 time, "now" or "now-<duration>" such as "now-1h30m"
+func parseTimeBound(value string, now time.Time) (time.Time, error) {
+	if value == "now" {
+		return now, nil
+	}
+
+	if ago, found := strings.CutPrefix(value, "now-"); found {
+		duration, err := time.ParseDuration(ago)
+		if err != nil || duration < 0 {
+			return time.Time{}, fmt.Errorf("invalid duration %q", ago)
+		}
+		return now.Add(-duration), nil
+	}
+
+	bound, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC3339 or now-<duration>", value)
+	}
+	return bound, nil
+}
+
+type exportFormat struct {
+	mediaType string
+	format    string
+	extension string
+}
+
+// The export formats, the first one is served when the client accepts anything
+var exportFormats = []exportFormat{
+	{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", service.FormatXLSX, "xlsx"},
+	{"text/csv", service.FormatCSV, "csv"},
+	{"application/json", service.FormatJSON, "json"},
+	{"application/x-ndjson", service.FormatNDJSON, "ndjson"},
+	{"application/ndjson", service.FormatNDJSON, "ndjson"},
+}
+
+/*
+	downloadWriter streams a file to the client. The headers go out with the first byte, so that an
+	error found before anything was written can still be answered with an error status. Without a
+	Content-Length the response is sent with chunked transfer encoding.
+*/
+type downloadWriter struct {
+	w         http.ResponseWriter
+	mediaType string
+	filename  string
+	started   bool
+}
+
+func (d *downloadWriter) start() {
+	if d.started {
+		return
+	}
+	d.started = true
+
+	d.w.Header().Set("Content-Type", d.mediaType)
+	d.w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition")
+	d.w.Header().Set("Content-Disposition", "attachment; filename="+d.filename)
+	d.w.Header().Set("File-Name", d.filename)
+	d.w.WriteHeader(http.StatusOK)
+}
+
+func (d *downloadWriter) Write(p []byte) (int, error) {
+	d.start()
+	return d.w.Write(p)
+}
+
+// abort ends a download that failed after its status was sent: only a broken connection tells the client the file is truncated
+func (d *downloadWriter) abort(message string, err error) {
+	logging.LogMessage("server_administration_service", message+": "+err.Error(), "ERROR")
+	panic(http.ErrAbortHandler)
+}
+
+/*
+	negotiateFormat picks the format of the Accept header with the highest quality among formats,
+	e.g. "text/csv" or "application/json;q=0.9, text/csv;q=0.5". false when none is supported.
+*/
+func negotiateFormat(accept string, formats []exportFormat) (exportFormat, bool) {
+	type acceptedRange struct {
+		mediaRange string
+		quality    float64
+	}
+
+	var ranges []acceptedRange
+	for _, item := range splitList(accept) {
+		mediaRange, params, _ := strings.Cut(item, ";")
+		accepted := acceptedRange{mediaRange: strings.ToLower(strings.TrimSpace(mediaRange)), quality: 1}
+
+		for _, param := range strings.Split(params, ";") {
+			if value, found := strings.CutPrefix(strings.TrimSpace(param), "q="); found {
+				if quality, err := strconv.ParseFloat(value, 64); err == nil {
+					accepted.quality = quality
+				}
+			}
+		}
+		if accepted.quality > 0 {
+			ranges = append(ranges, accepted)
+		}
+	}
+
+	if len(ranges) == 0 {
+		return formats[0], accept == ""
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	for _, accepted := range ranges {
+		for _, format := range formats {
+			mediaType, _, _ := strings.Cut(format.mediaType, "/")
+			if accepted.mediaRange == "*/*" || accepted.mediaRange == format.mediaType || accepted.mediaRange == mediaType+"/*" {
+				return format, true
+			}
+		}
+	}
+	return exportFormat{}, false
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+	AuditRepository reads the audit log. Entries are only written by the ServerRepository,
+	in the transaction of the change they record, and the table refuses updates and deletes.
+*/
+type AuditRepository interface {
+	GetAuditEntries(auditFilter *dto.AuditFilter, beforeID int64, limit int) ([]domain.AuditEntry, error)
+	StreamAuditEntries(auditFilter *dto.AuditFilter, fn func(entry domain.AuditEntry) error) error
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{
+		db: db,
+	}
+}
+
+/*
+	Entries are returned newest first, beforeID > 0 starts the page right after the entry with that id.
+	The id grows with every entry, so new entries never shift the pages.
+*/
+func (r *auditRepository) GetAuditEntries(auditFilter *dto.AuditFilter, beforeID int64, limit int) ([]domain.AuditEntry, error) {
+	query := filterAuditEntries(r.db.Model(&domain.AuditEntry{}), auditFilter).Order("id DESC")
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var entries []domain.AuditEntry
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// StreamAuditEntries calls fn with every matching entry in the order they were written, see StreamServers
+func (r *auditRepository) StreamAuditEntries(auditFilter *dto.AuditFilter, fn func(entry domain.AuditEntry) error) error {
+	rows, err := filterAuditEntries(r.db.Model(&domain.AuditEntry{}), auditFilter).Order("id ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry domain.AuditEntry
+		if err := r.db.ScanRows(rows, &entry); err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func filterAuditEntries(query *gorm.DB, auditFilter *dto.AuditFilter) *gorm.DB {
+	// A slice rather than a map keeps the conditions in a stable order
+	for _, condition := range []struct{ column, value string }{
+		{"actor_id", auditFilter.ActorID},
+		{"action", auditFilter.Action},
+		{"source", auditFilter.Source},
+		{"server_id", auditFilter.ServerID},
+		{"request_id", auditFilter.RequestID},
+	} {
+		if condition.value != "" {
+			query = query.Where(condition.column+" = ?", condition.value)
+		}
+	}
+
+	return filterTimeRange(query, "time", auditFilter.Time)
+}
+
+// About 10 parameters per entry
+const auditBatchSize = 1000
+
+/*
+	newAuditEntry records a change made by the actor of ctx, before is nil for a created server
+	and after is nil for a deleted one. false when nothing changed.
+*/
+func newAuditEntry(ctx context.Context, source domain.AuditSource, before, after *domain.Server) (domain.AuditEntry, bool) {
+	changes := domain.DiffServers(before, after)
+	if len(changes) == 0 {
+		return domain.AuditEntry{}, false
+	}
+
+	action, server := domain.AuditUpdate, after
+	if before == nil {
+		action = domain.AuditCreate
+	} else if after == nil {
+		action, server = domain.AuditDelete, before
+	}
+
+	actor := domain.ActorFromContext(ctx)
+	return domain.AuditEntry{
+		Time:      time.Now(),
+		ActorID:   actor.ID,
+		ActorName: actor.Name,
+		ActorRole: actor.Role,
+		RequestID: domain.RequestIDFromContext(ctx),
+		Action:    action,
+		Source:    source,
+		ServerID:  server.ServerID,
+		Changes:   changes,
+	}, true
+}
+
+// createAuditEntries must run in the transaction of the changes, an entry failing to be written rolls them back
+func createAuditEntries(tx *gorm.DB, entries []domain.AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(entries, auditBatchSize).Error
+}
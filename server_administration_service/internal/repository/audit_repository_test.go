@@ -0,0 +1,79 @@
+package repository_test
+
+import (
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAuditEntries(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewAuditRepository(db)
+
+	t.Run("Filtered page after a cursor", func(t *testing.T) {
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		auditFilter := &dto.AuditFilter{
+			ServerID: "srv-001",
+			Action:   "update",
+			Time:     dto.TimeRange{From: &from},
+		}
+
+		mock.ExpectQuery(`SELECT \* FROM "audit_entries" WHERE action = \$1 AND server_id = \$2 AND time >= \$3 AND id < \$4 ORDER BY id DESC LIMIT \$5`).
+			WithArgs("update", "srv-001", from, int64(42), 11).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "actor_id", "action", "source", "server_id", "changes"}).
+				AddRow(41, "user-1", "update", "api", "srv-001", `{"port":{"before":8080,"after":9090}}`))
+
+		entries, err := repo.GetAuditEntries(auditFilter, 42, 11)
+
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, int64(41), entries[0].ID)
+		assert.Equal(t, domain.AuditUpdate, entries[0].Action)
+		assert.Equal(t, domain.FieldChange{Before: float64(8080), After: float64(9090)}, entries[0].Changes["port"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("First page without filters", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT \* FROM "audit_entries" ORDER BY id DESC LIMIT \$1`).
+			WithArgs(21).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		entries, err := repo.GetAuditEntries(&dto.AuditFilter{}, 0, 21)
+
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStreamAuditEntries(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewAuditRepository(db)
+
+	mock.ExpectQuery(`SELECT \* FROM "audit_entries" WHERE actor_id = \$1 ORDER BY id ASC`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor_id", "server_id"}).
+			AddRow(1, "user-1", "srv-001").
+			AddRow(2, "user-1", "srv-002"))
+
+	var serverIDs []string
+	err = repo.StreamAuditEntries(&dto.AuditFilter{ActorID: "user-1"}, func(entry domain.AuditEntry) error {
+		serverIDs = append(serverIDs, entry.ServerID)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"srv-001", "srv-002"}, serverIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
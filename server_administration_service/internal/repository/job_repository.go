@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"errors"
+	"io"
+	"server_administration_service/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobFinished = errors.New("job already finished")
+)
+
+// Job files are stored in chunks of this size, so that neither side holds a whole file in memory
+const jobChunkSize = 1 << 20
+
+type JobRepository interface {
+	CreateJob(job *domain.Job, input []byte) error
+	GetJob(id string) (*domain.Job, error)
+	CancelJob(id string) (*domain.Job, error)
+
+	ClaimJob() (*domain.Job, error)
+	UpdateJobProgress(id string, processed, total int) (bool, error)
+	FinishJob(job *domain.Job) error
+	RequeueStaleJobs(before time.Time) (int, error)
+	DeleteJobsFinishedBefore(before time.Time) (int, error)
+
+	CreateJobFile(id, kind string) (io.WriteCloser, error)
+	ReadJobFile(id, kind string, fn func(data []byte) error) error
+}
+
+type jobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) JobRepository {
+	return &jobRepository{
+		db: db,
+	}
+}
+
+// CreateJob stores a queued job with its input file, if it has one
+func (r *jobRepository) CreateJob(job *domain.Job, input []byte) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+
+		file := &jobFileWriter{db: tx, jobID: job.ID, kind: domain.JobInput}
+		if _, err := file.Write(input); err != nil {
+			return err
+		}
+		return file.Close()
+	})
+}
+
+func (r *jobRepository) GetJob(id string) (*domain.Job, error) {
+	var job domain.Job
+	if err := r.db.Where("id = ?", id).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+/*
+	CancelJob cancels a queued or running job. A running job is stopped by its worker the next time
+	it reports its progress, FinishJob then overwrites the finished time.
+*/
+func (r *jobRepository) CancelJob(id string) (*domain.Job, error) {
+	result := r.db.Model(&domain.Job{}).
+		Where("id = ? AND status IN ?", id, []domain.JobStatus{domain.JobQueued, domain.JobRunning}).
+		Updates(map[string]interface{}{"status": domain.JobCanceled, "finished_time": time.Now()})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	job, err := r.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return job, ErrJobFinished
+	}
+
+	return job, nil
+}
+
+/*
+	ClaimJob marks the oldest queued job as running and returns it, nil when no job is queued.
+	SKIP LOCKED lets the workers of several replicas claim jobs at the same time.
+*/
+func (r *jobRepository) ClaimJob() (*domain.Job, error) {
+	now := time.Now()
+
+	var jobs []domain.Job
+	err := r.db.Raw(`UPDATE jobs SET status = ?, started_time = ?, last_updated = ?
+		WHERE id = (SELECT id FROM jobs WHERE status = ? ORDER BY created_time LIMIT 1 FOR UPDATE SKIP LOCKED)
+		RETURNING *`, domain.JobRunning, now, now, domain.JobQueued).Scan(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+
+	return &jobs[0], nil
+}
+
+// UpdateJobProgress also keeps the job from being requeued, false when the job is no longer running
+func (r *jobRepository) UpdateJobProgress(id string, processed, total int) (bool, error) {
+	result := r.db.Model(&domain.Job{}).
+		Where("id = ? AND status = ?", id, domain.JobRunning).
+		Updates(map[string]interface{}{"processed": processed, "total": total})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+/*
+	FinishJob writes the outcome of a job. A job canceled while its worker was completing it
+	still gets the outcome, the import or the export did happen.
+*/
+func (r *jobRepository) FinishJob(job *domain.Job) error {
+	result := r.db.Model(&domain.Job{}).
+		Where("id = ? AND status IN ?", job.ID, []domain.JobStatus{domain.JobRunning, domain.JobCanceled}).
+		Updates(map[string]interface{}{
+			"status":        job.Status,
+			"processed":     job.Processed,
+			"total":         job.Total,
+			"error":         job.Error,
+			"result_name":   job.ResultName,
+			"result_type":   job.ResultType,
+			"finished_time": job.FinishedTime,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobNotFound
+	}
+
+	return nil
+}
+
+// RequeueStaleJobs puts back the running jobs whose worker stopped reporting, e.g. after a crash
+func (r *jobRepository) RequeueStaleJobs(before time.Time) (int, error) {
+	result := r.db.Model(&domain.Job{}).
+		Where("status = ? AND last_updated < ?", domain.JobRunning, before).
+		Updates(map[string]interface{}{"status": domain.JobQueued, "started_time": nil})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return int(result.RowsAffected), nil
+}
+
+func (r *jobRepository) DeleteJobsFinishedBefore(before time.Time) (int, error) {
+	deleted := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var ids []string
+		if err := tx.Model(&domain.Job{}).Where("finished_time < ?", before).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := tx.Where("job_id IN ?", ids).Delete(&domain.JobChunk{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id IN ?", ids).Delete(&domain.Job{})
+		deleted = int(result.RowsAffected)
+		return result.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// CreateJobFile replaces a file of a job, the chunks of an interrupted run are dropped first
+func (r *jobRepository) CreateJobFile(id, kind string) (io.WriteCloser, error) {
+	if err := r.db.Where("job_id = ? AND kind = ?", id, kind).Delete(&domain.JobChunk{}).Error; err != nil {
+		return nil, err
+	}
+
+	return &jobFileWriter{db: r.db, jobID: id, kind: kind}, nil
+}
+
+// ReadJobFile calls fn with every chunk of a file in order, reading them one at a time
+func (r *jobRepository) ReadJobFile(id, kind string, fn func(data []byte) error) error {
+	rows, err := r.db.Model(&domain.JobChunk{}).
+		Where("job_id = ? AND kind = ?", id, kind).
+		Order("sequence").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var chunk domain.JobChunk
+		if err := r.db.ScanRows(rows, &chunk); err != nil {
+			return err
+		}
+		if err := fn(chunk.Data); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// jobFileWriter stores what is written to it as chunks of jobChunkSize bytes, Close stores the rest
+type jobFileWriter struct {
+	db       *gorm.DB
+	jobID    string
+	kind     string
+	sequence int
+	buf      []byte
+}
+
+func (w *jobFileWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= jobChunkSize {
+		if err := w.writeChunk(w.buf[:jobChunkSize]); err != nil {
+			return 0, err
+		}
+		w.buf = append(w.buf[:0], w.buf[jobChunkSize:]...)
+	}
+
+	return len(p), nil
+}
+
+func (w *jobFileWriter) Close() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	err := w.writeChunk(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *jobFileWriter) writeChunk(data []byte) error {
+	chunk := domain.JobChunk{JobID: w.jobID, Kind: w.kind, Sequence: w.sequence, Data: data}
+	if err := w.db.Create(&chunk).Error; err != nil {
+		return err
+	}
+
+	w.sequence++
+	return nil
+}
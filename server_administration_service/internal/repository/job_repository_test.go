@@ -0,0 +1,306 @@
+package repository_test
+
+import (
+	"bytes"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateJob(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewJobRepository(db)
+
+	job := &domain.Job{
+		ID:         "job-1",
+		Type:       domain.JobImport,
+		Status:     domain.JobQueued,
+		Parameters: `{"mode":"insert","dry_run":false}`,
+		ActorID:    "user-1",
+		ActorName:  "alice",
+		ActorRole:  "user",
+		RequestID:  "req-1",
+	}
+	input := []byte("server_id,server_name,ipv4\nsrv-1,Server One,10.0.0.1\n")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "jobs"`).
+		WithArgs("job-1", domain.JobImport, domain.JobQueued, job.Parameters, 0, 0, "", "user-1", "alice", "user", "req-1", "", "", sqlmock.AnyArg(), nil, nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO "job_chunks" \("job_id","kind","sequence","data"\) VALUES \(\$1,\$2,\$3,\$4\)`).
+		WithArgs("job-1", domain.JobInput, 0, input).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.CreateJob(job, input)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetJob_NotFound(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewJobRepository(db)
+
+	mock.ExpectQuery(`SELECT \* FROM "jobs" WHERE id = \$1 ORDER BY "jobs"."id" LIMIT \$2`).
+		WithArgs("missing", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	job, err := repo.GetJob("missing")
+
+	assert.Nil(t, job)
+	assert.ErrorIs(t, err, repository.ErrJobNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCancelJob(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewJobRepository(db)
+
+	t.Run("Cancel a running job", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "jobs" SET "finished_time"=\$1,"status"=\$2,"last_updated"=\$3 WHERE id = \$4 AND status IN \(\$5,\$6\)`).
+			WithArgs(sqlmock.AnyArg(), domain.JobCanceled, sqlmock.AnyArg(), "job-1", domain.JobQueued, domain.JobRunning).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`SELECT \* FROM "jobs" WHERE id = \$1`).
+			WithArgs("job-1", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "type", "status"}).AddRow("job-1", "export", "canceled"))
+
+		job, err := repo.CancelJob("job-1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.JobCanceled, job.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Job already finished", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "jobs" SET`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`SELECT \* FROM "jobs" WHERE id = \$1`).
+			WithArgs("job-2", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "type", "status"}).AddRow("job-2", "import", "succeeded"))
+
+		job, err := repo.CancelJob("job-2")
+
+		assert.ErrorIs(t, err, repository.ErrJobFinished)
+		assert.Equal(t, domain.JobSucceeded, job.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestClaimJob(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewJobRepository(db)
+
+	t.Run("Claim the oldest queued job", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE jobs SET status = \$1, started_time = \$2, last_updated = \$3\s+WHERE id = \(SELECT id FROM jobs WHERE status = \$4 ORDER BY created_time LIMIT 1 FOR UPDATE SKIP LOCKED\)\s+RETURNING \*`).
+			WithArgs(domain.JobRunning, sqlmock.AnyArg(), sqlmock.AnyArg(), domain.JobQueued).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "type", "status", "parameters"}).
+				AddRow("job-1", "export", "running", `{"format":"csv"}`))
+
+		job, err := repo.ClaimJob()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "job-1", job.ID)
+		assert.Equal(t, domain.JobRunning, job.Status)
+		assert.Equal(t, `{"format":"csv"}`, job.Parameters)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No queued job", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE jobs SET status`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "type", "status"}))
+
+		job, err := repo.ClaimJob()
+
+		assert.NoError(t, err)
+		assert.Nil(t, job)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpdateJobProgress(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewJobRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "jobs" SET "processed"=\$1,"total"=\$2,"last_updated"=\$3 WHERE id = \$4 AND status = \$5`).
+		WithArgs(250, 1000, sqlmock.AnyArg(), "job-1", domain.JobRunning).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	running, err := repo.UpdateJobProgress("job-1", 250, 1000)
+	assert.NoError(t, err)
+	assert.True(t, running)
+
+	// Canceled meanwhile
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "jobs" SET`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	running, err = repo.UpdateJobProgress("job-1", 500, 1000)
+	assert.NoError(t, err)
+	assert.False(t, running)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFinishJob(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewJobRepository(db)
+
+	finishedTime := time.Unix(2000, 0)
+	job := &domain.Job{
+		ID:           "job-1",
+		Status:       domain.JobSucceeded,
+		Processed:    3,
+		Total:        3,
+		ResultName:   "servers.csv",
+		ResultType:   "text/csv",
+		FinishedTime: &finishedTime,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "jobs" SET "error"=\$1,"finished_time"=\$2,"processed"=\$3,"result_name"=\$4,"result_type"=\$5,"status"=\$6,"total"=\$7,"last_updated"=\$8 WHERE id = \$9 AND status IN \(\$10,\$11\)`).
+		WithArgs("", &finishedTime, 3, "servers.csv", "text/csv", domain.JobSucceeded, 3, sqlmock.AnyArg(), "job-1", domain.JobRunning, domain.JobCanceled).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.FinishJob(job))
+
+	// Requeued and claimed again, or deleted
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "jobs" SET`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	assert.ErrorIs(t, repo.FinishJob(job), repository.ErrJobNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRequeueStaleJobs(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewJobRepository(db)
+
+	before := time.Unix(1000, 0)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "jobs" SET "started_time"=\$1,"status"=\$2,"last_updated"=\$3 WHERE status = \$4 AND last_updated < \$5`).
+		WithArgs(nil, domain.JobQueued, sqlmock.AnyArg(), domain.JobRunning, before).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	requeued, err := repo.RequeueStaleJobs(before)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, requeued)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteJobsFinishedBefore(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewJobRepository(db)
+
+	before := time.Unix(1000, 0)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT "id" FROM "jobs" WHERE finished_time < \$1`).
+		WithArgs(before).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("job-1").AddRow("job-2"))
+	mock.ExpectExec(`DELETE FROM "job_chunks" WHERE job_id IN \(\$1,\$2\)`).
+		WithArgs("job-1", "job-2").
+		WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectExec(`DELETE FROM "jobs" WHERE id IN \(\$1,\$2\)`).
+		WithArgs("job-1", "job-2").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	deleted, err := repo.DeleteJobsFinishedBefore(before)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobFiles(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewJobRepository(db)
+
+	t.Run("Write a file in chunks", func(t *testing.T) {
+		data := bytes.Repeat([]byte("0123456789"), 250000)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM "job_chunks" WHERE job_id = \$1 AND kind = \$2`).
+			WithArgs("job-1", domain.JobResult).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		for sequence, chunk := range [][]byte{data[:1<<20], data[1<<20 : 2<<20], data[2<<20:]} {
+			mock.ExpectBegin()
+			mock.ExpectExec(`INSERT INTO "job_chunks"`).
+				WithArgs("job-1", domain.JobResult, sequence, chunk).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		}
+
+		file, err := repo.CreateJobFile("job-1", domain.JobResult)
+		assert.NoError(t, err)
+
+		// Written in uneven pieces
+		for start := 0; start < len(data); start += 300000 {
+			_, err := file.Write(data[start:min(start+300000, len(data))])
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, file.Close())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Read a file in order", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT \* FROM "job_chunks" WHERE job_id = \$1 AND kind = \$2 ORDER BY sequence`).
+			WithArgs("job-1", domain.JobResult).
+			WillReturnRows(sqlmock.NewRows([]string{"job_id", "kind", "sequence", "data"}).
+				AddRow("job-1", "result", 0, []byte("server_id\n")).
+				AddRow("job-1", "result", 1, []byte("srv-1\n")))
+
+		var buf bytes.Buffer
+		err := repo.ReadJobFile("job-1", domain.JobResult, func(data []byte) error {
+			buf.Write(data)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "server_id\nsrv-1\n", buf.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
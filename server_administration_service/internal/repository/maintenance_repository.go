@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"errors"
+	"server_administration_service/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrMaintenanceWindowNotFound = errors.New("maintenance window not found")
+
+type MaintenanceRepository interface {
+	CreateWindow(window *domain.MaintenanceWindow) error
+	GetWindows(activeAt *time.Time) ([]domain.MaintenanceWindow, error)
+	GetWindow(id int) (*domain.MaintenanceWindow, error)
+	UpdateWindow(window *domain.MaintenanceWindow) error
+	DeleteWindow(id int) error
+
+	GetActiveServerIDs(at time.Time) ([]int, error)
+}
+
+type maintenanceRepository struct {
+	db *gorm.DB
+}
+
+func NewMaintenanceRepository(db *gorm.DB) MaintenanceRepository {
+	return &maintenanceRepository{
+		db: db,
+	}
+}
+
+func (r *maintenanceRepository) CreateWindow(window *domain.MaintenanceWindow) error {
+	return r.db.Create(window).Error
+}
+
+/*
+	activeAt == nil returns every window, otherwise only the ones covering that time
+*/
+func (r *maintenanceRepository) GetWindows(activeAt *time.Time) ([]domain.MaintenanceWindow, error) {
+	query := r.db.Model(&domain.MaintenanceWindow{})
+	if activeAt != nil {
+		query = query.Where("start_time <= ? AND end_time > ?", *activeAt, *activeAt)
+	}
+
+	var windows []domain.MaintenanceWindow
+	if err := query.Order("start_time ASC").Find(&windows).Error; err != nil {
+		return nil, err
+	}
+
+	return windows, nil
+}
+
+func (r *maintenanceRepository) GetWindow(id int) (*domain.MaintenanceWindow, error) {
+	var window domain.MaintenanceWindow
+	if err := r.db.Where("id = ?", id).First(&window).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMaintenanceWindowNotFound
+		}
+		return nil, err
+	}
+
+	return &window, nil
+}
+
+func (r *maintenanceRepository) UpdateWindow(window *domain.MaintenanceWindow) error {
+	result := r.db.Model(&domain.MaintenanceWindow{}).
+		Where("id = ?", window.ID).
+		Select("server_ids", "server_name_filter", "ipv4_prefix", "start_time", "end_time", "reason").
+		Updates(window)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMaintenanceWindowNotFound
+	}
+
+	return nil
+}
+
+func (r *maintenanceRepository) DeleteWindow(id int) error {
+	result := r.db.Where("id = ?", id).Delete(&domain.MaintenanceWindow{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMaintenanceWindowNotFound
+	}
+
+	return nil
+}
+
+/*
+	Resolves the windows active at the given time to the ids of existing servers.
+	Inside a window the name and IPv4 filters must both match, the windows are OR-ed.
+*/
+func (r *maintenanceRepository) GetActiveServerIDs(at time.Time) ([]int, error) {
+	windows, err := r.GetWindows(&at)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int{}
+	if len(windows) == 0 {
+		return ids, nil
+	}
+
+	matches := r.db.Where("1 = 0")
+	for _, window := range windows {
+		if len(window.ServerIDs) > 0 {
+			matches = matches.Or("id IN ?", window.ServerIDs)
+		}
+
+		if window.HasFilter() {
+			filter := r.db
+			if window.ServerNameFilter != "" {
+				filter = filter.Where("server_name LIKE ?", "%"+window.ServerNameFilter+"%")
+			}
+			if window.IPv4Prefix != "" {
+				filter = filter.Where("ipv4 LIKE ?", window.IPv4Prefix+"%")
+			}
+			matches = matches.Or(filter)
+		}
+	}
+
+	if err := r.db.Model(&domain.Server{}).Where(matches).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,117 @@
+package repository_test
+
+import (
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateMaintenanceWindow(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewMaintenanceRepository(db)
+
+	window := &domain.MaintenanceWindow{
+		ServerIDs: []int{1, 2},
+		StartTime: time.Unix(1000, 0),
+		EndTime:   time.Unix(2000, 0),
+		Reason:    "Kernel upgrade",
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "maintenance_windows"`).
+		WithArgs(`[1,2]`, "", "", window.StartTime, window.EndTime, "Kernel upgrade", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectCommit()
+
+	err = repo.CreateWindow(window)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, window.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMaintenanceWindows_ActiveAt(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewMaintenanceRepository(db)
+
+	at := time.Unix(1500, 0)
+	mock.ExpectQuery(`SELECT \* FROM "maintenance_windows" WHERE start_time <= \$1 AND end_time > \$2 ORDER BY start_time ASC`).
+		WithArgs(at, at).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "server_ids", "server_name_filter", "start_time", "end_time", "reason"}).
+			AddRow(1, `[3,4]`, "", time.Unix(1000, 0), time.Unix(2000, 0), "Reboot"))
+
+	windows, err := repo.GetWindows(&at)
+
+	assert.NoError(t, err)
+	assert.Len(t, windows, 1)
+	assert.Equal(t, []int{3, 4}, windows[0].ServerIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteMaintenanceWindow_NotFound(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewMaintenanceRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "maintenance_windows" WHERE id = \$1`).
+		WithArgs(42).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err = repo.DeleteWindow(42)
+
+	assert.ErrorIs(t, err, repository.ErrMaintenanceWindowNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetActiveServerIDs(t *testing.T) {
+	db, mock, _, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewMaintenanceRepository(db)
+
+	at := time.Unix(1500, 0)
+
+	t.Run("Resolves ids and filters against the servers", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT \* FROM "maintenance_windows"`).
+			WithArgs(at, at).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "server_ids", "server_name_filter", "ipv4_prefix"}).
+				AddRow(1, `[3]`, "", "").
+				AddRow(2, `null`, "db-", "10.0."))
+		mock.ExpectQuery(`SELECT "id" FROM "servers" WHERE \(1 = 0 OR id IN \(\$1\) OR \(server_name LIKE \$2 AND ipv4 LIKE \$3\)\) AND "servers"."deleted_at" IS NULL`).
+			WithArgs(3, "%db-%", "10.0.%").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(8))
+
+		ids, err := repo.GetActiveServerIDs(at)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{3, 8}, ids)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No active window", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT \* FROM "maintenance_windows"`).
+			WithArgs(at, at).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		ids, err := repo.GetActiveServerIDs(at)
+
+		assert.NoError(t, err)
+		assert.Empty(t, ids)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -4,32 +4,67 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"server_administration_service/internal/domain"
 	"server_administration_service/internal/dto"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/flashhhhh/pkg/logging"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrServerNotInTrash = errors.New("server not in the trash")
+	ErrServerConflict   = errors.New("a server with this server_id or server_name already exists, possibly in the trash")
 )
 
 type ServerRepository interface {
-	CreateServer(server *domain.Server) (int, error)
-	CreateServers(servers []domain.Server) ([]domain.Server, []domain.Server, error)
-	ViewServers(serverFilter *dto.ServerFilter, from, to int, sortedColumn string, order string) ([]domain.Server, error)
-	UpdateServer(server_id string, updatedData map[string]interface{}) error
-	DeleteServer(serverID string) error
+	CreateServer(ctx context.Context, server *domain.Server) (int, error)
+	CreateServers(ctx context.Context, servers []domain.Server) ([]domain.Server, []dto.RejectedServer, error)
+	ViewServers(serverFilter *dto.ServerFilter, sortFields []dto.SortField, cursor *dto.ServerCursor, limit int) ([]domain.Server, error)
+	StreamServers(serverFilter *dto.ServerFilter, sortFields []dto.SortField, fn func(server domain.Server) error) error
+	CountServers(serverFilter *dto.ServerFilter) (int, error)
+	UpdateServer(ctx context.Context, server_id string, updatedData map[string]interface{}) error
+	DeleteServer(ctx context.Context, serverID string) error
+	GetDeletedServers(offset, limit int) ([]domain.Server, int, error)
+	RestoreServer(ctx context.Context, serverID string) error
+	PurgeServersDeletedBefore(before time.Time) (int, error)
+	FindServers(serverIDs, serverNames []string) ([]domain.Server, error)
+	GetAllServers() ([]domain.Server, error)
+	ApplyServerImport(ctx context.Context, plan dto.ServerImportPlan) (*dto.ServerImportResult, error)
 	
-	UpdateServerStatus(id int, status string) error
+	GetServerStatus(id int) (domain.ServerStatus, error)
+	UpdateServerStatus(id int, previousStatus, status domain.ServerStatus) (bool, error)
 	GetAllAddresses() ([]dto.ServerAddress, error)
-
-	AddServerStatus(id int, status string) error
+	GetAddressesUpdatedSince(since time.Time) ([]dto.ServerAddress, error)
+	GetCheckedServerIDs() ([]int, error)
+	GetServerIDsByLabels(labels map[string]string) ([]int, error)
+
+	AddServerStatus(result dto.HealthCheckResult, status, decidedStatus domain.ServerStatus) error
+	SetLocationStatus(id int, status dto.LocationStatus) ([]dto.LocationStatus, error)
+	GetLocationStatuses(id int) ([]dto.LocationStatus, error)
+	AddStatusTransition(id int, previousStatus, status domain.ServerStatus, changedAt time.Time) error
+	GetStatusTransitions(id int, startTime, endTime time.Time, offset, limit int) ([]dto.StatusTransition, int, error)
+	GetDownServers(since time.Time, maxChecks int) ([]dto.DownServer, error)
 	GetNumOnServers() (int, error)
+	GetStatusCounts(ids []int) (map[domain.ServerStatus]int, error)
 	GetNumServers() (int, error)
-	GetServerUptimeRatio(startTime, endTime time.Time) (float64, error)
+	GetServerUptimeRatio(startTime, endTime time.Time, ids []int) (float64, error)
+	GetServersUptime(startTime, endTime time.Time, ids []int) ([]dto.ServerUptime, error)
+	GetUptimeTimeline(id int, startTime, endTime time.Time, interval string) ([]dto.UptimeBucket, error)
+	GetLatencyPercentiles(id int, startTime, endTime time.Time) ([]dto.LatencyPercentiles, error)
+
+	SearchServers(searchQuery dto.ServerSearchQuery) (*dto.ServerSearchResult, error)
+	EnsureServerIndex() error
+	SyncServerIndex() error
 
 	SyncServerStatus() error
 }
@@ -48,31 +83,135 @@ func NewServerRepository(db *gorm.DB, redis *redis.Client, es *elasticsearch.Cli
 	}
 }
 
-func (r *serverRepository) CreateServer(server *domain.Server) (int, error) {
-	err := r.db.Create(server).Error
+/*
+	The create, update and delete methods record the change in the audit log, on behalf of
+	the actor of ctx, in the transaction of the change
+*/
+func (r *serverRepository) CreateServer(ctx context.Context, server *domain.Server) (int, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(server).Error; err != nil {
+			return err
+		}
+
+		entry, _ := newAuditEntry(ctx, domain.AuditSourceAPI, nil, server)
+		return createAuditEntries(tx, []domain.AuditEntry{entry})
+	})
+	if isUniqueViolation(err) {
+		return 0, ErrServerConflict
+	}
 	if err != nil {
 		return 0, err
 	}
 
-	// Turn the bit id in Redis bitmap 
-	status := 0
-	if server.Status == "On" {
-		status = 1
-	}
-
 	/*
 		WARNING: We are thinking how to make sure Redis must be updated after creating a server
 		To make sure Redis is synced with the database
 	*/
-	r.redis.SetBit(context.Background(), "server_status", int64(server.ID), status).Err()
+	if err := r.cacheStatus(context.Background(), server.ID, server.Status); err != nil {
+		logging.LogMessage("server_administration_service", "Error caching status of server ID: "+strconv.Itoa(server.ID)+", error: "+err.Error(), "ERROR")
+	}
+	logIndexError(server.ID, r.indexServer(*server))
 
 	return server.ID, nil
 }
 
-func (r *serverRepository) ViewServers(serverFilter *dto.ServerFilter, from, to int, sortedColumn string, order string) ([]domain.Server, error) {
+// isUniqueViolation tells whether the database rejected a row taking the unique value of another one
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+/*
+	Keyset pagination: the servers are sorted by sortFields and the page starts right after the cursor,
+	so deleted rows and filters don't shift the pages. sortFields must end with a unique column, e.g. the id.
+	limit <= 0 returns every remaining server.
+*/
+func (r *serverRepository) ViewServers(serverFilter *dto.ServerFilter, sortFields []dto.SortField, cursor *dto.ServerCursor, limit int) ([]domain.Server, error) {
 	var servers []domain.Server
-	query := r.db.Model(&domain.Server{})
+	query := sortServers(filterServers(r.db.Model(&domain.Server{}), serverFilter), sortFields)
+
+	if cursor != nil {
+		query = query.Where(afterCursor(sortFields, cursor.Values))
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if err := query.Find(&servers).Error; err != nil {
+		return nil, err
+	}
+
+	return servers, nil
+}
+
+/*
+	StreamServers calls fn with every matching server, reading them one at a time from a database cursor
+	so that an export of the whole inventory never holds it in memory. It stops at the first error of fn.
+*/
+func (r *serverRepository) StreamServers(serverFilter *dto.ServerFilter, sortFields []dto.SortField, fn func(server domain.Server) error) error {
+	query := sortServers(filterServers(r.db.Model(&domain.Server{}), serverFilter), sortFields)
+
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var server domain.Server
+		if err := r.db.ScanRows(rows, &server); err != nil {
+			return err
+		}
+		if err := fn(server); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func sortServers(query *gorm.DB, sortFields []dto.SortField) *gorm.DB {
+	for _, field := range sortFields {
+		// Columns are quoted, they can't inject anything into the ORDER BY
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: field.Column}, Desc: field.Order == "desc"})
+	}
+	return query
+}
+
+/*
+	afterCursor matches the servers sorted after values. Fields may go in different directions,
+	so it is written as (a > v1) OR (a = v1 AND b < v2) OR ... instead of a row comparison.
+*/
+func afterCursor(sortFields []dto.SortField, values []interface{}) clause.Expression {
+	alternatives := make([]clause.Expression, len(sortFields))
+	for i, field := range sortFields {
+		comparison := " > ?"
+		if field.Order == "desc" {
+			comparison = " < ?"
+		}
 
+		conditions := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, clause.Eq{Column: clause.Column{Name: sortFields[j].Column}, Value: values[j]})
+		}
+		conditions = append(conditions, clause.Expr{SQL: "?" + comparison, Vars: []interface{}{clause.Column{Name: field.Column}, values[i]}})
+
+		alternatives[i] = clause.And(conditions...)
+	}
+	return clause.Or(alternatives...)
+}
+
+func (r *serverRepository) CountServers(serverFilter *dto.ServerFilter) (int, error) {
+	var count int64
+	if err := filterServers(r.db.Model(&domain.Server{}), serverFilter).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+const ipv4Pattern = `^((25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])\.){3}(25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])$`
+
+func filterServers(query *gorm.DB, serverFilter *dto.ServerFilter) *gorm.DB {
 	if serverFilter.ServerID != "" {
 		query = query.Where("server_id = ?", serverFilter.ServerID)
 	}
@@ -81,222 +220,1090 @@ func (r *serverRepository) ViewServers(serverFilter *dto.ServerFilter, from, to
 		query = query.Where("server_name LIKE ?", "%"+serverFilter.ServerName+"%")
 	}
 
-	if serverFilter.Status != "" {
-		query = query.Where("status = ?", serverFilter.Status)
+	if len(serverFilter.Statuses) > 0 {
+		query = query.Where("status IN ?", serverFilter.Statuses)
 	}
 
 	if serverFilter.IPv4 != "" {
 		query = query.Where("ipv4 = ?", serverFilter.IPv4)
 	}
 
+	if serverFilter.IPv4CIDR != "" {
+		// ipv4 is free text, only the rows holding an actual address can be cast to inet
+		query = query.Where("CASE WHEN ipv4 ~ ? THEN ipv4::inet <<= ?::cidr ELSE false END", ipv4Pattern, serverFilter.IPv4CIDR)
+	}
+
 	if serverFilter.Port >= 0 {
 		query = query.Where("port = ?", serverFilter.Port)
 	}
 
-	query = query.Where("id BETWEEN ? AND ?", from, to)
+	if serverFilter.PortRange.From != nil {
+		query = query.Where("port >= ?", *serverFilter.PortRange.From)
+	}
+	if serverFilter.PortRange.To != nil {
+		query = query.Where("port <= ?", *serverFilter.PortRange.To)
+	}
+
+	query = filterTimeRange(query, "created_time", serverFilter.CreatedTime)
+	query = filterTimeRange(query, "last_updated", serverFilter.LastUpdated)
 
-	// sortedColumn is mandatory
-	err := query.Order(sortedColumn + " " + order).Find(&servers).Error
-	if err != nil {
-		return nil, err
+	if len(serverFilter.Labels) > 0 {
+		query = query.Where("labels @> ?", encodeLabels(serverFilter.Labels))
 	}
 
-	return servers, nil
+	return query
+}
+
+// column is one of the server columns named by filterServers, never user input
+func filterTimeRange(query *gorm.DB, column string, timeRange dto.TimeRange) *gorm.DB {
+	if timeRange.From != nil {
+		query = query.Where(column+" >= ?", *timeRange.From)
+	}
+	if timeRange.To != nil {
+		query = query.Where(column+" < ?", *timeRange.To)
+	}
+	return query
+}
+
+// 13 parameters per server keeps a batch well below the 65535 parameters Postgres accepts
+const createServersBatchSize = 500
+
+var createServersColumns = []string{
+	"server_id", "server_name", "status", "ipv4", "port", "labels",
+	"probe_type", "probe_path", "probe_expected_status", "probe_expected_body",
+	"check_interval_seconds", "timeout_ms", "retries",
 }
 
 /*
-	UPDATE: Using Raw query to receive just the inserted records
+	Inserts the servers in batches inside a single transaction. Servers conflicting with an existing one,
+	or with an earlier server of the same call, are rejected with the reason. A batch failing for another
+	reason is retried server by server so that only the faulty servers are rejected.
+	Redis and the search index only learn about the servers once the transaction is committed.
 */
-func (r *serverRepository) CreateServers(servers []domain.Server) (inserted []domain.Server, nonInserted []domain.Server, err error) {
-	// Use raw SQL to insert multiple rows and get the inserted IDs
-	query := `
-		INSERT INTO servers (server_id, server_name, status, ipv4, port) VALUES 
-	`
+func (r *serverRepository) CreateServers(ctx context.Context, servers []domain.Server) ([]domain.Server, []dto.RejectedServer, error) {
+	result, err := r.ApplyServerImport(ctx, dto.ServerImportPlan{Create: servers})
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Created, result.Rejected, nil
+}
 
-	for i, server := range servers {
-		query += fmt.Sprintf("('%s', '%s', '%s', '%s', %d)",
-			server.ServerID, server.ServerName, server.Status, server.IPv4, server.Port)
-		
-		if i < len(servers)-1 {
-			query += ", "
+/*
+	Applies an import in a single transaction, the deletes first so that their server names can be taken
+	by the updates and the inserts. A server failing to update is rejected with the reason like a failing
+	insert, see CreateServers. Only the configuration columns are updated, the status belongs to the health checks.
+	Every server changed is recorded in the audit log with its rows as they were before and after the import.
+*/
+func (r *serverRepository) ApplyServerImport(ctx context.Context, plan dto.ServerImportPlan) (*dto.ServerImportResult, error) {
+	result := &dto.ServerImportResult{}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var entries []domain.AuditEntry
+		audit := func(before, after *domain.Server) {
+			if entry, changed := newAuditEntry(ctx, domain.AuditSourceImport, before, after); changed {
+				entries = append(entries, entry)
+			}
 		}
-	}
 
-	query += " ON CONFLICT DO NOTHING RETURNING *"
+		for start := 0; start < len(plan.Delete); start += createServersBatchSize {
+			end := min(start+createServersBatchSize, len(plan.Delete))
 
-	var result []domain.Server
-	err = r.db.Raw(query).Scan(&result).Error
+			ids := make([]int, 0, end-start)
+			for _, server := range plan.Delete[start:end] {
+				ids = append(ids, server.ID)
+			}
+
+			var deleted []domain.Server
+			if err := tx.Clauses(clause.Returning{}).Where("id IN ?", ids).Delete(&deleted).Error; err != nil {
+				return err
+			}
+			for i := range deleted {
+				audit(&deleted[i], nil)
+			}
+		}
+		result.Deleted = plan.Delete
+
+		previous, err := lockServers(tx, plan.Update)
+		if err != nil {
+			return err
+		}
+
+		for _, server := range plan.Update {
+			if err := tx.SavePoint("server_row").Error; err != nil {
+				return err
+			}
+
+			var updated domain.Server
+			updateErr := tx.Model(&updated).
+				Clauses(clause.Returning{}).
+				Where("id = ?", server.ID).
+				Updates(map[string]interface{}{
+					"server_name":            server.ServerName,
+					"ipv4":                   server.IPv4,
+					"port":                   server.Port,
+					"labels":                 encodeLabels(server.Labels),
+					"check_interval_seconds": server.CheckIntervalSeconds,
+					"timeout_ms":             server.TimeoutMs,
+					"retries":                server.Retries,
+				}).Error
+			if updateErr != nil {
+				if err := tx.RollbackTo("server_row").Error; err != nil {
+					return err
+				}
+				result.Rejected = append(result.Rejected, dto.RejectedServer{Server: server, Reason: updateErr.Error()})
+				continue
+			}
+			result.Updated = append(result.Updated, server)
+
+			// A server deleted since the import was planned updates nothing
+			if before, ok := previous[server.ID]; ok {
+				audit(&before, &updated)
+			}
+		}
+
+		for start := 0; start < len(plan.Create); start += createServersBatchSize {
+			end := min(start+createServersBatchSize, len(plan.Create))
+
+			batchInserted, batchRejected, err := insertServerBatch(tx, plan.Create[start:end])
+			if err != nil {
+				return err
+			}
+			result.Created = append(result.Created, batchInserted...)
+			result.Rejected = append(result.Rejected, batchRejected...)
+		}
+		for i := range result.Created {
+			audit(nil, &result.Created[i])
+		}
+
+		return createAuditEntries(tx, entries)
+	})
 	if err != nil {
-		logging.LogMessage("server_administration_service", "Error inserting servers: "+err.Error(), "ERROR")
-		return nil, nil, err
+		logging.LogMessage("server_administration_service", "Error importing servers: "+err.Error(), "ERROR")
+		return nil, err
 	}
 
-	// Determine non-inserted records
-	insertedMap := make(map[string]bool)
-	for _, server := range result {
-		insertedMap[server.ServerID] = true
-		inserted = append(inserted, server)
+	// Conflicts are only known after the insert, compare with what went in
+	insertedIDs := make(map[string]bool)
+	insertedNames := make(map[string]bool)
+	for _, server := range result.Created {
+		insertedIDs[server.ServerID] = true
+		insertedNames[server.ServerName] = true
 	}
-
-	for _, server := range servers {
-		if !insertedMap[server.ServerID] {
-			nonInserted = append(nonInserted, server)
+	for i, rejection := range result.Rejected {
+		if rejection.Reason != "" {
+			continue
+		}
+		if insertedIDs[rejection.Server.ServerID] || insertedNames[rejection.Server.ServerName] {
+			result.Rejected[i].Reason = "duplicates the server_id or server_name of another server of the same import"
+		} else {
+			result.Rejected[i].Reason = "a server with this server_id or server_name already exists, possibly in the trash"
 		}
 	}
 
-	// Update Redis bitmap for inserted records
-	for _, server := range result {
+	for _, server := range result.Created {
 		logging.LogMessage("server_administration_service", "Server "+strconv.Itoa(server.ID)+" inserted successfully", "INFO")
 
-		status := 0
-		if server.Status == "On" {
-			status = 1
+		if err := r.cacheStatus(context.Background(), server.ID, server.Status); err != nil {
+			logging.LogMessage("server_administration_service", "Error updating Redis bitmap for server ID: "+strconv.Itoa(server.ID)+", error: "+err.Error(), "ERROR")
 		}
+	}
 
-		if err := r.redis.SetBit(context.Background(), "server_status", int64(server.ID), status).Err(); err != nil {
+	for _, server := range result.Deleted {
+		logging.LogMessage("server_administration_service", "Server "+strconv.Itoa(server.ID)+" deleted by an import", "INFO")
+
+		if err := r.uncacheStatus(context.Background(), server.ID); err != nil {
 			logging.LogMessage("server_administration_service", "Error updating Redis bitmap for server ID: "+strconv.Itoa(server.ID)+", error: "+err.Error(), "ERROR")
 		}
+		logIndexError(server.ID, r.deleteServerDocument(server.ID))
+	}
+
+	if err := r.bulkIndexServers(append(append([]domain.Server{}, result.Created...), result.Updated...)); err != nil {
+		logging.LogMessage("server_administration_service", "Error mirroring imported servers to the servers index: "+err.Error(), "ERROR")
 	}
 
-	return inserted, nonInserted, nil
+	return result, nil
 }
 
-func (r *serverRepository) UpdateServer(serverID string, updatedData map[string]interface{}) error {
-	if err := r.db.Model(&domain.Server{}).
-		Where("server_id = ?", serverID).
-		Updates(updatedData).Error; err != nil {
-			return err
+// lockServers reads the servers as they are before an update and locks them until the transaction ends, by id
+func lockServers(tx *gorm.DB, servers []domain.Server) (map[int]domain.Server, error) {
+	locked := make(map[int]domain.Server, len(servers))
+
+	for start := 0; start < len(servers); start += createServersBatchSize {
+		end := min(start+createServersBatchSize, len(servers))
+
+		ids := make([]int, 0, end-start)
+		for _, server := range servers[start:end] {
+			ids = append(ids, server.ID)
+		}
+
+		var batch []domain.Server
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		for _, server := range batch {
+			locked[server.ID] = server
+		}
 	}
 
-	// Get the server's id
-	var server domain.Server
-	if err := r.db.Where("server_id = ?", serverID).First(&server).Error; err != nil {
-		return err
+	return locked, nil
+}
+
+/*
+	insertServerBatch leaves Reason empty for the servers skipped by ON CONFLICT, the caller explains them.
+	The savepoints keep the transaction usable after a failed statement.
+*/
+func insertServerBatch(tx *gorm.DB, batch []domain.Server) (inserted []domain.Server, rejected []dto.RejectedServer, err error) {
+	if err := tx.SavePoint("server_batch").Error; err != nil {
+		return nil, nil, err
 	}
 
-	// Update Redis bitmap
-	statusValue := 0
-	if updatedData["status"] == "On" {
-		statusValue = 1
+	inserted, err = insertServers(tx, batch)
+	if err == nil {
+		return inserted, notInserted(batch, inserted), nil
+	}
+	if err := tx.RollbackTo("server_batch").Error; err != nil {
+		return nil, nil, err
 	}
 
-	if err := r.redis.SetBit(context.Background(), "server_status", int64(server.ID), statusValue).Err(); err != nil {
-		return err
+	for _, server := range batch {
+		if err := tx.SavePoint("server_row").Error; err != nil {
+			return nil, nil, err
+		}
+
+		rows, err := insertServers(tx, []domain.Server{server})
+		if err != nil {
+			if err := tx.RollbackTo("server_row").Error; err != nil {
+				return nil, nil, err
+			}
+			rejected = append(rejected, dto.RejectedServer{Server: server, Reason: err.Error()})
+			continue
+		}
+
+		inserted = append(inserted, rows...)
+		rejected = append(rejected, notInserted([]domain.Server{server}, rows)...)
 	}
 
-	return nil
+	return inserted, rejected, nil
 }
 
-func (r *serverRepository) DeleteServer(serverID string) error {
-	// Get the server's ID before deleting
-	var server domain.Server
-	if err := r.db.Where("server_id = ?", serverID).First(&server).Error; err != nil {
-		return err
+func insertServers(tx *gorm.DB, servers []domain.Server) ([]domain.Server, error) {
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(createServersColumns)), ", ") + ")"
+
+	values := make([]string, len(servers))
+	args := make([]interface{}, 0, len(servers)*len(createServersColumns))
+	for i, server := range servers {
+		values[i] = placeholders
+		args = append(args,
+			server.ServerID, server.ServerName, server.Status, server.IPv4, server.Port, encodeLabels(server.Labels),
+			server.ProbeType, server.ProbePath, server.ProbeExpectedStatus, server.ProbeExpectedBody,
+			server.CheckIntervalSeconds, server.TimeoutMs, server.Retries,
+		)
 	}
 
-	// Delete the server
-	if err := r.db.Where("server_id = ?", serverID).Delete(&domain.Server{}).Error; err != nil {
-		return err
+	query := "INSERT INTO servers (" + strings.Join(createServersColumns, ", ") + ") VALUES " +
+		strings.Join(values, ", ") + " ON CONFLICT DO NOTHING RETURNING *"
+
+	var result []domain.Server
+	if err := tx.Raw(query, args...).Scan(&result).Error; err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	// Update Redis bitmap
-	if err := r.redis.SetBit(context.Background(), "server_status", int64(server.ID), 0).Err(); err != nil {
-		return err
+func notInserted(servers []domain.Server, inserted []domain.Server) []dto.RejectedServer {
+	insertedKeys := make(map[string]int)
+	for _, server := range inserted {
+		insertedKeys[server.ServerID+"\x00"+server.ServerName]++
 	}
 
-	return nil
+	var rejected []dto.RejectedServer
+	for _, server := range servers {
+		// Of two identical servers ON CONFLICT only inserts the first one
+		key := server.ServerID + "\x00" + server.ServerName
+		if insertedKeys[key] > 0 {
+			insertedKeys[key]--
+			continue
+		}
+		rejected = append(rejected, dto.RejectedServer{Server: server})
+	}
+	return rejected
 }
 
-func (r *serverRepository) UpdateServerStatus(id int, status string) error {
-	/*
-		WARNING: Not handling the case when Redis is crashed
-	*/
-	currentStatus := int(r.redis.GetBit(context.Background(), "server_status", int64(id)).Val())
-	nextStatus := 0
-	if status == "On" {
-		nextStatus = 1
+func (r *serverRepository) UpdateServer(ctx context.Context, serverID string, updatedData map[string]interface{}) error {
+	// Updates with a map skips the JSON serializer of the field
+	if labels, existed := updatedData["labels"].(map[string]string); existed {
+		updatedData["labels"] = encodeLabels(labels)
 	}
 
-	if currentStatus == nextStatus {
-		logging.LogMessage("server_administration_service", "Server ID "+strconv.Itoa(id)+" status is already "+status, "INFO")
-		return nil
-	}
+	var server domain.Server
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Locked so that no other change slips between the version read and the update
+		var before domain.Server
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("server_id = ?", serverID).First(&before).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&domain.Server{}).
+			Where("id = ?", before.ID).
+			Updates(updatedData).Error; err != nil {
+				return err
+		}
 
-	// Update Redis bitmap
-	r.redis.SetBit(context.Background(), "server_status", int64(id), nextStatus)
+		if err := tx.Where("id = ?", before.ID).First(&server).Error; err != nil {
+			return err
+		}
 
-	if err := r.db.Model(&domain.Server{}).
-		Where("id = ?", id).
-		Update("status", status).Error; err != nil {
+		entry, changed := newAuditEntry(ctx, domain.AuditSourceAPI, &before, &server)
+		if !changed {
+			return nil
+		}
+		return createAuditEntries(tx, []domain.AuditEntry{entry})
+	})
+	if err != nil {
 		return err
 	}
-	return nil
+	logIndexError(server.ID, r.indexServer(server))
+
+	// Only a status change touches the Redis cache
+	status, existed := updatedData["status"].(domain.ServerStatus)
+	if !existed {
+		return nil
+	}
+
+	return r.cacheStatus(context.Background(), server.ID, status)
 }
 
-func (r *serverRepository) GetAllAddresses() ([]dto.ServerAddress, error) {
-	var addresses []dto.ServerAddress
-	if err := r.db.Model(&domain.Server{}).
-		Select("id", "ipv4", "port").
-		Find(&addresses).Error; err != nil {
-		return nil, err
+/*
+	Moves the server to the trash, its status history stays in Elasticsearch until it is purged.
+	It leaves the search index and the status cache like a deleted server, RestoreServer puts it back.
+*/
+func (r *serverRepository) DeleteServer(ctx context.Context, serverID string) error {
+	var server domain.Server
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Get the server before deleting, the audit log keeps its last version
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("server_id = ?", serverID).First(&server).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ?", server.ID).Delete(&domain.Server{}).Error; err != nil {
+			return err
+		}
+
+		entry, _ := newAuditEntry(ctx, domain.AuditSourceAPI, &server, nil)
+		return createAuditEntries(tx, []domain.AuditEntry{entry})
+	})
+	if err != nil {
+		return err
 	}
 
-	return addresses, nil
+	logIndexError(server.ID, r.deleteServerDocument(server.ID))
+
+	return r.uncacheStatus(context.Background(), server.ID)
 }
 
-func (r *serverRepository) AddServerStatus(id int, status string) error {
-	// Add server status to Elasticsearch
-	ctx := context.Background()
+// GetDeletedServers lists the trash, the most recently deleted servers first
+func (r *serverRepository) GetDeletedServers(offset, limit int) ([]domain.Server, int, error) {
+	query := r.db.Unscoped().Model(&domain.Server{}).Where("deleted_at IS NOT NULL")
 
-	doc := map[string]interface{}{
-		"id":     id,
-		"status": status,
-		"timestamp": time.Now(),
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
 
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(doc); err != nil {
-		return err
+	servers := make([]domain.Server, 0)
+	if err := query.Order("deleted_at DESC").Order("id").Offset(offset).Limit(limit).Find(&servers).Error; err != nil {
+		return nil, 0, err
 	}
 
-	res, err := r.es.Index(
-		"server_status",
-		&buf,
-		r.es.Index.WithContext(ctx),
-	)
+	return servers, int(total), nil
+}
+
+// RestoreServer takes the server out of the trash
+func (r *serverRepository) RestoreServer(ctx context.Context, serverID string) error {
+	var server domain.Server
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var deleted domain.Server
+		err := tx.Unscoped().
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("server_id = ? AND deleted_at IS NOT NULL", serverID).
+			First(&deleted).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrServerNotInTrash
+		}
+		if err != nil {
+			return err
+		}
+
+		// last_updated moves too, so healthcheck_service picks the server up again
+		if err := tx.Unscoped().Model(&domain.Server{}).
+			Where("id = ?", deleted.ID).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
 
+		if err := tx.Where("id = ?", deleted.ID).First(&server).Error; err != nil {
+			return err
+		}
+
+		entry, _ := newAuditEntry(ctx, domain.AuditSourceAPI, nil, &server)
+		entry.Action = domain.AuditRestore
+		return createAuditEntries(tx, []domain.AuditEntry{entry})
+	})
 	if err != nil {
 		return err
 	}
-	defer res.Body.Close()
 
-	if res.IsError() {
-		return fmt.Errorf("Error indexing document: %s", res.String())
+	logIndexError(server.ID, r.indexServer(server))
+
+	return r.cacheStatus(context.Background(), server.ID, server.Status)
+}
+
+/*
+	Removes the servers deleted before the given time for good.
+	Their health check history is kept in Elasticsearch so past reports stay queryable.
+	The purge is recorded in the audit log on behalf of the system.
+*/
+func (r *serverRepository) PurgeServersDeletedBefore(before time.Time) (int, error) {
+	var purged []domain.Server
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().
+			Clauses(clause.Returning{}).
+			Where("deleted_at < ?", before).
+			Delete(&purged).Error; err != nil {
+			return err
+		}
+
+		entries := make([]domain.AuditEntry, 0, len(purged))
+		for i := range purged {
+			entry, _ := newAuditEntry(context.Background(), domain.AuditSourceSystem, &purged[i], nil)
+			entry.Action = domain.AuditPurge
+			entries = append(entries, entry)
+		}
+		return createAuditEntries(tx, entries)
+	})
+	if err != nil {
+		return 0, err
 	}
-	return nil
+
+	return len(purged), nil
 }
 
-func (r *serverRepository) GetNumOnServers() (int, error) {
-	numOnServers, _ := r.redis.BitCount(context.Background(), "server_status", nil).Result()
-	return int(numOnServers), nil
+// FindServers returns the servers having one of the server IDs or one of the server names
+func (r *serverRepository) FindServers(serverIDs, serverNames []string) ([]domain.Server, error) {
+	found := make(map[int]bool)
+	var servers []domain.Server
+
+	for column, values := range map[string][]string{"server_id": serverIDs, "server_name": serverNames} {
+		for start := 0; start < len(values); start += createServersBatchSize {
+			end := min(start+createServersBatchSize, len(values))
+
+			var batch []domain.Server
+			if err := r.db.Where(column+" IN ?", values[start:end]).Find(&batch).Error; err != nil {
+				return nil, err
+			}
+
+			// A server can match both by its server_id and by its name
+			for _, server := range batch {
+				if !found[server.ID] {
+					found[server.ID] = true
+					servers = append(servers, server)
+				}
+			}
+		}
+	}
+
+	return servers, nil
 }
 
-func (r *serverRepository) GetNumServers() (int, error) {
-	var count int64
-	if err := r.db.Model(&domain.Server{}).Count(&count).Error; err != nil {
-		return 0, err
+func (r *serverRepository) GetAllServers() ([]domain.Server, error) {
+	var servers []domain.Server
+	if err := r.db.Find(&servers).Error; err != nil {
+		return nil, err
 	}
-	return int(count), nil
+	return servers, nil
 }
 
-func (r *serverRepository) GetServerUptimeRatio(startTime, endTime time.Time) (float64, error) {
-	query := map[string]interface{}{
-		"size": 0,
-		"query": map[string]interface{}{
-			"range": map[string]interface{}{
+/*
+	The bitmap counts the available servers in O(1), the hash keeps the exact status of each one
+*/
+const statusHashKey = "server_statuses"
+
+func (r *serverRepository) cacheStatus(ctx context.Context, id int, status domain.ServerStatus) error {
+	bit := 0
+	if status.IsAvailable() {
+		bit = 1
+	}
+
+	if err := r.redis.SetBit(ctx, "server_status", int64(id), bit).Err(); err != nil {
+		return err
+	}
+
+	return r.redis.HSet(ctx, statusHashKey, strconv.Itoa(id), string(status)).Err()
+}
+
+func (r *serverRepository) uncacheStatus(ctx context.Context, id int) error {
+	if err := r.redis.SetBit(ctx, "server_status", int64(id), 0).Err(); err != nil {
+		return err
+	}
+
+	return r.redis.HDel(ctx, statusHashKey, strconv.Itoa(id)).Err()
+}
+
+/*
+	Reads the status from Redis, the database is only hit when Redis does not know the server
+*/
+func (r *serverRepository) GetServerStatus(id int) (domain.ServerStatus, error) {
+	status, err := r.redis.HGet(context.Background(), statusHashKey, strconv.Itoa(id)).Result()
+	if err == nil {
+		return domain.ServerStatus(status), nil
+	}
+	if err != redis.Nil {
+		logging.LogMessage("server_administration_service", "Error getting cached status of server ID: "+strconv.Itoa(id)+", error: "+err.Error(), "ERROR")
+	}
+
+	var server domain.Server
+	if err := r.db.Select("status").Where("id = ?", id).First(&server).Error; err != nil {
+		return "", err
+	}
+
+	return server.Status, nil
+}
+
+/*
+	Compare and set: the status only changes if the server is still in previousStatus,
+	so concurrent results cannot both flip it. Returns false when another update won
+*/
+func (r *serverRepository) UpdateServerStatus(id int, previousStatus, status domain.ServerStatus) (bool, error) {
+	result := r.db.Model(&domain.Server{}).
+		Where("id = ? AND status = ?", id, previousStatus).
+		Update("status", status)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		// The cached status may be the stale one, reload it so the next result compares against the database
+		var server domain.Server
+		if err := r.db.Select("status").Where("id = ?", id).First(&server).Error; err != nil {
+			logging.LogMessage("server_administration_service", "Error reloading status of server ID: "+strconv.Itoa(id)+", error: "+err.Error(), "ERROR")
+		} else if err := r.cacheStatus(context.Background(), id, server.Status); err != nil {
+			logging.LogMessage("server_administration_service", "Error caching status of server ID: "+strconv.Itoa(id)+", error: "+err.Error(), "ERROR")
+		}
+		return false, nil
+	}
+
+	/*
+		WARNING: Not handling the case when Redis is crashed
+	*/
+	if err := r.cacheStatus(context.Background(), id, status); err != nil {
+		logging.LogMessage("server_administration_service", "Error caching status of server ID: "+strconv.Itoa(id)+", error: "+err.Error(), "ERROR")
+	}
+
+	logIndexError(id, r.updateServerDocument(id, map[string]interface{}{"status": status}))
+	return true, nil
+}
+
+var addressColumns = []string{"id", "ipv4", "port", "probe_type", "probe_path", "probe_expected_status", "probe_expected_body", "check_interval_seconds", "timeout_ms", "retries"}
+
+// checkedServers leaves out the decommissioned servers, healthcheck_service only probes the others
+func checkedServers(query *gorm.DB) *gorm.DB {
+	return query.Where("status <> ?", domain.StatusDecommissioned)
+}
+
+func (r *serverRepository) GetAllAddresses() ([]dto.ServerAddress, error) {
+	var addresses []dto.ServerAddress
+	if err := checkedServers(r.db.Model(&domain.Server{})).
+		Select(addressColumns).
+		Find(&addresses).Error; err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+/*
+	Rows inserted with raw SQL may have no last_updated, they are always returned
+*/
+func (r *serverRepository) GetAddressesUpdatedSince(since time.Time) ([]dto.ServerAddress, error) {
+	var addresses []dto.ServerAddress
+	if err := checkedServers(r.db.Model(&domain.Server{})).
+		Select(addressColumns).
+		Where("last_updated > ? OR last_updated IS NULL", since).
+		Find(&addresses).Error; err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+// GetCheckedServerIDs returns the servers healthcheck_service probes, it drops every other one
+func (r *serverRepository) GetCheckedServerIDs() ([]int, error) {
+	var ids []int
+	if err := checkedServers(r.db.Model(&domain.Server{})).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *serverRepository) GetServerIDsByLabels(labels map[string]string) ([]int, error) {
+	ids := make([]int, 0)
+	if err := r.db.Model(&domain.Server{}).
+		Where("labels @> ?", encodeLabels(labels)).
+		Order("id").
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func encodeLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "{}"
+	}
+
+	encoded, _ := json.Marshal(labels)
+	return string(encoded)
+}
+
+/*
+	status is the recorded result of the location, it differs from result.Status for servers in maintenance.
+	decidedStatus is the status the quorum of locations decided after it, empty when there was no quorum.
+*/
+func (r *serverRepository) AddServerStatus(result dto.HealthCheckResult, status, decidedStatus domain.ServerStatus) error {
+	timestamp := result.CheckedAt
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	// Add server status to Elasticsearch
+	doc := map[string]interface{}{
+		"id":     result.ID,
+		"status": string(status),
+		"timestamp": timestamp,
+		"latency_ms": result.LatencyMs,
+	}
+	if result.Location != "" {
+		doc["location"] = result.Location
+	}
+	if result.ErrorClass != "" {
+		doc["error_class"] = result.ErrorClass
+	}
+	if decidedStatus != "" {
+		doc["decided_status"] = string(decidedStatus)
+	}
+
+	return r.indexDocument("server_status", doc)
+}
+
+func locationStatusKey(id int) string {
+	return "server_location_status:" + strconv.Itoa(id)
+}
+
+/*
+	Stores the latest result of a probe location and returns the latest result of every location
+*/
+func (r *serverRepository) SetLocationStatus(id int, status dto.LocationStatus) ([]dto.LocationStatus, error) {
+	value, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.redis.HSet(context.Background(), locationStatusKey(id), status.Location, value).Err(); err != nil {
+		return nil, err
+	}
+
+	return r.GetLocationStatuses(id)
+}
+
+func (r *serverRepository) GetLocationStatuses(id int) ([]dto.LocationStatus, error) {
+	values, err := r.redis.HGetAll(context.Background(), locationStatusKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]dto.LocationStatus, 0, len(values))
+	for location, value := range values {
+		var status dto.LocationStatus
+		if err := json.Unmarshal([]byte(value), &status); err != nil {
+			logging.LogMessage("server_administration_service", "Invalid location status of server ID: "+strconv.Itoa(id)+", location: "+location, "ERROR")
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Location < statuses[j].Location
+	})
+
+	return statuses, nil
+}
+
+func (r *serverRepository) AddStatusTransition(id int, previousStatus, status domain.ServerStatus, changedAt time.Time) error {
+	ctx := context.Background()
+	field := strconv.Itoa(id)
+
+	doc := map[string]interface{}{
+		"id":              id,
+		"previous_status": string(previousStatus),
+		"status":          string(status),
+		"timestamp":       changedAt,
+	}
+
+	// The last transition time of every server is kept in Redis to know how long the previous state lasted
+	lastChangedAt, err := r.redis.HGet(ctx, "server_status_changed_at", field).Int64()
+	if err == nil {
+		doc["previous_duration_seconds"] = (changedAt.UnixMilli() - lastChangedAt) / 1000
+	} else if err != redis.Nil {
+		logging.LogMessage("server_administration_service", "Error getting last status change of server ID: "+field+", error: "+err.Error(), "ERROR")
+	}
+
+	if err := r.redis.HSet(ctx, "server_status_changed_at", field, changedAt.UnixMilli()).Err(); err != nil {
+		logging.LogMessage("server_administration_service", "Error saving last status change of server ID: "+field+", error: "+err.Error(), "ERROR")
+	}
+
+	return r.indexDocument("server_status_transitions", doc)
+}
+
+/*
+	id <= 0 lists the transitions of every server, newest first
+*/
+func (r *serverRepository) GetStatusTransitions(id int, startTime, endTime time.Time, offset, limit int) ([]dto.StatusTransition, int, error) {
+	filters := []interface{}{timeRangeQuery(startTime, endTime)}
+	if id > 0 {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{
+				"id": id,
+			},
+		})
+	}
+
+	query := map[string]interface{}{
+		"from": offset,
+		"size": limit,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": filters,
+			},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{
 				"timestamp": map[string]interface{}{
-					"gte": startTime.Format(time.RFC3339),
-					"lte": endTime.Format(time.RFC3339),
+					"order": "desc",
+				},
+			},
+		},
+	}
+
+	result, err := r.search("server_status_transitions", query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error getting status transitions: %w", err)
+	}
+
+	hits := result["hits"].(map[string]interface{})
+	total := int(hits["total"].(map[string]interface{})["value"].(float64))
+
+	transitions := make([]dto.StatusTransition, 0)
+	for _, h := range hits["hits"].([]interface{}) {
+		source, err := json.Marshal(h.(map[string]interface{})["_source"])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var transition dto.StatusTransition
+		if err := json.Unmarshal(source, &transition); err != nil {
+			return nil, 0, err
+		}
+		transitions = append(transitions, transition)
+	}
+
+	return transitions, total, nil
+}
+
+func (r *serverRepository) GetNumOnServers() (int, error) {
+	numOnServers, _ := r.redis.BitCount(context.Background(), "server_status", nil).Result()
+	return int(numOnServers), nil
+}
+
+/*
+	ids == nil counts every server, otherwise only the given ones
+*/
+func (r *serverRepository) GetStatusCounts(ids []int) (map[domain.ServerStatus]int, error) {
+	counts := make(map[domain.ServerStatus]int)
+	if ids != nil && len(ids) == 0 {
+		return counts, nil
+	}
+
+	query := r.db.Model(&domain.Server{}).Select("status, COUNT(*) AS count")
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var rows []struct {
+		Status domain.ServerStatus
+		Count  int
+	}
+	if err := query.Group("status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *serverRepository) GetNumServers() (int, error) {
+	var count int64
+	if err := r.db.Model(&domain.Server{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+/*
+	ids == nil covers every server, otherwise only the given ones
+*/
+func (r *serverRepository) GetServerUptimeRatio(startTime, endTime time.Time, ids []int) (float64, error) {
+	query := map[string]interface{}{
+		"size": 0,
+		"query": uptimeQuery(scopedFilters(ids, timeRangeQuery(startTime, endTime))...),
+		"aggs": map[string]interface{}{
+			"per_server": perServerUptimeAggregation(),
+			"avg_ratio": map[string]interface{}{
+				"avg_bucket": map[string]interface{}{
+					"buckets_path": "per_server>on_ratio.value",
+				},
+			},
+		},
+	}
+
+	result, err := r.searchServerStatus(query)
+	if err != nil {
+		return 0, fmt.Errorf("Error getting server uptime ratio: %w", err)
+	}
+
+	aggregations := result["aggregations"].(map[string]interface{})
+
+	// avg_bucket needs a terms aggregation, refuse an average that would leave servers out
+	if perServer, ok := aggregations["per_server"].(map[string]interface{}); ok {
+		if others, _ := perServer["sum_other_doc_count"].(float64); others > 0 {
+			return 0, fmt.Errorf("Error getting server uptime ratio: more than %d servers", maxUptimeRatioServers)
+		}
+	}
+
+	avgRatio := aggregations["avg_ratio"].(map[string]interface{})["value"]
+	if avgRatio == nil {
+		return 0, nil
+	}
+	return avgRatio.(float64), nil
+}
+
+func (r *serverRepository) GetServersUptime(startTime, endTime time.Time, ids []int) ([]dto.ServerUptime, error) {
+	buckets, err := r.perServerBuckets(
+		"server_status",
+		uptimeQuery(scopedFilters(ids, timeRangeQuery(startTime, endTime))...),
+		map[string]interface{}{
+			"on_count": map[string]interface{}{
+				"filter": availableStatusQuery(),
+			},
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting servers uptime: %w", err)
+	}
+
+	statusChanges, err := r.countStatusTransitions(startTime, endTime, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	uptimes := make([]dto.ServerUptime, 0, len(buckets))
+	for _, bucket := range buckets {
+		uptime := dto.ServerUptime{
+			ID:        bucketServerID(bucket),
+			NumChecks: int(bucket["doc_count"].(float64)),
+		}
+		uptime.NumStatusChanges = statusChanges[uptime.ID]
+
+		if uptime.NumChecks > 0 {
+			onCount := bucket["on_count"].(map[string]interface{})["doc_count"].(float64)
+			uptime.UptimeRatio = onCount / float64(uptime.NumChecks)
+		}
+
+		uptimes = append(uptimes, uptime)
+	}
+
+	return uptimes, nil
+}
+
+/*
+	Every status change is recorded by AddStatusTransition, so the changes
+	of each server are counted per server on that index
+*/
+func (r *serverRepository) countStatusTransitions(startTime, endTime time.Time, ids []int) (map[int]int, error) {
+	query := map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter": scopedFilters(ids, timeRangeQuery(startTime, endTime)),
+		},
+	}
+
+	buckets, err := r.perServerBuckets("server_status_transitions", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error counting status transitions: %w", err)
+	}
+
+	changes := make(map[int]int, len(buckets))
+	for _, bucket := range buckets {
+		changes[bucketServerID(bucket)] = int(bucket["doc_count"].(float64))
+	}
+
+	return changes, nil
+}
+
+// Buckets of a composite aggregation fetched per request, see perServerBuckets
+const perServerPageSize = 1000
+
+/*
+	perServerBuckets returns a bucket per server of the documents matching the query, with the given sub-aggregations.
+	A terms aggregation silently drops the servers past its size, so the buckets are paged through
+	with a composite aggregation and its after_key instead.
+*/
+func (r *serverRepository) perServerBuckets(index string, query map[string]interface{}, aggs map[string]interface{}) ([]map[string]interface{}, error) {
+	var buckets []map[string]interface{}
+	var afterKey interface{}
+
+	for {
+		composite := map[string]interface{}{
+			"size": perServerPageSize,
+			"sources": []interface{}{
+				map[string]interface{}{
+					"id": map[string]interface{}{
+						"terms": map[string]interface{}{"field": "id"},
+					},
+				},
+			},
+		}
+		if afterKey != nil {
+			composite["after"] = afterKey
+		}
+
+		perServer := map[string]interface{}{"composite": composite}
+		if aggs != nil {
+			perServer["aggs"] = aggs
+		}
+
+		result, err := r.search(index, map[string]interface{}{
+			"size":  0,
+			"query": query,
+			"aggs": map[string]interface{}{
+				"per_server": perServer,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		aggregation := result["aggregations"].(map[string]interface{})["per_server"].(map[string]interface{})
+		page := aggregation["buckets"].([]interface{})
+		for _, bucket := range page {
+			buckets = append(buckets, bucket.(map[string]interface{}))
+		}
+
+		// The last page is the one not filled up, or the empty one after it
+		afterKey = aggregation["after_key"]
+		if len(page) < perServerPageSize || afterKey == nil {
+			return buckets, nil
+		}
+	}
+}
+
+func bucketServerID(bucket map[string]interface{}) int {
+	return int(bucket["key"].(map[string]interface{})["id"].(float64))
+}
+
+/*
+	id <= 0 builds the timeline of the whole fleet
+*/
+func (r *serverRepository) GetUptimeTimeline(id int, startTime, endTime time.Time, interval string) ([]dto.UptimeBucket, error) {
+	filters := []interface{}{timeRangeQuery(startTime, endTime)}
+	if id > 0 {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{
+				"id": id,
+			},
+		})
+	}
+
+	query := map[string]interface{}{
+		"size": 0,
+		"query": uptimeQuery(filters...),
+		"aggs": map[string]interface{}{
+			"timeline": map[string]interface{}{
+				"date_histogram": map[string]interface{}{
+					"field":             "timestamp",
+					"calendar_interval": interval,
+					"min_doc_count":     0,
+					"extended_bounds": map[string]interface{}{
+						"min": startTime.Format(time.RFC3339),
+						"max": endTime.Format(time.RFC3339),
+					},
+				},
+				"aggs": map[string]interface{}{
+					"on_count": map[string]interface{}{
+						"filter": availableStatusQuery(),
+					},
+				},
+			},
+		},
+	}
+
+	result, err := r.searchServerStatus(query)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting uptime timeline: %w", err)
+	}
+
+	buckets := result["aggregations"].(map[string]interface{})["timeline"].(map[string]interface{})["buckets"].([]interface{})
+
+	timeline := make([]dto.UptimeBucket, 0, len(buckets))
+	for _, b := range buckets {
+		bucket := b.(map[string]interface{})
+
+		numChecks := int(bucket["doc_count"].(float64))
+		numOn := int(bucket["on_count"].(map[string]interface{})["doc_count"].(float64))
+
+		uptimeBucket := dto.UptimeBucket{
+			Timestamp: time.UnixMilli(int64(bucket["key"].(float64))).UTC(),
+			NumChecks: numChecks,
+		}
+		if numChecks > 0 {
+			uptimeBucket.UptimeRatio = float64(numOn) / float64(numChecks)
+		}
+
+		timeline = append(timeline, uptimeBucket)
+	}
+
+	return timeline, nil
+}
+
+/*
+	Looks at the latest maxChecks results of every server since the given time
+	and counts how many of them the quorum decided Down in a row, starting from the newest one.
+	Results without a quorum decision are left out, a single location cannot raise an alert
+*/
+func (r *serverRepository) GetDownServers(since time.Time, maxChecks int) ([]dto.DownServer, error) {
+	query := map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					timeRangeQuery(since, time.Now()),
+					map[string]interface{}{
+						"exists": map[string]interface{}{
+							"field": "decided_status",
+						},
+					},
 				},
 			},
 		},
@@ -307,70 +1314,324 @@ func (r *serverRepository) GetServerUptimeRatio(startTime, endTime time.Time) (f
 					"size":  10000,
 				},
 				"aggs": map[string]interface{}{
-					"total_docs": map[string]interface{}{
-						"value_count": map[string]interface{}{
-							"field": "id",
+					"latest_checks": map[string]interface{}{
+						"top_hits": map[string]interface{}{
+							"size": maxChecks,
+							"sort": []interface{}{
+								map[string]interface{}{
+									"timestamp": map[string]interface{}{
+										"order": "desc",
+									},
+								},
+							},
+							"_source": []string{"decided_status", "timestamp"},
 						},
 					},
-					"on_count": map[string]interface{}{
-						"filter": map[string]interface{}{
-							"term": map[string]interface{}{
-								"status.keyword": "On",
-							},
+				},
+			},
+		},
+	}
+
+	result, err := r.searchServerStatus(query)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting down servers: %w", err)
+	}
+
+	buckets := result["aggregations"].(map[string]interface{})["per_server"].(map[string]interface{})["buckets"].([]interface{})
+
+	downServers := make([]dto.DownServer, 0)
+	for _, b := range buckets {
+		bucket := b.(map[string]interface{})
+		hits := bucket["latest_checks"].(map[string]interface{})["hits"].(map[string]interface{})["hits"].([]interface{})
+
+		downServer := dto.DownServer{
+			ID: int(bucket["key"].(float64)),
+		}
+
+		for i, h := range hits {
+			source := h.(map[string]interface{})["_source"].(map[string]interface{})
+			if i == 0 {
+				downServer.LastCheck, _ = time.Parse(time.RFC3339Nano, source["timestamp"].(string))
+			}
+			if !isDownStatus(source["decided_status"]) {
+				break
+			}
+			downServer.ConsecutiveDownChecks++
+		}
+
+		if downServer.ConsecutiveDownChecks > 0 {
+			downServers = append(downServers, downServer)
+		}
+	}
+
+	if len(downServers) == 0 {
+		return downServers, nil
+	}
+
+	// Fill in the server names from the database
+	ids := make([]int, len(downServers))
+	for i, downServer := range downServers {
+		ids[i] = downServer.ID
+	}
+
+	var servers []domain.Server
+	if err := r.db.Select("id", "server_name").Where("id IN ?", ids).Find(&servers).Error; err != nil {
+		return nil, err
+	}
+
+	names := make(map[int]string, len(servers))
+	for _, server := range servers {
+		names[server.ID] = server.ServerName
+	}
+
+	for i := range downServers {
+		downServers[i].ServerName = names[downServers[i].ID]
+	}
+
+	return downServers, nil
+}
+
+/*
+	Only successful checks count, a failed check's latency is mostly the probe timeout.
+	id <= 0 returns every server.
+*/
+func (r *serverRepository) GetLatencyPercentiles(id int, startTime, endTime time.Time) ([]dto.LatencyPercentiles, error) {
+	filters := []interface{}{
+		timeRangeQuery(startTime, endTime),
+		availableStatusQuery(),
+		map[string]interface{}{
+			"exists": map[string]interface{}{
+				"field": "latency_ms",
+			},
+		},
+	}
+	if id > 0 {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{
+				"id": id,
+			},
+		})
+	}
+
+	query := map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": filters,
+			},
+		},
+		"aggs": map[string]interface{}{
+			"per_server": map[string]interface{}{
+				"terms": map[string]interface{}{
+					"field": "id",
+					"size":  10000,
+				},
+				"aggs": map[string]interface{}{
+					"latency": map[string]interface{}{
+						"percentiles": map[string]interface{}{
+							"field":    "latency_ms",
+							"percents": []float64{50, 90, 95, 99},
 						},
 					},
-					"on_ratio": map[string]interface{}{
-						"bucket_script": map[string]interface{}{
-							"buckets_path": map[string]interface{}{
-								"on":  "on_count._count",
-								"all": "total_docs.value",
-							},
-							"script": "params.all > 0 ? params.on / params.all : 0",
+					"avg_latency": map[string]interface{}{
+						"avg": map[string]interface{}{
+							"field": "latency_ms",
+						},
+					},
+					"max_latency": map[string]interface{}{
+						"max": map[string]interface{}{
+							"field": "latency_ms",
 						},
 					},
 				},
 			},
-			"avg_ratio": map[string]interface{}{
-				"avg_bucket": map[string]interface{}{
-					"buckets_path": "per_server>on_ratio.value",
+		},
+	}
+
+	result, err := r.searchServerStatus(query)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting latency percentiles: %w", err)
+	}
+
+	buckets := result["aggregations"].(map[string]interface{})["per_server"].(map[string]interface{})["buckets"].([]interface{})
+
+	latencies := make([]dto.LatencyPercentiles, 0, len(buckets))
+	for _, b := range buckets {
+		bucket := b.(map[string]interface{})
+
+		latency := dto.LatencyPercentiles{
+			ID:        int(bucket["key"].(float64)),
+			NumChecks: int(bucket["doc_count"].(float64)),
+		}
+
+		values, _ := bucket["latency"].(map[string]interface{})["values"].(map[string]interface{})
+		latency.P50, _ = values["50.0"].(float64)
+		latency.P90, _ = values["90.0"].(float64)
+		latency.P95, _ = values["95.0"].(float64)
+		latency.P99, _ = values["99.0"].(float64)
+		latency.Avg, _ = bucket["avg_latency"].(map[string]interface{})["value"].(float64)
+		latency.Max, _ = bucket["max_latency"].(map[string]interface{})["value"].(float64)
+
+		latencies = append(latencies, latency)
+	}
+
+	return latencies, nil
+}
+
+func timeRangeQuery(startTime, endTime time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"range": map[string]interface{}{
+			"timestamp": map[string]interface{}{
+				"gte": startTime.Format(time.RFC3339),
+				"lte": endTime.Format(time.RFC3339),
+			},
+		},
+	}
+}
+
+/*
+	Documents written before the status enum use On / Off
+*/
+var (
+	availableStatuses = []string{string(domain.StatusUp), string(domain.StatusDegraded), "On"}
+	downStatuses      = []string{string(domain.StatusDown), "Off"}
+)
+
+func availableStatusQuery() map[string]interface{} {
+	return map[string]interface{}{
+		"terms": map[string]interface{}{
+			"status.keyword": availableStatuses,
+		},
+	}
+}
+
+func isDownStatus(status interface{}) bool {
+	for _, downStatus := range downStatuses {
+		if status == downStatus {
+			return true
+		}
+	}
+	return false
+}
+
+// scopedFilters restricts the filters to the given server ids, nil keeps every server
+func scopedFilters(ids []int, filters ...interface{}) []interface{} {
+	if ids == nil {
+		return filters
+	}
+
+	return append(filters, map[string]interface{}{
+		"terms": map[string]interface{}{
+			"id": ids,
+		},
+	})
+}
+
+/*
+	Results recorded during a maintenance window do not count towards uptime
+*/
+func uptimeQuery(filters ...interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter": filters,
+			"must_not": []interface{}{
+				map[string]interface{}{
+					"term": map[string]interface{}{
+						"status.keyword": string(domain.StatusMaintenance),
+					},
+				},
+			},
+		},
+	}
+}
+
+const maxUptimeRatioServers = 10000
+
+func perServerUptimeAggregation() map[string]interface{} {
+	return map[string]interface{}{
+		"terms": map[string]interface{}{
+			"field": "id",
+			"size":  maxUptimeRatioServers,
+		},
+		"aggs": map[string]interface{}{
+			"total_docs": map[string]interface{}{
+				"value_count": map[string]interface{}{
+					"field": "id",
+				},
+			},
+			"on_count": map[string]interface{}{
+				"filter": availableStatusQuery(),
+			},
+			"on_ratio": map[string]interface{}{
+				"bucket_script": map[string]interface{}{
+					"buckets_path": map[string]interface{}{
+						"on":  "on_count._count",
+						"all": "total_docs.value",
+					},
+					"script": "params.all > 0 ? params.on / params.all : 0",
 				},
 			},
 		},
 	}
+}
 
+func (r *serverRepository) searchServerStatus(query map[string]interface{}) (map[string]interface{}, error) {
+	return r.search("server_status", query)
+}
+
+func (r *serverRepository) search(index string, query map[string]interface{}) (map[string]interface{}, error) {
 	// Encode the query
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(query); err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	res, err := r.es.Search(
 		r.es.Search.WithContext(context.Background()),
-		r.es.Search.WithIndex("server_status"),
+		r.es.Search.WithIndex(index),
 		r.es.Search.WithBody(&buf),
 		r.es.Search.WithTrackTotalHits(true),
 		r.es.Search.WithPretty(),
 	)
 
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
-		return 0, fmt.Errorf("Error getting server uptime ratio: %s", res.String())
+		return nil, fmt.Errorf("%s", res.String())
 	}
 
 	var result map[string]interface{}
 	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	avgRatio := result["aggregations"].(map[string]interface{})["avg_ratio"].(map[string]interface{})["value"]
-	if avgRatio == nil {
-		return 0, nil
+	return result, nil
+}
+
+func (r *serverRepository) indexDocument(index string, doc map[string]interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(doc); err != nil {
+		return err
 	}
-	return avgRatio.(float64), nil
+
+	res, err := r.es.Index(
+		index,
+		&buf,
+		r.es.Index.WithContext(context.Background()),
+	)
+
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("Error indexing document: %s", res.String())
+	}
+	return nil
 }
 
 func (r *serverRepository) SyncServerStatus() error {
@@ -383,26 +1644,16 @@ func (r *serverRepository) SyncServerStatus() error {
 	ctx := context.Background()
 
 	// Delete old data
-	r.redis.Del(ctx, "server_status")
+	r.redis.Del(ctx, "server_status", statusHashKey)
 
-	// Set all bits first
 	for _, server := range servers {
-		status := 0
-		if server.Status == "On" {
-			status = 1
-		}
-
-		if status == 1 {
-			println("Server ID: " + strconv.Itoa(server.ID) + " is On")
-			_, err := r.redis.SetBit(ctx, "server_status", int64(server.ID), status).Result()
-			if err != nil {
-				println("SetBit failed for server ID: " + strconv.Itoa(server.ID) + " error: " + err.Error())
-			}
+		if err := r.cacheStatus(ctx, server.ID, server.Status); err != nil {
+			logging.LogMessage("server_administration_service", "Error caching status of server ID: "+strconv.Itoa(server.ID)+", error: "+err.Error(), "ERROR")
 		}
 	}
 
 	numOnServers, _ := r.redis.BitCount(ctx, "server_status", nil).Result()
-	println("Num On server: " + strconv.Itoa(int(numOnServers)))
+	logging.LogMessage("server_administration_service", "Synced server statuses, "+strconv.Itoa(int(numOnServers))+" servers available", "INFO")
 
 	return nil
 }
\ No newline at end of file
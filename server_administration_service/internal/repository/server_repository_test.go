@@ -1,15 +1,25 @@
 package repository_test
 
 import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"server_administration_service/internal/domain"
 	"server_administration_service/internal/dto"
 	"server_administration_service/internal/repository"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	es "github.com/elastic/go-elasticsearch/v8"
 	"github.com/go-redis/redismock/v9"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/postgres"
@@ -43,18 +53,49 @@ func setupMocks() (*gorm.DB, sqlmock.Sqlmock, *redis.Client, redismock.ClientMoc
 	return db, sqlMock, redisCli, redisMock, esClient, nil
 }
 
+// setupFakeES starts an HTTP server answering every Elasticsearch request with the given body
+func setupFakeES(t *testing.T, body string) *es.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	esClient, err := es.NewClient(es.Config{Addresses: []string{server.URL}})
+	if err != nil {
+		t.Fatalf("Failed to create Elasticsearch client: %v", err)
+	}
+	return esClient
+}
+
+// auditContext is the context of a request made by an admin
+func auditContext() context.Context {
+	ctx := domain.ContextWithActor(context.Background(), domain.Actor{ID: "user-1", Name: "alice", Role: "admin"})
+	return domain.ContextWithRequestID(ctx, "req-1")
+}
+
+// auditArgs are the values of an audit entry written for auditContext
+func auditArgs(action domain.AuditAction, source domain.AuditSource, serverID string, changes driver.Value) []driver.Value {
+	return []driver.Value{sqlmock.AnyArg(), "user-1", "alice", "admin", "req-1", action, source, serverID, changes}
+}
+
+func expectAuditInsert(mock sqlmock.Sqlmock) *sqlmock.ExpectedQuery {
+	return mock.ExpectQuery(`INSERT INTO "audit_entries"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
+
 func TestCreateServer(t *testing.T) {
 	db, mock, redisCli, redisMock, esClient, err := setupMocks()
 	if err != nil {
 		t.Fatalf("Failed to setup mocks: %v", err)
 	}
 
-	// Test creating a server with "On" status
-	t.Run("Create server with On status", func(t *testing.T) {
+	// Test creating a server with "Up" status
+	t.Run("Create server with Up status", func(t *testing.T) {
 		server := &domain.Server{
 			ServerID:   "srv-001",
 			ServerName: "Test Server",
-			Status:     "On",
+			Status:     domain.StatusUp,
 			IPv4:       "192.168.1.1",
 			Port:       8080,
 		}
@@ -63,13 +104,15 @@ func TestCreateServer(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectQuery(`INSERT INTO "servers"`).
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		expectAuditInsert(mock)
 		mock.ExpectCommit()
 
 		// Redis mock expectations
 		redisMock.ExpectSetBit("server_status", 1, 1).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "1", "Up").SetVal(1)
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		id, err := repo.CreateServer(server)
+		id, err := repo.CreateServer(auditContext(), server)
 
 		assert.NoError(t, err)
 		assert.Equal(t, 1, id)
@@ -77,12 +120,12 @@ func TestCreateServer(t *testing.T) {
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
 
-	// Test creating a server with "Off" status
-	t.Run("Create server with Off status", func(t *testing.T) {
+	// Test creating a server with "Unknown" status
+	t.Run("Create server with Unknown status", func(t *testing.T) {
 		server := &domain.Server{
 			ServerID:   "srv-002",
 			ServerName: "Test Server 2",
-			Status:     "Off",
+			Status:     domain.StatusUnknown,
 			IPv4:       "192.168.1.2",
 			Port:       8081,
 		}
@@ -91,13 +134,15 @@ func TestCreateServer(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectQuery(`INSERT INTO "servers"`).
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		expectAuditInsert(mock)
 		mock.ExpectCommit()
 
 		// Redis mock expectations
 		redisMock.ExpectSetBit("server_status", 2, 0).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "2", "Unknown").SetVal(1)
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		id, err := repo.CreateServer(server)
+		id, err := repo.CreateServer(auditContext(), server)
 
 		assert.NoError(t, err)
 		assert.Equal(t, 2, id)
@@ -110,7 +155,7 @@ func TestCreateServer(t *testing.T) {
 		server := &domain.Server{
 			ServerID:   "srv-003",
 			ServerName: "Test Server 3",
-			Status:     "On",
+			Status:     domain.StatusUp,
 			IPv4:       "192.168.1.3",
 			Port:       8082,
 		}
@@ -122,12 +167,35 @@ func TestCreateServer(t *testing.T) {
 		mock.ExpectRollback()
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		id, err := repo.CreateServer(server)
+		id, err := repo.CreateServer(auditContext(), server)
 
 		assert.Error(t, err)
 		assert.Equal(t, 0, id)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	// The server_id or the server_name is held by another server, maybe a deleted one
+	t.Run("Unique violation", func(t *testing.T) {
+		server := &domain.Server{
+			ServerID:   "srv-001",
+			ServerName: "Test Server",
+			Status:     domain.StatusUp,
+			IPv4:       "192.168.1.1",
+			Port:       8080,
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "servers"`).
+			WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "servers_server_name_key"})
+		mock.ExpectRollback()
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		id, err := repo.CreateServer(auditContext(), server)
+
+		assert.ErrorIs(t, err, repository.ErrServerConflict)
+		assert.Equal(t, 0, id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestViewServers(t *testing.T) {
@@ -135,12 +203,13 @@ func TestViewServers(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to setup mocks: %v", err)
 	}
+	byID := []dto.SortField{{Column: "id", Order: "asc"}}
 
 	t.Run("View servers with filters", func(t *testing.T) {
 		filter := &dto.ServerFilter{
 			ServerID:   "srv-001",
 			ServerName: "Test",
-			Status:     "On",
+			Statuses:   []string{"Up"},
 			IPv4:       "192.168.1.1",
 			Port:       8080,
 		}
@@ -152,7 +221,7 @@ func TestViewServers(t *testing.T) {
 			WillReturnRows(rows)
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		servers, err := repo.ViewServers(filter, 1, 10, "id", "asc")
+		servers, err := repo.ViewServers(filter, byID, nil, 10)
 
 		assert.NoError(t, err)
 		assert.Equal(t, 1, len(servers))
@@ -171,7 +240,120 @@ func TestViewServers(t *testing.T) {
 			WillReturnRows(rows)
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		servers, err := repo.ViewServers(filter, 1, 10, "id", "asc")
+		servers, err := repo.ViewServers(filter, byID, nil, 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(servers))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Filter by labels", func(t *testing.T) {
+		filter := &dto.ServerFilter{
+			Port:   -1,
+			Labels: map[string]string{"env": "prod", "team": "payments"},
+		}
+
+		rows := sqlmock.NewRows([]string{"id", "server_id", "server_name", "status", "ipv4", "port", "labels"}).
+			AddRow(3, "srv-003", "Payments 1", "Up", "10.0.0.3", 443, `{"env": "prod", "team": "payments", "dc": "hn1"}`)
+
+		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE labels @> \$1 AND "servers"."deleted_at" IS NULL ORDER BY "id" LIMIT \$2`).
+			WithArgs(`{"env":"prod","team":"payments"}`, 10).
+			WillReturnRows(rows)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		servers, err := repo.ViewServers(filter, byID, nil, 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(servers))
+		assert.Equal(t, map[string]string{"env": "prod", "team": "payments", "dc": "hn1"}, servers[0].Labels)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("After cursor sorted by id", func(t *testing.T) {
+		filter := &dto.ServerFilter{Port: -1}
+
+		rows := sqlmock.NewRows([]string{"id", "server_id"}).
+			AddRow(6, "srv-006")
+
+		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE "id" < \$1 AND "servers"."deleted_at" IS NULL ORDER BY "id" DESC LIMIT \$2`).
+			WithArgs(7, 5).
+			WillReturnRows(rows)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		servers, err := repo.ViewServers(filter, []dto.SortField{{Column: "id", Order: "desc"}}, &dto.ServerCursor{Values: []interface{}{7}}, 5)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(servers))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("After cursor sorted by another column", func(t *testing.T) {
+		filter := &dto.ServerFilter{Port: -1, Statuses: []string{"Up"}}
+
+		rows := sqlmock.NewRows([]string{"id", "server_id", "server_name"}).
+			AddRow(2, "srv-002", "beta")
+
+		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE status IN \(\$1\) AND \("server_name" > \$2 OR \("server_name" = \$3 AND "id" > \$4\)\) AND "servers"."deleted_at" IS NULL ORDER BY "server_name","id" LIMIT \$5`).
+			WithArgs("Up", "alpha", "alpha", 9, 5).
+			WillReturnRows(rows)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		sortFields := []dto.SortField{{Column: "server_name", Order: "asc"}, {Column: "id", Order: "asc"}}
+		servers, err := repo.ViewServers(filter, sortFields, &dto.ServerCursor{Values: []interface{}{"alpha", 9}}, 5)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "beta", servers[0].ServerName)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("After cursor with mixed directions", func(t *testing.T) {
+		filter := &dto.ServerFilter{Port: -1}
+		lastUpdated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE \("status" > \$1 OR \("status" = \$2 AND "last_updated" < \$3\) OR \("status" = \$4 AND "last_updated" = \$5 AND "id" < \$6\)\) AND "servers"."deleted_at" IS NULL ORDER BY "status","last_updated" DESC,"id" DESC LIMIT \$7`).
+			WithArgs("Down", "Down", lastUpdated, "Down", lastUpdated, 4, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		sortFields := []dto.SortField{{Column: "status", Order: "asc"}, {Column: "last_updated", Order: "desc"}, {Column: "id", Order: "desc"}}
+		servers, err := repo.ViewServers(filter, sortFields, &dto.ServerCursor{Values: []interface{}{"Down", lastUpdated, 4}}, 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(servers))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Range filters", func(t *testing.T) {
+		from, to := 8000, 9000
+		since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		filter := &dto.ServerFilter{
+			Port:        -1,
+			Statuses:    []string{"Down", "Unknown"},
+			IPv4CIDR:    "10.2.0.0/16",
+			PortRange:   dto.IntRange{From: &from, To: &to},
+			LastUpdated: dto.TimeRange{From: &since},
+		}
+
+		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE status IN \(\$1,\$2\) AND CASE WHEN ipv4 ~ \$3 THEN ipv4::inet <<= \$4::cidr ELSE false END AND port >= \$5 AND port <= \$6 AND last_updated >= \$7 AND "servers"."deleted_at" IS NULL ORDER BY "id" LIMIT \$8`).
+			WithArgs("Down", "Unknown", sqlmock.AnyArg(), "10.2.0.0/16", 8000, 9000, since, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "ipv4"}).AddRow(5, "10.2.3.4"))
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		servers, err := repo.ViewServers(filter, byID, nil, 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "10.2.3.4", servers[0].IPv4)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No limit", func(t *testing.T) {
+		filter := &dto.ServerFilter{Port: -1}
+
+		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE "servers"."deleted_at" IS NULL ORDER BY "id"$`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		servers, err := repo.ViewServers(filter, byID, nil, 0)
 
 		assert.NoError(t, err)
 		assert.Equal(t, 2, len(servers))
@@ -185,7 +367,7 @@ func TestViewServers(t *testing.T) {
 			WillReturnError(errors.New("database error"))
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		servers, err := repo.ViewServers(filter, 1, 10, "id", "asc")
+		servers, err := repo.ViewServers(filter, byID, nil, 10)
 
 		assert.Error(t, err)
 		assert.Nil(t, servers)
@@ -193,35 +375,168 @@ func TestViewServers(t *testing.T) {
 	})
 }
 
+func TestStreamServers(t *testing.T) {
+	db, mock, redisCli, _, esClient, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	byName := []dto.SortField{{Column: "server_name", Order: "desc"}, {Column: "id", Order: "asc"}}
+	repo := repository.NewServerRepository(db, redisCli, esClient)
+
+	t.Run("Stream every matching server", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "server_id", "server_name", "status", "ipv4", "port", "labels"}).
+			AddRow(2, "srv-002", "Server 2", "Up", "192.168.1.2", 8081, `{"env": "prod"}`).
+			AddRow(1, "srv-001", "Server 1", "Down", "192.168.1.1", 8080, `{}`)
+
+		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE status IN \(\$1\) AND "servers"."deleted_at" IS NULL ORDER BY "server_name" DESC,"id"$`).
+			WithArgs("Up").
+			WillReturnRows(rows)
+
+		var servers []domain.Server
+		err := repo.StreamServers(&dto.ServerFilter{Port: -1, Statuses: []string{"Up"}}, byName, func(server domain.Server) error {
+			servers = append(servers, server)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(servers))
+		assert.Equal(t, "srv-002", servers[0].ServerID)
+		assert.Equal(t, map[string]string{"env": "prod"}, servers[0].Labels)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Stop at the first error of the callback", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "server_id"}).
+			AddRow(1, "srv-001").
+			AddRow(2, "srv-002")
+
+		mock.ExpectQuery(`SELECT \* FROM "servers"`).WillReturnRows(rows)
+
+		calls := 0
+		err := repo.StreamServers(&dto.ServerFilter{Port: -1}, byName, func(server domain.Server) error {
+			calls++
+			return assert.AnError
+		})
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Equal(t, 1, calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Query error", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT \* FROM "servers"`).WillReturnError(assert.AnError)
+
+		err := repo.StreamServers(&dto.ServerFilter{Port: -1}, byName, func(server domain.Server) error {
+			t.Error("Expected no server")
+			return nil
+		})
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCountServers(t *testing.T) {
+	db, mock, redisCli, _, esClient, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	t.Run("Count with filters", func(t *testing.T) {
+		filter := &dto.ServerFilter{Port: -1, Statuses: []string{"Down"}}
+
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "servers" WHERE status IN \(\$1\)`).
+			WithArgs("Down").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		count, err := repo.CountServers(filter)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		filter := &dto.ServerFilter{Port: -1}
+
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "servers"`).
+			WillReturnError(errors.New("database error"))
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		_, err := repo.CountServers(filter)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestCreateServers(t *testing.T) {
 	db, mock, redisCli, redisMock, esClient, err := setupMocks()
 	if err != nil {
 		t.Fatalf("Failed to setup mocks: %v", err)
 	}
 
+	insertQuery := `INSERT INTO servers \(server_id, server_name, status, ipv4, port, labels, probe_type, probe_path, probe_expected_status, probe_expected_body, check_interval_seconds, timeout_ms, retries\) VALUES`
+
 	// Test successful insertion of multiple servers
 	t.Run("Successfully insert multiple servers", func(t *testing.T) {
 		servers := []domain.Server{
-			{ServerID: "srv-001", ServerName: "Server 1", Status: "On", IPv4: "192.168.1.1", Port: 8080},
-			{ServerID: "srv-002", ServerName: "Server 2", Status: "Off", IPv4: "192.168.1.2", Port: 8081},
+			{ServerID: "srv-001", ServerName: "Server 1", Status: domain.StatusUp, IPv4: "192.168.1.1", Port: 8080, Labels: map[string]string{"env": "prod"}},
+			{ServerID: "srv-002", ServerName: "Server 2", Status: domain.StatusDown, IPv4: "192.168.1.2", Port: 8081},
 		}
 
 		rows := sqlmock.NewRows([]string{"id", "server_id", "server_name", "status", "ipv4", "port"}).
-			AddRow(1, "srv-001", "Server 1", "On", "192.168.1.1", 8080).
-			AddRow(2, "srv-002", "Server 2", "Off", "192.168.1.2", 8081)
+			AddRow(1, "srv-001", "Server 1", "Up", "192.168.1.1", 8080).
+			AddRow(2, "srv-002", "Server 2", "Down", "192.168.1.2", 8081)
 
-		mock.ExpectQuery(`INSERT INTO servers \(server_id, server_name, status, ipv4, port\) VALUES`).
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT server_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(insertQuery+` \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10, \$11, \$12, \$13\), \(\$14, .+, \$26\) ON CONFLICT DO NOTHING RETURNING \*`).
+			WithArgs(
+				"srv-001", "Server 1", domain.StatusUp, "192.168.1.1", 8080, `{"env":"prod"}`, "", "", 0, "", 0, 0, 0,
+				"srv-002", "Server 2", domain.StatusDown, "192.168.1.2", 8081, `{}`, "", "", 0, "", 0, 0, 0,
+			).
 			WillReturnRows(rows)
+		expectAuditInsert(mock)
+		mock.ExpectCommit()
 
 		redisMock.ExpectSetBit("server_status", 1, 1).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "1", "Up").SetVal(1)
 		redisMock.ExpectSetBit("server_status", 2, 0).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "2", "Down").SetVal(1)
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		inserted, nonInserted, err := repo.CreateServers(servers)
+		inserted, rejected, err := repo.CreateServers(auditContext(), servers)
 
 		assert.NoError(t, err)
 		assert.Equal(t, 2, len(inserted))
-		assert.Equal(t, 0, len(nonInserted))
+		assert.Equal(t, 0, len(rejected))
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("Quotes are sent as parameters", func(t *testing.T) {
+		servers := []domain.Server{
+			{ServerID: "srv-009", ServerName: "O'Brien'); DROP TABLE servers; --", Status: domain.StatusUp, IPv4: "192.168.1.9", Port: 80},
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT server_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(insertQuery+` \(\$1, .+, \$13\) ON CONFLICT DO NOTHING RETURNING \*$`).
+			WithArgs("srv-009", "O'Brien'); DROP TABLE servers; --", domain.StatusUp, "192.168.1.9", 80, `{}`, "", "", 0, "", 0, 0, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "server_id", "server_name", "status"}).AddRow(9, "srv-009", "O'Brien'); DROP TABLE servers; --", "Up"))
+		expectAuditInsert(mock)
+		mock.ExpectCommit()
+
+		redisMock.ExpectSetBit("server_status", 9, 1).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "9", "Up").SetVal(1)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		inserted, _, err := repo.CreateServers(auditContext(), servers)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(inserted))
 		assert.NoError(t, mock.ExpectationsWereMet())
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
@@ -229,114 +544,320 @@ func TestCreateServers(t *testing.T) {
 	// Test partial insertion (some servers succeed, some fail)
 	t.Run("Partial insertion of servers", func(t *testing.T) {
 		servers := []domain.Server{
-			{ServerID: "srv-003", ServerName: "Server 3", Status: "On", IPv4: "192.168.1.3", Port: 8083},
-			{ServerID: "srv-004", ServerName: "Server 4", Status: "Off", IPv4: "192.168.1.4", Port: 8084},
+			{ServerID: "srv-003", ServerName: "Server 3", Status: domain.StatusUp, IPv4: "192.168.1.3", Port: 8083},
+			{ServerID: "srv-004", ServerName: "Server 4", Status: domain.StatusDown, IPv4: "192.168.1.4", Port: 8084},
+			{ServerID: "srv-003", ServerName: "Server 3", Status: domain.StatusUp, IPv4: "192.168.1.3", Port: 8083},
 		}
 
 		rows := sqlmock.NewRows([]string{"id", "server_id", "server_name", "status", "ipv4", "port"}).
-			AddRow(3, "srv-003", "Server 3", "On", "192.168.1.3", 8083)
+			AddRow(3, "srv-003", "Server 3", "Up", "192.168.1.3", 8083)
 
-		mock.ExpectQuery(`INSERT INTO servers \(server_id, server_name, status, ipv4, port\) VALUES`).
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT server_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(insertQuery).
 			WillReturnRows(rows)
+		expectAuditInsert(mock)
+		mock.ExpectCommit()
 
 		redisMock.ExpectSetBit("server_status", 3, 1).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "3", "Up").SetVal(1)
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		inserted, nonInserted, err := repo.CreateServers(servers)
+		inserted, rejected, err := repo.CreateServers(auditContext(), servers)
 
 		assert.NoError(t, err)
 		assert.Equal(t, 1, len(inserted))
-		assert.Equal(t, 1, len(nonInserted))
 		assert.Equal(t, "srv-003", inserted[0].ServerID)
-		assert.Equal(t, "srv-004", nonInserted[0].ServerID)
+		assert.Equal(t, 2, len(rejected))
+		assert.Equal(t, "srv-004", rejected[0].Server.ServerID)
+		assert.Equal(t, "a server with this server_id or server_name already exists, possibly in the trash", rejected[0].Reason)
+		assert.Equal(t, "srv-003", rejected[1].Server.ServerID)
+		assert.Equal(t, "duplicates the server_id or server_name of another server of the same import", rejected[1].Reason)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("Failing batch is retried server by server", func(t *testing.T) {
+		servers := []domain.Server{
+			{ServerID: "srv-005", ServerName: "Server 5", Status: domain.StatusUp, IPv4: "192.168.1.5", Port: 8085},
+			{ServerID: "srv-006", ServerName: "Server 6", Status: domain.StatusUp, IPv4: "192.168.1.6", Port: 99999},
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT server_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(insertQuery).
+			WillReturnError(errors.New("port out of range"))
+		mock.ExpectExec(`ROLLBACK TO SAVEPOINT server_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectExec(`SAVEPOINT server_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(insertQuery).
+			WithArgs("srv-005", "Server 5", domain.StatusUp, "192.168.1.5", 8085, `{}`, "", "", 0, "", 0, 0, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "server_id", "server_name", "status"}).AddRow(5, "srv-005", "Server 5", "Up"))
+
+		mock.ExpectExec(`SAVEPOINT server_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(insertQuery).
+			WithArgs("srv-006", "Server 6", domain.StatusUp, "192.168.1.6", 99999, `{}`, "", "", 0, "", 0, 0, 0).
+			WillReturnError(errors.New("port out of range"))
+		mock.ExpectExec(`ROLLBACK TO SAVEPOINT server_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+		expectAuditInsert(mock)
+		mock.ExpectCommit()
+
+		// Only the committed server reaches Redis
+		redisMock.ExpectSetBit("server_status", 5, 1).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "5", "Up").SetVal(1)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		inserted, rejected, err := repo.CreateServers(auditContext(), servers)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(inserted))
+		assert.Equal(t, "srv-005", inserted[0].ServerID)
+		assert.Equal(t, []dto.RejectedServer{{Server: servers[1], Reason: "port out of range"}}, rejected)
 		assert.NoError(t, mock.ExpectationsWereMet())
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
 
+	t.Run("Large imports are split in batches", func(t *testing.T) {
+		servers := make([]domain.Server, 501)
+		for i := range servers {
+			servers[i] = domain.Server{ServerID: fmt.Sprintf("srv-%04d", i), ServerName: fmt.Sprintf("Server %d", i), Status: domain.StatusUnknown}
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT server_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(insertQuery + ` .+\$6500\) ON CONFLICT`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec(`SAVEPOINT server_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(insertQuery + ` \(\$1, .+, \$13\) ON CONFLICT`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		_, rejected, err := repo.CreateServers(auditContext(), servers)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 501, len(rejected))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	// Test database error
 	t.Run("Database error during insertion", func(t *testing.T) {
 		servers := []domain.Server{
-			{ServerID: "srv-005", ServerName: "Server 5", Status: "On", IPv4: "192.168.1.5", Port: 8085},
+			{ServerID: "srv-007", ServerName: "Server 7", Status: domain.StatusUp, IPv4: "192.168.1.7", Port: 8087},
 		}
 
-		mock.ExpectQuery(`INSERT INTO servers \(server_id, server_name, status, ipv4, port\) VALUES`).
-			WillReturnError(errors.New("database error"))
+		mock.ExpectBegin().WillReturnError(errors.New("database error"))
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		inserted, nonInserted, err := repo.CreateServers(servers)
+		inserted, rejected, err := repo.CreateServers(auditContext(), servers)
 
 		assert.Error(t, err)
 		assert.Nil(t, inserted)
-		assert.Nil(t, nonInserted)
+		assert.Nil(t, rejected)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestApplyServerImport(t *testing.T) {
+	db, mock, redisCli, redisMock, esClient, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	t.Run("Deletes, updates and inserts in one transaction", func(t *testing.T) {
+		plan := dto.ServerImportPlan{
+			Delete: []domain.Server{{ID: 9, ServerID: "srv-009"}, {ID: 10, ServerID: "srv-010"}},
+			Update: []domain.Server{
+				{ID: 1, ServerID: "srv-001", ServerName: "Server 1", Status: domain.StatusDown, IPv4: "192.168.1.1", Port: 9090, CheckIntervalSeconds: 60, TimeoutMs: 5000},
+				{ID: 2, ServerID: "srv-002", ServerName: "Server 9", Status: domain.StatusUp, IPv4: "192.168.1.2", Port: 80, CheckIntervalSeconds: 60, TimeoutMs: 5000},
+			},
+			Create: []domain.Server{
+				{ServerID: "srv-003", ServerName: "Server 3", Status: domain.StatusUp, IPv4: "192.168.1.3", Port: 80},
+			},
+		}
+
+		serverColumns := []string{"id", "server_id", "server_name", "status", "ipv4", "port", "check_interval_seconds", "timeout_ms"}
+
+		mock.ExpectBegin()
+		// The deleted servers go to the trash
+		mock.ExpectQuery(`UPDATE "servers" SET "deleted_at"=\$1 WHERE id IN \(\$2,\$3\) AND "servers"."deleted_at" IS NULL RETURNING \*`).
+			WithArgs(sqlmock.AnyArg(), 9, 10).
+			WillReturnRows(sqlmock.NewRows(serverColumns).
+				AddRow(9, "srv-009", "Server 9", "Up", "192.168.1.9", 80, 60, 5000).
+				AddRow(10, "srv-010", "Server 10", "Down", "192.168.1.10", 80, 60, 5000))
+
+		// The servers to update are read as they are before the update
+		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE id IN \(\$1,\$2\) AND "servers"."deleted_at" IS NULL FOR UPDATE`).
+			WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows(serverColumns).
+				AddRow(1, "srv-001", "Server 1", "Down", "192.168.1.1", 8080, 60, 5000).
+				AddRow(2, "srv-002", "Server 2", "Up", "192.168.1.2", 80, 60, 5000))
+
+		mock.ExpectExec(`SAVEPOINT server_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`UPDATE "servers" SET "check_interval_seconds"=\$1,"ipv4"=\$2,"labels"=\$3,"port"=\$4,"retries"=\$5,"server_name"=\$6,"timeout_ms"=\$7,"last_updated"=\$8 WHERE id = \$9 AND "servers"."deleted_at" IS NULL RETURNING \*`).
+			WithArgs(60, "192.168.1.1", `{}`, 9090, 0, "Server 1", 5000, sqlmock.AnyArg(), 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(1, "srv-001", "Server 1", "Down", "192.168.1.1", 9090, 60, 5000))
+
+		// The new name of srv-002 is still taken
+		mock.ExpectExec(`SAVEPOINT server_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`UPDATE "servers" SET`).
+			WillReturnError(errors.New(`duplicate key value violates unique constraint "servers_server_name_key"`))
+		mock.ExpectExec(`ROLLBACK TO SAVEPOINT server_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectExec(`SAVEPOINT server_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`INSERT INTO servers`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "server_id", "server_name", "status"}).AddRow(3, "srv-003", "Server 3", "Up"))
+
+		// The rejected update of srv-002 is not recorded
+		mock.ExpectQuery(`INSERT INTO "audit_entries" \("time","actor_id","actor_name","actor_role","request_id","action","source","server_id","changes"\)`).
+			WithArgs(append(append(append(
+				auditArgs(domain.AuditDelete, domain.AuditSourceImport, "srv-009", sqlmock.AnyArg()),
+				auditArgs(domain.AuditDelete, domain.AuditSourceImport, "srv-010", sqlmock.AnyArg())...),
+				auditArgs(domain.AuditUpdate, domain.AuditSourceImport, "srv-001", `{"port":{"before":8080,"after":9090}}`)...),
+				auditArgs(domain.AuditCreate, domain.AuditSourceImport, "srv-003", sqlmock.AnyArg())...)...).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3).AddRow(4))
+		mock.ExpectCommit()
+
+		// The updates keep their status, only the created and deleted servers touch Redis
+		redisMock.ExpectSetBit("server_status", 3, 1).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "3", "Up").SetVal(1)
+		redisMock.ExpectSetBit("server_status", 9, 0).SetVal(1)
+		redisMock.ExpectHDel("server_statuses", "9").SetVal(1)
+		redisMock.ExpectSetBit("server_status", 10, 0).SetVal(0)
+		redisMock.ExpectHDel("server_statuses", "10").SetVal(1)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		result, err := repo.ApplyServerImport(auditContext(), plan)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.Server{plan.Update[0]}, result.Updated)
+		assert.Equal(t, plan.Delete, result.Deleted)
+		assert.Equal(t, 1, len(result.Created))
+		assert.Equal(t, []dto.RejectedServer{{Server: plan.Update[1], Reason: `duplicate key value violates unique constraint "servers_server_name_key"`}}, result.Rejected)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("Failing delete rolls back the import", func(t *testing.T) {
+		plan := dto.ServerImportPlan{
+			Delete: []domain.Server{{ID: 9, ServerID: "srv-009"}},
+			Create: []domain.Server{{ServerID: "srv-003", ServerName: "Server 3"}},
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE "servers" SET "deleted_at"`).
+			WillReturnError(errors.New("database error"))
+		mock.ExpectRollback()
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		result, err := repo.ApplyServerImport(auditContext(), plan)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
 		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
 }
 
+func TestFindServers(t *testing.T) {
+	db, mock, redisCli, _, esClient, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery(`SELECT \* FROM "servers" WHERE server_id IN \(\$1,\$2\)`).
+		WithArgs("srv-001", "srv-002").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "server_id", "server_name"}).AddRow(1, "srv-001", "Server 1"))
+	mock.ExpectQuery(`SELECT \* FROM "servers" WHERE server_name IN \(\$1,\$2\)`).
+		WithArgs("Server 1", "Server 2").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "server_id", "server_name"}).
+			AddRow(1, "srv-001", "Server 1").
+			AddRow(7, "srv-007", "Server 2"))
+
+	repo := repository.NewServerRepository(db, redisCli, esClient)
+	servers, err := repo.FindServers([]string{"srv-001", "srv-002"}, []string{"Server 1", "Server 2"})
+
+	assert.NoError(t, err)
+	// srv-001 matches twice but is returned once
+	assert.ElementsMatch(t, []int{1, 7}, []int{servers[0].ID, servers[1].ID})
+	assert.Equal(t, 2, len(servers))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestUpdateServer(t *testing.T) {
 	db, mock, redisCli, redisMock, esClient, err := setupMocks()
 	if err != nil {
 		t.Fatalf("Failed to setup mocks: %v", err)
 	}
 
-	// Test updating a server with "On" status
-	t.Run("Update server with On status", func(t *testing.T) {
+	serverColumns := []string{"id", "server_id", "server_name", "status", "ipv4", "port"}
+	lockQuery := `SELECT \* FROM "servers" WHERE server_id = \$1 AND "servers"."deleted_at" IS NULL ORDER BY "servers"."server_id" LIMIT \$2 FOR UPDATE`
+	reloadQuery := `SELECT \* FROM "servers" WHERE id = \$1 AND "servers"."deleted_at" IS NULL ORDER BY "servers"."server_id" LIMIT \$2`
+
+	// Test updating a server with "Up" status
+	t.Run("Update server with Up status", func(t *testing.T) {
 		serverID := "srv-001"
 		updatedData := map[string]interface{}{
 			"server_name": "Updated Server",
-			"status":      "On",
+			"status":      domain.StatusUp,
 			"ipv4":        "192.168.1.100",
 		}
 
-		// SQL mock expectations for update
+		// The server is read and locked before the update
 		mock.ExpectBegin()
-		mock.ExpectExec(`UPDATE "servers" SET`).
+		mock.ExpectQuery(lockQuery).
+			WithArgs(serverID, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(1, serverID, "Server", "Down", "192.168.1.1", 8080))
+		mock.ExpectExec(`UPDATE "servers" SET .+ WHERE id = \$\d+`).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(reloadQuery).
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(1, serverID, "Updated Server", "Up", "192.168.1.100", 8080))
+		mock.ExpectQuery(`INSERT INTO "audit_entries"`).
+			WithArgs(auditArgs(domain.AuditUpdate, domain.AuditSourceAPI, serverID,
+				`{"ipv4":{"before":"192.168.1.1","after":"192.168.1.100"},"server_name":{"before":"Server","after":"Updated Server"},"status":{"before":"Down","after":"Up"}}`)...).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 		mock.ExpectCommit()
 
-		// SQL mock expectations for getting server ID
-		rows := sqlmock.NewRows([]string{"id", "server_id", "server_name", "status", "ipv4", "port"}).
-			AddRow(1, serverID, "Updated Server", "On", "192.168.1.100", 8080)
-		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE server_id = \$1 ORDER BY "servers"."server_id" LIMIT \$2`).
-			WithArgs(serverID, 1).
-			WillReturnRows(rows)
-
 		// Redis mock expectations
 		redisMock.ExpectSetBit("server_status", 1, 1).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "1", "Up").SetVal(1)
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		err := repo.UpdateServer(serverID, updatedData)
+		err := repo.UpdateServer(auditContext(), serverID, updatedData)
 
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
 
-	// Test updating a server with "Off" status
-	t.Run("Update server with Off status", func(t *testing.T) {
+	// Test updating a server with "Decommissioned" status
+	t.Run("Update server with Decommissioned status", func(t *testing.T) {
 		serverID := "srv-002"
 		updatedData := map[string]interface{}{
 			"server_name": "Updated Server 2",
-			"status":      "Off",
+			"status":      domain.StatusDecommissioned,
 		}
 
-		// SQL mock expectations for update
 		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).
+			WithArgs(serverID, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(2, serverID, "Server 2", "Up", "192.168.1.2", 8081))
 		mock.ExpectExec(`UPDATE "servers" SET`).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(reloadQuery).
+			WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(2, serverID, "Updated Server 2", "Decommissioned", "192.168.1.2", 8081))
+		expectAuditInsert(mock)
 		mock.ExpectCommit()
 
-		// SQL mock expectations for getting server ID
-		rows := sqlmock.NewRows([]string{"id", "server_id", "server_name", "status", "ipv4", "port"}).
-			AddRow(2, serverID, "Updated Server 2", "Off", "192.168.1.2", 8081)
-		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE server_id = \$1 ORDER BY "servers"."server_id" LIMIT \$2`).
-			WithArgs(serverID, 1).
-			WillReturnRows(rows)
-
 		// Redis mock expectations
 		redisMock.ExpectSetBit("server_status", 2, 0).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "2", "Decommissioned").SetVal(1)
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		err := repo.UpdateServer(serverID, updatedData)
+		err := repo.UpdateServer(auditContext(), serverID, updatedData)
 
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -347,46 +868,43 @@ func TestUpdateServer(t *testing.T) {
 	t.Run("Database update error", func(t *testing.T) {
 		serverID := "srv-003"
 		updatedData := map[string]interface{}{
-			"status": "On",
+			"status": domain.StatusUp,
 		}
 
 		// SQL mock expectations - simulate error
 		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).
+			WithArgs(serverID, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(3, serverID, "Server 3", "Down", "192.168.1.3", 8082))
 		mock.ExpectExec(`UPDATE "servers" SET`).
 			WillReturnError(errors.New("database update error"))
 		mock.ExpectRollback()
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		err := repo.UpdateServer(serverID, updatedData)
+		err := repo.UpdateServer(auditContext(), serverID, updatedData)
 
 		assert.Error(t, err)
 		assert.Equal(t, "database update error", err.Error())
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	// Test with error getting server ID
-	t.Run("Error getting server ID", func(t *testing.T) {
+	// Test with a server that doesn't exist
+	t.Run("Server not found", func(t *testing.T) {
 		serverID := "srv-004"
 		updatedData := map[string]interface{}{
-			"status": "On",
+			"status": domain.StatusUp,
 		}
 
-		// SQL mock expectations for update
 		mock.ExpectBegin()
-		mock.ExpectExec(`UPDATE "servers" SET`).
-			WillReturnResult(sqlmock.NewResult(0, 1))
-		mock.ExpectCommit()
-
-		// SQL mock expectations for getting server ID - simulate error
-		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE server_id = \$1 ORDER BY "servers"."server_id" LIMIT \$2`).
+		mock.ExpectQuery(lockQuery).
 			WithArgs(serverID, 1).
-			WillReturnError(errors.New("server not found"))
+			WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectRollback()
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		err := repo.UpdateServer(serverID, updatedData)
+		err := repo.UpdateServer(auditContext(), serverID, updatedData)
 
-		assert.Error(t, err)
-		assert.Equal(t, "server not found", err.Error())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
@@ -394,27 +912,26 @@ func TestUpdateServer(t *testing.T) {
 	t.Run("Redis error", func(t *testing.T) {
 		serverID := "srv-005"
 		updatedData := map[string]interface{}{
-			"status": "On",
+			"status": domain.StatusUp,
 		}
 
-		// SQL mock expectations for update
 		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).
+			WithArgs(serverID, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(5, serverID, "Some Server", "Down", "192.168.1.5", 8080))
 		mock.ExpectExec(`UPDATE "servers" SET`).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(reloadQuery).
+			WithArgs(5, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(5, serverID, "Some Server", "Up", "192.168.1.5", 8080))
+		expectAuditInsert(mock)
 		mock.ExpectCommit()
 
-		// SQL mock expectations for getting server ID
-		rows := sqlmock.NewRows([]string{"id", "server_id", "server_name", "status", "ipv4", "port"}).
-			AddRow(5, serverID, "Some Server", "On", "192.168.1.5", 8080)
-		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE server_id = \$1 ORDER BY "servers"."server_id" LIMIT \$2`).
-			WithArgs(serverID, 1).
-			WillReturnRows(rows)
-
 		// Redis mock expectations - simulate error
 		redisMock.ExpectSetBit("server_status", 5, 1).SetErr(errors.New("redis connection error"))
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		err := repo.UpdateServer(serverID, updatedData)
+		err := repo.UpdateServer(auditContext(), serverID, updatedData)
 
 		assert.Error(t, err)
 		assert.Equal(t, "redis connection error", err.Error())
@@ -430,28 +947,49 @@ func TestUpdateServer(t *testing.T) {
 			"ipv4":        "192.168.1.200",
 		}
 
-		// SQL mock expectations for update
 		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).
+			WithArgs(serverID, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(6, serverID, "Name", "Up", "192.168.1.6", 8080))
 		mock.ExpectExec(`UPDATE "servers" SET`).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(reloadQuery).
+			WithArgs(6, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(6, serverID, "Just Name Updated", "Up", "192.168.1.200", 8080))
+		expectAuditInsert(mock)
 		mock.ExpectCommit()
 
-		// SQL mock expectations for getting server ID
-		rows := sqlmock.NewRows([]string{"id", "server_id", "server_name", "status", "ipv4", "port"}).
-			AddRow(6, serverID, "Just Name Updated", "Off", "192.168.1.200", 8080)
-		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE server_id = \$1 ORDER BY "servers"."server_id" LIMIT \$2`).
-			WithArgs(serverID, 1).
-			WillReturnRows(rows)
+		// No Redis expectations - the cached status must stay untouched
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		err := repo.UpdateServer(auditContext(), serverID, updatedData)
 
-		// Redis mock expectations - should still update to 0 since status is not "On"
-		redisMock.ExpectSetBit("server_status", 6, 0).SetVal(0)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("Update changing nothing is not recorded", func(t *testing.T) {
+		serverID := "srv-007"
+		updatedData := map[string]interface{}{
+			"server_name": "Same Name",
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).
+			WithArgs(serverID, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(7, serverID, "Same Name", "Up", "192.168.1.7", 8080))
+		mock.ExpectExec(`UPDATE "servers" SET`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(reloadQuery).
+			WithArgs(7, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(7, serverID, "Same Name", "Up", "192.168.1.7", 8080))
+		mock.ExpectCommit()
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		err := repo.UpdateServer(serverID, updatedData)
+		err := repo.UpdateServer(auditContext(), serverID, updatedData)
 
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
-		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
 }
 
@@ -461,99 +999,120 @@ func TestDeleteServer(t *testing.T) {
 		t.Fatalf("Failed to setup mocks: %v", err)
 	}
 
+	serverColumns := []string{"id", "server_id", "server_name", "status", "ipv4", "port"}
+	lockQuery := `SELECT \* FROM "servers" WHERE server_id = \$1 AND "servers"."deleted_at" IS NULL ORDER BY "servers"."server_id" LIMIT \$2 FOR UPDATE`
+
 	// Test successful deletion
 	t.Run("Successfully delete server", func(t *testing.T) {
 		serverID := "srv-001"
-		
-		// SQL mock expectations for getting the server ID
-		rows := sqlmock.NewRows([]string{"id", "server_id", "server_name", "status", "ipv4", "port"}).
-			AddRow(1, serverID, "Test Server", "On", "192.168.1.1", 8080)
-		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE server_id = \$1 ORDER BY "servers"."server_id" LIMIT \$2`).
-			WithArgs(serverID, 1).
-			WillReturnRows(rows)
-		
-		// SQL mock expectations for deleting
+
+		// The server is read before it goes to the trash, the audit log keeps its last version
 		mock.ExpectBegin()
-		mock.ExpectExec(`DELETE FROM "servers" WHERE`).
+		mock.ExpectQuery(lockQuery).
+			WithArgs(serverID, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(1, serverID, "Test Server", "Up", "192.168.1.1", 8080))
+		mock.ExpectExec(`UPDATE "servers" SET "deleted_at"=\$1 WHERE id = \$2 AND "servers"."deleted_at" IS NULL`).
+			WithArgs(sqlmock.AnyArg(), 1).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO "audit_entries"`).
+			WithArgs(auditArgs(domain.AuditDelete, domain.AuditSourceAPI, serverID, sqlmock.AnyArg())...).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 		mock.ExpectCommit()
-		
+
 		// Redis mock expectations
 		redisMock.ExpectSetBit("server_status", 1, 0).SetVal(0)
-		
+		redisMock.ExpectHDel("server_statuses", "1").SetVal(1)
+
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		err := repo.DeleteServer(serverID)
-		
+		err := repo.DeleteServer(auditContext(), serverID)
+
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
-	
+
 	// Test error when getting server ID
 	t.Run("Server not found", func(t *testing.T) {
 		serverID := "srv-not-exist"
-		
+
 		// SQL mock expectations - server not found
-		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE server_id = \$1 ORDER BY "servers"."server_id" LIMIT \$2`).
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).
 			WithArgs(serverID, 1).
 			WillReturnError(gorm.ErrRecordNotFound)
-		
+		mock.ExpectRollback()
+
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		err := repo.DeleteServer(serverID)
-		
+		err := repo.DeleteServer(auditContext(), serverID)
+
 		assert.Error(t, err)
 		assert.Equal(t, gorm.ErrRecordNotFound, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
-	
+
 	// Test error when deleting server
 	t.Run("Error when deleting server", func(t *testing.T) {
 		serverID := "srv-002"
-		
-		// SQL mock expectations for getting the server ID
-		rows := sqlmock.NewRows([]string{"id", "server_id", "server_name", "status", "ipv4", "port"}).
-			AddRow(2, serverID, "Test Server 2", "Off", "192.168.1.2", 8081)
-		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE server_id = \$1 ORDER BY "servers"."server_id" LIMIT \$2`).
-			WithArgs(serverID, 1).
-			WillReturnRows(rows)
-		
+
 		// SQL mock expectations for deleting - simulate error
 		mock.ExpectBegin()
-		mock.ExpectExec(`DELETE FROM "servers" WHERE`).
+		mock.ExpectQuery(lockQuery).
+			WithArgs(serverID, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(2, serverID, "Test Server 2", "Down", "192.168.1.2", 8081))
+		mock.ExpectExec(`UPDATE "servers" SET "deleted_at"`).
 			WillReturnError(errors.New("deletion error"))
 		mock.ExpectRollback()
-		
+
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		err := repo.DeleteServer(serverID)
-		
+		err := repo.DeleteServer(auditContext(), serverID)
+
 		assert.Error(t, err)
 		assert.Equal(t, "deletion error", err.Error())
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
-	
-	// // Test error when updating Redis
+
+	t.Run("Failing audit entry rolls back the delete", func(t *testing.T) {
+		serverID := "srv-004"
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).
+			WithArgs(serverID, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(4, serverID, "Test Server 4", "Up", "192.168.1.4", 8083))
+		mock.ExpectExec(`UPDATE "servers" SET "deleted_at"`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO "audit_entries"`).
+			WillReturnError(errors.New("audit error"))
+		mock.ExpectRollback()
+
+		// Nothing reaches Redis
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		err := repo.DeleteServer(auditContext(), serverID)
+
+		assert.Error(t, err)
+		assert.Equal(t, "audit error", err.Error())
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	// Test error when updating Redis
 	t.Run("Redis error", func(t *testing.T) {
 		serverID := "srv-003"
-		
-		// SQL mock expectations for getting the server ID
-		rows := sqlmock.NewRows([]string{"id", "server_id", "server_name", "status", "ipv4", "port"}).
-			AddRow(3, serverID, "Test Server 3", "On", "192.168.1.3", 8082)
-		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE server_id = \$1 ORDER BY "servers"."server_id" LIMIT \$2`).
-			WithArgs(serverID, 1).
-			WillReturnRows(rows)
-		
-		// SQL mock expectations for deleting
+
 		mock.ExpectBegin()
-		mock.ExpectExec(`DELETE FROM "servers" WHERE`).
+		mock.ExpectQuery(lockQuery).
+			WithArgs(serverID, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(3, serverID, "Test Server 3", "Up", "192.168.1.3", 8082))
+		mock.ExpectExec(`UPDATE "servers" SET "deleted_at"`).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAuditInsert(mock)
 		mock.ExpectCommit()
-		
+
 		// Redis mock expectations - simulate error
 		redisMock.ExpectSetBit("server_status", 3, 0).SetErr(errors.New("redis connection error"))
-		
+
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		err := repo.DeleteServer(serverID)
-		
+		err := repo.DeleteServer(auditContext(), serverID)
+
 		assert.Error(t, err)
 		assert.Equal(t, "redis connection error", err.Error())
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -561,46 +1120,247 @@ func TestDeleteServer(t *testing.T) {
 	})
 }
 
+func TestGetDeletedServers(t *testing.T) {
+	db, mock, redisCli, _, esClient, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	deletedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "servers" WHERE deleted_at IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(`SELECT \* FROM "servers" WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC,id LIMIT \$1 OFFSET \$2`).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "server_id", "deleted_at"}).
+			AddRow(4, "srv-004", deletedAt).
+			AddRow(2, "srv-002", deletedAt.Add(-time.Hour)))
+
+	repo := repository.NewServerRepository(db, redisCli, esClient)
+	servers, total, err := repo.GetDeletedServers(1, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Len(t, servers, 2)
+	assert.Equal(t, "srv-004", servers[0].ServerID)
+	assert.True(t, servers[0].DeletedAt.Valid)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreServer(t *testing.T) {
+	db, mock, redisCli, redisMock, esClient, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	serverColumns := []string{"id", "server_id", "server_name", "status", "ipv4", "port", "deleted_at"}
+	trashQuery := `SELECT \* FROM "servers" WHERE server_id = \$1 AND deleted_at IS NOT NULL ORDER BY "servers"."server_id" LIMIT \$2 FOR UPDATE`
+
+	t.Run("Restore a deleted server", func(t *testing.T) {
+		serverID := "srv-001"
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(trashQuery).
+			WithArgs(serverID, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(1, serverID, "Test Server", "Up", "192.168.1.1", 8080, time.Now()))
+		mock.ExpectExec(`UPDATE "servers" SET "deleted_at"=\$1,"last_updated"=\$2 WHERE id = \$3`).
+			WithArgs(nil, sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE id = \$1 AND "servers"."deleted_at" IS NULL`).
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns).AddRow(1, serverID, "Test Server", "Up", "192.168.1.1", 8080, nil))
+		mock.ExpectQuery(`INSERT INTO "audit_entries"`).
+			WithArgs(auditArgs(domain.AuditRestore, domain.AuditSourceAPI, serverID, sqlmock.AnyArg())...).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		// The status is cached again
+		redisMock.ExpectSetBit("server_status", 1, 1).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "1", "Up").SetVal(1)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		err := repo.RestoreServer(auditContext(), serverID)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("Server not in the trash", func(t *testing.T) {
+		serverID := "srv-002"
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(trashQuery).
+			WithArgs(serverID, 1).
+			WillReturnRows(sqlmock.NewRows(serverColumns))
+		mock.ExpectRollback()
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		err := repo.RestoreServer(auditContext(), serverID)
+
+		assert.ErrorIs(t, err, repository.ErrServerNotInTrash)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+}
+
+func TestPurgeServersDeletedBefore(t *testing.T) {
+	db, mock, redisCli, redisMock, esClient, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	before := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	purgeQuery := `DELETE FROM "servers" WHERE deleted_at < \$1 RETURNING \*`
+
+	t.Run("Purge the servers past their retention", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(purgeQuery).
+			WithArgs(before).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "server_id", "server_name", "deleted_at"}).
+				AddRow(4, "srv-004", "Server 4", before.Add(-time.Hour)).
+				AddRow(7, "srv-007", "Server 7", before.Add(-2*time.Hour)))
+
+		// Purged on behalf of the system
+		mock.ExpectQuery(`INSERT INTO "audit_entries"`).
+			WithArgs(
+				sqlmock.AnyArg(), "system", "system", "system", "", domain.AuditPurge, domain.AuditSourceSystem, "srv-004", sqlmock.AnyArg(),
+				sqlmock.AnyArg(), "system", "system", "system", "", domain.AuditPurge, domain.AuditSourceSystem, "srv-007", sqlmock.AnyArg(),
+			).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+		mock.ExpectCommit()
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		purged, err := repo.PurgeServersDeletedBefore(before)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, purged)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("Nothing to purge", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(purgeQuery).
+			WithArgs(before).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		purged, err := repo.PurgeServersDeletedBefore(before)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, purged)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+}
+
+func TestGetServerStatus(t *testing.T) {
+	db, mock, redisCli, redisMock, esClient, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	t.Run("Status cached in Redis", func(t *testing.T) {
+		redisMock.ExpectHGet("server_statuses", "1").SetVal("Degraded")
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		status, err := repo.GetServerStatus(1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.StatusDegraded, status)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("Fall back to the database", func(t *testing.T) {
+		redisMock.ExpectHGet("server_statuses", "2").RedisNil()
+		mock.ExpectQuery(`SELECT "status" FROM "servers" WHERE id = \$1`).
+			WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("Maintenance"))
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		status, err := repo.GetServerStatus(2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.StatusMaintenance, status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+}
+
 func TestUpdateServerStatus(t *testing.T) {
 	db, mock, redisCli, redisMock, esClient, err := setupMocks()
 	if err != nil {
 		t.Fatalf("Failed to setup mocks: %v", err)
 	}
 
-	t.Run("Update status from Off to On", func(t *testing.T) {
+	t.Run("Update status to Up", func(t *testing.T) {
 		serverID := 1
-		newStatus := "On"
+
+		// SQL expectations
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "servers" SET "status"=\$1,"last_updated"=\$2 WHERE \(id = \$3 AND status = \$4\)`).
+			WithArgs(domain.StatusUp, sqlmock.AnyArg(), serverID, domain.StatusDown).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 
 		// Redis expectations
-		redisMock.ExpectGetBit("server_status", int64(serverID)).SetVal(0) // Current status is Off (0)
 		redisMock.ExpectSetBit("server_status", int64(serverID), 1).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "1", "Up").SetVal(1)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		changed, err := repo.UpdateServerStatus(serverID, domain.StatusDown, domain.StatusUp)
+
+		assert.NoError(t, err)
+		assert.True(t, changed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("Degraded still counts as available", func(t *testing.T) {
+		serverID := 2
 
-		// SQL expectations
 		mock.ExpectBegin()
-		mock.ExpectExec(`UPDATE "servers" SET "status"=\$1,"last_updated"=\$2 WHERE id = \$3`).
-			WithArgs(newStatus, sqlmock.AnyArg(), serverID).
+		mock.ExpectExec(`UPDATE "servers" SET "status"=\$1,"last_updated"=\$2 WHERE \(id = \$3 AND status = \$4\)`).
+			WithArgs(domain.StatusDegraded, sqlmock.AnyArg(), serverID, domain.StatusUp).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
+		redisMock.ExpectSetBit("server_status", int64(serverID), 1).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "2", "Degraded").SetVal(1)
+
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		err := repo.UpdateServerStatus(serverID, newStatus)
+		changed, err := repo.UpdateServerStatus(serverID, domain.StatusUp, domain.StatusDegraded)
 
 		assert.NoError(t, err)
+		assert.True(t, changed)
 		assert.NoError(t, mock.ExpectationsWereMet())
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
 
-	t.Run("No change in status", func(t *testing.T) {
-		serverID := 1
-		newStatus := "On"
+	t.Run("Another update already changed the status", func(t *testing.T) {
+		serverID := 3
 
-		// Redis expectations - status is already On
-		redisMock.ExpectGetBit("server_status", int64(serverID)).SetVal(1) // Current status is already On (1)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "servers" SET "status"=\$1,"last_updated"=\$2 WHERE \(id = \$3 AND status = \$4\)`).
+			WithArgs(domain.StatusUp, sqlmock.AnyArg(), serverID, domain.StatusDown).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		// The cache is resynced with the status in the database
+		mock.ExpectQuery(`SELECT "status" FROM "servers" WHERE id = \$1`).
+			WithArgs(serverID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(domain.StatusMaintenance))
+		redisMock.ExpectSetBit("server_status", int64(serverID), 0).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "3", "Maintenance").SetVal(1)
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
-		err := repo.UpdateServerStatus(serverID, newStatus)
+		changed, err := repo.UpdateServerStatus(serverID, domain.StatusDown, domain.StatusUp)
 
 		assert.NoError(t, err)
+		assert.False(t, changed)
+		assert.NoError(t, mock.ExpectationsWereMet())
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
 }
@@ -614,19 +1374,20 @@ func TestGetAllAddresses(t *testing.T) {
 	t.Run("Successfully get all addresses", func(t *testing.T) {
 		// Define expected results
 		expectedAddresses := []dto.ServerAddress{
-			{ID: 1, IPv4: "192.168.1.1", Port: 8080},
-			{ID: 2, IPv4: "192.168.1.2", Port: 8081},
-			{ID: 3, IPv4: "192.168.1.3", Port: 8082},
+			{ID: 1, IPv4: "192.168.1.1", Port: 8080, ProbeType: "tcp", CheckIntervalSeconds: 60, TimeoutMs: 5000},
+			{ID: 2, IPv4: "192.168.1.2", Port: 8081, ProbeType: "http", ProbePath: "/health", ProbeExpectedStatus: 200, ProbeExpectedBody: "ok", CheckIntervalSeconds: 10, TimeoutMs: 2000, Retries: 2},
+			{ID: 3, IPv4: "192.168.1.3", Port: 8082, ProbeType: "tls", CheckIntervalSeconds: 600, TimeoutMs: 5000},
 		}
 
 		// Create mock rows
-		rows := sqlmock.NewRows([]string{"id", "ipv4", "port"})
+		rows := sqlmock.NewRows([]string{"id", "ipv4", "port", "probe_type", "probe_path", "probe_expected_status", "probe_expected_body", "check_interval_seconds", "timeout_ms", "retries"})
 		for _, addr := range expectedAddresses {
-			rows.AddRow(addr.ID, addr.IPv4, addr.Port)
+			rows.AddRow(addr.ID, addr.IPv4, addr.Port, addr.ProbeType, addr.ProbePath, addr.ProbeExpectedStatus, addr.ProbeExpectedBody, addr.CheckIntervalSeconds, addr.TimeoutMs, addr.Retries)
 		}
 
-		// Set up SQL mock expectations
-		mock.ExpectQuery(`SELECT "id","ipv4","port" FROM "servers"`).
+		// Decommissioned servers are not probed anymore
+		mock.ExpectQuery(`SELECT "id","ipv4","port","probe_type","probe_path","probe_expected_status","probe_expected_body","check_interval_seconds","timeout_ms","retries" FROM "servers" WHERE status <> \$1 AND "servers"."deleted_at" IS NULL`).
+			WithArgs(domain.StatusDecommissioned).
 			WillReturnRows(rows)
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
@@ -639,7 +1400,7 @@ func TestGetAllAddresses(t *testing.T) {
 
 	t.Run("Database error", func(t *testing.T) {
 		// Set up SQL mock to return an error
-		mock.ExpectQuery(`SELECT "id","ipv4","port" FROM "servers"`).
+		mock.ExpectQuery(`SELECT "id","ipv4","port","probe_type","probe_path","probe_expected_status","probe_expected_body","check_interval_seconds","timeout_ms","retries" FROM "servers"`).
 			WillReturnError(errors.New("database error"))
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
@@ -655,7 +1416,7 @@ func TestGetAllAddresses(t *testing.T) {
 		// Set up SQL mock to return empty result
 		rows := sqlmock.NewRows([]string{"id", "ipv4", "port"})
 
-		mock.ExpectQuery(`SELECT "id","ipv4","port" FROM "servers"`).
+		mock.ExpectQuery(`SELECT "id","ipv4","port","probe_type","probe_path","probe_expected_status","probe_expected_body","check_interval_seconds","timeout_ms","retries" FROM "servers"`).
 			WillReturnRows(rows)
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
@@ -667,6 +1428,47 @@ func TestGetAllAddresses(t *testing.T) {
 	})
 }
 
+func TestGetAddressesUpdatedSince(t *testing.T) {
+	db, mock, redisCli, _, esClient, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	since := time.UnixMilli(1700000000000)
+	rows := sqlmock.NewRows([]string{"id", "ipv4", "port", "check_interval_seconds"}).
+		AddRow(2, "192.168.1.2", 8081, 10)
+
+	mock.ExpectQuery(`SELECT (.+) FROM "servers" WHERE status <> \$1 AND \(last_updated > \$2 OR last_updated IS NULL\) AND "servers"."deleted_at" IS NULL`).
+		WithArgs(domain.StatusDecommissioned, since).
+		WillReturnRows(rows)
+
+	repo := repository.NewServerRepository(db, redisCli, esClient)
+	addresses, err := repo.GetAddressesUpdatedSince(since)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.ServerAddress{{ID: 2, IPv4: "192.168.1.2", Port: 8081, CheckIntervalSeconds: 10}}, addresses)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCheckedServerIDs(t *testing.T) {
+	db, mock, redisCli, _, esClient, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	// Neither decommissioned nor deleted servers are probed
+	mock.ExpectQuery(`SELECT "id" FROM "servers" WHERE status <> \$1 AND "servers"."deleted_at" IS NULL`).
+		WithArgs(domain.StatusDecommissioned).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(3))
+
+	repo := repository.NewServerRepository(db, redisCli, esClient)
+	ids, err := repo.GetCheckedServerIDs()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetNumOnServers(t *testing.T) {
 	db, _, redisCli, redisMock, esClient, err := setupMocks()
 	if err != nil {
@@ -688,6 +1490,135 @@ func TestGetNumOnServers(t *testing.T) {
 	})
 }
 
+func TestGetServerIDsByLabels(t *testing.T) {
+	db, mock, redisCli, _, esClient, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewServerRepository(db, redisCli, esClient)
+
+	t.Run("Servers having every label", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT "id" FROM "servers" WHERE labels @> \$1 AND "servers"."deleted_at" IS NULL ORDER BY id`).
+			WithArgs(`{"team":"payments"}`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(8))
+
+		ids, err := repo.GetServerIDsByLabels(map[string]string{"team": "payments"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{3, 8}, ids)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No matching server", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT "id" FROM "servers" WHERE labels @> \$1`).
+			WithArgs(`{"team":"unknown"}`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		ids, err := repo.GetServerIDsByLabels(map[string]string{"team": "unknown"})
+
+		assert.NoError(t, err)
+		assert.NotNil(t, ids)
+		assert.Empty(t, ids)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetStatusCounts(t *testing.T) {
+	db, mock, redisCli, _, esClient, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+	repo := repository.NewServerRepository(db, redisCli, esClient)
+
+	t.Run("Counts every server", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT status, COUNT\(\*\) AS count FROM "servers" WHERE "servers"."deleted_at" IS NULL GROUP BY "status"`).
+			WillReturnRows(sqlmock.NewRows([]string{"status", "count"}).
+				AddRow("Up", 4).
+				AddRow("Down", 1).
+				AddRow("Unknown", 2))
+
+		counts, err := repo.GetStatusCounts(nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[domain.ServerStatus]int{
+			domain.StatusUp:      4,
+			domain.StatusDown:    1,
+			domain.StatusUnknown: 2,
+		}, counts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Counts the given servers", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT status, COUNT\(\*\) AS count FROM "servers" WHERE id IN \(\$1,\$2\) AND "servers"."deleted_at" IS NULL GROUP BY "status"`).
+			WithArgs(2, 5).
+			WillReturnRows(sqlmock.NewRows([]string{"status", "count"}).AddRow("Degraded", 2))
+
+		counts, err := repo.GetStatusCounts([]int{2, 5})
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[domain.ServerStatus]int{domain.StatusDegraded: 2}, counts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No servers", func(t *testing.T) {
+		counts, err := repo.GetStatusCounts([]int{})
+
+		assert.NoError(t, err)
+		assert.Empty(t, counts)
+	})
+}
+
+func TestGetServerUptimeRatio_ExcludesMaintenance(t *testing.T) {
+	db, _, redisCli, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	var query map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&query)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"aggregations": {"avg_ratio": {"value": 0.5}}}`))
+	}))
+	defer server.Close()
+
+	esClient, err := es.NewClient(es.Config{Addresses: []string{server.URL}})
+	if err != nil {
+		t.Fatalf("Failed to create Elasticsearch client: %v", err)
+	}
+	repo := repository.NewServerRepository(db, redisCli, esClient)
+
+	ratio, err := repo.GetServerUptimeRatio(time.Unix(0, 0), time.Unix(3600, 0), nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, ratio)
+
+	mustNot := query["query"].(map[string]interface{})["bool"].(map[string]interface{})["must_not"].([]interface{})
+	assert.Equal(t, "Maintenance", mustNot[0].(map[string]interface{})["term"].(map[string]interface{})["status.keyword"])
+
+	// Scoped to some servers, e.g. the ones of a team
+	_, err = repo.GetServerUptimeRatio(time.Unix(0, 0), time.Unix(3600, 0), []int{3, 8})
+	assert.NoError(t, err)
+
+	filters := query["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"].([]interface{})
+	assert.Equal(t, 2, len(filters))
+	assert.Equal(t, []interface{}{float64(3), float64(8)}, filters[1].(map[string]interface{})["terms"].(map[string]interface{})["id"])
+}
+
+func TestGetServerUptimeRatio_TooManyServers(t *testing.T) {
+	db, _, redisCli, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	esClient := setupFakeES(t, `{"aggregations": {"per_server": {"sum_other_doc_count": 42, "buckets": []}, "avg_ratio": {"value": 0.9}}}`)
+	repo := repository.NewServerRepository(db, redisCli, esClient)
+
+	_, err = repo.GetServerUptimeRatio(time.Unix(0, 0), time.Unix(3600, 0), nil)
+	assert.Error(t, err)
+}
+
 func TestGetNumServers(t *testing.T) {
 	db, mock, redisCli, _, esClient, err := setupMocks()
 	if err != nil {
@@ -726,21 +1657,63 @@ func TestGetNumServers(t *testing.T) {
 func TestAddServerStatus(t *testing.T) {
 	// This test is complex because it involves Elasticsearch
 	// A simplified version is provided, but may need adjustments
-	db, _, redisCli, _, esClient, err := setupMocks()
+	db, _, redisCli, _, _, err := setupMocks()
 	if err != nil {
 		t.Fatalf("Failed to setup mocks: %v", err)
 	}
 
-	// Create a mock implementation for testing AddServerStatus
-	// In a real implementation, you might want to use a proper ES mock
+	var indexed map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		indexed = nil
+		json.NewDecoder(r.Body).Decode(&indexed)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"result": "created"}`))
+	}))
+	defer server.Close()
+
+	esClient, err := es.NewClient(es.Config{Addresses: []string{server.URL}})
+	if err != nil {
+		t.Fatalf("Failed to create Elasticsearch client: %v", err)
+	}
 	repo := repository.NewServerRepository(db, redisCli, esClient)
-	
-	// Note: This is more of an integration test since ES is hard to mock
-	// In a real test environment, you might want to skip this test or use a test ES instance
-	t.Run("Add server status - integration test", func(t *testing.T) {
-		t.Skip("Skipping Elasticsearch integration test")
-		err := repo.AddServerStatus(1, "On")
+
+	t.Run("Failed check keeps latency and error class", func(t *testing.T) {
+		checkedAt := time.UnixMilli(1700000000000).UTC()
+		err := repo.AddServerStatus(dto.HealthCheckResult{
+			ID:         1,
+			Status:     false,
+			LatencyMs:  5000.2,
+			ErrorClass: "timeout",
+			CheckedAt:  checkedAt,
+		}, domain.StatusDown, domain.StatusUp)
+
+		assert.NoError(t, err)
+		assert.Equal(t, float64(1), indexed["id"])
+		assert.Equal(t, "Down", indexed["status"])
+		// One location failed, the quorum still says Up
+		assert.Equal(t, "Up", indexed["decided_status"])
+		assert.Equal(t, 5000.2, indexed["latency_ms"])
+		assert.Equal(t, "timeout", indexed["error_class"])
+		assert.Equal(t, checkedAt.Format(time.RFC3339Nano), indexed["timestamp"])
+	})
+
+	t.Run("Successful check has no error class", func(t *testing.T) {
+		err := repo.AddServerStatus(dto.HealthCheckResult{ID: 2, Status: true, LatencyMs: 3.4}, domain.StatusUp, domain.StatusUp)
+
 		assert.NoError(t, err)
+		assert.Equal(t, "Up", indexed["status"])
+		assert.NotContains(t, indexed, "error_class")
+		assert.NotEmpty(t, indexed["timestamp"])
+	})
+
+	t.Run("Failed check in maintenance is recorded as Maintenance", func(t *testing.T) {
+		err := repo.AddServerStatus(dto.HealthCheckResult{ID: 3, Status: false, ErrorClass: "connection_refused"}, domain.StatusMaintenance, "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Maintenance", indexed["status"])
+		assert.NotContains(t, indexed, "decided_status")
+		assert.Equal(t, "connection_refused", indexed["error_class"])
 	})
 }
 
@@ -753,17 +1726,21 @@ func TestSyncServerStatus(t *testing.T) {
 	t.Run("Sync server status", func(t *testing.T) {
 		// Mock DB query to get servers
 		rows := sqlmock.NewRows([]string{"id", "server_id", "server_name", "status", "ipv4", "port"}).
-			AddRow(1, "srv-001", "Server 1", "On", "192.168.1.1", 8080).
-			AddRow(2, "srv-002", "Server 2", "Off", "192.168.1.2", 8081).
-			AddRow(3, "srv-003", "Server 3", "On", "192.168.1.3", 8082)
+			AddRow(1, "srv-001", "Server 1", "Up", "192.168.1.1", 8080).
+			AddRow(2, "srv-002", "Server 2", "Down", "192.168.1.2", 8081).
+			AddRow(3, "srv-003", "Server 3", "Degraded", "192.168.1.3", 8082)
 
 		mock.ExpectQuery(`SELECT (.+) FROM "servers"`).
 			WillReturnRows(rows)
 
 		// Redis expectations
-		redisMock.ExpectDel("server_status").SetVal(1)
+		redisMock.ExpectDel("server_status", "server_statuses").SetVal(2)
 		redisMock.ExpectSetBit("server_status", 1, 1).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "1", "Up").SetVal(1)
+		redisMock.ExpectSetBit("server_status", 2, 0).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "2", "Down").SetVal(1)
 		redisMock.ExpectSetBit("server_status", 3, 1).SetVal(0)
+		redisMock.ExpectHSet("server_statuses", "3", "Degraded").SetVal(1)
 		redisMock.ExpectBitCount("server_status", nil).SetVal(2)
 
 		repo := repository.NewServerRepository(db, redisCli, esClient)
@@ -787,3 +1764,470 @@ func TestSyncServerStatus(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+
+// setupFakeESByIndex answers the searches of each index with its own responses, one after the other
+func setupFakeESByIndex(t *testing.T, responses map[string][]string, queries map[string][]map[string]interface{}) *es.Client {
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		index := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")[0]
+		var query map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&query)
+		queries[index] = append(queries[index], query)
+
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if len(responses[index]) == 0 {
+			t.Errorf("Unexpected search on %s", index)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(responses[index][0]))
+		responses[index] = responses[index][1:]
+	}))
+	t.Cleanup(server.Close)
+
+	esClient, err := es.NewClient(es.Config{Addresses: []string{server.URL}})
+	if err != nil {
+		t.Fatalf("Failed to create Elasticsearch client: %v", err)
+	}
+	return esClient
+}
+
+func TestGetServersUptime(t *testing.T) {
+	db, _, redisCli, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	t.Run("Parse per server buckets", func(t *testing.T) {
+		queries := map[string][]map[string]interface{}{}
+		esClient := setupFakeESByIndex(t, map[string][]string{
+			"server_status": {`{
+				"aggregations": {
+					"per_server": {
+						"after_key": {"id": 2},
+						"buckets": [
+							{"key": {"id": 1}, "doc_count": 10, "on_count": {"doc_count": 8}},
+							{"key": {"id": 2}, "doc_count": 4, "on_count": {"doc_count": 4}}
+						]
+					}
+				}
+			}`},
+			"server_status_transitions": {`{
+				"aggregations": {
+					"per_server": {
+						"after_key": {"id": 1},
+						"buckets": [
+							{"key": {"id": 1}, "doc_count": 3}
+						]
+					}
+				}
+			}`},
+		}, queries)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		uptimes, err := repo.GetServersUptime(time.Now().Add(-time.Hour), time.Now(), nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []dto.ServerUptime{
+			{ID: 1, UptimeRatio: 0.8, NumChecks: 10, NumStatusChanges: 3},
+			{ID: 2, UptimeRatio: 1, NumChecks: 4, NumStatusChanges: 0},
+		}, uptimes)
+
+		// A single page each, the buckets came back short of a full page
+		assert.Len(t, queries["server_status"], 1)
+		assert.Len(t, queries["server_status_transitions"], 1)
+	})
+
+	t.Run("Pages through every server", func(t *testing.T) {
+		// A full page, then what is left
+		var fullPage []string
+		for id := 1; id <= 1000; id++ {
+			fullPage = append(fullPage, fmt.Sprintf(`{"key": {"id": %d}, "doc_count": 2, "on_count": {"doc_count": 1}}`, id))
+		}
+
+		queries := map[string][]map[string]interface{}{}
+		esClient := setupFakeESByIndex(t, map[string][]string{
+			"server_status": {
+				`{"aggregations": {"per_server": {"after_key": {"id": 1000}, "buckets": [` + strings.Join(fullPage, ",") + `]}}}`,
+				`{"aggregations": {"per_server": {"after_key": {"id": 1001}, "buckets": [{"key": {"id": 1001}, "doc_count": 4, "on_count": {"doc_count": 0}}]}}}`,
+			},
+			"server_status_transitions": {
+				`{"aggregations": {"per_server": {"buckets": []}}}`,
+			},
+		}, queries)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		uptimes, err := repo.GetServersUptime(time.Now().Add(-time.Hour), time.Now(), nil)
+
+		assert.NoError(t, err)
+		assert.Len(t, uptimes, 1001)
+		assert.Equal(t, dto.ServerUptime{ID: 1001, UptimeRatio: 0, NumChecks: 4}, uptimes[1000])
+
+		// The second page starts after the last server of the first one
+		assert.Len(t, queries["server_status"], 2)
+		firstPage := queries["server_status"][0]["aggs"].(map[string]interface{})["per_server"].(map[string]interface{})["composite"].(map[string]interface{})
+		secondPage := queries["server_status"][1]["aggs"].(map[string]interface{})["per_server"].(map[string]interface{})["composite"].(map[string]interface{})
+		assert.NotContains(t, firstPage, "after")
+		assert.Equal(t, map[string]interface{}{"id": float64(1000)}, secondPage["after"])
+	})
+}
+
+func TestGetUptimeTimeline(t *testing.T) {
+	db, _, redisCli, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	t.Run("Parse date histogram buckets", func(t *testing.T) {
+		esClient := setupFakeES(t, `{
+			"aggregations": {
+				"timeline": {
+					"buckets": [
+						{"key": 0, "doc_count": 4, "on_count": {"doc_count": 3}},
+						{"key": 3600000, "doc_count": 0, "on_count": {"doc_count": 0}}
+					]
+				}
+			}
+		}`)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		timeline, err := repo.GetUptimeTimeline(1, time.Unix(0, 0), time.Unix(7200, 0), "hour")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []dto.UptimeBucket{
+			{Timestamp: time.Unix(0, 0).UTC(), UptimeRatio: 0.75, NumChecks: 4},
+			{Timestamp: time.Unix(3600, 0).UTC(), UptimeRatio: 0, NumChecks: 0},
+		}, timeline)
+	})
+}
+
+func TestGetLatencyPercentiles(t *testing.T) {
+	db, _, redisCli, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	t.Run("Parse percentiles per server", func(t *testing.T) {
+		esClient := setupFakeES(t, `{
+			"aggregations": {
+				"per_server": {
+					"buckets": [
+						{
+							"key": 1,
+							"doc_count": 60,
+							"latency": {"values": {"50.0": 10.5, "90.0": 20, "95.0": 25, "99.0": 80}},
+							"avg_latency": {"value": 12.25},
+							"max_latency": {"value": 95}
+						}
+					]
+				}
+			}
+		}`)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		latencies, err := repo.GetLatencyPercentiles(0, time.Unix(0, 0), time.Unix(3600, 0))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []dto.LatencyPercentiles{
+			{ID: 1, NumChecks: 60, P50: 10.5, P90: 20, P95: 25, P99: 80, Avg: 12.25, Max: 95},
+		}, latencies)
+	})
+}
+
+func TestSetLocationStatus(t *testing.T) {
+	db, _, redisCli, redisMock, esClient, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	hanoi := dto.LocationStatus{Location: "hanoi", Status: "Off", CheckedAt: time.UnixMilli(1700000000000).UTC()}
+	singapore := dto.LocationStatus{Location: "singapore", Status: "On", CheckedAt: time.UnixMilli(1700000001000).UTC()}
+	hanoiValue, _ := json.Marshal(hanoi)
+	singaporeValue, _ := json.Marshal(singapore)
+
+	redisMock.ExpectHSet("server_location_status:1", "hanoi", hanoiValue).SetVal(1)
+	redisMock.ExpectHGetAll("server_location_status:1").SetVal(map[string]string{
+		"singapore": string(singaporeValue),
+		"hanoi":     string(hanoiValue),
+	})
+
+	repo := repository.NewServerRepository(db, redisCli, esClient)
+	statuses, err := repo.SetLocationStatus(1, hanoi)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.LocationStatus{hanoi, singapore}, statuses)
+	assert.NoError(t, redisMock.ExpectationsWereMet())
+}
+
+func TestAddStatusTransition(t *testing.T) {
+	db, _, redisCli, redisMock, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	esClient := setupFakeES(t, `{"result": "created"}`)
+	changedAt := time.UnixMilli(1700000600000)
+
+	t.Run("First transition of a server", func(t *testing.T) {
+		redisMock.ExpectHGet("server_status_changed_at", "1").RedisNil()
+		redisMock.ExpectHSet("server_status_changed_at", "1", changedAt.UnixMilli()).SetVal(1)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		err := repo.AddStatusTransition(1, domain.StatusUnknown, domain.StatusUp, changedAt)
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("Transition after a known change", func(t *testing.T) {
+		redisMock.ExpectHGet("server_status_changed_at", "2").SetVal("1700000000000")
+		redisMock.ExpectHSet("server_status_changed_at", "2", changedAt.UnixMilli()).SetVal(0)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		err := repo.AddStatusTransition(2, domain.StatusUp, domain.StatusDown, changedAt)
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+}
+
+func TestGetStatusTransitions(t *testing.T) {
+	db, _, redisCli, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	t.Run("Parse transition hits", func(t *testing.T) {
+		esClient := setupFakeES(t, `{
+			"hits": {
+				"total": {"value": 2},
+				"hits": [
+					{"_source": {"id": 1, "previous_status": "On", "status": "Off", "timestamp": "2024-01-01T01:00:00Z", "previous_duration_seconds": 3600}},
+					{"_source": {"id": 1, "previous_status": "Off", "status": "On", "timestamp": "2024-01-01T00:00:00Z"}}
+				]
+			}
+		}`)
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		transitions, total, err := repo.GetStatusTransitions(1, time.Unix(0, 0), time.Now(), 0, 10)
+
+		duration := int64(3600)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, total)
+		assert.Equal(t, []dto.StatusTransition{
+			{ID: 1, PreviousStatus: "On", Status: "Off", Timestamp: time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), PreviousDurationSeconds: &duration},
+			{ID: 1, PreviousStatus: "Off", Status: "On", Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		}, transitions)
+	})
+}
+
+func TestGetDownServers(t *testing.T) {
+	db, mock, redisCli, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	t.Run("Count leading checks decided Down", func(t *testing.T) {
+		esClient := setupFakeES(t, `{
+			"aggregations": {
+				"per_server": {
+					"buckets": [
+						{"key": 1, "latest_checks": {"hits": {"hits": [
+							{"_source": {"decided_status": "Down", "timestamp": "2024-01-01T00:02:00Z"}},
+							{"_source": {"decided_status": "Down", "timestamp": "2024-01-01T00:01:00Z"}},
+							{"_source": {"decided_status": "Up", "timestamp": "2024-01-01T00:00:00Z"}}
+						]}}},
+						{"key": 2, "latest_checks": {"hits": {"hits": [
+							{"_source": {"decided_status": "Up", "timestamp": "2024-01-01T00:02:00Z"}}
+						]}}}
+					]
+				}
+			}
+		}`)
+
+		mock.ExpectQuery(`SELECT "id","server_name" FROM "servers" WHERE id IN \(\$1\)`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "server_name"}).AddRow(1, "Server 1"))
+
+		repo := repository.NewServerRepository(db, redisCli, esClient)
+		downServers, err := repo.GetDownServers(time.Now().Add(-time.Hour), 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []dto.DownServer{
+			{ID: 1, ServerName: "Server 1", ConsecutiveDownChecks: 2, LastCheck: time.Date(2024, 1, 1, 0, 2, 0, 0, time.UTC)},
+		}, downServers)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+func TestSearchServers(t *testing.T) {
+	db, _, redisCli, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	var path string
+	var query map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		query = nil
+		json.NewDecoder(r.Body).Decode(&query)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"hits": {
+				"total": {"value": 1},
+				"hits": [
+					{"_score": 2.5, "_source": {"id": 3, "server_name": "payments-api", "ipv4": "10.2.0.3", "status": "Down", "port": 443},
+					 "highlight": {"server_name": ["<em>payments</em>-api"]}}
+				]
+			},
+			"aggregations": {
+				"status": {"buckets": [{"key": "Down", "doc_count": 1}, {"key": "Up", "doc_count": 4}]},
+				"port": {"buckets": [{"key": 443, "doc_count": 5}]}
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	esClient, err := es.NewClient(es.Config{Addresses: []string{server.URL}})
+	if err != nil {
+		t.Fatalf("Failed to create Elasticsearch client: %v", err)
+	}
+	repo := repository.NewServerRepository(db, redisCli, esClient)
+
+	t.Run("Fuzzy text with facet filters", func(t *testing.T) {
+		result, err := repo.SearchServers(dto.ServerSearchQuery{Text: "paymnts", Statuses: []string{"Down"}, Port: 443, Offset: 10, Limit: 5})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/servers/_search", path)
+		assert.Equal(t, float64(10), query["from"])
+		assert.Equal(t, float64(5), query["size"])
+
+		should := query["query"].(map[string]interface{})["bool"].(map[string]interface{})["should"].([]interface{})
+		nameMatch := should[0].(map[string]interface{})["match"].(map[string]interface{})["server_name"].(map[string]interface{})
+		assert.Equal(t, "AUTO", nameMatch["fuzziness"])
+		ipPrefix := should[2].(map[string]interface{})["prefix"].(map[string]interface{})["ipv4"].(map[string]interface{})
+		assert.Equal(t, "paymnts", ipPrefix["value"])
+
+		// The facets are counted before the filters
+		filters := query["post_filter"].(map[string]interface{})["bool"].(map[string]interface{})["filter"].([]interface{})
+		assert.Equal(t, 2, len(filters))
+
+		assert.Equal(t, 1, result.Total)
+		assert.Equal(t, 1, len(result.Hits))
+		assert.Equal(t, "payments-api", result.Hits[0].Server.ServerName)
+		assert.Equal(t, domain.StatusDown, result.Hits[0].Server.Status)
+		assert.Equal(t, 2.5, result.Hits[0].Score)
+		assert.Equal(t, []string{"<em>payments</em>-api"}, result.Hits[0].Highlights["server_name"])
+		assert.Equal(t, map[string]int{"Down": 1, "Up": 4}, result.StatusCounts)
+		assert.Equal(t, map[int]int{443: 5}, result.PortCounts)
+	})
+
+	t.Run("No text matches everything", func(t *testing.T) {
+		_, err := repo.SearchServers(dto.ServerSearchQuery{Port: -1, Limit: 20})
+
+		assert.NoError(t, err)
+		assert.Contains(t, query["query"], "match_all")
+		assert.NotContains(t, query, "post_filter")
+	})
+}
+
+func TestCreateServer_MirrorsToSearchIndex(t *testing.T) {
+	db, mock, redisCli, redisMock, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	var method, path string
+	var indexed map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		json.NewDecoder(r.Body).Decode(&indexed)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"result": "created"}`))
+	}))
+	defer server.Close()
+
+	esClient, err := es.NewClient(es.Config{Addresses: []string{server.URL}})
+	if err != nil {
+		t.Fatalf("Failed to create Elasticsearch client: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "servers"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	expectAuditInsert(mock)
+	mock.ExpectCommit()
+	redisMock.ExpectSetBit("server_status", 7, 1).SetVal(0)
+	redisMock.ExpectHSet("server_statuses", "7", "Up").SetVal(1)
+
+	repo := repository.NewServerRepository(db, redisCli, esClient)
+	_, err = repo.CreateServer(auditContext(), &domain.Server{ServerID: "srv-007", ServerName: "payments-api", Status: domain.StatusUp, IPv4: "10.2.0.7", Port: 443})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "PUT", method)
+	assert.Equal(t, "/servers/_doc/7", path)
+	assert.Equal(t, "payments-api", indexed["server_name"])
+	assert.Equal(t, "10.2.0.7", indexed["ipv4"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSyncServerIndex(t *testing.T) {
+	db, mock, redisCli, _, _, err := setupMocks()
+	if err != nil {
+		t.Fatalf("Failed to setup mocks: %v", err)
+	}
+
+	var deleteQuery map[string]interface{}
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if r.URL.Path == "/servers/_delete_by_query" {
+			json.NewDecoder(r.Body).Decode(&deleteQuery)
+		}
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"errors": false, "deleted": 1}`))
+	}))
+	defer server.Close()
+
+	esClient, err := es.NewClient(es.Config{Addresses: []string{server.URL}})
+	if err != nil {
+		t.Fatalf("Failed to create Elasticsearch client: %v", err)
+	}
+	repo := repository.NewServerRepository(db, redisCli, esClient)
+
+	t.Run("Documents of the servers gone are deleted", func(t *testing.T) {
+		paths = nil
+		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE "servers"."deleted_at" IS NULL`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "server_id", "server_name"}).
+				AddRow(2, "srv-002", "Server 2").
+				AddRow(5, "srv-005", "Server 5"))
+
+		assert.NoError(t, repo.SyncServerIndex())
+		assert.Equal(t, []string{"HEAD /servers", "POST /servers/_bulk", "POST /servers/_delete_by_query"}, paths)
+
+		boolQuery := deleteQuery["query"].(map[string]interface{})["bool"].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"values": []interface{}{"2", "5"}}, boolQuery["must_not"].(map[string]interface{})["ids"])
+		// A server created during the sync is not deleted
+		assert.Equal(t, map[string]interface{}{"lte": float64(5)}, boolQuery["filter"].(map[string]interface{})["range"].(map[string]interface{})["id"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No server left empties the index", func(t *testing.T) {
+		paths = nil
+		mock.ExpectQuery(`SELECT \* FROM "servers" WHERE "servers"."deleted_at" IS NULL`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		assert.NoError(t, repo.SyncServerIndex())
+		assert.Equal(t, []string{"HEAD /servers", "POST /servers/_delete_by_query"}, paths)
+		assert.Contains(t, deleteQuery["query"], "match_all")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,431 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"strconv"
+
+	"github.com/flashhhhh/pkg/logging"
+)
+
+/*
+	The servers index mirrors the servers table for the full-text search, Postgres stays the source of truth.
+	Writes to it are best effort: a failure is logged and repaired by SyncServerIndex on the next start,
+	which indexes every server again and removes the documents of the servers that are gone.
+*/
+const serversIndex = "servers"
+
+var serversIndexMapping = map[string]interface{}{
+	"settings": map[string]interface{}{
+		"analysis": map[string]interface{}{
+			"analyzer": map[string]interface{}{
+				// Splits "payments-api_01" into payments / api / 01 so every part can be matched
+				"server_name": map[string]interface{}{
+					"type":      "custom",
+					"tokenizer": "server_name",
+					"filter":    []string{"lowercase"},
+				},
+			},
+			"tokenizer": map[string]interface{}{
+				"server_name": map[string]interface{}{
+					"type":    "pattern",
+					"pattern": "[^A-Za-z0-9]+",
+				},
+			},
+		},
+	},
+	"mappings": map[string]interface{}{
+		"properties": map[string]interface{}{
+			"id":        map[string]interface{}{"type": "integer"},
+			"server_id": map[string]interface{}{"type": "keyword"},
+			"server_name": map[string]interface{}{
+				"type":     "text",
+				"analyzer": "server_name",
+				"fields": map[string]interface{}{
+					"keyword": map[string]interface{}{"type": "keyword"},
+				},
+			},
+			"status":       map[string]interface{}{"type": "keyword"},
+			"ipv4":         map[string]interface{}{"type": "keyword"},
+			"port":         map[string]interface{}{"type": "integer"},
+			"labels":       map[string]interface{}{"type": "flattened"},
+			"created_time": map[string]interface{}{"type": "date"},
+			"last_updated": map[string]interface{}{"type": "date"},
+		},
+	},
+}
+
+// EnsureServerIndex creates the servers index with its mapping when it doesn't exist yet
+func (r *serverRepository) EnsureServerIndex() error {
+	res, err := r.es.Indices.Exists([]string{serversIndex})
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	if res.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("Error checking the %s index: %s", serversIndex, res.String())
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(serversIndexMapping); err != nil {
+		return err
+	}
+
+	res, err = r.es.Indices.Create(serversIndex, r.es.Indices.Create.WithBody(&buf))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("Error creating the %s index: %s", serversIndex, res.String())
+	}
+	return nil
+}
+
+/*
+	SyncServerIndex indexes every server of the database again, e.g. after Elasticsearch was down,
+	and deletes the documents of the servers deleted or moved to the trash in the meantime
+*/
+func (r *serverRepository) SyncServerIndex() error {
+	if err := r.EnsureServerIndex(); err != nil {
+		return err
+	}
+
+	var servers []domain.Server
+	if err := r.db.Find(&servers).Error; err != nil {
+		return err
+	}
+
+	if err := r.bulkIndexServers(servers); err != nil {
+		return err
+	}
+	return r.deleteStaleDocuments(servers)
+}
+
+// deleteStaleDocuments deletes the documents of the index whose server is not among the live servers
+func (r *serverRepository) deleteStaleDocuments(servers []domain.Server) error {
+	// Without any server every document is stale
+	query := map[string]interface{}{"match_all": map[string]interface{}{}}
+	if len(servers) > 0 {
+		ids := make([]string, len(servers))
+		maxID := 0
+		for i, server := range servers {
+			ids[i] = strconv.Itoa(server.ID)
+			maxID = max(maxID, server.ID)
+		}
+
+		// Servers created since they were read have a higher id, their documents are kept
+		query = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": map[string]interface{}{
+					"range": map[string]interface{}{"id": map[string]interface{}{"lte": maxID}},
+				},
+				"must_not": map[string]interface{}{
+					"ids": map[string]interface{}{"values": ids},
+				},
+			},
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"query": query}); err != nil {
+		return err
+	}
+
+	res, err := r.es.DeleteByQuery(
+		[]string{serversIndex},
+		&buf,
+		r.es.DeleteByQuery.WithContext(context.Background()),
+		r.es.DeleteByQuery.WithConflicts("proceed"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("Error deleting stale server documents: %s", res.String())
+	}
+	return nil
+}
+
+func (r *serverRepository) indexServer(server domain.Server) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(server); err != nil {
+		return err
+	}
+
+	res, err := r.es.Index(
+		serversIndex,
+		&buf,
+		r.es.Index.WithDocumentID(strconv.Itoa(server.ID)),
+		r.es.Index.WithContext(context.Background()),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("Error indexing server: %s", res.String())
+	}
+	return nil
+}
+
+func (r *serverRepository) bulkIndexServers(servers []domain.Server) error {
+	if len(servers) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, server := range servers {
+		action := map[string]interface{}{
+			"index": map[string]interface{}{"_id": strconv.Itoa(server.ID)},
+		}
+		if err := encoder.Encode(action); err != nil {
+			return err
+		}
+		if err := encoder.Encode(server); err != nil {
+			return err
+		}
+	}
+
+	res, err := r.es.Bulk(
+		&buf,
+		r.es.Bulk.WithIndex(serversIndex),
+		r.es.Bulk.WithContext(context.Background()),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("Error bulk indexing servers: %s", res.String())
+	}
+
+	// A bulk request succeeds even when some of its items fail
+	var result struct {
+		Errors bool `json:"errors"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return err
+	}
+	if result.Errors {
+		return fmt.Errorf("Error bulk indexing servers: some servers were not indexed")
+	}
+	return nil
+}
+
+func (r *serverRepository) updateServerDocument(id int, fields map[string]interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"doc": fields}); err != nil {
+		return err
+	}
+
+	res, err := r.es.Update(
+		serversIndex,
+		strconv.Itoa(id),
+		&buf,
+		r.es.Update.WithContext(context.Background()),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("Error updating server document: %s", res.String())
+	}
+	return nil
+}
+
+func (r *serverRepository) deleteServerDocument(id int) error {
+	res, err := r.es.Delete(
+		serversIndex,
+		strconv.Itoa(id),
+		r.es.Delete.WithContext(context.Background()),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// Already missing from the index is fine
+	if res.IsError() && res.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("Error deleting server document: %s", res.String())
+	}
+	return nil
+}
+
+// logIndexError keeps the database write successful when mirroring it to Elasticsearch fails
+func logIndexError(id int, err error) {
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Error mirroring server ID "+strconv.Itoa(id)+" to the servers index: "+err.Error(), "ERROR")
+	}
+}
+
+/*
+	SearchServers matches the text against the server name with typos allowed, the server_id exactly
+	and the ipv4 by prefix, e.g. "10.2." finds the whole subnet. The status and port facets are
+	counted before the status / port filters are applied, so the other values stay visible.
+*/
+func (r *serverRepository) SearchServers(searchQuery dto.ServerSearchQuery) (*dto.ServerSearchResult, error) {
+	match := map[string]interface{}{"match_all": map[string]interface{}{}}
+	if searchQuery.Text != "" {
+		match = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should": []interface{}{
+					map[string]interface{}{
+						"match": map[string]interface{}{
+							"server_name": map[string]interface{}{
+								"query":     searchQuery.Text,
+								"fuzziness": "AUTO",
+								"operator":  "and",
+							},
+						},
+					},
+					map[string]interface{}{
+						"term": map[string]interface{}{
+							"server_id": map[string]interface{}{"value": searchQuery.Text, "boost": 3},
+						},
+					},
+					map[string]interface{}{
+						"prefix": map[string]interface{}{
+							"ipv4": map[string]interface{}{"value": searchQuery.Text, "boost": 2},
+						},
+					},
+				},
+				"minimum_should_match": 1,
+			},
+		}
+	}
+
+	var filters []interface{}
+	if len(searchQuery.Statuses) > 0 {
+		filters = append(filters, map[string]interface{}{
+			"terms": map[string]interface{}{"status": searchQuery.Statuses},
+		})
+	}
+	if searchQuery.Port >= 0 {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"port": searchQuery.Port},
+		})
+	}
+
+	query := map[string]interface{}{
+		"from":  searchQuery.Offset,
+		"size":  searchQuery.Limit,
+		"query": match,
+		"highlight": map[string]interface{}{
+			"fields": map[string]interface{}{
+				"server_name": map[string]interface{}{},
+				"ipv4":        map[string]interface{}{},
+			},
+		},
+		"aggs": map[string]interface{}{
+			"status": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "status", "size": len(domain.ServerStatuses)},
+			},
+			"port": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "port", "size": 20},
+			},
+		},
+	}
+
+	if len(filters) > 0 {
+		query["post_filter"] = map[string]interface{}{
+			"bool": map[string]interface{}{"filter": filters},
+		}
+	}
+
+	result, err := r.search(serversIndex, query)
+	if err != nil {
+		return nil, err
+	}
+
+	searchResult := &dto.ServerSearchResult{
+		Hits:         []dto.ServerSearchHit{},
+		StatusCounts: make(map[string]int),
+		PortCounts:   make(map[int]int),
+	}
+
+	hits, _ := result["hits"].(map[string]interface{})
+	if total, ok := hits["total"].(map[string]interface{}); ok {
+		if value, ok := total["value"].(float64); ok {
+			searchResult.Total = int(value)
+		}
+	}
+
+	hitList, _ := hits["hits"].([]interface{})
+	for _, item := range hitList {
+		hit, _ := item.(map[string]interface{})
+
+		source, err := json.Marshal(hit["_source"])
+		if err != nil {
+			return nil, err
+		}
+		var searchHit dto.ServerSearchHit
+		if err := json.Unmarshal(source, &searchHit.Server); err != nil {
+			return nil, err
+		}
+
+		searchHit.Score, _ = hit["_score"].(float64)
+		if highlight, ok := hit["highlight"].(map[string]interface{}); ok {
+			searchHit.Highlights = make(map[string][]string)
+			for field, fragments := range highlight {
+				fragmentList, _ := fragments.([]interface{})
+				for _, fragment := range fragmentList {
+					if text, ok := fragment.(string); ok {
+						searchHit.Highlights[field] = append(searchHit.Highlights[field], text)
+					}
+				}
+			}
+		}
+
+		searchResult.Hits = append(searchResult.Hits, searchHit)
+	}
+
+	aggregations, _ := result["aggregations"].(map[string]interface{})
+	for _, bucket := range termsBuckets(aggregations, "status") {
+		if key, ok := bucket["key"].(string); ok {
+			searchResult.StatusCounts[key] = bucketCount(bucket)
+		}
+	}
+	for _, bucket := range termsBuckets(aggregations, "port") {
+		if key, ok := bucket["key"].(float64); ok {
+			searchResult.PortCounts[int(key)] = bucketCount(bucket)
+		}
+	}
+
+	return searchResult, nil
+}
+
+func termsBuckets(aggregations map[string]interface{}, name string) []map[string]interface{} {
+	aggregation, _ := aggregations[name].(map[string]interface{})
+	items, _ := aggregation["buckets"].([]interface{})
+
+	buckets := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if bucket, ok := item.(map[string]interface{}); ok {
+			buckets = append(buckets, bucket)
+		}
+	}
+	return buckets
+}
+
+func bucketCount(bucket map[string]interface{}) int {
+	count, _ := bucket["doc_count"].(float64)
+	return int(count)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/flashhhhh/pkg/logging"
+)
+
+// AuditService reads the audit log of the server changes, the entries are written by the ServerRepository
+type AuditService interface {
+	GetAuditEntries(auditFilter *dto.AuditFilter, cursor string, pageSize int) (*dto.AuditPage, error)
+	ExportAuditEntries(w io.Writer, auditFilter *dto.AuditFilter, format string) error
+}
+
+type auditService struct {
+	auditRepository repository.AuditRepository
+}
+
+func NewAuditService(auditRepository repository.AuditRepository) AuditService {
+	return &auditService{
+		auditRepository: auditRepository,
+	}
+}
+
+func prepareAuditFilter(auditFilter *dto.AuditFilter) error {
+	if auditFilter.Action != "" && !domain.AuditAction(auditFilter.Action).IsValid() {
+		return fmt.Errorf("%w: invalid action %q, expected create, update, delete, restore or purge", ErrInvalidInput, auditFilter.Action)
+	}
+	if auditFilter.Source != "" && !domain.AuditSource(auditFilter.Source).IsValid() {
+		return fmt.Errorf("%w: invalid source %q, expected api, import or system", ErrInvalidInput, auditFilter.Source)
+	}
+
+	timeRange := auditFilter.Time
+	if timeRange.From != nil && timeRange.To != nil && !timeRange.From.Before(*timeRange.To) {
+		return fmt.Errorf("%w: time range starts after it ends", ErrInvalidInput)
+	}
+	return nil
+}
+
+/*
+	Returns the entries newest first, the cursor is the next_cursor of the previous page.
+	pageSize 0 means DefaultPageSize.
+*/
+func (s *auditService) GetAuditEntries(auditFilter *dto.AuditFilter, cursor string, pageSize int) (*dto.AuditPage, error) {
+	if err := prepareAuditFilter(auditFilter); err != nil {
+		return nil, err
+	}
+
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize < 0 || pageSize > MaxPageSize {
+		return nil, fmt.Errorf("%w: page size must be between 1 and %d", ErrInvalidInput, MaxPageSize)
+	}
+
+	// The cursor is the id of the last entry of the previous page
+	var beforeID int64
+	if cursor != "" {
+		var err error
+		beforeID, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil || beforeID <= 0 {
+			return nil, fmt.Errorf("%w: invalid cursor", ErrInvalidInput)
+		}
+	}
+
+	// One more entry than the page size tells whether there is a next page
+	entries, err := s.auditRepository.GetAuditEntries(auditFilter, beforeID, pageSize+1)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get audit entries: "+err.Error(), "ERROR")
+		return nil, err
+	}
+
+	page := &dto.AuditPage{Entries: entries}
+	if len(entries) > pageSize {
+		page.Entries = entries[:pageSize]
+		page.NextCursor = strconv.FormatInt(page.Entries[pageSize-1].ID, 10)
+	}
+	if page.Entries == nil {
+		page.Entries = []domain.AuditEntry{}
+	}
+
+	return page, nil
+}
+
+// The columns of the CSV export, changes holds the JSON of the changed fields
+var auditColumns = []string{"id", "time", "actor_id", "actor_name", "actor_role", "request_id", "action", "source", "server_id", "changes"}
+
+/*
+	Streams the matching entries in the order they were written, as csv, json or ndjson.
+	Nothing is written when the filter or the format is invalid.
+*/
+func (s *auditService) ExportAuditEntries(w io.Writer, auditFilter *dto.AuditFilter, format string) error {
+	if err := prepareAuditFilter(auditFilter); err != nil {
+		return err
+	}
+
+	var encode func(entry domain.AuditEntry) error
+	finish := func() error { return nil }
+
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(auditColumns); err != nil {
+			return err
+		}
+		encode = func(entry domain.AuditEntry) error {
+			changes, err := json.Marshal(entry.Changes)
+			if err != nil {
+				return err
+			}
+			return writer.Write([]string{
+				strconv.FormatInt(entry.ID, 10),
+				entry.Time.UTC().Format(time.RFC3339Nano),
+				entry.ActorID,
+				entry.ActorName,
+				entry.ActorRole,
+				entry.RequestID,
+				string(entry.Action),
+				string(entry.Source),
+				entry.ServerID,
+				string(changes),
+			})
+		}
+		finish = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	case FormatJSON:
+		// Written as an array, an element at a time
+		encoder := &jsonEncoder{w: w, encoder: json.NewEncoder(w)}
+		encode = func(entry domain.AuditEntry) error { return encoder.encode(entry) }
+		finish = encoder.Finish
+	case FormatNDJSON:
+		encoder := json.NewEncoder(w)
+		encode = func(entry domain.AuditEntry) error { return encoder.Encode(entry) }
+	default:
+		return fmt.Errorf("%w: unknown audit export format %q", ErrInvalidInput, format)
+	}
+
+	count := 0
+	err := s.auditRepository.StreamAuditEntries(auditFilter, func(entry domain.AuditEntry) error {
+		count++
+		return encode(entry)
+	})
+	if err == nil {
+		err = finish()
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to export audit entries: "+err.Error(), "ERROR")
+		return err
+	}
+
+	logging.LogMessage("server_administration_service", "Exported "+strconv.Itoa(count)+" audit entries", "INFO")
+	return nil
+}
@@ -0,0 +1,186 @@
+package service_test
+
+import (
+	"bytes"
+	"errors"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/service"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type mockAuditRepo struct {
+	mock.Mock
+}
+
+func (m *mockAuditRepo) GetAuditEntries(auditFilter *dto.AuditFilter, beforeID int64, limit int) ([]domain.AuditEntry, error) {
+	args := m.Called(auditFilter, beforeID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.AuditEntry), args.Error(1)
+}
+
+// StreamAuditEntries passes the entries it is given to fn, then returns its error
+func (m *mockAuditRepo) StreamAuditEntries(auditFilter *dto.AuditFilter, fn func(entry domain.AuditEntry) error) error {
+	args := m.Called(auditFilter)
+	if entries, ok := args.Get(0).([]domain.AuditEntry); ok {
+		for _, entry := range entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func TestGetAuditEntries_Pages(t *testing.T) {
+	mockRepo := new(mockAuditRepo)
+	auditService := service.NewAuditService(mockRepo)
+
+	filter := &dto.AuditFilter{ServerID: "srv-001"}
+	entries := []domain.AuditEntry{{ID: 9}, {ID: 8}, {ID: 7}}
+
+	// One entry more than the page size means there is a next page
+	mockRepo.On("GetAuditEntries", filter, int64(10), 3).Return(entries, nil)
+
+	page, err := auditService.GetAuditEntries(filter, "10", 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(page.Entries) != 2 {
+		t.Errorf("Expected 2 entries, got %d", len(page.Entries))
+	}
+	if page.NextCursor != "8" {
+		t.Errorf("Expected next cursor 8, got %q", page.NextCursor)
+	}
+
+	// The last page has no next cursor
+	mockRepo.On("GetAuditEntries", filter, int64(8), 3).Return(entries[2:], nil)
+
+	page, err = auditService.GetAuditEntries(filter, page.NextCursor, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(page.Entries) != 1 || page.NextCursor != "" {
+		t.Errorf("Expected a last page of 1 entry, got %d entries and cursor %q", len(page.Entries), page.NextCursor)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetAuditEntries_Empty(t *testing.T) {
+	mockRepo := new(mockAuditRepo)
+	auditService := service.NewAuditService(mockRepo)
+
+	filter := &dto.AuditFilter{}
+	mockRepo.On("GetAuditEntries", filter, int64(0), service.DefaultPageSize+1).Return(nil, nil)
+
+	page, err := auditService.GetAuditEntries(filter, "", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if page.Entries == nil || len(page.Entries) != 0 {
+		t.Errorf("Expected an empty list of entries, got %v", page.Entries)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetAuditEntries_InvalidInput(t *testing.T) {
+	mockRepo := new(mockAuditRepo)
+	auditService := service.NewAuditService(mockRepo)
+
+	from := time.Now()
+	to := from.Add(-time.Hour)
+
+	cases := []struct {
+		name     string
+		filter   *dto.AuditFilter
+		cursor   string
+		pageSize int
+	}{
+		{"unknown action", &dto.AuditFilter{Action: "rename"}, "", 0},
+		{"unknown source", &dto.AuditFilter{Source: "grpc"}, "", 0},
+		{"reversed time range", &dto.AuditFilter{Time: dto.TimeRange{From: &from, To: &to}}, "", 0},
+		{"invalid cursor", &dto.AuditFilter{}, "abc", 0},
+		{"page size too large", &dto.AuditFilter{}, "", service.MaxPageSize + 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := auditService.GetAuditEntries(c.filter, c.cursor, c.pageSize)
+			if !errors.Is(err, service.ErrInvalidInput) {
+				t.Errorf("Expected ErrInvalidInput, got %v", err)
+			}
+		})
+	}
+	mockRepo.AssertNotCalled(t, "GetAuditEntries", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExportAuditEntries_CSV(t *testing.T) {
+	mockRepo := new(mockAuditRepo)
+	auditService := service.NewAuditService(mockRepo)
+
+	filter := &dto.AuditFilter{ActorID: "user-1"}
+	entries := []domain.AuditEntry{{
+		ID:        1,
+		Time:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		ActorID:   "user-1",
+		ActorName: "alice",
+		ActorRole: "admin",
+		RequestID: "req-1",
+		Action:    domain.AuditUpdate,
+		Source:    domain.AuditSourceAPI,
+		ServerID:  "srv-001",
+		Changes:   map[string]domain.FieldChange{"port": {Before: 8080, After: 9090}},
+	}}
+	mockRepo.On("StreamAuditEntries", filter).Return(entries, nil)
+
+	var buf bytes.Buffer
+	if err := auditService.ExportAuditEntries(&buf, filter, service.FormatCSV); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "id,time,actor_id,actor_name,actor_role,request_id,action,source,server_id,changes\n" +
+		`1,2024-05-01T12:00:00Z,user-1,alice,admin,req-1,update,api,srv-001,"{""port"":{""before"":8080,""after"":9090}}"` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected CSV export:\n%s", buf.String())
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestExportAuditEntries_UnknownFormat(t *testing.T) {
+	mockRepo := new(mockAuditRepo)
+	auditService := service.NewAuditService(mockRepo)
+
+	var buf bytes.Buffer
+	err := auditService.ExportAuditEntries(&buf, &dto.AuditFilter{}, "xml")
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing written, got %q", buf.String())
+	}
+	mockRepo.AssertNotCalled(t, "StreamAuditEntries", mock.Anything)
+}
+
+func TestExportAuditEntries_JSON(t *testing.T) {
+	mockRepo := new(mockAuditRepo)
+	auditService := service.NewAuditService(mockRepo)
+
+	filter := &dto.AuditFilter{}
+	mockRepo.On("StreamAuditEntries", filter).Return([]domain.AuditEntry{{ID: 1, ServerID: "srv-001"}, {ID: 2, ServerID: "srv-002"}}, nil)
+
+	var buf bytes.Buffer
+	if err := auditService.ExportAuditEntries(&buf, filter, service.FormatJSON); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := strings.TrimSpace(buf.String())
+	if !strings.HasPrefix(output, "[") || !strings.HasSuffix(output, "]") || strings.Count(output, `"server_id"`) != 2 {
+		t.Errorf("Expected a JSON array of 2 entries, got %s", output)
+	}
+}
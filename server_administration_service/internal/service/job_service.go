@@ -0,0 +1,388 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/repository"
+	"sync"
+	"time"
+
+	"github.com/flashhhhh/pkg/logging"
+)
+
+const (
+	// How often the worker looks for jobs queued by another replica, requeues stale jobs and deletes old ones
+	jobPollInterval = 5 * time.Second
+	// How often a running job saves its progress and checks whether it was canceled
+	jobHeartbeatInterval = 5 * time.Second
+	// A running job whose worker didn't report for that long is requeued
+	jobStaleAfter = time.Minute
+	// Finished jobs and their files are deleted after that
+	jobRetention = 24 * time.Hour
+)
+
+/*
+	JobService runs imports and exports in the background. A job is stored in Postgres as soon
+	as it is submitted, then Run claims it, runs it and stores its result. Jobs survive a restart:
+	a job left running by a stopped process is requeued once it is stale.
+*/
+type JobService interface {
+	SubmitImport(ctx context.Context, buf []byte, options dto.ImportOptions) (*domain.Job, error)
+	SubmitExport(ctx context.Context, request dto.ExportRequest) (*domain.Job, error)
+	GetJob(id string) (*domain.Job, error)
+	CancelJob(id string) (*domain.Job, error)
+	WriteJobResult(w io.Writer, id string) error
+
+	Run(ctx context.Context)
+}
+
+type jobService struct {
+	jobRepository repository.JobRepository
+	serverService ServerService
+	// Wakes Run up when a job is submitted through this process
+	wake chan struct{}
+
+	mu sync.Mutex
+	// Stops the jobs running in this process, by job ID
+	cancels map[string]context.CancelFunc
+}
+
+func NewJobService(jobRepository repository.JobRepository, serverService ServerService) JobService {
+	return &jobService{
+		jobRepository: jobRepository,
+		serverService: serverService,
+		wake:          make(chan struct{}, 1),
+		cancels:       make(map[string]context.CancelFunc),
+	}
+}
+
+// The actor of ctx is recorded as the author of the changes made by the import
+func (s *jobService) SubmitImport(ctx context.Context, buf []byte, options dto.ImportOptions) (*domain.Job, error) {
+	if len(buf) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidInput)
+	}
+
+	mode, err := importMode(options.Mode)
+	if err != nil {
+		return nil, err
+	}
+	options.Mode = mode
+
+	return s.submit(ctx, domain.JobImport, options, buf)
+}
+
+// SubmitExport checks the request right away, a job only fails on errors found in the data
+func (s *jobService) SubmitExport(ctx context.Context, request dto.ExportRequest) (*domain.Job, error) {
+	if request.Filter == nil {
+		request.Filter = &dto.ServerFilter{Port: -1}
+	}
+	if err := prepareFilter(request.Filter); err != nil {
+		return nil, err
+	}
+	if _, err := prepareSort(request.SortFields); err != nil {
+		return nil, err
+	}
+
+	if request.Format == "" {
+		request.Format = FormatXLSX
+	}
+	if _, ok := formatMediaTypes[request.Format]; !ok {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidInput, request.Format)
+	}
+
+	return s.submit(ctx, domain.JobExport, request, nil)
+}
+
+func (s *jobService) submit(ctx context.Context, jobType domain.JobType, parameters interface{}, input []byte) (*domain.Job, error) {
+	parametersJSON, err := json.Marshal(parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &domain.Job{
+		ID:         rand.Text(),
+		Type:       jobType,
+		Status:     domain.JobQueued,
+		Parameters: string(parametersJSON),
+	}
+	actor := domain.ActorFromContext(ctx)
+	job.ActorID, job.ActorName, job.ActorRole = actor.ID, actor.Name, actor.Role
+	job.RequestID = domain.RequestIDFromContext(ctx)
+	if err := s.jobRepository.CreateJob(job, input); err != nil {
+		logging.LogMessage("server_administration_service", "Failed to create "+string(jobType)+" job: "+err.Error(), "ERROR")
+		return nil, err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	logging.LogMessage("server_administration_service", fmt.Sprintf("%s job %s queued", jobType, job.ID), "INFO")
+	return job, nil
+}
+
+func (s *jobService) GetJob(id string) (*domain.Job, error) {
+	return s.jobRepository.GetJob(id)
+}
+
+func (s *jobService) CancelJob(id string) (*domain.Job, error) {
+	job, err := s.jobRepository.CancelJob(id)
+	if err != nil {
+		return job, err
+	}
+
+	// A job running in another process stops at its next heartbeat
+	s.mu.Lock()
+	if cancel, ok := s.cancels[id]; ok {
+		cancel()
+	}
+	s.mu.Unlock()
+
+	logging.LogMessage("server_administration_service", "Job "+id+" canceled", "INFO")
+	return job, nil
+}
+
+func (s *jobService) WriteJobResult(w io.Writer, id string) error {
+	return s.jobRepository.ReadJobFile(id, domain.JobResult, func(data []byte) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+/*
+	Run is the worker loop, it runs the queued jobs one at a time until ctx is done.
+	Several processes may run it, each job is claimed by a single one.
+*/
+func (s *jobService) Run(ctx context.Context) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	s.cleanUp()
+	for {
+		for s.runNextJob(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+			s.cleanUp()
+		}
+	}
+}
+
+func (s *jobService) cleanUp() {
+	requeued, err := s.jobRepository.RequeueStaleJobs(time.Now().Add(-jobStaleAfter))
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to requeue stale jobs: "+err.Error(), "ERROR")
+	} else if requeued > 0 {
+		logging.LogMessage("server_administration_service", fmt.Sprintf("Requeued %d stale jobs", requeued), "WARN")
+	}
+
+	if _, err := s.jobRepository.DeleteJobsFinishedBefore(time.Now().Add(-jobRetention)); err != nil {
+		logging.LogMessage("server_administration_service", "Failed to delete old jobs: "+err.Error(), "ERROR")
+	}
+}
+
+// runNextJob runs the oldest queued job, false when there is none
+func (s *jobService) runNextJob(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	job, err := s.jobRepository.ClaimJob()
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to claim a job: "+err.Error(), "ERROR")
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	s.runJob(ctx, job)
+	return true
+}
+
+func (s *jobService) runJob(ctx context.Context, job *domain.Job) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.mu.Lock()
+	s.cancels[job.ID] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.cancels, job.ID)
+		s.mu.Unlock()
+	}()
+
+	logging.LogMessage("server_administration_service", fmt.Sprintf("Running %s job %s", job.Type, job.ID), "INFO")
+
+	progress := &jobProgress{}
+	stopHeartbeat := s.heartbeat(job.ID, progress, cancel)
+	report := func(processed, total int) error {
+		progress.set(processed, total)
+		return jobCtx.Err()
+	}
+
+	var err error
+	switch job.Type {
+	case domain.JobImport:
+		err = s.runImport(job.Context(jobCtx), job, report)
+	case domain.JobExport:
+		err = s.runExport(job, report)
+	default:
+		err = fmt.Errorf("unknown job type %q", job.Type)
+	}
+	stopHeartbeat()
+
+	job.Processed, job.Total = progress.get()
+	switch {
+	case err == nil:
+		job.Status = domain.JobSucceeded
+	case ctx.Err() != nil:
+		// The process is stopping, the job is requeued once stale
+		logging.LogMessage("server_administration_service", "Job "+job.ID+" interrupted: "+err.Error(), "WARN")
+		return
+	case jobCtx.Err() != nil:
+		job.Status = domain.JobCanceled
+	case errors.Is(err, ErrInvalidInput):
+		job.Status = domain.JobFailed
+		job.Error = err.Error()
+	default:
+		logging.LogMessage("server_administration_service", "Job "+job.ID+" failed: "+err.Error(), "ERROR")
+		job.Status = domain.JobFailed
+		job.Error = fmt.Sprintf("Failed to %s servers", job.Type)
+	}
+
+	finishedTime := time.Now()
+	job.FinishedTime = &finishedTime
+	if err := s.jobRepository.FinishJob(job); err != nil {
+		logging.LogMessage("server_administration_service", "Failed to finish job "+job.ID+": "+err.Error(), "ERROR")
+		return
+	}
+
+	logging.LogMessage("server_administration_service", fmt.Sprintf("%s job %s %s", job.Type, job.ID, job.Status), "INFO")
+}
+
+// heartbeat saves the progress of a running job until the returned function is called
+func (s *jobService) heartbeat(id string, progress *jobProgress, cancel context.CancelFunc) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(jobHeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			processed, total := progress.get()
+			running, err := s.jobRepository.UpdateJobProgress(id, processed, total)
+			if err != nil {
+				logging.LogMessage("server_administration_service", "Failed to save the progress of job "+id+": "+err.Error(), "WARN")
+				continue
+			}
+			// Canceled through another process
+			if !running {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (s *jobService) runImport(ctx context.Context, job *domain.Job, progress dto.ProgressFunc) error {
+	var options dto.ImportOptions
+	if err := json.Unmarshal([]byte(job.Parameters), &options); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err := s.jobRepository.ReadJobFile(job.ID, domain.JobInput, func(data []byte) error {
+		buf.Write(data)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	options.Progress = progress
+	report, err := s.serverService.ImportServers(ctx, buf.Bytes(), options)
+	if err != nil {
+		return err
+	}
+
+	return s.writeResult(job, "import_report_"+job.ID+".json", "application/json", func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(report)
+	})
+}
+
+func (s *jobService) runExport(job *domain.Job, progress dto.ProgressFunc) error {
+	var request dto.ExportRequest
+	if err := json.Unmarshal([]byte(job.Parameters), &request); err != nil {
+		return err
+	}
+
+	filename := "servers_" + job.CreatedTime.Format("2006-01-02_15-04-05") + "." + request.Format
+	return s.writeResult(job, filename, formatMediaTypes[request.Format], func(w io.Writer) error {
+		return s.serverService.ExportServers(w, request.Filter, request.SortFields, dto.ExportOptions{Format: request.Format, Progress: progress})
+	})
+}
+
+func (s *jobService) writeResult(job *domain.Job, name, mediaType string, write func(w io.Writer) error) error {
+	file, err := s.jobRepository.CreateJobFile(job.ID, domain.JobResult)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	job.ResultName = name
+	job.ResultType = mediaType
+	return nil
+}
+
+// jobProgress is written by the job and read by its heartbeat
+type jobProgress struct {
+	mu        sync.Mutex
+	processed int
+	total     int
+}
+
+func (p *jobProgress) set(processed, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.processed = processed
+	p.total = total
+}
+
+func (p *jobProgress) get() (int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.processed, p.total
+}
@@ -0,0 +1,285 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/repository"
+	"server_administration_service/internal/service"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type mockJobRepo struct {
+	mock.Mock
+}
+
+func (m *mockJobRepo) CreateJob(job *domain.Job, input []byte) error {
+	args := m.Called(job, input)
+	return args.Error(0)
+}
+
+func (m *mockJobRepo) GetJob(id string) (*domain.Job, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Job), args.Error(1)
+}
+
+func (m *mockJobRepo) CancelJob(id string) (*domain.Job, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Job), args.Error(1)
+}
+
+func (m *mockJobRepo) ClaimJob() (*domain.Job, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Job), args.Error(1)
+}
+
+func (m *mockJobRepo) UpdateJobProgress(id string, processed, total int) (bool, error) {
+	args := m.Called(id, processed, total)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockJobRepo) FinishJob(job *domain.Job) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *mockJobRepo) RequeueStaleJobs(before time.Time) (int, error) {
+	args := m.Called(before)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockJobRepo) DeleteJobsFinishedBefore(before time.Time) (int, error) {
+	args := m.Called(before)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockJobRepo) CreateJobFile(id, kind string) (io.WriteCloser, error) {
+	args := m.Called(id, kind)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(io.WriteCloser), args.Error(1)
+}
+
+// ReadJobFile passes the bytes it is given to fn as a single chunk
+func (m *mockJobRepo) ReadJobFile(id, kind string, fn func(data []byte) error) error {
+	args := m.Called(id, kind)
+	if data, ok := args.Get(0).([]byte); ok {
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+type resultFile struct {
+	bytes.Buffer
+}
+
+func (f *resultFile) Close() error {
+	return nil
+}
+
+func TestSubmitImportJob(t *testing.T) {
+	mockRepo := new(mockJobRepo)
+	jobService := service.NewJobService(mockRepo, service.NewServerService(new(mockServerRepo)))
+
+	buf := []byte("server_id,server_name,ipv4\nsrv-1,Server One,10.0.0.1\n")
+	mockRepo.On("CreateJob", mock.MatchedBy(func(job *domain.Job) bool {
+		return job.ID != "" && job.Type == domain.JobImport && job.Status == domain.JobQueued &&
+			job.Parameters == `{"mode":"insert","dry_run":true}` &&
+			job.ActorID == "user-1" && job.ActorName == "alice" && job.RequestID == "req-1"
+	}), buf).Return(nil)
+
+	// The job records who submitted it, the import then runs on their behalf
+	ctx := domain.ContextWithActor(context.Background(), domain.Actor{ID: "user-1", Name: "alice", Role: "admin"})
+	ctx = domain.ContextWithRequestID(ctx, "req-1")
+
+	job, err := jobService.SubmitImport(ctx, buf, dto.ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if job.Status != domain.JobQueued {
+		t.Errorf("Expected a queued job, got %s", job.Status)
+	}
+	mockRepo.AssertExpectations(t)
+
+	if _, err := jobService.SubmitImport(context.Background(), buf, dto.ImportOptions{Mode: "merge"}); !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for an invalid mode, got %v", err)
+	}
+	if _, err := jobService.SubmitImport(context.Background(), nil, dto.ImportOptions{}); !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for an empty file, got %v", err)
+	}
+	mockRepo.AssertNumberOfCalls(t, "CreateJob", 1)
+}
+
+func TestSubmitExportJob(t *testing.T) {
+	mockRepo := new(mockJobRepo)
+	jobService := service.NewJobService(mockRepo, service.NewServerService(new(mockServerRepo)))
+
+	mockRepo.On("CreateJob", mock.MatchedBy(func(job *domain.Job) bool {
+		var request dto.ExportRequest
+		if err := json.Unmarshal([]byte(job.Parameters), &request); err != nil {
+			return false
+		}
+		return job.Type == domain.JobExport && request.Format == service.FormatXLSX &&
+			request.Filter.Statuses[0] == "Up" && request.Filter.Port == -1
+	}), []byte(nil)).Return(nil)
+
+	_, err := jobService.SubmitExport(context.Background(), dto.ExportRequest{Filter: &dto.ServerFilter{Statuses: []string{"up"}, Port: -1}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	mockRepo.AssertExpectations(t)
+
+	invalidRequests := []dto.ExportRequest{
+		{Format: "pdf"},
+		{SortFields: []dto.SortField{{Column: "password"}}},
+		{Filter: &dto.ServerFilter{Statuses: []string{"Sleeping"}}},
+	}
+	for _, request := range invalidRequests {
+		if _, err := jobService.SubmitExport(context.Background(), request); !errors.Is(err, service.ErrInvalidInput) {
+			t.Errorf("Expected ErrInvalidInput for %+v, got %v", request, err)
+		}
+	}
+	mockRepo.AssertNumberOfCalls(t, "CreateJob", 1)
+}
+
+func TestCancelJob_Finished(t *testing.T) {
+	mockRepo := new(mockJobRepo)
+	jobService := service.NewJobService(mockRepo, service.NewServerService(new(mockServerRepo)))
+
+	mockRepo.On("CancelJob", "job-1").Return(&domain.Job{ID: "job-1", Status: domain.JobSucceeded}, repository.ErrJobFinished)
+
+	job, err := jobService.CancelJob("job-1")
+	if !errors.Is(err, repository.ErrJobFinished) {
+		t.Errorf("Expected ErrJobFinished, got %v", err)
+	}
+	if job == nil || job.Status != domain.JobSucceeded {
+		t.Errorf("Expected the finished job, got %+v", job)
+	}
+}
+
+// runJobs runs the worker until the job it claims is finished, and returns the finished job
+func runJobs(t *testing.T, mockRepo *mockJobRepo, jobService service.JobService, job *domain.Job) *domain.Job {
+	finished := make(chan *domain.Job, 1)
+
+	mockRepo.On("RequeueStaleJobs", mock.Anything).Return(0, nil)
+	mockRepo.On("DeleteJobsFinishedBefore", mock.Anything).Return(0, nil)
+	mockRepo.On("ClaimJob").Return(job, nil).Once()
+	mockRepo.On("ClaimJob").Return(nil, nil)
+	mockRepo.On("FinishJob", mock.Anything).Run(func(args mock.Arguments) {
+		finished <- args.Get(0).(*domain.Job)
+	}).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go jobService.Run(ctx)
+
+	select {
+	case finishedJob := <-finished:
+		return finishedJob
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the job to finish")
+		return nil
+	}
+}
+
+func TestRunJobs_Export(t *testing.T) {
+	mockRepo := new(mockJobRepo)
+	mockServerRepository := new(mockServerRepo)
+	jobService := service.NewJobService(mockRepo, service.NewServerService(mockServerRepository))
+
+	job := &domain.Job{
+		ID:          "job-1",
+		Type:        domain.JobExport,
+		Status:      domain.JobRunning,
+		Parameters:  `{"filter":{"port":-1},"sort_fields":null,"format":"csv"}`,
+		CreatedTime: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	servers := []domain.Server{
+		importedServer(1, "srv-1", "Server One", domain.StatusUp, "10.0.0.1", 80),
+		importedServer(2, "srv-2", "Server Two", domain.StatusDown, "10.0.0.2", 80),
+	}
+
+	result := &resultFile{}
+	mockRepo.On("CreateJobFile", "job-1", domain.JobResult).Return(result, nil)
+	mockServerRepository.On("CountServers", mock.Anything).Return(2, nil)
+	mockServerRepository.On("StreamServers", mock.Anything, []dto.SortField{{Column: "id", Order: "asc"}}).Return(servers, nil)
+
+	finishedJob := runJobs(t, mockRepo, jobService, job)
+
+	if finishedJob.Status != domain.JobSucceeded {
+		t.Fatalf("Expected the job to succeed, got %s: %s", finishedJob.Status, finishedJob.Error)
+	}
+	if finishedJob.Processed != 2 || finishedJob.Total != 2 {
+		t.Errorf("Expected 2 of 2 servers processed, got %d of %d", finishedJob.Processed, finishedJob.Total)
+	}
+	if finishedJob.ResultName != "servers_2025-01-02_03-04-05.csv" || finishedJob.ResultType != "text/csv" {
+		t.Errorf("Expected a CSV result, got %s (%s)", finishedJob.ResultName, finishedJob.ResultType)
+	}
+	if lines := strings.Split(strings.TrimSpace(result.String()), "\n"); len(lines) != 3 {
+		t.Errorf("Expected a header and 2 servers, got %q", result.String())
+	}
+}
+
+func TestRunJobs_ImportFailed(t *testing.T) {
+	mockRepo := new(mockJobRepo)
+	jobService := service.NewJobService(mockRepo, service.NewServerService(new(mockServerRepo)))
+
+	job := &domain.Job{ID: "job-1", Type: domain.JobImport, Status: domain.JobRunning, Parameters: `{"mode":"insert","dry_run":false}`}
+	mockRepo.On("ReadJobFile", "job-1", domain.JobInput).Return([]byte("server_id,status\nsrv-1,Up\n"), nil)
+
+	finishedJob := runJobs(t, mockRepo, jobService, job)
+
+	if finishedJob.Status != domain.JobFailed {
+		t.Fatalf("Expected the job to fail, got %s", finishedJob.Status)
+	}
+	if !strings.Contains(finishedJob.Error, "missing columns Server Name, IPv4") {
+		t.Errorf("Expected the invalid file to be reported, got %q", finishedJob.Error)
+	}
+	if finishedJob.FinishedTime == nil {
+		t.Errorf("Expected a finished time")
+	}
+	mockRepo.AssertNotCalled(t, "CreateJobFile", mock.Anything, mock.Anything)
+}
+
+func TestRunJobs_Canceled(t *testing.T) {
+	mockRepo := new(mockJobRepo)
+	mockServerRepository := new(mockServerRepo)
+	jobService := service.NewJobService(mockRepo, service.NewServerService(mockServerRepository))
+
+	job := &domain.Job{ID: "job-1", Type: domain.JobImport, Status: domain.JobRunning, Parameters: `{"mode":"upsert","dry_run":false}`}
+	mockRepo.On("CancelJob", "job-1").Return(&domain.Job{ID: "job-1", Status: domain.JobCanceled}, nil)
+	// Canceled while the file is read
+	mockRepo.On("ReadJobFile", "job-1", domain.JobInput).Run(func(args mock.Arguments) {
+		if _, err := jobService.CancelJob("job-1"); err != nil {
+			t.Errorf("Expected no error canceling the job, got %v", err)
+		}
+	}).Return(createImportBuffer([][]interface{}{{"srv-1", "Server One", "Up", "10.0.0.1", 80}}), nil)
+
+	finishedJob := runJobs(t, mockRepo, jobService, job)
+
+	if finishedJob.Status != domain.JobCanceled {
+		t.Errorf("Expected the job to be canceled, got %s", finishedJob.Status)
+	}
+	mockServerRepository.AssertNotCalled(t, "FindServers", mock.Anything, mock.Anything)
+	mockServerRepository.AssertNotCalled(t, "ApplyServerImport", mock.Anything)
+}
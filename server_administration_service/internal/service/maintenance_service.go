@@ -0,0 +1,178 @@
+package service
+
+import (
+	"fmt"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/repository"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flashhhhh/pkg/logging"
+)
+
+/*
+	MaintenanceService manages the maintenance windows and tells which servers are in one.
+	The ids of the servers under maintenance are cached for cacheTTL, so a window created
+	through another process (REST vs Kafka consumer) takes effect within that delay.
+*/
+type MaintenanceService interface {
+	CreateWindow(window *domain.MaintenanceWindow) error
+	GetWindows(activeOnly bool) ([]domain.MaintenanceWindow, error)
+	GetWindow(id int) (*domain.MaintenanceWindow, error)
+	UpdateWindow(window *domain.MaintenanceWindow) error
+	DeleteWindow(id int) error
+
+	IsInMaintenance(id int) (bool, error)
+	GetServersInMaintenance() ([]int, error)
+}
+
+type maintenanceService struct {
+	maintenanceRepository repository.MaintenanceRepository
+	cacheTTL              time.Duration
+
+	mu        sync.Mutex
+	activeIDs map[int]bool
+	expiresAt time.Time
+}
+
+func NewMaintenanceService(maintenanceRepository repository.MaintenanceRepository, cacheTTL time.Duration) MaintenanceService {
+	return &maintenanceService{
+		maintenanceRepository: maintenanceRepository,
+		cacheTTL:              cacheTTL,
+	}
+}
+
+func validateWindow(window *domain.MaintenanceWindow) error {
+	window.Reason = strings.TrimSpace(window.Reason)
+	if window.Reason == "" {
+		return fmt.Errorf("%w: reason is required", ErrInvalidInput)
+	}
+
+	if len(window.ServerIDs) == 0 && !window.HasFilter() {
+		return fmt.Errorf("%w: server ids or a filter are required", ErrInvalidInput)
+	}
+
+	for _, id := range window.ServerIDs {
+		if id <= 0 {
+			return fmt.Errorf("%w: invalid server id %d", ErrInvalidInput, id)
+		}
+	}
+
+	if window.StartTime.IsZero() || window.EndTime.IsZero() {
+		return fmt.Errorf("%w: start and end time are required", ErrInvalidInput)
+	}
+	if !window.EndTime.After(window.StartTime) {
+		return fmt.Errorf("%w: end time must be after start time", ErrInvalidInput)
+	}
+
+	return nil
+}
+
+func (s *maintenanceService) CreateWindow(window *domain.MaintenanceWindow) error {
+	if err := validateWindow(window); err != nil {
+		return err
+	}
+
+	if err := s.maintenanceRepository.CreateWindow(window); err != nil {
+		logging.LogMessage("server_administration_service", "Failed to create maintenance window: "+err.Error(), "ERROR")
+		return err
+	}
+
+	s.invalidateCache()
+	logging.LogMessage("server_administration_service", fmt.Sprintf("Maintenance window %d created: %s", window.ID, window.Reason), "INFO")
+	return nil
+}
+
+func (s *maintenanceService) GetWindows(activeOnly bool) ([]domain.MaintenanceWindow, error) {
+	if activeOnly {
+		now := time.Now()
+		return s.maintenanceRepository.GetWindows(&now)
+	}
+	return s.maintenanceRepository.GetWindows(nil)
+}
+
+func (s *maintenanceService) GetWindow(id int) (*domain.MaintenanceWindow, error) {
+	return s.maintenanceRepository.GetWindow(id)
+}
+
+func (s *maintenanceService) UpdateWindow(window *domain.MaintenanceWindow) error {
+	if window.ID <= 0 {
+		return fmt.Errorf("%w: invalid maintenance window id %d", ErrInvalidInput, window.ID)
+	}
+	if err := validateWindow(window); err != nil {
+		return err
+	}
+
+	if err := s.maintenanceRepository.UpdateWindow(window); err != nil {
+		logging.LogMessage("server_administration_service", "Failed to update maintenance window: "+err.Error(), "ERROR")
+		return err
+	}
+
+	s.invalidateCache()
+	logging.LogMessage("server_administration_service", fmt.Sprintf("Maintenance window %d updated", window.ID), "INFO")
+	return nil
+}
+
+func (s *maintenanceService) DeleteWindow(id int) error {
+	if err := s.maintenanceRepository.DeleteWindow(id); err != nil {
+		logging.LogMessage("server_administration_service", "Failed to delete maintenance window: "+err.Error(), "ERROR")
+		return err
+	}
+
+	s.invalidateCache()
+	logging.LogMessage("server_administration_service", fmt.Sprintf("Maintenance window %d deleted", id), "INFO")
+	return nil
+}
+
+func (s *maintenanceService) IsInMaintenance(id int) (bool, error) {
+	activeIDs, err := s.loadActiveIDs()
+	if err != nil {
+		return false, err
+	}
+
+	return activeIDs[id], nil
+}
+
+func (s *maintenanceService) GetServersInMaintenance() ([]int, error) {
+	activeIDs, err := s.loadActiveIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(activeIDs))
+	for id := range activeIDs {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (s *maintenanceService) loadActiveIDs() (map[int]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.activeIDs != nil && now.Before(s.expiresAt) {
+		return s.activeIDs, nil
+	}
+
+	ids, err := s.maintenanceRepository.GetActiveServerIDs(now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.activeIDs = make(map[int]bool, len(ids))
+	for _, id := range ids {
+		s.activeIDs[id] = true
+	}
+	s.expiresAt = now.Add(s.cacheTTL)
+
+	return s.activeIDs, nil
+}
+
+func (s *maintenanceService) invalidateCache() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.activeIDs = nil
+}
@@ -0,0 +1,160 @@
+package service_test
+
+import (
+	"errors"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type mockMaintenanceRepo struct {
+	mock.Mock
+}
+
+func (m *mockMaintenanceRepo) CreateWindow(window *domain.MaintenanceWindow) error {
+	args := m.Called(window)
+	return args.Error(0)
+}
+
+func (m *mockMaintenanceRepo) GetWindows(activeAt *time.Time) ([]domain.MaintenanceWindow, error) {
+	args := m.Called(activeAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.MaintenanceWindow), args.Error(1)
+}
+
+func (m *mockMaintenanceRepo) GetWindow(id int) (*domain.MaintenanceWindow, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.MaintenanceWindow), args.Error(1)
+}
+
+func (m *mockMaintenanceRepo) UpdateWindow(window *domain.MaintenanceWindow) error {
+	args := m.Called(window)
+	return args.Error(0)
+}
+
+func (m *mockMaintenanceRepo) DeleteWindow(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockMaintenanceRepo) GetActiveServerIDs(at time.Time) ([]int, error) {
+	args := m.Called(at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func TestCreateMaintenanceWindow_Validation(t *testing.T) {
+	start := time.Unix(1000, 0)
+	end := time.Unix(2000, 0)
+
+	tests := []struct {
+		name   string
+		window domain.MaintenanceWindow
+	}{
+		{"Missing reason", domain.MaintenanceWindow{ServerIDs: []int{1}, StartTime: start, EndTime: end, Reason: "  "}},
+		{"No servers", domain.MaintenanceWindow{StartTime: start, EndTime: end, Reason: "Reboot"}},
+		{"Invalid server id", domain.MaintenanceWindow{ServerIDs: []int{0}, StartTime: start, EndTime: end, Reason: "Reboot"}},
+		{"Missing start time", domain.MaintenanceWindow{ServerIDs: []int{1}, EndTime: end, Reason: "Reboot"}},
+		{"End before start", domain.MaintenanceWindow{ServerIDs: []int{1}, StartTime: end, EndTime: start, Reason: "Reboot"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockMaintenanceRepo)
+			maintenanceService := service.NewMaintenanceService(mockRepo, time.Minute)
+
+			err := maintenanceService.CreateWindow(&tt.window)
+			if !errors.Is(err, service.ErrInvalidInput) {
+				t.Errorf("Expected ErrInvalidInput, got %v", err)
+			}
+			mockRepo.AssertNotCalled(t, "CreateWindow", mock.Anything)
+		})
+	}
+}
+
+func TestCreateMaintenanceWindow_WithFilter(t *testing.T) {
+	mockRepo := new(mockMaintenanceRepo)
+	maintenanceService := service.NewMaintenanceService(mockRepo, time.Minute)
+
+	window := &domain.MaintenanceWindow{
+		IPv4Prefix: "10.0.",
+		StartTime:  time.Unix(1000, 0),
+		EndTime:    time.Unix(2000, 0),
+		Reason:     "Switch replacement",
+	}
+	mockRepo.On("CreateWindow", window).Return(nil)
+
+	err := maintenanceService.CreateWindow(window)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestIsInMaintenance_CachesActiveServers(t *testing.T) {
+	mockRepo := new(mockMaintenanceRepo)
+	maintenanceService := service.NewMaintenanceService(mockRepo, time.Minute)
+
+	mockRepo.On("GetActiveServerIDs", mock.Anything).Return([]int{2, 5}, nil).Once()
+
+	for id, expected := range map[int]bool{2: true, 3: false, 5: true} {
+		inMaintenance, err := maintenanceService.IsInMaintenance(id)
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if inMaintenance != expected {
+			t.Errorf("Expected server %d in maintenance to be %v, got %v", id, expected, inMaintenance)
+		}
+	}
+
+	mockRepo.AssertNumberOfCalls(t, "GetActiveServerIDs", 1)
+}
+
+func TestIsInMaintenance_ReloadsAfterChange(t *testing.T) {
+	mockRepo := new(mockMaintenanceRepo)
+	maintenanceService := service.NewMaintenanceService(mockRepo, time.Minute)
+
+	mockRepo.On("GetActiveServerIDs", mock.Anything).Return([]int{}, nil).Once()
+	mockRepo.On("DeleteWindow", 1).Return(nil)
+	mockRepo.On("GetActiveServerIDs", mock.Anything).Return([]int{7}, nil).Once()
+
+	inMaintenance, _ := maintenanceService.IsInMaintenance(7)
+	if inMaintenance {
+		t.Errorf("Expected server 7 not to be in maintenance")
+	}
+
+	if err := maintenanceService.DeleteWindow(1); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	inMaintenance, _ = maintenanceService.IsInMaintenance(7)
+	if !inMaintenance {
+		t.Errorf("Expected server 7 to be in maintenance after the cache was invalidated")
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestIsInMaintenance_RepositoryError(t *testing.T) {
+	mockRepo := new(mockMaintenanceRepo)
+	maintenanceService := service.NewMaintenanceService(mockRepo, time.Minute)
+
+	mockRepo.On("GetActiveServerIDs", mock.Anything).Return(nil, errors.New("database error"))
+
+	inMaintenance, err := maintenanceService.IsInMaintenance(1)
+	if err == nil {
+		t.Errorf("Expected an error")
+	}
+	if inMaintenance {
+		t.Errorf("Expected server not to be in maintenance on error")
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/repository"
+	"time"
+)
+
+// Location used by healthcheck_service instances that do not tag their results
+const DefaultLocation = "default"
+
+/*
+	QuorumService decides a server's status from the latest result of every probe location.
+	A status wins once at least quorum locations agree on it, results older than maxAge do
+	not vote. With a quorum of 1 every result decides, like a single location setup.
+*/
+type QuorumService interface {
+	Decide(result dto.HealthCheckResult) (status domain.ServerStatus, decided bool, err error)
+}
+
+type quorumService struct {
+	serverRepository repository.ServerRepository
+	quorum           int
+	maxAge           time.Duration
+}
+
+func NewQuorumService(serverRepository repository.ServerRepository, quorum int, maxAge time.Duration) QuorumService {
+	if quorum <= 0 {
+		quorum = 1
+	}
+
+	return &quorumService{
+		serverRepository: serverRepository,
+		quorum:           quorum,
+		maxAge:           maxAge,
+	}
+}
+
+func (s *quorumService) Decide(result dto.HealthCheckResult) (domain.ServerStatus, bool, error) {
+	status := domain.StatusDown
+	if result.Status {
+		status = domain.StatusUp
+	}
+
+	location := result.Location
+	if location == "" {
+		location = DefaultLocation
+	}
+
+	checkedAt := result.CheckedAt
+	if checkedAt.IsZero() {
+		checkedAt = time.Now()
+	}
+
+	statuses, err := s.serverRepository.SetLocationStatus(result.ID, dto.LocationStatus{
+		Location:  location,
+		Status:    string(status),
+		CheckedAt: checkedAt,
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	votes := make(map[domain.ServerStatus]int)
+	for _, locationStatus := range statuses {
+		if s.maxAge > 0 && time.Since(locationStatus.CheckedAt) > s.maxAge {
+			continue
+		}
+		votes[domain.ServerStatus(locationStatus.Status)]++
+	}
+
+	// The newest result breaks the tie when both sides reach a quorum that is too small
+	if votes[status] >= s.quorum {
+		return status, true, nil
+	}
+	for candidate, count := range votes {
+		if count >= s.quorum {
+			return candidate, true, nil
+		}
+	}
+
+	return "", false, nil
+}
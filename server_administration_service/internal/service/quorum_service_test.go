@@ -0,0 +1,90 @@
+package service_test
+
+import (
+	"errors"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestQuorumDecide_WaitsForQuorum(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	quorumService := service.NewQuorumService(mockRepo, 2, 5*time.Minute)
+
+	now := time.Now()
+	mockRepo.On("SetLocationStatus", 1, mock.Anything).Return([]dto.LocationStatus{
+		{Location: "hanoi", Status: "Down", CheckedAt: now},
+		{Location: "singapore", Status: "Up", CheckedAt: now},
+		{Location: "tokyo", Status: "Up", CheckedAt: now.Add(-10 * time.Minute)},
+	}, nil)
+
+	// tokyo is stale, so a single Off location cannot take the server down
+	_, decided, err := quorumService.Decide(dto.HealthCheckResult{ID: 1, Status: false, Location: "hanoi", CheckedAt: now})
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if decided {
+		t.Errorf("Expected no decision without a quorum")
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestQuorumDecide_QuorumReached(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	quorumService := service.NewQuorumService(mockRepo, 2, 5*time.Minute)
+
+	now := time.Now()
+	mockRepo.On("SetLocationStatus", 1, dto.LocationStatus{Location: "hanoi", Status: "Down", CheckedAt: now}).Return([]dto.LocationStatus{
+		{Location: "hanoi", Status: "Down", CheckedAt: now},
+		{Location: "singapore", Status: "Down", CheckedAt: now},
+		{Location: "tokyo", Status: "Up", CheckedAt: now},
+	}, nil)
+
+	status, decided, err := quorumService.Decide(dto.HealthCheckResult{ID: 1, Status: false, Location: "hanoi", CheckedAt: now})
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if !decided || status != domain.StatusDown {
+		t.Errorf("Expected Off to be decided, got %s (decided: %v)", status, decided)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestQuorumDecide_SingleLocation(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	quorumService := service.NewQuorumService(mockRepo, 1, 5*time.Minute)
+
+	mockRepo.On("SetLocationStatus", 3, mock.MatchedBy(func(status dto.LocationStatus) bool {
+		return status.Location == service.DefaultLocation && status.Status == "Up"
+	})).Return([]dto.LocationStatus{
+		{Location: service.DefaultLocation, Status: "Up", CheckedAt: time.Now()},
+	}, nil)
+
+	status, decided, err := quorumService.Decide(dto.HealthCheckResult{ID: 3, Status: true})
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if !decided || status != domain.StatusUp {
+		t.Errorf("Expected On to be decided, got %s (decided: %v)", status, decided)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestQuorumDecide_RepositoryError(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	quorumService := service.NewQuorumService(mockRepo, 2, 5*time.Minute)
+
+	mockRepo.On("SetLocationStatus", 1, mock.Anything).Return(nil, errors.New("redis down"))
+
+	_, decided, err := quorumService.Decide(dto.HealthCheckResult{ID: 1, Status: true, Location: "hanoi"})
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+	if decided {
+		t.Errorf("Expected no decision on error")
+	}
+}
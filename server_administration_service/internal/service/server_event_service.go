@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"server_administration_service/internal/dto"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/flashhhhh/pkg/logging"
+)
+
+const (
+	ServerDownEvent      = "server_down"
+	ServerRecoveredEvent = "server_recovered"
+)
+
+// EventPublisher is satisfied by kafka.KafkaProducer
+type EventPublisher interface {
+	SendMessageWithKey(topic string, key string, message []byte) error
+}
+
+// CheckedServerLister is satisfied by repository.ServerRepository
+type CheckedServerLister interface {
+	GetCheckedServerIDs() ([]int, error)
+}
+
+type ServerEventService interface {
+	ProcessCheckResult(result dto.HealthCheckResult, checkedAt time.Time) error
+	EvictStaleWindows(ctx context.Context, maxIdle time.Duration)
+}
+
+/*
+	ValidateEventWindow checks the window settings, both thresholds must fit in the window
+	and downThreshold + upThreshold > windowSize so that a server cannot be down and up at once
+*/
+func ValidateEventWindow(windowSize, downThreshold, upThreshold int) error {
+	if windowSize < 1 {
+		return fmt.Errorf("%w: window size must be positive", ErrInvalidInput)
+	}
+	if downThreshold < 1 || downThreshold > windowSize {
+		return fmt.Errorf("%w: down threshold must be between 1 and the window size %d", ErrInvalidInput, windowSize)
+	}
+	if upThreshold < 1 || upThreshold > windowSize {
+		return fmt.Errorf("%w: up threshold must be between 1 and the window size %d", ErrInvalidInput, windowSize)
+	}
+	if downThreshold+upThreshold <= windowSize {
+		return fmt.Errorf("%w: down threshold + up threshold must be greater than the window size %d", ErrInvalidInput, windowSize)
+	}
+	return nil
+}
+
+const (
+	windowStateUnknown = iota
+	windowStateUp
+	windowStateDown
+)
+
+type serverWindow struct {
+	// Ring buffer of the latest check results, true means the check failed
+	failures []bool
+	next     int
+	filled   int
+	state    int
+	lastSeen time.Time
+}
+
+/*
+	Keeps the latest windowSize results of every server and only changes the
+	state of a server when the window clearly says so: it goes down when at least
+	downThreshold checks failed and recovers when at least upThreshold checks passed.
+	With downThreshold + upThreshold > windowSize a single dropped dial cannot flap the state.
+*/
+type serverEventService struct {
+	publisher     EventPublisher
+	servers       CheckedServerLister
+	topic         string
+	windowSize    int
+	downThreshold int
+	upThreshold   int
+
+	mu      sync.Mutex
+	windows map[int]*serverWindow
+}
+
+func NewServerEventService(publisher EventPublisher, servers CheckedServerLister, topic string, windowSize, downThreshold, upThreshold int) ServerEventService {
+	return &serverEventService{
+		publisher:     publisher,
+		servers:       servers,
+		topic:         topic,
+		windowSize:    windowSize,
+		downThreshold: downThreshold,
+		upThreshold:   upThreshold,
+		windows:       make(map[int]*serverWindow),
+	}
+}
+
+func (s *serverEventService) ProcessCheckResult(result dto.HealthCheckResult, checkedAt time.Time) error {
+	event := s.record(result, checkedAt)
+	if event == nil {
+		return nil
+	}
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	// Keying by server id keeps the events of one server ordered within a partition
+	if err := s.publisher.SendMessageWithKey(s.topic, strconv.Itoa(event.ID), message); err != nil {
+		return err
+	}
+
+	logging.LogMessage("server_administration_service", "Published "+event.Type+" event for server ID "+strconv.Itoa(event.ID), "INFO")
+	return nil
+}
+
+// record adds the result to the window of the server and returns the event to publish, if any
+func (s *serverEventService) record(result dto.HealthCheckResult, checkedAt time.Time) *dto.ServerEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	window, existed := s.windows[result.ID]
+	if !existed {
+		window = &serverWindow{failures: make([]bool, s.windowSize)}
+		s.windows[result.ID] = window
+	}
+	window.lastSeen = time.Now()
+
+	window.failures[window.next] = !result.Status
+	window.next = (window.next + 1) % s.windowSize
+	if window.filled < s.windowSize {
+		window.filled++
+	}
+
+	failedChecks := 0
+	for i := 0; i < window.filled; i++ {
+		if window.failures[i] {
+			failedChecks++
+		}
+	}
+	passedChecks := window.filled - failedChecks
+
+	var eventType string
+	switch window.state {
+	case windowStateUnknown:
+		if failedChecks >= s.downThreshold {
+			window.state = windowStateDown
+			eventType = ServerDownEvent
+		} else if passedChecks >= s.upThreshold {
+			// A server first seen healthy has nothing to recover from
+			window.state = windowStateUp
+		}
+	case windowStateUp:
+		if failedChecks >= s.downThreshold {
+			window.state = windowStateDown
+			eventType = ServerDownEvent
+		}
+	case windowStateDown:
+		if passedChecks >= s.upThreshold {
+			window.state = windowStateUp
+			eventType = ServerRecoveredEvent
+		}
+	}
+
+	if eventType == "" {
+		return nil
+	}
+
+	return &dto.ServerEvent{
+		Type:         eventType,
+		ID:           result.ID,
+		IPv4:         result.IPv4,
+		Timestamp:    checkedAt,
+		FailedChecks: failedChecks,
+		WindowSize:   window.filled,
+	}
+}
+
+const evictionInterval = 10 * time.Minute
+
+/*
+	EvictStaleWindows drops the windows of servers which are no longer checked,
+	deleted, in the trash or decommissioned, and of servers without a result for maxIdle,
+	now and then every evictionInterval until ctx is done
+*/
+func (s *serverEventService) EvictStaleWindows(ctx context.Context, maxIdle time.Duration) {
+	ticker := time.NewTicker(evictionInterval)
+	defer ticker.Stop()
+
+	for {
+		ids, err := s.servers.GetCheckedServerIDs()
+		if err != nil {
+			// Still evict the idle windows, only the membership check is skipped
+			logging.LogMessage("server_administration_service", "Failed to get checked servers for event windows: "+err.Error(), "ERROR")
+		}
+		if evicted := s.evict(ids, err == nil, time.Now().Add(-maxIdle)); evicted > 0 {
+			logging.LogMessage("server_administration_service", "Evicted "+strconv.Itoa(evicted)+" stale server event windows", "INFO")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *serverEventService) evict(checkedIDs []int, checkMembership bool, idleBefore time.Time) int {
+	checked := make(map[int]bool, len(checkedIDs))
+	for _, id := range checkedIDs {
+		checked[id] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	evicted := 0
+	for id, window := range s.windows {
+		if (checkMembership && !checked[id]) || window.lastSeen.Before(idleBefore) {
+			delete(s.windows, id)
+			evicted++
+		}
+	}
+	return evicted
+}
\ No newline at end of file
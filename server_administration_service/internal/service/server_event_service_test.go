@@ -0,0 +1,182 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"server_administration_service/internal/dto"
+	"server_administration_service/internal/service"
+	"testing"
+	"time"
+)
+
+type fakePublisher struct {
+	events []dto.ServerEvent
+	keys   []string
+	err    error
+}
+
+func (p *fakePublisher) SendMessageWithKey(topic string, key string, message []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	var event dto.ServerEvent
+	if err := json.Unmarshal(message, &event); err != nil {
+		return err
+	}
+	p.events = append(p.events, event)
+	p.keys = append(p.keys, key)
+	return nil
+}
+
+type fakeServerLister struct {
+	ids []int
+	err error
+}
+
+func (l *fakeServerLister) GetCheckedServerIDs() ([]int, error) {
+	return l.ids, l.err
+}
+
+func feed(t *testing.T, eventService service.ServerEventService, id int, statuses ...bool) {
+	for _, status := range statuses {
+		err := eventService.ProcessCheckResult(dto.HealthCheckResult{ID: id, IPv4: "10.0.0.1:80", Status: status}, time.Now())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+}
+
+func TestServerEvents_SingleFailureDoesNotFlap(t *testing.T) {
+	publisher := &fakePublisher{}
+	eventService := service.NewServerEventService(publisher, &fakeServerLister{}, "server_events_topic", 5, 3, 4)
+
+	feed(t, eventService, 1, true, true, true, true, false, true, true, false, true)
+
+	if len(publisher.events) != 0 {
+		t.Errorf("Expected no events, got %v", publisher.events)
+	}
+}
+
+func TestServerEvents_DownThenRecovered(t *testing.T) {
+	publisher := &fakePublisher{}
+	eventService := service.NewServerEventService(publisher, &fakeServerLister{}, "server_events_topic", 5, 3, 4)
+
+	feed(t, eventService, 1, true, true, true, true, true)
+	feed(t, eventService, 1, false, false, false)
+
+	if len(publisher.events) != 1 || publisher.events[0].Type != service.ServerDownEvent {
+		t.Fatalf("Expected one server_down event, got %v", publisher.events)
+	}
+	if publisher.events[0].FailedChecks != 3 || publisher.keys[0] != "1" {
+		t.Errorf("Unexpected server_down event %v with key %s", publisher.events[0], publisher.keys[0])
+	}
+
+	// Still down while the window holds fewer than 4 successes
+	feed(t, eventService, 1, false, true, true, true)
+	if len(publisher.events) != 1 {
+		t.Fatalf("Expected no recovery yet, got %v", publisher.events)
+	}
+
+	feed(t, eventService, 1, true)
+	if len(publisher.events) != 2 || publisher.events[1].Type != service.ServerRecoveredEvent {
+		t.Errorf("Expected server_recovered event, got %v", publisher.events)
+	}
+}
+
+func TestServerEvents_ServerDownFromTheStart(t *testing.T) {
+	publisher := &fakePublisher{}
+	eventService := service.NewServerEventService(publisher, &fakeServerLister{}, "server_events_topic", 5, 3, 4)
+
+	feed(t, eventService, 2, false, false, false, false)
+
+	if len(publisher.events) != 1 || publisher.events[0].Type != service.ServerDownEvent || publisher.events[0].ID != 2 {
+		t.Errorf("Expected one server_down event for server 2, got %v", publisher.events)
+	}
+}
+
+func TestServerEvents_PublishError(t *testing.T) {
+	publisher := &fakePublisher{err: errors.New("kafka unavailable")}
+	eventService := service.NewServerEventService(publisher, &fakeServerLister{}, "server_events_topic", 3, 2, 2)
+
+	eventService.ProcessCheckResult(dto.HealthCheckResult{ID: 1, Status: false}, time.Now())
+	err := eventService.ProcessCheckResult(dto.HealthCheckResult{ID: 1, Status: false}, time.Now())
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
+func TestValidateEventWindow(t *testing.T) {
+	tests := []struct {
+		name                                   string
+		windowSize, downThreshold, upThreshold int
+		valid                                  bool
+	}{
+		{"Default settings", 5, 3, 4, true},
+		{"Whole window both ways", 3, 3, 3, true},
+		{"Empty window", 0, 1, 1, false},
+		{"Down threshold above the window", 5, 6, 4, false},
+		{"Up threshold above the window", 5, 3, 6, false},
+		{"Zero down threshold", 5, 0, 5, false},
+		{"No hysteresis", 5, 2, 3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.ValidateEventWindow(tt.windowSize, tt.downThreshold, tt.upThreshold)
+			if tt.valid && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, service.ErrInvalidInput) {
+				t.Errorf("Expected ErrInvalidInput, got %v", err)
+			}
+		})
+	}
+}
+
+func TestServerEvents_EvictDeletedServers(t *testing.T) {
+	publisher := &fakePublisher{}
+	lister := &fakeServerLister{ids: []int{2}}
+	eventService := service.NewServerEventService(publisher, lister, "server_events_topic", 5, 3, 4)
+
+	feed(t, eventService, 1, false, false, false)
+	feed(t, eventService, 2, false, false, false)
+	if len(publisher.events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(publisher.events))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	eventService.EvictStaleWindows(ctx, time.Hour)
+
+	// Server 1 is gone, a new window starts from scratch and reports it down again
+	feed(t, eventService, 1, false, false, false)
+	// Server 2 kept its window and is still down
+	feed(t, eventService, 2, false)
+	if len(publisher.events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(publisher.events))
+	}
+	if publisher.events[2].ID != 1 || publisher.events[2].Type != service.ServerDownEvent {
+		t.Errorf("Expected server_down of server 1, got %+v", publisher.events[2])
+	}
+}
+
+func TestServerEvents_EvictIdleServers(t *testing.T) {
+	publisher := &fakePublisher{}
+	// Listing the servers fails, the idle windows are still evicted
+	lister := &fakeServerLister{err: errors.New("database unavailable")}
+	eventService := service.NewServerEventService(publisher, lister, "server_events_topic", 5, 3, 4)
+
+	feed(t, eventService, 1, false, false, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	time.Sleep(time.Millisecond)
+	eventService.EvictStaleWindows(ctx, time.Nanosecond)
+
+	feed(t, eventService, 1, false, false, false)
+	if len(publisher.events) != 2 {
+		t.Errorf("Expected 2 events, got %d", len(publisher.events))
+	}
+}
@@ -0,0 +1,454 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"server_administration_service/internal/domain"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Formats of the import and export files, an import detects its format from the content
+const (
+	FormatXLSX   = "xlsx"
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// The Content-Type of the files of each format
+var formatMediaTypes = map[string]string{
+	FormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatCSV:    "text/csv",
+	FormatJSON:   "application/json",
+	FormatNDJSON: "application/x-ndjson",
+}
+
+// The columns of the import and export files, in this order. Excel files use the names as headers
+var serverColumns = []string{"Server ID", "Server Name", "Status", "IPv4", "Port", "Check Interval (s)", "Timeout (ms)", "Retries", "Labels"}
+
+// CSV headers and JSON keys, the same as the JSON of domain.Server
+var serverColumnKeys = []string{"server_id", "server_name", "status", "ipv4", "port", "check_interval_seconds", "timeout_ms", "retries", "labels"}
+
+const (
+	columnServerID = iota
+	columnServerName
+	columnStatus
+	columnIPv4
+	columnPort
+	columnCheckInterval
+	columnTimeout
+	columnRetries
+	columnLabels
+)
+
+// Other headers accepted for a column, normalized by normalizeHeader
+var columnAliases = map[string]int{
+	"name":          columnServerName,
+	"ip":            columnIPv4,
+	"ipaddress":     columnIPv4,
+	"checkinterval": columnCheckInterval,
+	"timeout":       columnTimeout,
+}
+
+const serversSheet = "Servers"
+
+/*
+	serverTable is an import file read into the columns of serverColumns, whatever its format.
+	Rows are numbered like the file: the header is row 1 of a spreadsheet or a CSV file, and the
+	objects of a JSON file are numbered from 1.
+*/
+type serverTable struct {
+	format string
+	// positions[i] is the column of the file holding serverColumns[i], -1 when the file has none
+	positions []int
+	rows      []tableRow
+}
+
+type tableRow struct {
+	number int
+	values []string
+}
+
+func readServerTable(buf []byte) (*serverTable, error) {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(buf, []byte("\xef\xbb\xbf")), " \t\r\n")
+
+	switch {
+	// An xlsx file is a zip archive
+	case bytes.HasPrefix(buf, []byte("PK\x03\x04")):
+		return readXLSXTable(buf)
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		return readJSONTable(trimmed, FormatJSON)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return readJSONTable(trimmed, FormatNDJSON)
+	default:
+		return readCSVTable(trimmed)
+	}
+}
+
+func readXLSXTable(buf []byte) (*serverTable, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not an Excel file", ErrInvalidInput)
+	}
+	defer f.Close()
+
+	// Files written by ExportServers have a Servers sheet, others are read from their first sheet
+	sheet := serversSheet
+	if index, _ := f.GetSheetIndex(sheet); index < 0 {
+		sheet = f.GetSheetName(0)
+	}
+
+	records, err := f.GetRows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	return newRecordTable(FormatXLSX, records)
+}
+
+func readCSVTable(buf []byte) (*serverTable, error) {
+	reader := csv.NewReader(bytes.NewReader(buf))
+	// Trailing empty cells are often left out
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid CSV file: %s", ErrInvalidInput, err.Error())
+	}
+	return newRecordTable(FormatCSV, records)
+}
+
+// newRecordTable maps the columns of a spreadsheet or a CSV file by their header, the first record
+func newRecordTable(format string, records [][]string) (*serverTable, error) {
+	if len(records) < 2 {
+		return nil, fmt.Errorf("%w: the file has no servers", ErrInvalidInput)
+	}
+
+	positions, err := mapHeaders(records[0])
+	if err != nil {
+		return nil, err
+	}
+
+	table := &serverTable{format: format, positions: positions}
+	for i, record := range records[1:] {
+		values := make([]string, len(serverColumns))
+		for column, position := range positions {
+			if position >= 0 && position < len(record) {
+				values[column] = record[position]
+			}
+		}
+		table.rows = append(table.rows, tableRow{number: i + 2, values: values})
+	}
+	return table, nil
+}
+
+// mapHeaders finds the position of every column, unknown headers such as an "Errors" column are ignored
+func mapHeaders(headers []string) ([]int, error) {
+	positions := make([]int, len(serverColumns))
+	for i := range positions {
+		positions[i] = -1
+	}
+
+	for position, header := range headers {
+		column, ok := headerColumn(header)
+		if !ok {
+			continue
+		}
+		if positions[column] >= 0 {
+			return nil, fmt.Errorf("%w: the %s column appears twice", ErrInvalidInput, serverColumns[column])
+		}
+		positions[column] = position
+	}
+
+	var missing []string
+	for _, column := range []int{columnServerID, columnServerName, columnIPv4} {
+		if positions[column] < 0 {
+			missing = append(missing, serverColumns[column])
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: missing columns %s", ErrInvalidInput, strings.Join(missing, ", "))
+	}
+
+	return positions, nil
+}
+
+// headerColumn matches "Server ID", "server_id" and "ServerID" alike
+func headerColumn(header string) (int, bool) {
+	normalized := normalizeHeader(header)
+	if normalized == "" {
+		return 0, false
+	}
+
+	for column := range serverColumns {
+		if normalized == normalizeHeader(serverColumns[column]) || normalized == normalizeHeader(serverColumnKeys[column]) {
+			return column, true
+		}
+	}
+
+	column, ok := columnAliases[normalized]
+	return column, ok
+}
+
+func normalizeHeader(header string) string {
+	var normalized strings.Builder
+	for _, char := range strings.ToLower(header) {
+		if (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') {
+			normalized.WriteRune(char)
+		}
+	}
+	return normalized.String()
+}
+
+/*
+	readJSONTable reads an array of objects or one object per line (NDJSON), keyed like the CSV headers.
+	Numbers may be written as numbers or strings, labels as an object or as "env=prod,team=payments".
+*/
+func readJSONTable(buf []byte, format string) (*serverTable, error) {
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+
+	var objects []map[string]interface{}
+	if format == FormatJSON {
+		if err := decoder.Decode(&objects); err != nil {
+			return nil, fmt.Errorf("%w: invalid JSON file: %s", ErrInvalidInput, err.Error())
+		}
+	} else {
+		for {
+			var object map[string]interface{}
+			err := decoder.Decode(&object)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid NDJSON file: %s", ErrInvalidInput, err.Error())
+			}
+			objects = append(objects, object)
+		}
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("%w: the file has no servers", ErrInvalidInput)
+	}
+
+	table := &serverTable{format: format}
+	for i, object := range objects {
+		values := make([]string, len(serverColumns))
+		for key, value := range object {
+			column, ok := headerColumn(key)
+			if !ok {
+				continue
+			}
+
+			values[column] = jsonValue(value)
+		}
+		table.rows = append(table.rows, tableRow{number: i + 1, values: values})
+	}
+	return table, nil
+}
+
+// jsonValue writes a JSON value the way it would be written in a cell, a wrong type is left to the validation
+func jsonValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case map[string]interface{}:
+		labels := make(map[string]string, len(value))
+		for key, labelValue := range value {
+			labels[key] = fmt.Sprint(labelValue)
+		}
+		return domain.FormatLabels(labels)
+	}
+	return fmt.Sprint(value)
+}
+
+// cell is the spreadsheet cell of a column of a row, empty for JSON files and columns missing from the file
+func (t *serverTable) cell(rowNumber, column int) string {
+	if t.positions == nil || t.positions[column] < 0 {
+		return ""
+	}
+	cell, _ := excelize.CoordinatesToCellName(t.positions[column]+1, rowNumber)
+	return cell
+}
+
+/*
+	serverEncoder writes servers one at a time, Finish completes the file and Close releases the encoder
+	whether or not the file was completed. Nothing is kept per server except by the xlsx encoder, whose
+	stream writer moves the rows to a temporary file once they grow large.
+*/
+type serverEncoder interface {
+	Encode(server domain.Server) error
+	Finish() error
+	Close() error
+}
+
+func newServerEncoder(w io.Writer, format string) (serverEncoder, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(serverColumnKeys); err != nil {
+			return nil, err
+		}
+		return &csvEncoder{writer: writer}, nil
+	case FormatJSON:
+		return &jsonEncoder{w: w, encoder: json.NewEncoder(w)}, nil
+	case FormatNDJSON:
+		return &ndjsonEncoder{encoder: json.NewEncoder(w)}, nil
+	case FormatXLSX, "":
+		return newXLSXEncoder(w)
+	}
+	return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidInput, format)
+}
+
+func serverRecord(server domain.Server) []string {
+	return []string{
+		server.ServerID,
+		server.ServerName,
+		string(server.Status),
+		server.IPv4,
+		strconv.Itoa(server.Port),
+		strconv.Itoa(server.CheckIntervalSeconds),
+		strconv.Itoa(server.TimeoutMs),
+		strconv.Itoa(server.Retries),
+		domain.FormatLabels(server.Labels),
+	}
+}
+
+type csvEncoder struct {
+	writer *csv.Writer
+}
+
+func (e *csvEncoder) Encode(server domain.Server) error {
+	return e.writer.Write(serverRecord(server))
+}
+
+func (e *csvEncoder) Finish() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	return nil
+}
+
+// jsonEncoder writes a JSON array an element at a time, the audit export uses it too
+type jsonEncoder struct {
+	w       io.Writer
+	encoder *json.Encoder
+	count   int
+}
+
+func (e *jsonEncoder) Encode(server domain.Server) error {
+	return e.encode(server)
+}
+
+func (e *jsonEncoder) encode(value interface{}) error {
+	separator := ","
+	if e.count == 0 {
+		separator = "["
+	}
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+
+	e.count++
+	return e.encoder.Encode(value)
+}
+
+func (e *jsonEncoder) Finish() error {
+	end := "]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	return nil
+}
+
+type ndjsonEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonEncoder) Encode(server domain.Server) error {
+	return e.encoder.Encode(server)
+}
+
+func (e *ndjsonEncoder) Finish() error {
+	return nil
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
+
+type xlsxEncoder struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXEncoder(w io.Writer) (*xlsxEncoder, error) {
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", serversSheet)
+
+	stream, err := f.NewStreamWriter(serversSheet)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	headers := make([]interface{}, len(serverColumns))
+	for i, column := range serverColumns {
+		headers[i] = column
+	}
+	if err := stream.SetRow("A1", headers); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &xlsxEncoder{w: w, file: f, stream: stream, row: 1}, nil
+}
+
+func (e *xlsxEncoder) Encode(server domain.Server) error {
+	e.row++
+	cell, _ := excelize.CoordinatesToCellName(1, e.row)
+	return e.stream.SetRow(cell, []interface{}{
+		server.ServerID,
+		server.ServerName,
+		string(server.Status),
+		server.IPv4,
+		server.Port,
+		server.CheckIntervalSeconds,
+		server.TimeoutMs,
+		server.Retries,
+		domain.FormatLabels(server.Labels),
+	})
+}
+
+// Finish writes the workbook, a zip archive can only be written once all its rows are known
+func (e *xlsxEncoder) Finish() error {
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.w)
+}
+
+// Close removes the temporary files of the stream writer
+func (e *xlsxEncoder) Close() error {
+	return e.file.Close()
+}
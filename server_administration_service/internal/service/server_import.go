@@ -0,0 +1,424 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flashhhhh/pkg/logging"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// Only creates the new servers, the existing ones are rejected
+	ImportModeInsert = "insert"
+	// Also updates the existing servers matched by server ID
+	ImportModeUpsert = "upsert"
+	// Also deletes the servers missing from the file, the file becomes the inventory
+	ImportModeReplace = "replace"
+)
+
+/*
+	Validates every row of the file and only imports the valid ones, a bad row never aborts the import.
+	Each rejected row is reported with its row number and the reason for every invalid cell.
+	A dry run stops after planning, neither Postgres nor Redis are written.
+*/
+func (s *serverService) ImportServers(ctx context.Context, buf []byte, options dto.ImportOptions) (*dto.ImportReport, error) {
+	mode, err := importMode(options.Mode)
+	if err != nil {
+		return nil, err
+	}
+	options.Mode = mode
+
+	table, err := readServerTable(buf)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to read import file: "+err.Error(), "ERROR")
+		return nil, err
+	}
+
+	progress := func(processed int) error {
+		if options.Progress == nil {
+			return nil
+		}
+		return options.Progress(processed, len(table.rows))
+	}
+	if err := progress(0); err != nil {
+		return nil, err
+	}
+
+	report := &dto.ImportReport{
+		Mode:      options.Mode,
+		DryRun:    options.DryRun,
+		Created:   []domain.Server{},
+		Updated:   []domain.Server{},
+		Unchanged: []domain.Server{},
+		Deleted:   []domain.Server{},
+		Rejected:  []dto.RejectedRow{},
+	}
+
+	servers := make([]domain.Server, 0, len(table.rows))
+	// Row of each valid server, by server ID
+	serverRows := make(map[string]int)
+	rowValues := make(map[int][]string)
+	serverNames := make(map[string]int)
+	// Every server ID of the file, a replace keeps the servers of the rejected rows too
+	fileServerIDs := make(map[string]bool)
+
+	for i, row := range table.rows {
+		if err := progress(i); err != nil {
+			return nil, err
+		}
+
+		rowNumber := row.number
+		if isEmptyRow(row.values) {
+			continue
+		}
+		rowValues[rowNumber] = row.values
+
+		server, rowErrors := table.parseRow(row)
+		fileServerIDs[server.ServerID] = true
+
+		if first, ok := serverRows[server.ServerID]; ok && server.ServerID != "" {
+			rowErrors = append(rowErrors, table.importError(rowNumber, columnServerID, fmt.Sprintf("duplicates the server ID of row %d", first)))
+		}
+		if first, ok := serverNames[server.ServerName]; ok && server.ServerName != "" {
+			rowErrors = append(rowErrors, table.importError(rowNumber, columnServerName, fmt.Sprintf("duplicates the server name of row %d", first)))
+		}
+
+		if len(rowErrors) > 0 {
+			report.Rejected = append(report.Rejected, dto.RejectedRow{Row: rowNumber, Values: row.values, Errors: rowErrors})
+			continue
+		}
+
+		serverRows[server.ServerID] = rowNumber
+		serverNames[server.ServerName] = rowNumber
+		servers = append(servers, server)
+	}
+
+	var existing []domain.Server
+	if options.Mode == ImportModeReplace {
+		existing, err = s.serverRepository.GetAllServers()
+	} else if len(servers) > 0 {
+		serverIDs := make([]string, len(servers))
+		names := make([]string, len(servers))
+		for i, server := range servers {
+			serverIDs[i] = server.ServerID
+			names[i] = server.ServerName
+		}
+		existing, err = s.serverRepository.FindServers(serverIDs, names)
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get the existing servers: "+err.Error(), "ERROR")
+		return nil, err
+	}
+
+	plan := planImport(servers, existing, fileServerIDs, options.Mode)
+	report.Unchanged = append(report.Unchanged, plan.unchanged...)
+	for _, rowError := range plan.rejected {
+		rowNumber := serverRows[rowError.Server.ServerID]
+		report.Rejected = append(report.Rejected, dto.RejectedRow{
+			Row:    rowNumber,
+			Values: rowValues[rowNumber],
+			Errors: []dto.ImportError{table.importError(rowNumber, rowError.column, rowError.Reason)},
+		})
+	}
+
+	// Last chance to stop, the servers are then written in one transaction
+	if err := progress(len(table.rows)); err != nil {
+		return nil, err
+	}
+
+	if options.DryRun {
+		report.Created = append(report.Created, plan.Create...)
+		report.Updated = append(report.Updated, plan.Update...)
+		report.Deleted = append(report.Deleted, plan.Delete...)
+	} else if len(plan.Create)+len(plan.Update)+len(plan.Delete) > 0 {
+		result, err := s.serverRepository.ApplyServerImport(ctx, plan.ServerImportPlan)
+		if err != nil {
+			logging.LogMessage("server_administration_service", "Failed to import servers: "+err.Error(), "ERROR")
+			return nil, err
+		}
+		report.Created = append(report.Created, result.Created...)
+		report.Updated = append(report.Updated, result.Updated...)
+		report.Deleted = append(report.Deleted, result.Deleted...)
+
+		// Server IDs are unique within the servers sent, so they lead back to the row
+		for _, rejected := range result.Rejected {
+			rowNumber := serverRows[rejected.Server.ServerID]
+			report.Rejected = append(report.Rejected, dto.RejectedRow{
+				Row:    rowNumber,
+				Values: rowValues[rowNumber],
+				Errors: []dto.ImportError{{Row: rowNumber, Reason: rejected.Reason}},
+			})
+		}
+	}
+
+	sort.Slice(report.Rejected, func(i, j int) bool {
+		return report.Rejected[i].Row < report.Rejected[j].Row
+	})
+
+	logging.LogMessage("server_administration_service", fmt.Sprintf("Servers imported (mode %s, dry run %t): %d created, %d updated, %d unchanged, %d deleted, %d rejected",
+		report.Mode, report.DryRun, len(report.Created), len(report.Updated), len(report.Unchanged), len(report.Deleted), len(report.Rejected)), "INFO")
+	return report, nil
+}
+
+// importMode validates an import mode, insert by default
+func importMode(mode string) (string, error) {
+	switch mode {
+	case "":
+		return ImportModeInsert, nil
+	case ImportModeInsert, ImportModeUpsert, ImportModeReplace:
+		return mode, nil
+	}
+	return "", fmt.Errorf("%w: invalid import mode %q, expected insert, upsert or replace", ErrInvalidInput, mode)
+}
+
+type importPlan struct {
+	dto.ServerImportPlan
+	unchanged []domain.Server
+	rejected  []importConflict
+}
+
+// importConflict is a valid row conflicting with an existing server, column is the conflicting one
+type importConflict struct {
+	dto.RejectedServer
+	column int
+}
+
+/*
+	planImport matches the servers of the file with the existing ones by server ID. A server name
+	still used by another server is a conflict, unless a replace deletes that server first.
+*/
+func planImport(servers, existing []domain.Server, fileServerIDs map[string]bool, mode string) importPlan {
+	var plan importPlan
+
+	byServerID := make(map[string]domain.Server, len(existing))
+	byName := make(map[string]domain.Server, len(existing))
+	for _, server := range existing {
+		byServerID[server.ServerID] = server
+		byName[server.ServerName] = server
+
+		if mode == ImportModeReplace && !fileServerIDs[server.ServerID] {
+			plan.Delete = append(plan.Delete, server)
+		}
+	}
+
+	for _, server := range servers {
+		current, exists := byServerID[server.ServerID]
+		if exists && mode == ImportModeInsert {
+			plan.rejected = append(plan.rejected, importConflict{
+				RejectedServer: dto.RejectedServer{Server: server, Reason: "a server with this server ID already exists"},
+				column:         columnServerID,
+			})
+			continue
+		}
+
+		other, taken := byName[server.ServerName]
+		if taken && other.ServerID != server.ServerID && (mode != ImportModeReplace || fileServerIDs[other.ServerID]) {
+			plan.rejected = append(plan.rejected, importConflict{
+				RejectedServer: dto.RejectedServer{Server: server, Reason: "is already used by server " + other.ServerID},
+				column:         columnServerName,
+			})
+			continue
+		}
+
+		if !exists {
+			plan.Create = append(plan.Create, server)
+			continue
+		}
+
+		// The status and the probe settings are not in the file, they are kept
+		server.ID = current.ID
+		server.Status = current.Status
+		server.CreatedTime = current.CreatedTime
+		server.LastUpdated = current.LastUpdated
+		server.ProbeType = current.ProbeType
+		server.ProbePath = current.ProbePath
+		server.ProbeExpectedStatus = current.ProbeExpectedStatus
+		server.ProbeExpectedBody = current.ProbeExpectedBody
+
+		if sameImportedFields(server, current) {
+			plan.unchanged = append(plan.unchanged, current)
+		} else {
+			plan.Update = append(plan.Update, server)
+		}
+	}
+
+	return plan
+}
+
+// sameImportedFields compares the columns of the import file, except the status
+func sameImportedFields(a, b domain.Server) bool {
+	return a.ServerName == b.ServerName &&
+		a.IPv4 == b.IPv4 &&
+		a.Port == b.Port &&
+		a.CheckIntervalSeconds == b.CheckIntervalSeconds &&
+		a.TimeoutMs == b.TimeoutMs &&
+		a.Retries == b.Retries &&
+		maps.Equal(a.Labels, b.Labels)
+}
+
+/*
+	parseRow validates the values of a row, empty optional cells keep their defaults:
+	port 80 and the default health check settings.
+*/
+func (t *serverTable) parseRow(tableRow tableRow) (domain.Server, []dto.ImportError) {
+	row := tableRow.values
+	var rowErrors []dto.ImportError
+	invalid := func(column int, reason string) {
+		rowErrors = append(rowErrors, t.importError(tableRow.number, column, reason))
+	}
+
+	server := domain.Server{
+		ServerID:   cellValue(row, columnServerID),
+		ServerName: cellValue(row, columnServerName),
+		IPv4:       cellValue(row, columnIPv4),
+		Port:       80,
+	}
+
+	if server.ServerID == "" {
+		invalid(columnServerID, "is required")
+	}
+	if server.ServerName == "" {
+		invalid(columnServerName, "is required")
+	}
+
+	server.Status = domain.StatusUnknown
+	if value := cellValue(row, columnStatus); value != "" {
+		status, err := domain.ParseServerStatus(value)
+		if err != nil {
+			invalid(columnStatus, err.Error())
+		}
+		server.Status = status
+	}
+
+	if server.IPv4 == "" {
+		invalid(columnIPv4, "is required")
+	} else if ip := net.ParseIP(server.IPv4); ip == nil || ip.To4() == nil || strings.Contains(server.IPv4, ":") {
+		invalid(columnIPv4, fmt.Sprintf("invalid IPv4 address %q", server.IPv4))
+	}
+
+	if value := cellValue(row, columnPort); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			invalid(columnPort, fmt.Sprintf("port must be a number between 1 and 65535, got %q", value))
+		}
+		server.Port = port
+	}
+
+	checkConfig := []struct {
+		column int
+		field  *int
+	}{
+		{columnCheckInterval, &server.CheckIntervalSeconds},
+		{columnTimeout, &server.TimeoutMs},
+		{columnRetries, &server.Retries},
+	}
+	for _, config := range checkConfig {
+		value := cellValue(row, config.column)
+		if value == "" {
+			continue
+		}
+
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			invalid(config.column, fmt.Sprintf("expected a number, got %q", value))
+			continue
+		}
+		*config.field = number
+	}
+
+	if value := cellValue(row, columnLabels); value != "" {
+		labels, err := domain.ParseLabels(value)
+		if err != nil {
+			invalid(columnLabels, err.Error())
+		}
+		server.Labels = labels
+	}
+
+	// The remaining checks need the cells above to be readable
+	if len(rowErrors) > 0 {
+		return server, rowErrors
+	}
+
+	if err := prepareServer(&server); err != nil {
+		invalid(-1, strings.TrimPrefix(err.Error(), ErrInvalidInput.Error()+": "))
+	}
+
+	return server, rowErrors
+}
+
+// ExportRejectedRows writes the rejected rows as they were imported plus an Errors column, ready to be fixed and imported again
+func (s *serverService) ExportRejectedRows(report *dto.ImportReport) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetSheetName("Sheet1", serversSheet)
+
+	headers := append(append([]string{}, serverColumns...), "Errors")
+	if err := f.SetSheetRow(serversSheet, "A1", &headers); err != nil {
+		return nil, err
+	}
+
+	errorsColumn := len(serverColumns) + 1
+	for i, rejected := range report.Rejected {
+		values := make([]interface{}, len(serverColumns))
+		for column := range serverColumns {
+			values[column] = cellValue(rejected.Values, column)
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := f.SetSheetRow(serversSheet, cell, &values); err != nil {
+			return nil, err
+		}
+
+		reasons := make([]string, len(rejected.Errors))
+		for j, rowError := range rejected.Errors {
+			if rowError.Column != "" {
+				reasons[j] = rowError.Column + ": " + rowError.Reason
+			} else {
+				reasons[j] = rowError.Reason
+			}
+		}
+		cell, _ = excelize.CoordinatesToCellName(errorsColumn, i+2)
+		f.SetCellValue(serversSheet, cell, strings.Join(reasons, "; "))
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		logging.LogMessage("server_administration_service", "Failed to write Excel file to buffer: "+err.Error(), "ERROR")
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// importError names the column and the cell of a reason, a negative column is about the whole row
+func (t *serverTable) importError(rowNumber, column int, reason string) dto.ImportError {
+	importError := dto.ImportError{Row: rowNumber, Reason: reason}
+	if column >= 0 {
+		importError.Column = serverColumns[column]
+		importError.Cell = t.cell(rowNumber, column)
+	}
+	return importError
+}
+
+func cellValue(row []string, column int) string {
+	if column < len(row) {
+		return strings.TrimSpace(row[column])
+	}
+	return ""
+}
+
+func isEmptyRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
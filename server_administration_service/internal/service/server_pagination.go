@@ -0,0 +1,157 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"server_administration_service/internal/domain"
+	"server_administration_service/internal/dto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 1000
+)
+
+/*
+	The columns ViewServers can be sorted by, nothing else reaches the ORDER BY. A cursor keeps
+	the sort values of the last server as strings, parse turns them back into the column types.
+*/
+type serverSortColumn struct {
+	value func(server domain.Server) string
+	parse func(value string) (interface{}, error)
+}
+
+var serverSortColumns = map[string]serverSortColumn{
+	"id":           intSortColumn(func(server domain.Server) int { return server.ID }),
+	"server_id":    textSortColumn(func(server domain.Server) string { return server.ServerID }),
+	"server_name":  textSortColumn(func(server domain.Server) string { return server.ServerName }),
+	"status":       textSortColumn(func(server domain.Server) string { return string(server.Status) }),
+	"ipv4":         textSortColumn(func(server domain.Server) string { return server.IPv4 }),
+	"port":         intSortColumn(func(server domain.Server) int { return server.Port }),
+	"created_time": timeSortColumn(func(server domain.Server) time.Time { return server.CreatedTime }),
+	"last_updated": timeSortColumn(func(server domain.Server) time.Time { return server.LastUpdated }),
+}
+
+func textSortColumn(get func(server domain.Server) string) serverSortColumn {
+	return serverSortColumn{
+		value: get,
+		parse: func(value string) (interface{}, error) { return value, nil },
+	}
+}
+
+func intSortColumn(get func(server domain.Server) int) serverSortColumn {
+	return serverSortColumn{
+		value: func(server domain.Server) string { return strconv.Itoa(get(server)) },
+		parse: func(value string) (interface{}, error) { return strconv.Atoi(value) },
+	}
+}
+
+func timeSortColumn(get func(server domain.Server) time.Time) serverSortColumn {
+	return serverSortColumn{
+		value: func(server domain.Server) string { return get(server).Format(time.RFC3339Nano) },
+		parse: func(value string) (interface{}, error) { return time.Parse(time.RFC3339Nano, value) },
+	}
+}
+
+/*
+	prepareSort defaults to the id in ascending order and rejects unknown or repeated columns.
+	The id is appended when missing, in the order of the last field, so that the order is total
+	and a cursor always points to a single server.
+*/
+func prepareSort(sortFields []dto.SortField) ([]dto.SortField, error) {
+	prepared := make([]dto.SortField, 0, len(sortFields)+1)
+	seen := make(map[string]bool)
+
+	for _, field := range sortFields {
+		if _, existed := serverSortColumns[field.Column]; !existed {
+			return nil, fmt.Errorf("%w: invalid sort column %s", ErrInvalidInput, field.Column)
+		}
+		if seen[field.Column] {
+			return nil, fmt.Errorf("%w: duplicated sort column %s", ErrInvalidInput, field.Column)
+		}
+		seen[field.Column] = true
+
+		if field.Order == "" {
+			field.Order = "asc"
+		}
+		if field.Order != "asc" && field.Order != "desc" {
+			return nil, fmt.Errorf("%w: invalid sort order %s", ErrInvalidInput, field.Order)
+		}
+		prepared = append(prepared, field)
+	}
+
+	if !seen["id"] {
+		order := "asc"
+		if len(prepared) > 0 {
+			order = prepared[len(prepared)-1].Order
+		}
+		prepared = append(prepared, dto.SortField{Column: "id", Order: order})
+	}
+
+	return prepared, nil
+}
+
+// formatSort writes the sort as "status:asc,id:asc", the form recorded in cursors
+func formatSort(sortFields []dto.SortField) string {
+	fields := make([]string, len(sortFields))
+	for i, field := range sortFields {
+		fields[i] = field.Column + ":" + field.Order
+	}
+	return strings.Join(fields, ",")
+}
+
+/*
+	The cursor is opaque to clients, it also records the sort it was made for
+	so that it can't be reused with another one
+*/
+type serverCursorToken struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+func encodeServerCursor(server domain.Server, sortFields []dto.SortField) string {
+	values := make([]string, len(sortFields))
+	for i, field := range sortFields {
+		values[i] = serverSortColumns[field.Column].value(server)
+	}
+
+	token, _ := json.Marshal(serverCursorToken{
+		Sort:   formatSort(sortFields),
+		Values: values,
+	})
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+// decodeServerCursor returns nil for an empty cursor, i.e. the first page
+func decodeServerCursor(cursor string, sortFields []dto.SortField) (*dto.ServerCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidInput)
+	}
+
+	var token serverCursorToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidInput)
+	}
+	if token.Sort != formatSort(sortFields) || len(token.Values) != len(sortFields) {
+		return nil, fmt.Errorf("%w: the cursor was made for another sort", ErrInvalidInput)
+	}
+
+	values := make([]interface{}, len(sortFields))
+	for i, field := range sortFields {
+		values[i], err = serverSortColumns[field.Column].parse(token.Values[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidInput)
+		}
+	}
+
+	return &dto.ServerCursor{Values: values}, nil
+}
@@ -1,33 +1,58 @@
 package service
 
 import (
-	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"server_administration_service/internal/domain"
 	"server_administration_service/internal/dto"
 	"server_administration_service/internal/repository"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/flashhhhh/pkg/logging"
-	"github.com/xuri/excelize/v2"
 )
 
+// ErrInvalidInput wraps every error caused by a bad argument rather than a failing dependency
+var ErrInvalidInput = errors.New("invalid input")
+
+/*
+	ServerService mutations take the context of the request, its actor and request ID are recorded
+	in the audit log with the change
+*/
 type ServerService interface {
-	CreateServer(server_id, server_name, status, ipv4 string, port int) (int, error)
-	ViewServers(serverFilter *dto.ServerFilter, from, to int, sortedColumn string, order string) ([]domain.Server, error)
-	UpdateServer(server_id string, updatedData map[string]interface{}) error
-	DeleteServer(server_id string) error
-	ImportServers(buf []byte) ([]domain.Server, []domain.Server, error)
-	ExportServers(serverFilter *dto.ServerFilter, from, to int, sortedColumn string, order string) ([]byte, error)
+	CreateServer(ctx context.Context, server *domain.Server) (int, error)
+	ViewServers(serverFilter *dto.ServerFilter, sortFields []dto.SortField, cursor string, pageSize int) (*dto.ServerPage, error)
+	UpdateServer(ctx context.Context, server_id string, updatedData map[string]interface{}) error
+	DeleteServer(ctx context.Context, server_id string) error
+	GetDeletedServers(offset, limit int) ([]domain.Server, int, error)
+	RestoreServer(ctx context.Context, server_id string) error
+	PurgeDeletedServers(ctx context.Context, retention time.Duration)
+	ImportServers(ctx context.Context, buf []byte, options dto.ImportOptions) (*dto.ImportReport, error)
+	ExportRejectedRows(report *dto.ImportReport) ([]byte, error)
+	ExportServers(w io.Writer, serverFilter *dto.ServerFilter, sortFields []dto.SortField, options dto.ExportOptions) error
+	SearchServers(searchQuery dto.ServerSearchQuery) (*dto.ServerSearchResult, error)
 	
-	UpdateServerStatus(id int, status string) error
+	UpdateServerStatus(id int, status domain.ServerStatus) error
 	GetAllAddresses() ([]dto.ServerAddress, error)
+	GetUpdatedAddresses(since time.Time) ([]dto.ServerAddress, []int, error)
 
-	AddServerStatus(id int, status string) error
+	AddServerStatus(result dto.HealthCheckResult, status, decidedStatus domain.ServerStatus) error
 	GetNumOnServers() (int, error)
+	GetStatusCounts(ids []int) (map[domain.ServerStatus]int, error)
+	GetServerIDsByLabels(labels map[string]string) ([]int, error)
 	GetNumServers() (int, error)
-	GetServerUptimeRatio(startTime, endTime time.Time) (float64, error)
+	GetServerUptimeRatio(startTime, endTime time.Time, labels map[string]string) (float64, error)
+	GetServersUptime(startTime, endTime time.Time, labels map[string]string, offset, limit int, sortedColumn string, order string) ([]dto.ServerUptime, int, error)
+	GetUptimeTimeline(id int, startTime, endTime time.Time, interval string) ([]dto.UptimeBucket, error)
+	GetStatusTransitions(id int, startTime, endTime time.Time, offset, limit int) ([]dto.StatusTransition, int, error)
+	GetLatencyPercentiles(id int, startTime, endTime time.Time) ([]dto.LatencyPercentiles, error)
+	GetLocationStatuses(id int) ([]dto.LocationStatus, error)
+	GetDownServers(minConsecutiveChecks int) ([]dto.DownServer, error)
 }
 
 type serverService struct {
@@ -40,45 +65,326 @@ func NewServerService(serverRepository repository.ServerRepository) ServerServic
 	}
 }
 
-func (s *serverService) CreateServer(server_id, server_name, status, ipv4 string, port int) (int, error) {
-	server := &domain.Server{
-		ServerID:   server_id,
-		ServerName: server_name,
-		Status:     status,
-		IPv4:  ipv4,
-		Port: 	 port,
+/*
+	Fills the defaults of the status and health check settings and validates them
+*/
+func prepareServer(server *domain.Server) error {
+	// A new server has not been checked yet
+	if server.Status == "" {
+		server.Status = domain.StatusUnknown
+	}
+	status, err := domain.ParseServerStatus(string(server.Status))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+	}
+	server.Status = status
+
+	if server.Labels == nil {
+		server.Labels = make(map[string]string)
+	}
+	if err := domain.ValidateLabels(server.Labels); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+	}
+
+	if server.ProbeType == "" {
+		server.ProbeType = domain.ProbeTCP
+	}
+	if !domain.IsValidProbeType(server.ProbeType) {
+		return fmt.Errorf("%w: invalid probe type %s", ErrInvalidInput, server.ProbeType)
 	}
 
-	id, err := s.serverRepository.CreateServer(server)
+	if server.CheckIntervalSeconds == 0 {
+		server.CheckIntervalSeconds = domain.DefaultCheckIntervalSeconds
+	}
+	if server.TimeoutMs == 0 {
+		server.TimeoutMs = domain.DefaultTimeoutMs
+	}
+	if err := domain.ValidateCheckConfig(server.CheckIntervalSeconds, server.TimeoutMs, server.Retries); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+	}
+
+	return nil
+}
+
+func (s *serverService) CreateServer(ctx context.Context, server *domain.Server) (int, error) {
+	if err := prepareServer(server); err != nil {
+		return 0, err
+	}
+
+	id, err := s.serverRepository.CreateServer(ctx, server)
 	if err != nil {
 		return 0, err
 	}
 	return id, nil
 }
 
-func (s *serverService) ViewServers(serverFilter *dto.ServerFilter, from, to int, sortedColumn string, order string) ([]domain.Server, error) {
-	servers, err := s.serverRepository.ViewServers(serverFilter, from, to, sortedColumn, order)
+/*
+	Normalizes the statuses of the filter, e.g. "on" becomes "Up", and the CIDR range,
+	then validates the labels and the bounds of the ranges
+*/
+func prepareFilter(serverFilter *dto.ServerFilter) error {
+	if err := domain.ValidateLabels(serverFilter.Labels); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+	}
+
+	for i, value := range serverFilter.Statuses {
+		status, err := domain.ParseServerStatus(value)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+		}
+		serverFilter.Statuses[i] = string(status)
+	}
+
+	if serverFilter.IPv4CIDR != "" {
+		ip, network, err := net.ParseCIDR(serverFilter.IPv4CIDR)
+		if err != nil || ip.To4() == nil {
+			return fmt.Errorf("%w: invalid IPv4 CIDR range %s", ErrInvalidInput, serverFilter.IPv4CIDR)
+		}
+		serverFilter.IPv4CIDR = network.String()
+	}
+
+	portRange := serverFilter.PortRange
+	if (portRange.From != nil && (*portRange.From < 0 || *portRange.From > 65535)) ||
+		(portRange.To != nil && (*portRange.To < 0 || *portRange.To > 65535)) {
+		return fmt.Errorf("%w: port range bounds must be between 0 and 65535", ErrInvalidInput)
+	}
+	if portRange.From != nil && portRange.To != nil && *portRange.From > *portRange.To {
+		return fmt.Errorf("%w: port range starts after it ends", ErrInvalidInput)
+	}
+
+	for name, timeRange := range map[string]dto.TimeRange{
+		"created_time": serverFilter.CreatedTime,
+		"last_updated": serverFilter.LastUpdated,
+	} {
+		if timeRange.From != nil && timeRange.To != nil && !timeRange.From.Before(*timeRange.To) {
+			return fmt.Errorf("%w: %s range starts after it ends", ErrInvalidInput, name)
+		}
+	}
+
+	return nil
+}
+
+/*
+	Returns the page after the cursor, an empty cursor gives the first page.
+	pageSize 0 means DefaultPageSize.
+*/
+func (s *serverService) ViewServers(serverFilter *dto.ServerFilter, sortFields []dto.SortField, cursor string, pageSize int) (*dto.ServerPage, error) {
+	if err := prepareFilter(serverFilter); err != nil {
+		return nil, err
+	}
+
+	sortFields, err := prepareSort(sortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize < 0 || pageSize > MaxPageSize {
+		return nil, fmt.Errorf("%w: page size must be between 1 and %d", ErrInvalidInput, MaxPageSize)
+	}
+
+	after, err := decodeServerCursor(cursor, sortFields)
 	if err != nil {
 		return nil, err
 	}
-	return servers, nil
+
+	// One more server than the page size tells whether there is a next page
+	servers, err := s.serverRepository.ViewServers(serverFilter, sortFields, after, pageSize+1)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.serverRepository.CountServers(serverFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &dto.ServerPage{
+		Servers: servers,
+		Total:   total,
+	}
+	if len(servers) > pageSize {
+		page.Servers = servers[:pageSize]
+		page.NextCursor = encodeServerCursor(page.Servers[pageSize-1], sortFields)
+	}
+	if page.Servers == nil {
+		page.Servers = []domain.Server{}
+	}
+
+	return page, nil
+}
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+	// Elasticsearch refuses to page past its max_result_window
+	maxSearchWindow = 10000
+)
+
+func (s *serverService) SearchServers(searchQuery dto.ServerSearchQuery) (*dto.ServerSearchResult, error) {
+	searchQuery.Text = strings.TrimSpace(searchQuery.Text)
+
+	if searchQuery.Limit == 0 {
+		searchQuery.Limit = DefaultSearchLimit
+	}
+	if searchQuery.Limit < 0 || searchQuery.Limit > MaxSearchLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidInput, MaxSearchLimit)
+	}
+	if searchQuery.Offset < 0 || searchQuery.Offset+searchQuery.Limit > maxSearchWindow {
+		return nil, fmt.Errorf("%w: offset and limit must stay within the first %d results", ErrInvalidInput, maxSearchWindow)
+	}
+
+	statuses := make([]string, len(searchQuery.Statuses))
+	for i, value := range searchQuery.Statuses {
+		status, err := domain.ParseServerStatus(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+		}
+		statuses[i] = string(status)
+	}
+	searchQuery.Statuses = statuses
+
+	return s.serverRepository.SearchServers(searchQuery)
 }
 
-func (s *serverService) UpdateServer(server_id string, updatedData map[string]interface{}) error {
-	err := s.serverRepository.UpdateServer(server_id, updatedData)
+func (s *serverService) UpdateServer(ctx context.Context, server_id string, updatedData map[string]interface{}) error {
+	if value, existed := updatedData["status"]; existed {
+		statusStr, _ := value.(string)
+		status, err := domain.ParseServerStatus(statusStr)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+		}
+		updatedData["status"] = status
+	}
+
+	if value, existed := updatedData["labels"]; existed {
+		labels, ok := value.(map[string]string)
+		if !ok {
+			return fmt.Errorf("%w: labels must be a map of strings", ErrInvalidInput)
+		}
+		if err := domain.ValidateLabels(labels); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+		}
+	}
+
+	if probeType, existed := updatedData["probe_type"]; existed {
+		if probeType, ok := probeType.(string); !ok || !domain.IsValidProbeType(probeType) {
+			return fmt.Errorf("%w: invalid probe type %v", ErrInvalidInput, updatedData["probe_type"])
+		}
+	}
+
+	// Each cadence field is checked on its own, the others keep their stored values
+	for _, field := range []string{"check_interval_seconds", "timeout_ms", "retries"} {
+		value, existed := updatedData[field]
+		if !existed {
+			continue
+		}
+
+		intValue, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("%w: %s must be an integer", ErrInvalidInput, field)
+		}
+
+		config := map[string]int{
+			"check_interval_seconds": domain.DefaultCheckIntervalSeconds,
+			"timeout_ms":             domain.DefaultTimeoutMs,
+			"retries":                0,
+		}
+		config[field] = intValue
+		if err := domain.ValidateCheckConfig(config["check_interval_seconds"], config["timeout_ms"], config["retries"]); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+		}
+	}
+
+	err := s.serverRepository.UpdateServer(ctx, server_id, updatedData)
 	return err
 }
 
-func (s *serverService) DeleteServer(server_id string) error {
-	err := s.serverRepository.DeleteServer(server_id)
+// DeleteServer moves the server to the trash, it can be restored until PurgeDeletedServers removes it
+func (s *serverService) DeleteServer(ctx context.Context, server_id string) error {
+	err := s.serverRepository.DeleteServer(ctx, server_id)
 	return err
 }
 
-func (s *serverService) UpdateServerStatus(id int, status string) error {
-	err := s.serverRepository.UpdateServerStatus(id, status)
+func (s *serverService) GetDeletedServers(offset, limit int) ([]domain.Server, int, error) {
+	if offset < 0 || limit < 0 {
+		return nil, 0, fmt.Errorf("%w: offset and limit must not be negative", ErrInvalidInput)
+	}
+
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		return nil, 0, fmt.Errorf("%w: limit must be at most %d", ErrInvalidInput, MaxPageSize)
+	}
+
+	return s.serverRepository.GetDeletedServers(offset, limit)
+}
+
+func (s *serverService) RestoreServer(ctx context.Context, server_id string) error {
+	err := s.serverRepository.RestoreServer(ctx, server_id)
 	return err
 }
 
+// How often the trash is checked for servers past their retention
+const purgeInterval = time.Hour
+
+/*
+	PurgeDeletedServers removes the servers which have been in the trash for longer than retention,
+	now and then every purgeInterval until ctx is done
+*/
+func (s *serverService) PurgeDeletedServers(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.serverRepository.PurgeServersDeletedBefore(time.Now().Add(-retention))
+		if err != nil {
+			logging.LogMessage("server_administration_service", "Failed to purge deleted servers: "+err.Error(), "ERROR")
+		} else if purged > 0 {
+			logging.LogMessage("server_administration_service", "Purged "+strconv.Itoa(purged)+" servers deleted more than "+retention.String()+" ago", "INFO")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+	Applies a status decided from health checks, statuses set by an administrator are kept
+*/
+func (s *serverService) UpdateServerStatus(id int, status domain.ServerStatus) error {
+	previousStatus, err := s.serverRepository.GetServerStatus(id)
+	if err != nil {
+		return err
+	}
+
+	if previousStatus == status {
+		logging.LogMessage("server_administration_service", "Server ID "+strconv.Itoa(id)+" status is already "+string(status), "INFO")
+		return nil
+	}
+	if previousStatus.IsManual() {
+		logging.LogMessage("server_administration_service", "Server ID "+strconv.Itoa(id)+" is "+string(previousStatus)+", ignoring the health check status", "INFO")
+		return nil
+	}
+
+	changed, err := s.serverRepository.UpdateServerStatus(id, previousStatus, status)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		logging.LogMessage("server_administration_service", "Server ID "+strconv.Itoa(id)+" is no longer "+string(previousStatus)+", skipping the status change", "INFO")
+		return nil
+	}
+
+	return s.serverRepository.AddStatusTransition(id, previousStatus, status, time.Now())
+}
+
 func (s *serverService) GetAllAddresses() ([]dto.ServerAddress, error) {
 	addresses, err := s.serverRepository.GetAllAddresses()
 	if err != nil {
@@ -88,106 +394,271 @@ func (s *serverService) GetAllAddresses() ([]dto.ServerAddress, error) {
 	return addresses, nil
 }
 
-func (s *serverService) ImportServers(buf []byte) ([]domain.Server, []domain.Server, error) {
-	f, err := excelize.OpenReader(strings.NewReader(string(buf)))
+func (s *serverService) GetUpdatedAddresses(since time.Time) ([]dto.ServerAddress, []int, error) {
+	addresses, err := s.serverRepository.GetAddressesUpdatedSince(since)
 	if err != nil {
-		logging.LogMessage("server_administration_service", "Failed to open Excel file: "+err.Error(), "ERROR")
 		return nil, nil, err
 	}
 
-	rows, err := f.GetRows("Servers")
-	if err != nil || len(rows) < 2 {
-		logging.LogMessage("server_administration_service", "Failed to get rows from Excel file: "+err.Error(), "ERROR")
+	ids, err := s.serverRepository.GetCheckedServerIDs()
+	if err != nil {
 		return nil, nil, err
 	}
 
-	servers := make([]domain.Server, 0)
-
-	for _, row := range rows[1:] {
-		serverID := row[0]
-		serverName := row[1]
-		status := row[2]
-		ipv4 := row[3]
-		
-		port := 80
-		if len(row) > 4 {
-			port, err = strconv.Atoi(row[4])
-			if err != nil {
-				logging.LogMessage("server_administration_service", "Invalid port value: "+row[4], "ERROR")
-				return nil, nil, err
-			}
+	return addresses, ids, nil
+}
+
+/*
+	Exports every server matching the filter, not a single page, in one of the Format* formats.
+	Servers are streamed from the database to w. An invalid filter, sort or format is reported before
+	anything is written, a later error leaves w with a truncated file.
+*/
+func (s *serverService) ExportServers(w io.Writer, serverFilter *dto.ServerFilter, sortFields []dto.SortField, options dto.ExportOptions) error {
+	if err := prepareFilter(serverFilter); err != nil {
+		return err
+	}
+
+	sortFields, err := prepareSort(sortFields)
+	if err != nil {
+		return err
+	}
+
+	encoder, err := newServerEncoder(w, options.Format)
+	if err != nil {
+		return err
+	}
+	defer encoder.Close()
+
+	// Counting the servers costs a query, it is only done when someone follows the progress
+	total := 0
+	if options.Progress != nil {
+		if total, err = s.serverRepository.CountServers(serverFilter); err != nil {
+			logging.LogMessage("server_administration_service", "Failed to count servers: "+err.Error(), "ERROR")
+			return err
 		}
+	}
 
-		server := domain.Server{
-			ServerID:   serverID,
-			ServerName: serverName,
-			Status:     status,
-			IPv4:       ipv4,
-			Port:       port,
+	count := 0
+	err = s.serverRepository.StreamServers(serverFilter, sortFields, func(server domain.Server) error {
+		if options.Progress != nil {
+			if err := options.Progress(count, total); err != nil {
+				return err
+			}
 		}
 
-		servers = append(servers, server)
+		count++
+		return encoder.Encode(server)
+	})
+	if err == nil {
+		err = encoder.Finish()
+	}
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to export servers: "+err.Error(), "ERROR")
+		return err
+	}
+
+	if options.Progress != nil {
+		// The servers may have changed since they were counted
+		options.Progress(count, count)
+	}
+
+	logging.LogMessage("server_administration_service", "Exported "+strconv.Itoa(count)+" servers", "INFO")
+	return nil
+}
+
+func (s *serverService) AddServerStatus(result dto.HealthCheckResult, status, decidedStatus domain.ServerStatus) error {
+	err := s.serverRepository.AddServerStatus(result, status, decidedStatus)
+	return err
+}
+
+func (s *serverService) GetNumOnServers() (int, error) {
+	return s.serverRepository.GetNumOnServers()
+}
+
+func (s *serverService) GetStatusCounts(ids []int) (map[domain.ServerStatus]int, error) {
+	return s.serverRepository.GetStatusCounts(ids)
+}
+
+/*
+	Returns nil without labels, meaning every server, so that callers can pass the result
+	straight to the ids scoped methods
+*/
+func (s *serverService) GetServerIDsByLabels(labels map[string]string) ([]int, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	if err := domain.ValidateLabels(labels); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
 	}
 
-	insertedServers, nonInsertedServers, err := s.serverRepository.CreateServers(servers)
+	return s.serverRepository.GetServerIDsByLabels(labels)
+}
+
+func (s *serverService) GetNumServers() (int, error) {
+	return s.serverRepository.GetNumServers()
+}
+
+func (s *serverService) GetServerUptimeRatio(startTime, endTime time.Time, labels map[string]string) (float64, error) {
+	ids, err := s.GetServerIDsByLabels(labels)
 	if err != nil {
-		logging.LogMessage("server_administration_service", "Failed to insert servers: "+err.Error(), "ERROR")
-		return nil, nil, err
+		return 0, err
 	}
-	
-	logging.LogMessage("server_administration_service", "Servers imported successfully", "INFO")
-	return insertedServers, nonInsertedServers, nil	
+
+	return s.serverRepository.GetServerUptimeRatio(startTime, endTime, ids)
 }
 
-func (s *serverService) ExportServers(serverFilter *dto.ServerFilter, from, to int, sortedColumn string, order string) ([]byte, error) {
-	servers, err := s.serverRepository.ViewServers(serverFilter, from, to, sortedColumn, order)
+func (s *serverService) GetServersUptime(startTime, endTime time.Time, labels map[string]string, offset, limit int, sortedColumn string, order string) ([]dto.ServerUptime, int, error) {
+	var less func(a, b dto.ServerUptime) bool
+	switch sortedColumn {
+	case "", "id":
+		less = func(a, b dto.ServerUptime) bool { return a.ID < b.ID }
+	case "uptime_ratio":
+		less = func(a, b dto.ServerUptime) bool { return a.UptimeRatio < b.UptimeRatio }
+	case "num_checks":
+		less = func(a, b dto.ServerUptime) bool { return a.NumChecks < b.NumChecks }
+	case "num_status_changes":
+		less = func(a, b dto.ServerUptime) bool { return a.NumStatusChanges < b.NumStatusChanges }
+	default:
+		return nil, 0, fmt.Errorf("%w: invalid sort column %s", ErrInvalidInput, sortedColumn)
+	}
+
+	if order != "" && order != "asc" && order != "desc" {
+		return nil, 0, fmt.Errorf("%w: invalid sort order %s", ErrInvalidInput, order)
+	}
+
+	ids, err := s.GetServerIDsByLabels(labels)
 	if err != nil {
-		logging.LogMessage("server_administration_service", "Failed to export servers: "+err.Error(), "ERROR")
-		return nil, err
+		return nil, 0, err
+	}
+
+	uptimes, err := s.serverRepository.GetServersUptime(startTime, endTime, ids)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get servers uptime: "+err.Error(), "ERROR")
+		return nil, 0, err
+	}
+
+	sort.SliceStable(uptimes, func(i, j int) bool {
+		if order == "desc" {
+			return less(uptimes[j], uptimes[i])
+		}
+		return less(uptimes[i], uptimes[j])
+	})
+
+	total := len(uptimes)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
 	}
 
-	f := excelize.NewFile()
-	sheet := "Servers"
-	f.SetSheetName("Sheet1", sheet)
+	return uptimes[offset:end], total, nil
+}
+
+// MaxTimelineBuckets bounds the date histogram, one day of minutes
+const MaxTimelineBuckets = 1440
+
+var timelineIntervals = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+}
 
-	headers := []string{"Server ID", "Server Name", "Status", "IPv4", "Port"}
-	for i, header := range headers {
-		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
-		f.SetCellValue(sheet, cell, header)
+func (s *serverService) GetUptimeTimeline(id int, startTime, endTime time.Time, interval string) ([]dto.UptimeBucket, error) {
+	bucketSize, ok := timelineIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid interval %s", ErrInvalidInput, interval)
 	}
 
-	for i, server := range servers {
-		f.SetCellValue(sheet, "A"+strconv.Itoa(i+2), server.ServerID)
-		f.SetCellValue(sheet, "B"+strconv.Itoa(i+2), server.ServerName)
-		f.SetCellValue(sheet, "C"+strconv.Itoa(i+2), server.Status)
-		f.SetCellValue(sheet, "D"+strconv.Itoa(i+2), server.IPv4)
-		f.SetCellValue(sheet, "E"+strconv.Itoa(i+2), server.Port)
+	if !startTime.Before(endTime) {
+		return nil, fmt.Errorf("%w: start time must be before end time", ErrInvalidInput)
 	}
 
-	var buf bytes.Buffer
-	err = f.Write(&buf)
+	if endTime.Sub(startTime)/bucketSize > MaxTimelineBuckets {
+		return nil, fmt.Errorf("%w: the time range spans more than %d %s buckets", ErrInvalidInput, MaxTimelineBuckets, interval)
+	}
+
+	timeline, err := s.serverRepository.GetUptimeTimeline(id, startTime, endTime, interval)
 	if err != nil {
-		logging.LogMessage("server_administration_service", "Failed to write Excel file to buffer: "+err.Error(), "ERROR")
+		logging.LogMessage("server_administration_service", "Failed to get uptime timeline: "+err.Error(), "ERROR")
 		return nil, err
 	}
 
-	logging.LogMessage("server_administration_service", "Servers exported successfully", "INFO")
-	return buf.Bytes(), nil
+	return timeline, nil
 }
 
-func (s *serverService) AddServerStatus(id int, status string) error {
-	err := s.serverRepository.AddServerStatus(id, status)
-	return err
+func (s *serverService) GetStatusTransitions(id int, startTime, endTime time.Time, offset, limit int) ([]dto.StatusTransition, int, error) {
+	if offset < 0 || limit < 0 {
+		return nil, 0, fmt.Errorf("%w: offset and limit must not be negative", ErrInvalidInput)
+	}
+
+	if limit == 0 {
+		limit = 100
+	}
+
+	transitions, total, err := s.serverRepository.GetStatusTransitions(id, startTime, endTime, offset, limit)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get status transitions: "+err.Error(), "ERROR")
+		return nil, 0, err
+	}
+
+	return transitions, total, nil
 }
 
-func (s *serverService) GetNumOnServers() (int, error) {
-	return s.serverRepository.GetNumOnServers()
+// Returns the latency percentiles of the server, or of every server for id <= 0, sorted by server id
+func (s *serverService) GetLatencyPercentiles(id int, startTime, endTime time.Time) ([]dto.LatencyPercentiles, error) {
+	if !startTime.Before(endTime) {
+		return nil, fmt.Errorf("%w: start time must be before end time", ErrInvalidInput)
+	}
+
+	latencies, err := s.serverRepository.GetLatencyPercentiles(id, startTime, endTime)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get latency percentiles: "+err.Error(), "ERROR")
+		return nil, err
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i].ID < latencies[j].ID
+	})
+
+	return latencies, nil
 }
 
-func (s *serverService) GetNumServers() (int, error) {
-	return s.serverRepository.GetNumServers()
+func (s *serverService) GetLocationStatuses(id int) ([]dto.LocationStatus, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: invalid server id %d", ErrInvalidInput, id)
+	}
+
+	return s.serverRepository.GetLocationStatuses(id)
 }
 
-func (s *serverService) GetServerUptimeRatio(startTime, endTime time.Time) (float64, error) {
-	return s.serverRepository.GetServerUptimeRatio(startTime, endTime)
+/*
+	Returns the servers whose latest minConsecutiveChecks results in the last 24 hours are all Down.
+	The reported ConsecutiveDownChecks is capped at minConsecutiveChecks.
+*/
+func (s *serverService) GetDownServers(minConsecutiveChecks int) ([]dto.DownServer, error) {
+	// Elasticsearch refuses top_hits bigger than index.max_inner_result_window (100 by default)
+	if minConsecutiveChecks < 1 || minConsecutiveChecks > 100 {
+		return nil, fmt.Errorf("%w: consecutive checks must be between 1 and 100", ErrInvalidInput)
+	}
+
+	servers, err := s.serverRepository.GetDownServers(time.Now().Add(-24*time.Hour), minConsecutiveChecks)
+	if err != nil {
+		logging.LogMessage("server_administration_service", "Failed to get down servers: "+err.Error(), "ERROR")
+		return nil, err
+	}
+
+	downServers := make([]dto.DownServer, 0, len(servers))
+	for _, server := range servers {
+		if server.ConsecutiveDownChecks >= minConsecutiveChecks {
+			downServers = append(downServers, server)
+		}
+	}
+
+	return downServers, nil
 }
\ No newline at end of file
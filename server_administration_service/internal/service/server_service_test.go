@@ -2,10 +2,16 @@ package service_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"reflect"
 	"server_administration_service/internal/domain"
 	"server_administration_service/internal/dto"
+	"server_administration_service/internal/repository"
 	"server_administration_service/internal/service"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -17,42 +23,123 @@ type mockServerRepo struct {
 	mock.Mock
 }
 
-func (m *mockServerRepo) CreateServer(server *domain.Server) (int, error) {
+func (m *mockServerRepo) CreateServer(ctx context.Context, server *domain.Server) (int, error) {
 	args := m.Called(server)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *mockServerRepo) CreateServers(servers []domain.Server) ([]domain.Server, []domain.Server, error) {
+func (m *mockServerRepo) CreateServers(ctx context.Context, servers []domain.Server) ([]domain.Server, []dto.RejectedServer, error) {
 	args := m.Called(servers)
 	if args.Get(0) == nil || args.Get(1) == nil {
 		return nil, nil, args.Error(2)
 	}
-	return args.Get(0).([]domain.Server), args.Get(1).([]domain.Server), args.Error(2)
+	return args.Get(0).([]domain.Server), args.Get(1).([]dto.RejectedServer), args.Error(2)
 }
 
-func (m *mockServerRepo) ViewServers(serverFilter *dto.ServerFilter, from, to int, sortedColumn string, order string) ([]domain.Server, error) {
-	args := m.Called(serverFilter, from, to, sortedColumn, order)
+func (m *mockServerRepo) FindServers(serverIDs, serverNames []string) ([]domain.Server, error) {
+	args := m.Called(serverIDs, serverNames)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]domain.Server), args.Error(1)
 }
 
-func (m *mockServerRepo) UpdateServer(serverID string, updatedData map[string]interface{}) error {
+func (m *mockServerRepo) GetAllServers() ([]domain.Server, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Server), args.Error(1)
+}
+
+func (m *mockServerRepo) ApplyServerImport(ctx context.Context, plan dto.ServerImportPlan) (*dto.ServerImportResult, error) {
+	args := m.Called(plan)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ServerImportResult), args.Error(1)
+}
+
+func (m *mockServerRepo) ViewServers(serverFilter *dto.ServerFilter, sortFields []dto.SortField, cursor *dto.ServerCursor, limit int) ([]domain.Server, error) {
+	args := m.Called(serverFilter, sortFields, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Server), args.Error(1)
+}
+
+// StreamServers passes the servers it is given to fn, then returns its error
+func (m *mockServerRepo) StreamServers(serverFilter *dto.ServerFilter, sortFields []dto.SortField, fn func(server domain.Server) error) error {
+	args := m.Called(serverFilter, sortFields)
+	if servers, ok := args.Get(0).([]domain.Server); ok {
+		for _, server := range servers {
+			if err := fn(server); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *mockServerRepo) CountServers(serverFilter *dto.ServerFilter) (int, error) {
+	args := m.Called(serverFilter)
+	return args.Int(0), args.Error(1)
+}
+
+// The sort the repository receives for a sort on server_id, with the id tie-breaker
+var byServerID = []dto.SortField{{Column: "server_id", Order: "asc"}, {Column: "id", Order: "asc"}}
+
+func (m *mockServerRepo) UpdateServer(ctx context.Context, serverID string, updatedData map[string]interface{}) error {
 	args := m.Called(serverID, updatedData)
 	return args.Error(0)
 }
 
-func (m *mockServerRepo) DeleteServer(serverID string) error {
+func (m *mockServerRepo) DeleteServer(ctx context.Context, serverID string) error {
 	args := m.Called(serverID)
 	return args.Error(0)
 }
 
-func (m *mockServerRepo) UpdateServerStatus(id int, status string) error {
-	args := m.Called(id, status)
+func (m *mockServerRepo) GetDeletedServers(offset, limit int) ([]domain.Server, int, error) {
+	args := m.Called(offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]domain.Server), args.Int(1), args.Error(2)
+}
+
+func (m *mockServerRepo) RestoreServer(ctx context.Context, serverID string) error {
+	args := m.Called(serverID)
+	return args.Error(0)
+}
+
+func (m *mockServerRepo) PurgeServersDeletedBefore(before time.Time) (int, error) {
+	args := m.Called(before)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockServerRepo) GetServerStatus(id int) (domain.ServerStatus, error) {
+	args := m.Called(id)
+	return args.Get(0).(domain.ServerStatus), args.Error(1)
+}
+
+func (m *mockServerRepo) UpdateServerStatus(id int, previousStatus, status domain.ServerStatus) (bool, error) {
+	args := m.Called(id, previousStatus, status)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockServerRepo) AddStatusTransition(id int, previousStatus, status domain.ServerStatus, changedAt time.Time) error {
+	args := m.Called(id, previousStatus, status, changedAt)
 	return args.Error(0)
 }
 
+func (m *mockServerRepo) GetStatusTransitions(id int, startTime, endTime time.Time, offset, limit int) ([]dto.StatusTransition, int, error) {
+	args := m.Called(id, startTime, endTime, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]dto.StatusTransition), args.Int(1), args.Error(2)
+}
+
 func (m *mockServerRepo) GetAllAddresses() ([]dto.ServerAddress, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
@@ -61,8 +148,40 @@ func (m *mockServerRepo) GetAllAddresses() ([]dto.ServerAddress, error) {
 	return args.Get(0).([]dto.ServerAddress), args.Error(1)
 }
 
-func (m *mockServerRepo) AddServerStatus(serverID int, status string) error {
-	args := m.Called(serverID, status)
+func (m *mockServerRepo) GetAddressesUpdatedSince(since time.Time) ([]dto.ServerAddress, error) {
+	args := m.Called(since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dto.ServerAddress), args.Error(1)
+}
+
+func (m *mockServerRepo) GetCheckedServerIDs() ([]int, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *mockServerRepo) SetLocationStatus(id int, status dto.LocationStatus) ([]dto.LocationStatus, error) {
+	args := m.Called(id, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dto.LocationStatus), args.Error(1)
+}
+
+func (m *mockServerRepo) GetLocationStatuses(id int) ([]dto.LocationStatus, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dto.LocationStatus), args.Error(1)
+}
+
+func (m *mockServerRepo) AddServerStatus(result dto.HealthCheckResult, status, decidedStatus domain.ServerStatus) error {
+	args := m.Called(result, status, decidedStatus)
 	return args.Error(0)
 }
 
@@ -71,20 +190,86 @@ func (m *mockServerRepo) GetNumOnServers() (int, error) {
 	return args.Int(0), args.Error(1)
 }
 
+func (m *mockServerRepo) GetStatusCounts(ids []int) (map[domain.ServerStatus]int, error) {
+	args := m.Called(ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[domain.ServerStatus]int), args.Error(1)
+}
+
 func (m *mockServerRepo) GetNumServers() (int, error) {
 	args := m.Called()
 	return args.Int(0), args.Error(1)
 }
-func (m *mockServerRepo) GetServerUptimeRatio(startTime, endTime time.Time) (float64, error) {
-	args := m.Called(startTime, endTime)
+func (m *mockServerRepo) GetServerIDsByLabels(labels map[string]string) ([]int, error) {
+	args := m.Called(labels)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *mockServerRepo) GetServerUptimeRatio(startTime, endTime time.Time, ids []int) (float64, error) {
+	args := m.Called(startTime, endTime, ids)
 	return args.Get(0).(float64), args.Error(1)
 }
 
+func (m *mockServerRepo) GetServersUptime(startTime, endTime time.Time, ids []int) ([]dto.ServerUptime, error) {
+	args := m.Called(startTime, endTime, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dto.ServerUptime), args.Error(1)
+}
+
+func (m *mockServerRepo) GetUptimeTimeline(id int, startTime, endTime time.Time, interval string) ([]dto.UptimeBucket, error) {
+	args := m.Called(id, startTime, endTime, interval)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dto.UptimeBucket), args.Error(1)
+}
+
+func (m *mockServerRepo) GetDownServers(since time.Time, maxChecks int) ([]dto.DownServer, error) {
+	args := m.Called(since, maxChecks)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dto.DownServer), args.Error(1)
+}
+
+func (m *mockServerRepo) GetLatencyPercentiles(id int, startTime, endTime time.Time) ([]dto.LatencyPercentiles, error) {
+	args := m.Called(id, startTime, endTime)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]dto.LatencyPercentiles), args.Error(1)
+}
+
 func (m *mockServerRepo) SyncServerStatus() error {
 	args := m.Called()
 	return args.Error(0)
 }
 
+func (m *mockServerRepo) SearchServers(searchQuery dto.ServerSearchQuery) (*dto.ServerSearchResult, error) {
+	args := m.Called(searchQuery)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ServerSearchResult), args.Error(1)
+}
+
+func (m *mockServerRepo) EnsureServerIndex() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *mockServerRepo) SyncServerIndex() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
 func TestCreateServer_Success(t *testing.T) {
 	mockRepo := new(mockServerRepo)
 	serverService := service.NewServerService(mockRepo)
@@ -98,7 +283,7 @@ func TestCreateServer_Success(t *testing.T) {
 	}
 	mockRepo.On("CreateServer", server).Return(1, nil)
 
-	id, err := serverService.CreateServer("server123", "Test Server", "On", "192.168.1.1", 8080)
+	id, err := serverService.CreateServer(context.Background(), server)
 	
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -122,7 +307,7 @@ func TestCreateServer_Failure(t *testing.T) {
 	}
 	mockRepo.On("CreateServer", server).Return(0, errors.New("server creation failed"))
 
-	id, err := serverService.CreateServer("server123", "Test Server", "On", "192.168.1.1", 8080)
+	id, err := serverService.CreateServer(context.Background(), server)
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -132,221 +317,1043 @@ func TestCreateServer_Failure(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
-func TestViewServers_Success(t *testing.T) {
+func TestCreateServer_DefaultsHealthCheckSettings(t *testing.T) {
 	mockRepo := new(mockServerRepo)
 	serverService := service.NewServerService(mockRepo)
-	
-	serverFilter := &dto.ServerFilter{
-		Status: "On",
-	}
-	servers := []domain.Server{
-		{
-			ServerID:   "server123",
-			ServerName: "Test Server",
-			Status:     "On",
-			IPv4:       "192.168.1.1",
-			Port:       8080,
-		},
-		{
-			ServerID:   "server124",
-			ServerName: "Test Server 2",
-			Status:     "On",
-			IPv4:       "192.168.1.2",
-			Port:       8081,
-		},
-	}
-	mockRepo.On("ViewServers", serverFilter, 0, 10, "ServerID", "asc").Return(servers, nil)
 
-	result, err := serverService.ViewServers(serverFilter, 0, 10, "ServerID", "asc")
+	server := &domain.Server{ServerID: "server123", ServerName: "Test Server", Status: "On", IPv4: "192.168.1.1", Port: 8080}
+	mockRepo.On("CreateServer", server).Return(1, nil)
+
+	_, err := serverService.CreateServer(context.Background(), server)
+
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
-	if len(result) != 2 {
-		t.Errorf("Expected 2 servers, got %d", len(result))
-	}
-	if result[0].ServerID != "server123" {
-		t.Errorf("Expected server ID 'server123', got %s", result[0].ServerID)
+	if server.ProbeType != domain.ProbeTCP {
+		t.Errorf("Expected probe type %s, got %s", domain.ProbeTCP, server.ProbeType)
 	}
-	if result[1].ServerID != "server124" {
-		t.Errorf("Expected server ID 'server124', got %s", result[1].ServerID)
+	if server.CheckIntervalSeconds != domain.DefaultCheckIntervalSeconds || server.TimeoutMs != domain.DefaultTimeoutMs {
+		t.Errorf("Expected default check interval and timeout, got %d and %d", server.CheckIntervalSeconds, server.TimeoutMs)
 	}
 	mockRepo.AssertExpectations(t)
 }
 
-func TestViewServers_Failure(t *testing.T) {
+func TestCreateServer_StatusDefaultsToUnknown(t *testing.T) {
 	mockRepo := new(mockServerRepo)
 	serverService := service.NewServerService(mockRepo)
-	
-	serverFilter := &dto.ServerFilter{
-		Status: "On",
-	}
-	mockRepo.On("ViewServers", serverFilter, 0, 10, "ServerID", "asc").Return(nil, errors.New("failed to fetch servers"))
 
-	result, err := serverService.ViewServers(serverFilter, 0, 10, "ServerID", "asc")
-	if err == nil {
-		t.Errorf("Expected error, got nil")
+	server := &domain.Server{ServerID: "server123", ServerName: "Test Server", IPv4: "192.168.1.1", Port: 8080}
+	mockRepo.On("CreateServer", server).Return(1, nil)
+
+	_, err := serverService.CreateServer(context.Background(), server)
+
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
 	}
-	if result != nil {
-		t.Errorf("Expected nil result, got %v", result)
+	if server.Status != domain.StatusUnknown {
+		t.Errorf("Expected status %s, got %s", domain.StatusUnknown, server.Status)
 	}
-	mockRepo.AssertExpectations(t)
 }
 
-func TestUpdateServer_Success(t *testing.T) {
+func TestCreateServer_NormalizesLegacyStatus(t *testing.T) {
 	mockRepo := new(mockServerRepo)
 	serverService := service.NewServerService(mockRepo)
-	
-	updatedData := map[string]interface{}{
-		"Status": "Off",
-	}
-	mockRepo.On("UpdateServer", "server123", updatedData).Return(nil)
 
-	err := serverService.UpdateServer("server123", updatedData)
+	server := &domain.Server{ServerID: "server123", ServerName: "Test Server", Status: "off", IPv4: "192.168.1.1", Port: 8080}
+	mockRepo.On("CreateServer", server).Return(1, nil)
+
+	_, err := serverService.CreateServer(context.Background(), server)
+
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
-	mockRepo.AssertExpectations(t)
+	if server.Status != domain.StatusDown {
+		t.Errorf("Expected status %s, got %s", domain.StatusDown, server.Status)
+	}
 }
 
-func TestDeleteServer_Success(t *testing.T) {
+func TestCreateServer_InvalidStatus(t *testing.T) {
 	mockRepo := new(mockServerRepo)
 	serverService := service.NewServerService(mockRepo)
-	
-	mockRepo.On("DeleteServer", "server123").Return(nil)
-	err := serverService.DeleteServer("server123")
-	if err != nil {
-		t.Errorf("Expected no error, got %v", err)
+
+	server := &domain.Server{ServerID: "server123", ServerName: "Test Server", Status: "active", IPv4: "192.168.1.1", Port: 8080}
+
+	_, err := serverService.CreateServer(context.Background(), server)
+
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
 	}
-	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "CreateServer", mock.Anything)
 }
 
-func TestUpdateServerStatus_Success(t *testing.T) {
+func TestCreateServer_InvalidLabels(t *testing.T) {
 	mockRepo := new(mockServerRepo)
 	serverService := service.NewServerService(mockRepo)
-	
-	mockRepo.On("UpdateServerStatus", 1, "On").Return(nil)
-	err := serverService.UpdateServerStatus(1, "On")
-	if err != nil {
-		t.Errorf("Expected no error, got %v", err)
+
+	server := &domain.Server{ServerID: "server123", ServerName: "Test Server", IPv4: "192.168.1.1", Port: 8080,
+		Labels: map[string]string{"team": "pay ments"}}
+
+	_, err := serverService.CreateServer(context.Background(), server)
+
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
 	}
-	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "CreateServer", mock.Anything)
 }
 
-func TestGetAllAddresses_Success(t *testing.T) {
+func TestCreateServer_InvalidProbeType(t *testing.T) {
 	mockRepo := new(mockServerRepo)
 	serverService := service.NewServerService(mockRepo)
-	
-	addresses := []dto.ServerAddress{
-		{
-			ID: 1,
-			IPv4:     "192.168.1.1",
-			Port:     8080,
-		},
-		{
-			ID: 2,
-			IPv4:     "192.168.1.2",
-			Port:     8081,
-		},
-	}
-	mockRepo.On("GetAllAddresses").Return(addresses, nil)
 
-	result, err := serverService.GetAllAddresses()
-	if err != nil {
-		t.Errorf("Expected no error, got %v", err)
-	}
-	if len(result) != 2 {
-		t.Errorf("Expected 2 addresses, got %d", len(result))
-	}
+	server := &domain.Server{ServerID: "server123", ServerName: "Test Server", Status: "On", IPv4: "192.168.1.1", Port: 8080, ProbeType: "icmp"}
 
-	mockRepo.AssertExpectations(t)
+	_, err := serverService.CreateServer(context.Background(), server)
+
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+	mockRepo.AssertNotCalled(t, "CreateServer", mock.Anything)
 }
 
-func TestGetAllAddresses_Failure(t *testing.T) {
+func TestCreateServer_InvalidCheckInterval(t *testing.T) {
 	mockRepo := new(mockServerRepo)
 	serverService := service.NewServerService(mockRepo)
-	
-	mockRepo.On("GetAllAddresses").Return(nil, errors.New("failed to fetch addresses"))
 
-	result, err := serverService.GetAllAddresses()
-	if err == nil {
-		t.Errorf("Expected error, got nil")
-	}
-	if result != nil {
-		t.Errorf("Expected nil result, got %v", result)
+	server := &domain.Server{ServerID: "server123", ServerName: "Test Server", Status: "On", IPv4: "192.168.1.1", Port: 8080, CheckIntervalSeconds: 1}
+
+	_, err := serverService.CreateServer(context.Background(), server)
+
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
 	}
-	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "CreateServer", mock.Anything)
 }
 
-func TestGetNumOnServers_Success(t *testing.T) {
+func TestUpdateServer_CheckSettings(t *testing.T) {
 	mockRepo := new(mockServerRepo)
 	serverService := service.NewServerService(mockRepo)
-	
-	mockRepo.On("GetNumOnServers").Return(5, nil)
 
-	result, err := serverService.GetNumOnServers()
-	if err != nil {
+	updatedData := map[string]interface{}{"check_interval_seconds": 10, "retries": 3}
+	mockRepo.On("UpdateServer", "server123", updatedData).Return(nil)
+
+	if err := serverService.UpdateServer(context.Background(), "server123", updatedData); err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
-	if result != 5 {
-		t.Errorf("Expected 5 servers, got %d", result)
+
+	err := serverService.UpdateServer(context.Background(), "server123", map[string]interface{}{"timeout_ms": 10})
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for timeout, got %v", err)
 	}
-	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNumberOfCalls(t, "UpdateServer", 1)
 }
 
-func TestGetNumServers(t *testing.T) {
+func TestUpdateServer_Status(t *testing.T) {
 	mockRepo := new(mockServerRepo)
 	serverService := service.NewServerService(mockRepo)
-	
-	mockRepo.On("GetNumServers").Return(10, nil)
 
-	result, err := serverService.GetNumServers()
+	mockRepo.On("UpdateServer", "server123", map[string]interface{}{"status": domain.StatusMaintenance}).Return(nil)
+
+	err := serverService.UpdateServer(context.Background(), "server123", map[string]interface{}{"status": "maintenance"})
+
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
-	if result != 10 {
-		t.Errorf("Expected 10 servers, got %d", result)
-	}
 	mockRepo.AssertExpectations(t)
 }
 
-func TestGetServerUptimeRatio_Success(t *testing.T) {
+func TestUpdateServer_Labels(t *testing.T) {
 	mockRepo := new(mockServerRepo)
 	serverService := service.NewServerService(mockRepo)
-	
-	startTime := time.Now().Add(-1 * time.Hour)
-	endTime := time.Now()
-	mockRepo.On("GetServerUptimeRatio", startTime, endTime).Return(0.95, nil)
 
-	result, err := serverService.GetServerUptimeRatio(startTime, endTime)
+	labels := map[string]string{"env": "prod", "team": "payments"}
+	mockRepo.On("UpdateServer", "server123", map[string]interface{}{"labels": labels}).Return(nil)
+
+	err := serverService.UpdateServer(context.Background(), "server123", map[string]interface{}{"labels": labels})
+
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
-	if result != 0.95 {
-		t.Errorf("Expected uptime ratio 0.95, got %f", result)
-	}
 	mockRepo.AssertExpectations(t)
 }
 
-func TestAddServerStatus(t *testing.T) {
+func TestUpdateServer_InvalidLabels(t *testing.T) {
 	mockRepo := new(mockServerRepo)
 	serverService := service.NewServerService(mockRepo)
-	
-	mockRepo.On("AddServerStatus", 1, "On").Return(nil)
 
-	err := serverService.AddServerStatus(1, "On")
-	if err != nil {
-		t.Errorf("Expected no error, got %v", err)
+	err := serverService.UpdateServer(context.Background(), "server123", map[string]interface{}{"labels": map[string]string{"env=": "prod"}})
+
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
 	}
-	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "UpdateServer", mock.Anything, mock.Anything)
 }
 
-func createTestExcelBuffer() []byte {
-	f := excelize.NewFile()
-	_ = f.SetSheetRow("Sheet1", "A1", &[]interface{}{"ServerID", "ServerName", "Status", "IPv4", "Port"})
-	_ = f.SetSheetRow("Sheet1", "A2", &[]interface{}{"srv-1", "Server One", "active", "192.168.1.1", 8080})
-	var buf bytes.Buffer
-	_ = f.Write(&buf)
-	return buf.Bytes()
+func TestUpdateServer_InvalidStatus(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	err := serverService.UpdateServer(context.Background(), "server123", map[string]interface{}{"status": "Sleeping"})
+
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+	mockRepo.AssertNotCalled(t, "UpdateServer", mock.Anything, mock.Anything)
+}
+
+func TestViewServers_InvalidStatusFilter(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	_, err := serverService.ViewServers(&dto.ServerFilter{Statuses: []string{"Up", "Sleeping"}, Port: -1}, nil, "", 10)
+
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+	mockRepo.AssertNotCalled(t, "ViewServers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateServer_InvalidProbeType(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	err := serverService.UpdateServer(context.Background(), "server123", map[string]interface{}{"probe_type": "icmp"})
+
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+	mockRepo.AssertNotCalled(t, "UpdateServer", mock.Anything, mock.Anything)
+}
+
+func TestViewServers_Success(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+	
+	serverFilter := &dto.ServerFilter{
+		Statuses: []string{"On"},
+	}
+	servers := []domain.Server{
+		{
+			ServerID:   "server123",
+			ServerName: "Test Server",
+			Status:     "On",
+			IPv4:       "192.168.1.1",
+			Port:       8080,
+		},
+		{
+			ServerID:   "server124",
+			ServerName: "Test Server 2",
+			Status:     "On",
+			IPv4:       "192.168.1.2",
+			Port:       8081,
+		},
+	}
+	mockRepo.On("ViewServers", serverFilter, byServerID, (*dto.ServerCursor)(nil), 11).Return(servers, nil)
+	mockRepo.On("CountServers", serverFilter).Return(2, nil)
+
+	result, err := serverService.ViewServers(serverFilter, []dto.SortField{{Column: "server_id"}}, "", 10)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if len(result.Servers) != 2 {
+		t.Errorf("Expected 2 servers, got %d", len(result.Servers))
+	}
+	if result.Servers[0].ServerID != "server123" {
+		t.Errorf("Expected server ID 'server123', got %s", result.Servers[0].ServerID)
+	}
+	if result.Servers[1].ServerID != "server124" {
+		t.Errorf("Expected server ID 'server124', got %s", result.Servers[1].ServerID)
+	}
+	if result.NextCursor != "" {
+		t.Errorf("Expected no next cursor on the last page, got %s", result.NextCursor)
+	}
+	if result.Total != 2 {
+		t.Errorf("Expected total 2, got %d", result.Total)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestViewServers_Failure(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+	
+	serverFilter := &dto.ServerFilter{
+		Statuses: []string{"On"},
+	}
+	mockRepo.On("ViewServers", serverFilter, byServerID, (*dto.ServerCursor)(nil), 11).Return(nil, errors.New("failed to fetch servers"))
+
+	result, err := serverService.ViewServers(serverFilter, []dto.SortField{{Column: "server_id", Order: "asc"}}, "", 10)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+	if result != nil {
+		t.Errorf("Expected nil result, got %v", result)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestViewServers_NextCursor(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	serverFilter := &dto.ServerFilter{Port: -1}
+	firstPage := []domain.Server{
+		{ID: 4, ServerName: "alpha"},
+		{ID: 2, ServerName: "beta"},
+		{ID: 9, ServerName: "gamma"},
+	}
+	sortFields := []dto.SortField{{Column: "server_name", Order: "asc"}, {Column: "id", Order: "asc"}}
+	mockRepo.On("ViewServers", serverFilter, sortFields, (*dto.ServerCursor)(nil), 3).Return(firstPage, nil)
+	mockRepo.On("ViewServers", serverFilter, sortFields, &dto.ServerCursor{Values: []interface{}{"beta", 2}}, 3).Return(firstPage[2:], nil)
+	mockRepo.On("CountServers", serverFilter).Return(3, nil)
+
+	result, err := serverService.ViewServers(serverFilter, []dto.SortField{{Column: "server_name", Order: "asc"}}, "", 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(result.Servers) != 2 {
+		t.Errorf("Expected 2 servers, got %d", len(result.Servers))
+	}
+	if result.NextCursor == "" {
+		t.Fatalf("Expected a next cursor")
+	}
+
+	next, err := serverService.ViewServers(serverFilter, []dto.SortField{{Column: "server_name", Order: "asc"}}, result.NextCursor, 2)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if len(next.Servers) != 1 || next.Servers[0].ServerName != "gamma" {
+		t.Errorf("Expected only gamma on the last page, got %v", next.Servers)
+	}
+	if next.NextCursor != "" {
+		t.Errorf("Expected no next cursor on the last page, got %s", next.NextCursor)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestViewServers_CursorForAnotherSort(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	serverFilter := &dto.ServerFilter{Port: -1}
+	mockRepo.On("ViewServers", serverFilter, []dto.SortField{{Column: "id", Order: "asc"}}, (*dto.ServerCursor)(nil), 2).Return([]domain.Server{{ID: 1}, {ID: 2}}, nil)
+	mockRepo.On("CountServers", serverFilter).Return(2, nil)
+
+	result, err := serverService.ViewServers(serverFilter, nil, "", 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = serverService.ViewServers(serverFilter, []dto.SortField{{Column: "id", Order: "desc"}}, result.NextCursor, 1)
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestViewServers_MixedSortCursor(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	serverFilter := &dto.ServerFilter{Port: -1}
+	lastUpdated := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sortFields := []dto.SortField{{Column: "status", Order: "asc"}, {Column: "last_updated", Order: "desc"}, {Column: "id", Order: "desc"}}
+	mockRepo.On("ViewServers", serverFilter, sortFields, (*dto.ServerCursor)(nil), 2).
+		Return([]domain.Server{{ID: 8, Status: domain.StatusDown, LastUpdated: lastUpdated}, {ID: 3}}, nil)
+	mockRepo.On("ViewServers", serverFilter, sortFields, &dto.ServerCursor{Values: []interface{}{"Down", lastUpdated, 8}}, 2).
+		Return([]domain.Server{}, nil)
+	mockRepo.On("CountServers", serverFilter).Return(2, nil)
+
+	requested := []dto.SortField{{Column: "status", Order: "asc"}, {Column: "last_updated", Order: "desc"}}
+	result, err := serverService.ViewServers(serverFilter, requested, "", 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := serverService.ViewServers(serverFilter, requested, result.NextCursor, 1); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestViewServers_RangeFilters(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	serverFilter := &dto.ServerFilter{Port: -1, Statuses: []string{"off", "degraded"}, IPv4CIDR: "10.2.7.1/16"}
+	mockRepo.On("ViewServers", serverFilter, mock.Anything, mock.Anything, mock.Anything).Return([]domain.Server{}, nil)
+	mockRepo.On("CountServers", serverFilter).Return(0, nil)
+
+	if _, err := serverService.ViewServers(serverFilter, nil, "", 10); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if serverFilter.Statuses[0] != "Down" || serverFilter.Statuses[1] != "Degraded" {
+		t.Errorf("Expected normalized statuses, got %v", serverFilter.Statuses)
+	}
+	if serverFilter.IPv4CIDR != "10.2.0.0/16" {
+		t.Errorf("Expected the network of the range, got %s", serverFilter.IPv4CIDR)
+	}
+}
+
+func TestViewServers_InvalidRangeFilters(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	low, high, tooHigh := 9000, 8000, 70000
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	filters := []*dto.ServerFilter{
+		{Port: -1, IPv4CIDR: "10.2.0.0/33"},
+		{Port: -1, IPv4CIDR: "2001:db8::/32"},
+		{Port: -1, PortRange: dto.IntRange{From: &low, To: &high}},
+		{Port: -1, PortRange: dto.IntRange{To: &tooHigh}},
+		{Port: -1, LastUpdated: dto.TimeRange{From: &now, To: &earlier}},
+	}
+	for _, filter := range filters {
+		_, err := serverService.ViewServers(filter, nil, "", 10)
+		if !errors.Is(err, service.ErrInvalidInput) {
+			t.Errorf("Expected ErrInvalidInput for %+v, got %v", filter, err)
+		}
+	}
+	mockRepo.AssertNotCalled(t, "ViewServers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestViewServers_InvalidCursor(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	_, err := serverService.ViewServers(&dto.ServerFilter{Port: -1}, nil, "not a cursor", 10)
+
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+	mockRepo.AssertNotCalled(t, "ViewServers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestViewServers_InvalidSort(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	cases := [][]dto.SortField{
+		{{Column: "password; DROP TABLE servers", Order: "asc"}},
+		{{Column: "id", Order: "sideways"}},
+		{{Column: "status"}, {Column: "status", Order: "desc"}},
+	}
+	for _, sortFields := range cases {
+		_, err := serverService.ViewServers(&dto.ServerFilter{Port: -1}, sortFields, "", 10)
+		if !errors.Is(err, service.ErrInvalidInput) {
+			t.Errorf("Expected ErrInvalidInput for %v, got %v", sortFields, err)
+		}
+	}
+	mockRepo.AssertNotCalled(t, "ViewServers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestViewServers_PageSize(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	serverFilter := &dto.ServerFilter{Port: -1}
+	mockRepo.On("ViewServers", serverFilter, []dto.SortField{{Column: "id", Order: "asc"}}, (*dto.ServerCursor)(nil), service.DefaultPageSize+1).Return([]domain.Server{}, nil)
+	mockRepo.On("CountServers", serverFilter).Return(0, nil)
+
+	result, err := serverService.ViewServers(serverFilter, nil, "", 0)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if result.Servers == nil {
+		t.Errorf("Expected an empty list of servers, got nil")
+	}
+
+	for _, pageSize := range []int{-1, service.MaxPageSize + 1} {
+		_, err := serverService.ViewServers(serverFilter, nil, "", pageSize)
+		if !errors.Is(err, service.ErrInvalidInput) {
+			t.Errorf("Expected ErrInvalidInput for page size %d, got %v", pageSize, err)
+		}
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSearchServers_Defaults(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	result := &dto.ServerSearchResult{Total: 1}
+	mockRepo.On("SearchServers", dto.ServerSearchQuery{
+		Text:     "payments",
+		Statuses: []string{"Down", "Up"},
+		Port:     -1,
+		Limit:    service.DefaultSearchLimit,
+	}).Return(result, nil)
+
+	got, err := serverService.SearchServers(dto.ServerSearchQuery{Text: "  payments ", Statuses: []string{"off", "On"}, Port: -1})
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if got != result {
+		t.Errorf("Expected the repository result, got %v", got)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSearchServers_InvalidQuery(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	queries := []dto.ServerSearchQuery{
+		{Port: -1, Limit: service.MaxSearchLimit + 1},
+		{Port: -1, Offset: -1},
+		{Port: -1, Offset: 9990, Limit: 20},
+		{Port: -1, Statuses: []string{"Sleeping"}},
+	}
+	for _, query := range queries {
+		_, err := serverService.SearchServers(query)
+		if !errors.Is(err, service.ErrInvalidInput) {
+			t.Errorf("Expected ErrInvalidInput for %+v, got %v", query, err)
+		}
+	}
+	mockRepo.AssertNotCalled(t, "SearchServers", mock.Anything)
+}
+
+func TestUpdateServer_Success(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+	
+	updatedData := map[string]interface{}{
+		"Status": "Off",
+	}
+	mockRepo.On("UpdateServer", "server123", updatedData).Return(nil)
+
+	err := serverService.UpdateServer(context.Background(), "server123", updatedData)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeleteServer_Success(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+	
+	mockRepo.On("DeleteServer", "server123").Return(nil)
+	err := serverService.DeleteServer(context.Background(), "server123")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetDeletedServers_Success(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	servers := []domain.Server{{ID: 4, ServerID: "srv-004"}}
+	mockRepo.On("GetDeletedServers", 0, service.DefaultPageSize).Return(servers, 1, nil)
+
+	result, total, err := serverService.GetDeletedServers(0, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(result) != 1 || total != 1 {
+		t.Errorf("Expected 1 deleted server, got %d of %d", len(result), total)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetDeletedServers_InvalidInput(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	for _, bounds := range [][2]int{{-1, 10}, {0, -1}, {0, service.MaxPageSize + 1}} {
+		if _, _, err := serverService.GetDeletedServers(bounds[0], bounds[1]); !errors.Is(err, service.ErrInvalidInput) {
+			t.Errorf("Expected ErrInvalidInput for offset %d and limit %d, got %v", bounds[0], bounds[1], err)
+		}
+	}
+	mockRepo.AssertNotCalled(t, "GetDeletedServers", mock.Anything, mock.Anything)
+}
+
+func TestRestoreServer_NotInTrash(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	mockRepo.On("RestoreServer", "srv-404").Return(repository.ErrServerNotInTrash)
+	err := serverService.RestoreServer(context.Background(), "srv-404")
+	if !errors.Is(err, repository.ErrServerNotInTrash) {
+		t.Errorf("Expected ErrServerNotInTrash, got %v", err)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPurgeDeletedServers(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	retention := 30 * 24 * time.Hour
+	start := time.Now()
+	mockRepo.On("PurgeServersDeletedBefore", mock.MatchedBy(func(before time.Time) bool {
+		cutoff := start.Add(-retention)
+		return !before.Before(cutoff) && before.Sub(cutoff) < time.Minute
+	})).Return(2, nil)
+
+	// A canceled context stops the purge after the first pass
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	serverService.PurgeDeletedServers(ctx, retention)
+
+	mockRepo.AssertNumberOfCalls(t, "PurgeServersDeletedBefore", 1)
+}
+
+func TestUpdateServerStatus_Success(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+	
+	mockRepo.On("GetServerStatus", 1).Return(domain.StatusDown, nil)
+	mockRepo.On("UpdateServerStatus", 1, domain.StatusDown, domain.StatusUp).Return(true, nil)
+	mockRepo.On("AddStatusTransition", 1, domain.StatusDown, domain.StatusUp, mock.AnythingOfType("time.Time")).Return(nil)
+	err := serverService.UpdateServerStatus(1, domain.StatusUp)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateServerStatus_FirstCheck(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	// A server that was never checked moves out of Unknown like any other transition
+	mockRepo.On("GetServerStatus", 1).Return(domain.StatusUnknown, nil)
+	mockRepo.On("UpdateServerStatus", 1, domain.StatusUnknown, domain.StatusDown).Return(true, nil)
+	mockRepo.On("AddStatusTransition", 1, domain.StatusUnknown, domain.StatusDown, mock.AnythingOfType("time.Time")).Return(nil)
+	err := serverService.UpdateServerStatus(1, domain.StatusDown)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateServerStatus_Unchanged(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	mockRepo.On("GetServerStatus", 1).Return(domain.StatusDown, nil)
+	err := serverService.UpdateServerStatus(1, domain.StatusDown)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "UpdateServerStatus", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "AddStatusTransition", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateServerStatus_KeepsManualStatus(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	mockRepo.On("GetServerStatus", 1).Return(domain.StatusDecommissioned, nil)
+	err := serverService.UpdateServerStatus(1, domain.StatusUp)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	mockRepo.AssertNotCalled(t, "UpdateServerStatus", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateServerStatus_LostRace(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	// Another result already moved the server out of Down
+	mockRepo.On("GetServerStatus", 1).Return(domain.StatusDown, nil)
+	mockRepo.On("UpdateServerStatus", 1, domain.StatusDown, domain.StatusUp).Return(false, nil)
+	err := serverService.UpdateServerStatus(1, domain.StatusUp)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	mockRepo.AssertNotCalled(t, "AddStatusTransition", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateServerStatus_ConcurrentFlips(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	// Every goroutine reads Down, the compare and set lets only the first one through
+	mockRepo.On("GetServerStatus", 1).Return(domain.StatusDown, nil)
+	mockRepo.On("UpdateServerStatus", 1, domain.StatusDown, domain.StatusUp).Return(true, nil).Once()
+	mockRepo.On("UpdateServerStatus", 1, domain.StatusDown, domain.StatusUp).Return(false, nil)
+	mockRepo.On("AddStatusTransition", 1, domain.StatusDown, domain.StatusUp, mock.AnythingOfType("time.Time")).Return(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := serverService.UpdateServerStatus(1, domain.StatusUp); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	mockRepo.AssertNumberOfCalls(t, "UpdateServerStatus", 10)
+	mockRepo.AssertNumberOfCalls(t, "AddStatusTransition", 1)
+}
+
+func TestGetStatusTransitions_DefaultLimit(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	startTime := time.Now().Add(-time.Hour)
+	endTime := time.Now()
+	transitions := []dto.StatusTransition{{ID: 1, PreviousStatus: "On", Status: "Off", Timestamp: endTime}}
+	mockRepo.On("GetStatusTransitions", 1, startTime, endTime, 0, 100).Return(transitions, 1, nil)
+
+	result, total, err := serverService.GetStatusTransitions(1, startTime, endTime, 0, 0)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if total != 1 || len(result) != 1 {
+		t.Errorf("Expected 1 transition, got %d of %d", len(result), total)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetAllAddresses_Success(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+	
+	addresses := []dto.ServerAddress{
+		{
+			ID: 1,
+			IPv4:     "192.168.1.1",
+			Port:     8080,
+		},
+		{
+			ID: 2,
+			IPv4:     "192.168.1.2",
+			Port:     8081,
+		},
+	}
+	mockRepo.On("GetAllAddresses").Return(addresses, nil)
+
+	result, err := serverService.GetAllAddresses()
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if len(result) != 2 {
+		t.Errorf("Expected 2 addresses, got %d", len(result))
+	}
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetAllAddresses_Failure(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+	
+	mockRepo.On("GetAllAddresses").Return(nil, errors.New("failed to fetch addresses"))
+
+	result, err := serverService.GetAllAddresses()
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+	if result != nil {
+		t.Errorf("Expected nil result, got %v", result)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetUpdatedAddresses_Success(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	since := time.UnixMilli(1700000000000)
+	addresses := []dto.ServerAddress{{ID: 2, IPv4: "192.168.1.2", Port: 8081, CheckIntervalSeconds: 10}}
+	mockRepo.On("GetAddressesUpdatedSince", since).Return(addresses, nil)
+	mockRepo.On("GetCheckedServerIDs").Return([]int{1, 2, 3}, nil)
+
+	result, ids, err := serverService.GetUpdatedAddresses(since)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if len(result) != 1 || len(ids) != 3 {
+		t.Errorf("Expected 1 address and 3 ids, got %d and %d", len(result), len(ids))
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetNumOnServers_Success(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+	
+	mockRepo.On("GetNumOnServers").Return(5, nil)
+
+	result, err := serverService.GetNumOnServers()
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if result != 5 {
+		t.Errorf("Expected 5 servers, got %d", result)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetNumServers(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+	
+	mockRepo.On("GetNumServers").Return(10, nil)
+
+	result, err := serverService.GetNumServers()
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if result != 10 {
+		t.Errorf("Expected 10 servers, got %d", result)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetServerUptimeRatio_Success(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+	
+	startTime := time.Now().Add(-1 * time.Hour)
+	endTime := time.Now()
+	mockRepo.On("GetServerUptimeRatio", startTime, endTime, []int(nil)).Return(0.95, nil)
+
+	result, err := serverService.GetServerUptimeRatio(startTime, endTime, nil)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if result != 0.95 {
+		t.Errorf("Expected uptime ratio 0.95, got %f", result)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetServersUptime_SortAndPaginate(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	startTime := time.Now().Add(-1 * time.Hour)
+	endTime := time.Now()
+	mockRepo.On("GetServersUptime", startTime, endTime, []int(nil)).Return([]dto.ServerUptime{
+		{ID: 1, UptimeRatio: 0.9, NumChecks: 10, NumStatusChanges: 2},
+		{ID: 2, UptimeRatio: 0.5, NumChecks: 10, NumStatusChanges: 6},
+		{ID: 3, UptimeRatio: 1, NumChecks: 10, NumStatusChanges: 0},
+	}, nil)
+
+	result, total, err := serverService.GetServersUptime(startTime, endTime, nil, 0, 2, "uptime_ratio", "asc")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if total != 3 {
+		t.Errorf("Expected total 3, got %d", total)
+	}
+	if len(result) != 2 || result[0].ID != 2 || result[1].ID != 1 {
+		t.Errorf("Expected servers [2 1], got %v", result)
+	}
+
+	result, _, err = serverService.GetServersUptime(startTime, endTime, nil, 2, 2, "num_status_changes", "desc")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if len(result) != 1 || result[0].ID != 3 {
+		t.Errorf("Expected servers [3], got %v", result)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetServersUptime_ScopedByLabels(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	startTime := time.Unix(100, 0)
+	endTime := time.Unix(200, 0)
+	labels := map[string]string{"team": "payments"}
+
+	mockRepo.On("GetServerIDsByLabels", labels).Return([]int{3, 8}, nil)
+	mockRepo.On("GetServersUptime", startTime, endTime, []int{3, 8}).Return([]dto.ServerUptime{
+		{ID: 3, UptimeRatio: 1},
+		{ID: 8, UptimeRatio: 0.5},
+	}, nil)
+
+	result, total, err := serverService.GetServersUptime(startTime, endTime, labels, 0, 0, "", "")
+
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if total != 2 || len(result) != 2 {
+		t.Errorf("Expected the 2 servers of the team, got %v", result)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetServerIDsByLabels_NoLabels(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	ids, err := serverService.GetServerIDsByLabels(map[string]string{})
+
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if ids != nil {
+		t.Errorf("Expected nil ids for every server, got %v", ids)
+	}
+	mockRepo.AssertNotCalled(t, "GetServerIDsByLabels", mock.Anything)
+}
+
+func TestGetServersUptime_InvalidSortColumn(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	_, _, err := serverService.GetServersUptime(time.Now(), time.Now(), nil, 0, 10, "server_name; DROP TABLE servers", "asc")
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+	mockRepo.AssertNotCalled(t, "GetServersUptime", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetUptimeTimeline_Success(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	startTime := time.Now().Add(-2 * time.Hour)
+	endTime := time.Now()
+	timeline := []dto.UptimeBucket{
+		{Timestamp: startTime.Truncate(time.Hour), UptimeRatio: 1, NumChecks: 60},
+		{Timestamp: startTime.Truncate(time.Hour).Add(time.Hour), UptimeRatio: 0.5, NumChecks: 60},
+	}
+	mockRepo.On("GetUptimeTimeline", 1, startTime, endTime, "hour").Return(timeline, nil)
+
+	result, err := serverService.GetUptimeTimeline(1, startTime, endTime, "hour")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if len(result) != 2 {
+		t.Errorf("Expected 2 buckets, got %d", len(result))
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetLatencyPercentiles_SortedByID(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	startTime := time.Now().Add(-time.Hour)
+	endTime := time.Now()
+	mockRepo.On("GetLatencyPercentiles", 0, startTime, endTime).Return([]dto.LatencyPercentiles{
+		{ID: 3, NumChecks: 60, P50: 20},
+		{ID: 1, NumChecks: 60, P50: 10},
+	}, nil)
+
+	result, err := serverService.GetLatencyPercentiles(0, startTime, endTime)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if len(result) != 2 || result[0].ID != 1 || result[1].ID != 3 {
+		t.Errorf("Expected servers sorted by ID, got %v", result)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetLatencyPercentiles_InvalidTimeRange(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	now := time.Now()
+
+	_, err := serverService.GetLatencyPercentiles(1, now, now.Add(-time.Hour))
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+	mockRepo.AssertNotCalled(t, "GetLatencyPercentiles", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetUptimeTimeline_InvalidInput(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	now := time.Now()
+
+	_, err := serverService.GetUptimeTimeline(0, now.Add(-time.Hour), now, "week")
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for interval, got %v", err)
+	}
+
+	_, err = serverService.GetUptimeTimeline(0, now, now.Add(-time.Hour), "minute")
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for time range, got %v", err)
+	}
+
+	_, err = serverService.GetUptimeTimeline(0, now.Add(-(service.MaxTimelineBuckets+1)*time.Minute), now, "minute")
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for too many buckets, got %v", err)
+	}
+	mockRepo.AssertNotCalled(t, "GetUptimeTimeline", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetDownServers_Success(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	mockRepo.On("GetDownServers", mock.AnythingOfType("time.Time"), 3).Return([]dto.DownServer{
+		{ID: 1, ServerName: "Server 1", ConsecutiveDownChecks: 3},
+		{ID: 2, ServerName: "Server 2", ConsecutiveDownChecks: 1},
+	}, nil)
+
+	result, err := serverService.GetDownServers(3)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if len(result) != 1 || result[0].ID != 1 {
+		t.Errorf("Expected only server 1 to be down, got %v", result)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetDownServers_InvalidChecks(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	_, err := serverService.GetDownServers(0)
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+	mockRepo.AssertNotCalled(t, "GetDownServers", mock.Anything, mock.Anything)
+}
+
+func TestAddServerStatus(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+	
+	result := dto.HealthCheckResult{ID: 1, IPv4: "192.168.1.1:8080", Status: true, LatencyMs: 12.5, CheckedAt: time.Now()}
+	mockRepo.On("AddServerStatus", result, domain.StatusUp, domain.StatusUp).Return(nil)
+
+	err := serverService.AddServerStatus(result, domain.StatusUp, domain.StatusUp)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func createTestExcelBuffer() []byte {
+	f := excelize.NewFile()
+	_ = f.SetSheetRow("Sheet1", "A1", &[]interface{}{"ServerID", "ServerName", "Status", "IPv4", "Port"})
+	_ = f.SetSheetRow("Sheet1", "A2", &[]interface{}{"srv-1", "Server One", "Up", "192.168.1.1", 8080})
+	var buf bytes.Buffer
+	_ = f.Write(&buf)
+	return buf.Bytes()
+}
+
+// createImportBuffer writes the rows below the header of an import file
+func createImportBuffer(rows [][]interface{}) []byte {
+	f := excelize.NewFile()
+	_ = f.SetSheetRow("Sheet1", "A1", &[]interface{}{"Server ID", "Server Name", "Status", "IPv4", "Port", "Check Interval (s)", "Timeout (ms)", "Retries", "Labels"})
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		_ = f.SetSheetRow("Sheet1", cell, &row)
+	}
+	var buf bytes.Buffer
+	_ = f.Write(&buf)
+	return buf.Bytes()
 }
 
 func TestImportServers_Success(t *testing.T) {
@@ -357,27 +1364,35 @@ func TestImportServers_Success(t *testing.T) {
 	expectedServers := []domain.Server{{
 		ServerID:   "srv-1",
 		ServerName: "Server One",
-		Status:     "active",
+		Status:     domain.StatusUp,
 		IPv4:       "192.168.1.1",
 		Port:       8080,
+		Labels:     map[string]string{},
+		ProbeType:  domain.ProbeTCP,
+		CheckIntervalSeconds: domain.DefaultCheckIntervalSeconds,
+		TimeoutMs:            domain.DefaultTimeoutMs,
 	}}
 
-	mockRepo.On("CreateServers", expectedServers).
-		Return(expectedServers, []domain.Server{}, nil)
+	mockRepo.On("FindServers", []string{"srv-1"}, []string{"Server One"}).Return([]domain.Server{}, nil)
+	mockRepo.On("ApplyServerImport", dto.ServerImportPlan{Create: expectedServers}).
+		Return(&dto.ServerImportResult{Created: expectedServers}, nil)
 
-	inserted, nonInserted, err := svc.ImportServers(testBuffer)
+	report, err := svc.ImportServers(context.Background(), testBuffer, dto.ImportOptions{})
 
 	if err != nil {
-		t.Errorf("Expected no error, got %v", err)
+		t.Fatalf("Expected no error, got %v", err)
 	}
-	if len(inserted) != 1 {
-		t.Errorf("Expected 1 inserted server, got %d", len(inserted))
+	if report.Mode != service.ImportModeInsert {
+		t.Errorf("Expected the insert mode by default, got %s", report.Mode)
 	}
-	if inserted[0].ServerID != "srv-1" {
-		t.Errorf("Expected inserted server ID 'srv-1', got %s", inserted[0].ServerID)
+	if len(report.Created) != 1 {
+		t.Fatalf("Expected 1 inserted server, got %d", len(report.Created))
 	}
-	if len(nonInserted) != 0 {
-		t.Errorf("Expected 0 non-inserted servers, got %d", len(nonInserted))
+	if report.Created[0].ServerID != "srv-1" {
+		t.Errorf("Expected inserted server ID 'srv-1', got %s", report.Created[0].ServerID)
+	}
+	if len(report.Rejected) != 0 {
+		t.Errorf("Expected 0 rejected rows, got %d", len(report.Rejected))
 	}
 
 	mockRepo.AssertExpectations(t)
@@ -388,17 +1403,11 @@ func TestImportServers_Failure(t *testing.T) {
 	svc := service.NewServerService(mockRepo)
 
 	testBuffer := createTestExcelBuffer()
-	expectedServers := []domain.Server{{
-		ServerID:   "srv-1",
-		ServerName: "Server One",
-		Status:     "active",
-		IPv4:       "192.168.1.1",
-		Port:       8080,
-	}}
-	mockRepo.On("CreateServers", expectedServers).
-		Return(nil, nil, errors.New("failed to insert servers"))
+	mockRepo.On("FindServers", mock.Anything, mock.Anything).Return([]domain.Server{}, nil)
+	mockRepo.On("ApplyServerImport", mock.Anything).
+		Return(nil, errors.New("failed to insert servers"))
 
-	_, _, err := svc.ImportServers(testBuffer)
+	_, err := svc.ImportServers(context.Background(), testBuffer, dto.ImportOptions{})
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -406,28 +1415,369 @@ func TestImportServers_Failure(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
-// func CreateWrongExcelBuffer() []byte {
-// 	f := excelize.NewFile()
-// 	_ = f.SetSheetRow("Sheet1", "A1", &[]interface{}{"ServerID", "ServerName", "Status", "IPv4", "Port"})
-	
-// 	var buf bytes.Buffer
-// 	_ = f.Write(&buf)
-// 	return buf.Bytes()	
-// }
+func TestImportServers_RowErrors(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	svc := service.NewServerService(mockRepo)
+
+	buf := createImportBuffer([][]interface{}{
+		{"srv-1", "Server One", "Up", "192.168.1.1", 8080},
+		// Short row, only the server ID
+		{"srv-2"},
+		{"srv-3", "Server Three", "Sleeping", "300.1.1.1", "http"},
+		{"srv-1", "Server Four", "Up", "192.168.1.4", 70000},
+		{},
+		{"srv-5", "Server One", "Down", "192.168.1.5"},
+		{"srv-6", "Server Six", "Up", "192.168.1.6", 80, 1},
+		{"srv-7", "Server Seven", "", "192.168.1.7", "", "", "", "", "env=prod"},
+	})
+
+	var sent []domain.Server
+	mockRepo.On("FindServers", mock.Anything, mock.Anything).Return([]domain.Server{}, nil)
+	mockRepo.On("ApplyServerImport", mock.Anything).
+		Run(func(args mock.Arguments) { sent = args.Get(0).(dto.ServerImportPlan).Create }).
+		Return(&dto.ServerImportResult{Created: []domain.Server{{ID: 1, ServerID: "srv-1"}, {ID: 2, ServerID: "srv-7"}}}, nil)
+
+	report, err := svc.ImportServers(context.Background(), buf, dto.ImportOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(sent) != 2 || sent[0].ServerID != "srv-1" || sent[1].ServerID != "srv-7" {
+		t.Fatalf("Expected only srv-1 and srv-7 to be inserted, got %+v", sent)
+	}
+	if sent[1].Status != domain.StatusUnknown || sent[1].Port != 80 || sent[1].Labels["env"] != "prod" {
+		t.Errorf("Expected the defaults and the labels of srv-7, got %+v", sent[1])
+	}
+
+	expected := map[int][]dto.ImportError{
+		3: {
+			{Row: 3, Column: "Server Name", Cell: "B3", Reason: "is required"},
+			{Row: 3, Column: "IPv4", Cell: "D3", Reason: "is required"},
+		},
+		4: {
+			{Row: 4, Column: "Status", Cell: "C4", Reason: `invalid status "Sleeping"`},
+			{Row: 4, Column: "IPv4", Cell: "D4", Reason: `invalid IPv4 address "300.1.1.1"`},
+			{Row: 4, Column: "Port", Cell: "E4", Reason: `port must be a number between 1 and 65535, got "http"`},
+		},
+		5: {
+			{Row: 5, Column: "Port", Cell: "E5", Reason: `port must be a number between 1 and 65535, got "70000"`},
+			{Row: 5, Column: "Server ID", Cell: "A5", Reason: "duplicates the server ID of row 2"},
+		},
+		7: {
+			{Row: 7, Column: "Server Name", Cell: "B7", Reason: "duplicates the server name of row 2"},
+		},
+		8: {
+			{Row: 8, Reason: "check interval must be between 5 and 86400 seconds, got 1"},
+		},
+	}
+
+	if len(report.Rejected) != len(expected) {
+		t.Fatalf("Expected %d rejected rows, got %+v", len(expected), report.Rejected)
+	}
+	for i, rejected := range report.Rejected {
+		if i > 0 && report.Rejected[i-1].Row >= rejected.Row {
+			t.Errorf("Expected the rejected rows in file order, got %+v", report.Rejected)
+		}
+
+		expectedErrors, ok := expected[rejected.Row]
+		if !ok {
+			t.Errorf("Unexpected rejected row %d: %+v", rejected.Row, rejected.Errors)
+			continue
+		}
+		if len(rejected.Errors) != len(expectedErrors) {
+			t.Errorf("Row %d: expected errors %+v, got %+v", rejected.Row, expectedErrors, rejected.Errors)
+			continue
+		}
+		for j := range expectedErrors {
+			if rejected.Errors[j] != expectedErrors[j] {
+				t.Errorf("Row %d: expected error %+v, got %+v", rejected.Row, expectedErrors[j], rejected.Errors[j])
+			}
+		}
+	}
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestImportServers_RejectedByRepository(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	svc := service.NewServerService(mockRepo)
+
+	f := excelize.NewFile()
+	_ = f.SetSheetName("Sheet1", "Servers")
+	_ = f.SetSheetRow("Servers", "A1", &[]interface{}{"Server ID", "Server Name", "Status", "IPv4", "Port"})
+	_ = f.SetSheetRow("Servers", "A2", &[]interface{}{"srv-1", "Server One", "Up", "192.168.1.1", 8080})
+	_ = f.SetSheetRow("Servers", "A3", &[]interface{}{"srv-2", "Server Two", "Up", "192.168.1.2", 8080})
+	var buf bytes.Buffer
+	_ = f.Write(&buf)
+
+	mockRepo.On("FindServers", mock.Anything, mock.Anything).Return([]domain.Server{}, nil)
+	mockRepo.On("ApplyServerImport", mock.Anything).Return(&dto.ServerImportResult{
+		Created:  []domain.Server{{ID: 1, ServerID: "srv-1"}},
+		Rejected: []dto.RejectedServer{{Server: domain.Server{ServerID: "srv-2"}, Reason: "a server with this server_id or server_name already exists"}},
+	}, nil)
+
+	report, err := svc.ImportServers(context.Background(), buf.Bytes(), dto.ImportOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(report.Rejected) != 1 {
+		t.Fatalf("Expected 1 rejected row, got %+v", report.Rejected)
+	}
+	rejected := report.Rejected[0]
+	if rejected.Row != 3 || rejected.Values[0] != "srv-2" {
+		t.Errorf("Expected row 3 of srv-2, got %+v", rejected)
+	}
+	if len(rejected.Errors) != 1 || rejected.Errors[0].Reason != "a server with this server_id or server_name already exists" || rejected.Errors[0].Column != "" {
+		t.Errorf("Expected the reason of the repository, got %+v", rejected.Errors)
+	}
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestImportServers_InvalidFile(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	svc := service.NewServerService(mockRepo)
+
+	f := excelize.NewFile()
+	_ = f.SetSheetRow("Sheet1", "A1", &[]interface{}{"ServerID", "ServerName", "Status", "IPv4", "Port"})
+	var headerOnly bytes.Buffer
+	_ = f.Write(&headerOnly)
+
+	for name, buf := range map[string][]byte{
+		"not an Excel file": []byte("server_id,server_name"),
+		"no servers":        headerOnly.Bytes(),
+	} {
+		_, err := svc.ImportServers(context.Background(), buf, dto.ImportOptions{})
+		if !errors.Is(err, service.ErrInvalidInput) {
+			t.Errorf("%s: expected ErrInvalidInput, got %v", name, err)
+		}
+	}
+
+	_, err := svc.ImportServers(context.Background(), createTestExcelBuffer(), dto.ImportOptions{Mode: "merge"})
+	if !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("invalid mode: expected ErrInvalidInput, got %v", err)
+	}
+
+	mockRepo.AssertNotCalled(t, "ApplyServerImport", mock.Anything)
+}
+
+func importedServer(id int, serverID, name string, status domain.ServerStatus, ipv4 string, port int) domain.Server {
+	return domain.Server{
+		ID:                   id,
+		ServerID:             serverID,
+		ServerName:           name,
+		Status:               status,
+		IPv4:                 ipv4,
+		Port:                 port,
+		Labels:               map[string]string{},
+		ProbeType:            domain.ProbeTCP,
+		CheckIntervalSeconds: domain.DefaultCheckIntervalSeconds,
+		TimeoutMs:            domain.DefaultTimeoutMs,
+	}
+}
+
+func TestImportServers_InsertConflicts(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	svc := service.NewServerService(mockRepo)
 
-// func TestImportServers_WrongFormat(t *testing.T) {
-// 	mockRepo := new(mockServerRepo)
-// 	svc := service.NewServerService(mockRepo)
+	buf := createImportBuffer([][]interface{}{
+		{"srv-1", "Server One", "Up", "192.168.1.1", 8080},
+		{"srv-2", "Server Two", "Up", "192.168.1.2", 8080},
+		{"srv-3", "Server Three", "Up", "192.168.1.3", 8080},
+	})
 
-// 	testBuffer := CreateWrongExcelBuffer()
+	mockRepo.On("FindServers", []string{"srv-1", "srv-2", "srv-3"}, []string{"Server One", "Server Two", "Server Three"}).
+		Return([]domain.Server{
+			importedServer(1, "srv-1", "Server One", domain.StatusDown, "192.168.1.1", 8080),
+			importedServer(9, "srv-9", "Server Two", domain.StatusDown, "192.168.1.9", 8080),
+		}, nil)
+	created := importedServer(0, "srv-3", "Server Three", domain.StatusUp, "192.168.1.3", 8080)
+	mockRepo.On("ApplyServerImport", dto.ServerImportPlan{Create: []domain.Server{created}}).
+		Return(&dto.ServerImportResult{Created: []domain.Server{created}}, nil)
 
-// 	_, _, err := svc.ImportServers(testBuffer)
-// 	if err == nil {
-// 		t.Errorf("Expected error for wrong format, got nil")
-// 	}
+	report, err := svc.ImportServers(context.Background(), buf, dto.ImportOptions{Mode: service.ImportModeInsert})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []dto.RejectedRow{
+		{Row: 2, Values: []string{"srv-1", "Server One", "Up", "192.168.1.1", "8080", "", "", "", ""}, Errors: []dto.ImportError{
+			{Row: 2, Column: "Server ID", Cell: "A2", Reason: "a server with this server ID already exists"},
+		}},
+		{Row: 3, Values: []string{"srv-2", "Server Two", "Up", "192.168.1.2", "8080", "", "", "", ""}, Errors: []dto.ImportError{
+			{Row: 3, Column: "Server Name", Cell: "B3", Reason: "is already used by server srv-9"},
+		}},
+	}
+	if !reflect.DeepEqual(report.Rejected, expected) {
+		t.Errorf("Expected rejected rows %+v, got %+v", expected, report.Rejected)
+	}
+	if len(report.Created) != 1 || len(report.Updated) != 0 || len(report.Deleted) != 0 {
+		t.Errorf("Expected only srv-3 to be created, got %+v", report)
+	}
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestImportServers_Upsert(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	svc := service.NewServerService(mockRepo)
+
+	buf := createImportBuffer([][]interface{}{
+		{"srv-1", "Server One", "Up", "192.168.1.1", 8080},
+		{"srv-2", "Server Two Renamed", "Up", "192.168.1.2", 9090},
+		{"srv-3", "Server Three", "Up", "192.168.1.3", 8080},
+	})
+
+	unchanged := importedServer(1, "srv-1", "Server One", domain.StatusDown, "192.168.1.1", 8080)
+	current := importedServer(2, "srv-2", "Server Two", domain.StatusDegraded, "192.168.1.2", 8080)
+	current.ProbeType = domain.ProbeHTTP
+	current.ProbePath = "/health"
+	mockRepo.On("FindServers", mock.Anything, mock.Anything).Return([]domain.Server{unchanged, current}, nil)
+
+	// The status and the probe settings of srv-2 are kept
+	updated := current
+	updated.ServerName = "Server Two Renamed"
+	updated.Port = 9090
+	created := importedServer(0, "srv-3", "Server Three", domain.StatusUp, "192.168.1.3", 8080)
+
+	plan := dto.ServerImportPlan{Create: []domain.Server{created}, Update: []domain.Server{updated}}
+	mockRepo.On("ApplyServerImport", plan).
+		Return(&dto.ServerImportResult{Created: plan.Create, Updated: plan.Update}, nil)
+
+	report, err := svc.ImportServers(context.Background(), buf, dto.ImportOptions{Mode: service.ImportModeUpsert})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !reflect.DeepEqual(report.Unchanged, []domain.Server{unchanged}) {
+		t.Errorf("Expected srv-1 to be unchanged, got %+v", report.Unchanged)
+	}
+	if len(report.Updated) != 1 || len(report.Created) != 1 || len(report.Rejected) != 0 {
+		t.Errorf("Expected srv-2 updated and srv-3 created, got %+v", report)
+	}
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestImportServers_Replace(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	svc := service.NewServerService(mockRepo)
+
+	buf := createImportBuffer([][]interface{}{
+		{"srv-1", "Server One", "Up", "192.168.1.1", 8080},
+		// Invalid, but srv-2 is still in the file and must not be deleted
+		{"srv-2", "Server Two", "Up", "not an ip", 8080},
+		// Takes the name of srv-9, which the replace deletes first
+		{"srv-3", "Server Nine", "Up", "192.168.1.3", 8080},
+	})
+
+	existing := []domain.Server{
+		importedServer(1, "srv-1", "Server One", domain.StatusUp, "192.168.1.1", 8080),
+		importedServer(2, "srv-2", "Server Two", domain.StatusUp, "192.168.1.2", 8080),
+		importedServer(9, "srv-9", "Server Nine", domain.StatusUp, "192.168.1.9", 8080),
+	}
+	mockRepo.On("GetAllServers").Return(existing, nil)
+
+	plan := dto.ServerImportPlan{
+		Create: []domain.Server{importedServer(0, "srv-3", "Server Nine", domain.StatusUp, "192.168.1.3", 8080)},
+		Delete: []domain.Server{existing[2]},
+	}
+	mockRepo.On("ApplyServerImport", plan).
+		Return(&dto.ServerImportResult{Created: plan.Create, Deleted: plan.Delete}, nil)
+
+	report, err := svc.ImportServers(context.Background(), buf, dto.ImportOptions{Mode: service.ImportModeReplace})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(report.Deleted) != 1 || report.Deleted[0].ServerID != "srv-9" {
+		t.Errorf("Expected only srv-9 to be deleted, got %+v", report.Deleted)
+	}
+	if len(report.Unchanged) != 1 || len(report.Created) != 1 || len(report.Rejected) != 1 {
+		t.Errorf("Expected srv-1 unchanged, srv-3 created and srv-2 rejected, got %+v", report)
+	}
+
+	mockRepo.AssertNotCalled(t, "FindServers", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestImportServers_DryRun(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	svc := service.NewServerService(mockRepo)
+
+	buf := createImportBuffer([][]interface{}{
+		{"srv-1", "Server One", "Up", "192.168.1.1", 9090},
+		{"srv-3", "Server Three", "Up", "192.168.1.3", 8080},
+	})
+
+	existing := []domain.Server{
+		importedServer(1, "srv-1", "Server One", domain.StatusUp, "192.168.1.1", 8080),
+		importedServer(2, "srv-2", "Server Two", domain.StatusUp, "192.168.1.2", 8080),
+	}
+	mockRepo.On("GetAllServers").Return(existing, nil)
+
+	report, err := svc.ImportServers(context.Background(), buf, dto.ImportOptions{Mode: service.ImportModeReplace, DryRun: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !report.DryRun {
+		t.Errorf("Expected the report of a dry run")
+	}
+	if len(report.Created) != 1 || report.Created[0].ServerID != "srv-3" || report.Created[0].ID != 0 {
+		t.Errorf("Expected srv-3 to be created, got %+v", report.Created)
+	}
+	if len(report.Updated) != 1 || report.Updated[0].ID != 1 || report.Updated[0].Port != 9090 {
+		t.Errorf("Expected srv-1 to be updated, got %+v", report.Updated)
+	}
+	if len(report.Deleted) != 1 || report.Deleted[0].ServerID != "srv-2" {
+		t.Errorf("Expected srv-2 to be deleted, got %+v", report.Deleted)
+	}
+
+	mockRepo.AssertNotCalled(t, "ApplyServerImport", mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestExportRejectedRows(t *testing.T) {
+	svc := service.NewServerService(new(mockServerRepo))
+
+	report := &dto.ImportReport{
+		Rejected: []dto.RejectedRow{
+			{
+				Row:    3,
+				Values: []string{"srv-3", "Server Three", "Sleeping", "300.1.1.1"},
+				Errors: []dto.ImportError{
+					{Row: 3, Column: "Status", Cell: "C3", Reason: `invalid status "Sleeping"`},
+					{Row: 3, Column: "IPv4", Cell: "D3", Reason: `invalid IPv4 address "300.1.1.1"`},
+				},
+			},
+		},
+	}
+
+	buf, err := svc.ExportRejectedRows(report)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
 
-// 	mockRepo.AssertExpectations(t)
-// }
+	f, err := excelize.OpenReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatalf("Expected an Excel file, got %v", err)
+	}
+	rows, _ := f.GetRows("Servers")
+	if len(rows) != 2 {
+		t.Fatalf("Expected a header and 1 row, got %v", rows)
+	}
+	if rows[0][9] != "Errors" {
+		t.Errorf("Expected an Errors column, got %v", rows[0])
+	}
+	if rows[1][0] != "srv-3" || rows[1][3] != "300.1.1.1" {
+		t.Errorf("Expected the cells as imported, got %v", rows[1])
+	}
+	if rows[1][9] != `Status: invalid status "Sleeping"; IPv4: invalid IPv4 address "300.1.1.1"` {
+		t.Errorf("Unexpected errors cell %q", rows[1][9])
+	}
+}
 
 func TestExportServers_Success(t *testing.T) {
 	mockRepo := new(mockServerRepo)
@@ -453,17 +1803,30 @@ func TestExportServers_Success(t *testing.T) {
 	filter := &dto.ServerFilter{
 		ServerID:   "server123",
 		ServerName: "Test Server",
-		Status:     "On",
+		Statuses:   []string{"On"},
 		IPv4:       "192.168.1.1",
 		Port:       8080,
 	}
-	mockRepo.On("ViewServers", filter, 0, 10, "ServerID", "asc").Return(servers, nil)
+	mockRepo.On("StreamServers", filter, byServerID).Return(servers, nil)
 	
-	_, err := serverService.ExportServers(filter, 0, 10, "ServerID", "asc")
+	var buf bytes.Buffer
+	err := serverService.ExportServers(&buf, filter, []dto.SortField{{Column: "server_id", Order: "asc"}}, dto.ExportOptions{Format: service.FormatXLSX})
 	if err != nil {
-		t.Errorf("Expected no error, got %v", err)
+		t.Fatalf("Expected no error, got %v", err)
 	}
 	mockRepo.AssertExpectations(t)
+
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("Expected an Excel file, got %v", err)
+	}
+	rows, err := f.GetRows("Servers")
+	if err != nil {
+		t.Fatalf("Expected a Servers sheet, got %v", err)
+	}
+	if len(rows) != 3 || rows[0][0] != "Server ID" || rows[2][0] != "server124" || rows[2][4] != "8081" {
+		t.Errorf("Expected a header and 2 servers, got %v", rows)
+	}
 }
 
 func TestExportServers_FailRetrieval(t *testing.T) {
@@ -473,15 +1836,164 @@ func TestExportServers_FailRetrieval(t *testing.T) {
 	filter := &dto.ServerFilter{
 		ServerID:   "server123",
 		ServerName: "Test Server",
-		Status:     "On",
+		Statuses:   []string{"On"},
 		IPv4:       "192.168.1.1",
 		Port:       8080,
 	}
-	mockRepo.On("ViewServers", filter, 0, 10, "ServerID", "asc").Return(nil, errors.New("failed to fetch servers"))
+	mockRepo.On("StreamServers", filter, byServerID).Return(nil, errors.New("failed to fetch servers"))
 
-	_, err := serverService.ExportServers(filter, 0, 10, "ServerID", "asc")
+	err := serverService.ExportServers(&bytes.Buffer{}, filter, []dto.SortField{{Column: "server_id", Order: "asc"}}, dto.ExportOptions{Format: service.FormatXLSX})
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
 	mockRepo.AssertExpectations(t)
-}
\ No newline at end of file
+}
+func TestExportServers_Formats(t *testing.T) {
+	servers := []domain.Server{{
+		ServerID:             "srv-1",
+		ServerName:           "Server, One",
+		Status:               domain.StatusUp,
+		IPv4:                 "192.168.1.1",
+		Port:                 8080,
+		CheckIntervalSeconds: 30,
+		TimeoutMs:            2000,
+		Retries:              1,
+		Labels:               map[string]string{"env": "prod"},
+	}}
+	filter := &dto.ServerFilter{Port: -1}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{service.FormatCSV, "server_id,server_name,status,ipv4,port,check_interval_seconds,timeout_ms,retries,labels\n" +
+			"srv-1,\"Server, One\",Up,192.168.1.1,8080,30,2000,1,env=prod\n"},
+		{service.FormatNDJSON, `{"id":0,"server_id":"srv-1"`},
+		{service.FormatJSON, `[{"id":0,"server_id":"srv-1"`},
+	}
+
+	for _, test := range tests {
+		mockRepo := new(mockServerRepo)
+		serverService := service.NewServerService(mockRepo)
+		mockRepo.On("StreamServers", mock.Anything, mock.Anything).Return(append(servers, servers...), nil)
+
+		var buf bytes.Buffer
+		err := serverService.ExportServers(&buf, filter, nil, dto.ExportOptions{Format: test.format})
+		if err != nil {
+			t.Fatalf("Expected no error exporting %s, got %v", test.format, err)
+		}
+		if !strings.HasPrefix(buf.String(), test.expected) {
+			t.Errorf("Expected the %s export to start with %q, got %q", test.format, test.expected, buf.String())
+		}
+		if test.format == service.FormatJSON {
+			var exported []domain.Server
+			if err := json.Unmarshal(buf.Bytes(), &exported); err != nil || len(exported) != 2 {
+				t.Errorf("Expected a JSON array of 2 servers, got %q", buf.String())
+			}
+		}
+	}
+
+	mockRepo := new(mockServerRepo)
+	serverService := service.NewServerService(mockRepo)
+
+	var buf bytes.Buffer
+	if err := serverService.ExportServers(&buf, filter, nil, dto.ExportOptions{Format: "pdf"}); !errors.Is(err, service.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for an unknown format, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing written for an unknown format, got %q", buf.String())
+	}
+	mockRepo.AssertNotCalled(t, "StreamServers", mock.Anything, mock.Anything)
+
+	// An empty JSON export is still an array
+	mockRepo.On("StreamServers", mock.Anything, mock.Anything).Return([]domain.Server{}, nil)
+	if err := serverService.ExportServers(&buf, filter, nil, dto.ExportOptions{Format: service.FormatJSON}); err != nil || buf.String() != "[]\n" {
+		t.Errorf("Expected an empty JSON array, got %q and %v", buf.String(), err)
+	}
+}
+
+func TestImportServers_Formats(t *testing.T) {
+	expectedServers := []domain.Server{
+		importedServer(0, "srv-1", "Server One", domain.StatusUp, "192.168.1.1", 8080),
+		importedServer(0, "srv-2", "Server Two", domain.StatusUnknown, "192.168.1.2", 80),
+	}
+	expectedServers[0].Labels = map[string]string{"env": "prod", "team": "payments"}
+
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"csv with aliases and reordered columns", "\xef\xbb\xbfip_address,Name,server_id,status,port,labels,notes\n" +
+			"192.168.1.1,Server One,srv-1,Up,8080,\"env=prod,team=payments\",primary\n" +
+			"192.168.1.2,Server Two,srv-2,,,,\n"},
+		{"json", `[
+			{"server_id": "srv-1", "server_name": "Server One", "status": "Up", "ipv4": "192.168.1.1", "port": 8080, "labels": {"env": "prod", "team": "payments"}},
+			{"server_id": "srv-2", "server_name": "Server Two", "ipv4": "192.168.1.2"}
+		]`},
+		{"ndjson", `{"server_id": "srv-1", "server_name": "Server One", "status": "Up", "ipv4": "192.168.1.1", "port": "8080", "labels": "env=prod,team=payments"}
+{"server_id": "srv-2", "server_name": "Server Two", "ipv4": "192.168.1.2"}
+`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo := new(mockServerRepo)
+			svc := service.NewServerService(mockRepo)
+
+			mockRepo.On("FindServers", []string{"srv-1", "srv-2"}, []string{"Server One", "Server Two"}).Return([]domain.Server{}, nil)
+			mockRepo.On("ApplyServerImport", dto.ServerImportPlan{Create: expectedServers}).
+				Return(&dto.ServerImportResult{Created: expectedServers}, nil)
+
+			report, err := svc.ImportServers(context.Background(), []byte(test.file), dto.ImportOptions{})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(report.Created) != 2 || len(report.Rejected) != 0 {
+				t.Errorf("Expected 2 created and 0 rejected servers, got %d and %d", len(report.Created), len(report.Rejected))
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestImportServers_FormatErrors(t *testing.T) {
+	svc := service.NewServerService(new(mockServerRepo))
+
+	files := map[string]string{
+		"missing columns":  "server_id,status\nsrv-1,Up\n",
+		"duplicate column": "server_id,name,server_name,ipv4\nsrv-1,a,b,192.168.1.1\n",
+		"invalid json":     `[{"server_id": "srv-1"`,
+		"empty json":       `[]`,
+	}
+
+	for name, file := range files {
+		if _, err := svc.ImportServers(context.Background(), []byte(file), dto.ImportOptions{}); !errors.Is(err, service.ErrInvalidInput) {
+			t.Errorf("%s: expected ErrInvalidInput, got %v", name, err)
+		}
+	}
+}
+
+func TestImportServers_CSVRowErrors(t *testing.T) {
+	mockRepo := new(mockServerRepo)
+	svc := service.NewServerService(mockRepo)
+
+	file := "server_name,server_id,ipv4\nServer One,srv-1,not-an-ip\n"
+
+	mockRepo.On("FindServers", []string{}, []string{}).Return([]domain.Server{}, nil).Maybe()
+
+	report, err := svc.ImportServers(context.Background(), []byte(file), dto.ImportOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(report.Rejected) != 1 || len(report.Rejected[0].Errors) != 1 {
+		t.Fatalf("Expected 1 rejected row with 1 error, got %+v", report.Rejected)
+	}
+
+	importError := report.Rejected[0].Errors[0]
+	if importError.Row != 2 || importError.Column != "IPv4" || importError.Cell != "C2" {
+		t.Errorf("Expected the error at IPv4 C2 of row 2, got %+v", importError)
+	}
+	if report.Rejected[0].Values[0] != "srv-1" {
+		t.Errorf("Expected the values in column order, got %v", report.Rejected[0].Values)
+	}
+}
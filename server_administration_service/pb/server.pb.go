@@ -1,7 +1,7 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
 // 	protoc-gen-go v1.36.6
-// 	protoc        v3.12.4
+// 	protoc        v3.21.12
 // source: proto/server.proto
 
 package pb
@@ -57,9 +57,11 @@ func (*EmptyRequest) Descriptor() ([]byte, []int) {
 	return file_proto_server_proto_rawDescGZIP(), []int{0}
 }
 
+// serverTime (unix milliseconds) is the updatedSince of the first GetUpdatedAddresses call
 type AddressesResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Addresses     []*AddressInfo         `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	ServerTime    int64                  `protobuf:"varint,2,opt,name=serverTime,proto3" json:"serverTime,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -101,17 +103,137 @@ func (x *AddressesResponse) GetAddresses() []*AddressInfo {
 	return nil
 }
 
-type AddressInfo struct {
+func (x *AddressesResponse) GetServerTime() int64 {
+	if x != nil {
+		return x.ServerTime
+	}
+	return 0
+}
+
+// Servers created or updated after updatedSince (unix milliseconds), plus the ids of every
+// current server so callers can drop deleted ones. serverTime is the next updatedSince.
+type GetUpdatedAddressesRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	UpdatedSince  int64                  `protobuf:"varint,1,opt,name=updatedSince,proto3" json:"updatedSince,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *GetUpdatedAddressesRequest) Reset() {
+	*x = GetUpdatedAddressesRequest{}
+	mi := &file_proto_server_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUpdatedAddressesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUpdatedAddressesRequest) ProtoMessage() {}
+
+func (x *GetUpdatedAddressesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_server_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUpdatedAddressesRequest.ProtoReflect.Descriptor instead.
+func (*GetUpdatedAddressesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_server_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetUpdatedAddressesRequest) GetUpdatedSince() int64 {
+	if x != nil {
+		return x.UpdatedSince
+	}
+	return 0
+}
+
+type GetUpdatedAddressesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Addresses     []*AddressInfo         `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	Ids           []int64                `protobuf:"varint,2,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	ServerTime    int64                  `protobuf:"varint,3,opt,name=serverTime,proto3" json:"serverTime,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUpdatedAddressesResponse) Reset() {
+	*x = GetUpdatedAddressesResponse{}
+	mi := &file_proto_server_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUpdatedAddressesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUpdatedAddressesResponse) ProtoMessage() {}
+
+func (x *GetUpdatedAddressesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_server_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUpdatedAddressesResponse.ProtoReflect.Descriptor instead.
+func (*GetUpdatedAddressesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_server_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetUpdatedAddressesResponse) GetAddresses() []*AddressInfo {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+func (x *GetUpdatedAddressesResponse) GetIds() []int64 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *GetUpdatedAddressesResponse) GetServerTime() int64 {
+	if x != nil {
+		return x.ServerTime
+	}
+	return 0
+}
+
+type AddressInfo struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Id                   int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Address              string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	ProbeType            string                 `protobuf:"bytes,3,opt,name=probeType,proto3" json:"probeType,omitempty"` // tcp, http, https, udp or tls
+	ProbePath            string                 `protobuf:"bytes,4,opt,name=probePath,proto3" json:"probePath,omitempty"`
+	ProbeExpectedStatus  int32                  `protobuf:"varint,5,opt,name=probeExpectedStatus,proto3" json:"probeExpectedStatus,omitempty"`
+	ProbeExpectedBody    string                 `protobuf:"bytes,6,opt,name=probeExpectedBody,proto3" json:"probeExpectedBody,omitempty"`
+	CheckIntervalSeconds int32                  `protobuf:"varint,7,opt,name=checkIntervalSeconds,proto3" json:"checkIntervalSeconds,omitempty"`
+	TimeoutMs            int32                  `protobuf:"varint,8,opt,name=timeoutMs,proto3" json:"timeoutMs,omitempty"`
+	Retries              int32                  `protobuf:"varint,9,opt,name=retries,proto3" json:"retries,omitempty"` // extra attempts before reporting the server Off
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
 func (x *AddressInfo) Reset() {
 	*x = AddressInfo{}
-	mi := &file_proto_server_proto_msgTypes[2]
+	mi := &file_proto_server_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -123,7 +245,7 @@ func (x *AddressInfo) String() string {
 func (*AddressInfo) ProtoMessage() {}
 
 func (x *AddressInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_server_proto_msgTypes[2]
+	mi := &file_proto_server_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -136,7 +258,7 @@ func (x *AddressInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AddressInfo.ProtoReflect.Descriptor instead.
 func (*AddressInfo) Descriptor() ([]byte, []int) {
-	return file_proto_server_proto_rawDescGZIP(), []int{2}
+	return file_proto_server_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *AddressInfo) GetId() int64 {
@@ -153,17 +275,67 @@ func (x *AddressInfo) GetAddress() string {
 	return ""
 }
 
+func (x *AddressInfo) GetProbeType() string {
+	if x != nil {
+		return x.ProbeType
+	}
+	return ""
+}
+
+func (x *AddressInfo) GetProbePath() string {
+	if x != nil {
+		return x.ProbePath
+	}
+	return ""
+}
+
+func (x *AddressInfo) GetProbeExpectedStatus() int32 {
+	if x != nil {
+		return x.ProbeExpectedStatus
+	}
+	return 0
+}
+
+func (x *AddressInfo) GetProbeExpectedBody() string {
+	if x != nil {
+		return x.ProbeExpectedBody
+	}
+	return ""
+}
+
+func (x *AddressInfo) GetCheckIntervalSeconds() int32 {
+	if x != nil {
+		return x.CheckIntervalSeconds
+	}
+	return 0
+}
+
+func (x *AddressInfo) GetTimeoutMs() int32 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+func (x *AddressInfo) GetRetries() int32 {
+	if x != nil {
+		return x.Retries
+	}
+	return 0
+}
+
 type GetServerInformationRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	StartTime     int64                  `protobuf:"varint,1,opt,name=startTime,proto3" json:"startTime,omitempty"` // timestamp in unix format
-	EndTime       int64                  `protobuf:"varint,2,opt,name=endTime,proto3" json:"endTime,omitempty"`     // timestamp in unix format
+	StartTime     int64                  `protobuf:"varint,1,opt,name=startTime,proto3" json:"startTime,omitempty"`                                                                    // timestamp in unix format
+	EndTime       int64                  `protobuf:"varint,2,opt,name=endTime,proto3" json:"endTime,omitempty"`                                                                        // timestamp in unix format
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // only servers having all of these labels, empty for every server
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetServerInformationRequest) Reset() {
 	*x = GetServerInformationRequest{}
-	mi := &file_proto_server_proto_msgTypes[3]
+	mi := &file_proto_server_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -175,7 +347,7 @@ func (x *GetServerInformationRequest) String() string {
 func (*GetServerInformationRequest) ProtoMessage() {}
 
 func (x *GetServerInformationRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_server_proto_msgTypes[3]
+	mi := &file_proto_server_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -188,7 +360,7 @@ func (x *GetServerInformationRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetServerInformationRequest.ProtoReflect.Descriptor instead.
 func (*GetServerInformationRequest) Descriptor() ([]byte, []int) {
-	return file_proto_server_proto_rawDescGZIP(), []int{3}
+	return file_proto_server_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *GetServerInformationRequest) GetStartTime() int64 {
@@ -205,19 +377,30 @@ func (x *GetServerInformationRequest) GetEndTime() int64 {
 	return 0
 }
 
+func (x *GetServerInformationRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
 type GetServerInformationResponse struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	NumServers      int64                  `protobuf:"varint,1,opt,name=numServers,proto3" json:"numServers,omitempty"`
-	NumOnServers    int64                  `protobuf:"varint,2,opt,name=numOnServers,proto3" json:"numOnServers,omitempty"`
-	NumOffServers   int64                  `protobuf:"varint,3,opt,name=numOffServers,proto3" json:"numOffServers,omitempty"`
-	MeanUptimeRatio float32                `protobuf:"fixed32,4,opt,name=meanUptimeRatio,proto3" json:"meanUptimeRatio,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	state                    protoimpl.MessageState `protogen:"open.v1"`
+	NumServers               int64                  `protobuf:"varint,1,opt,name=numServers,proto3" json:"numServers,omitempty"`
+	NumOnServers             int64                  `protobuf:"varint,2,opt,name=numOnServers,proto3" json:"numOnServers,omitempty"`   // Up or Degraded
+	NumOffServers            int64                  `protobuf:"varint,3,opt,name=numOffServers,proto3" json:"numOffServers,omitempty"` // Down
+	MeanUptimeRatio          float32                `protobuf:"fixed32,4,opt,name=meanUptimeRatio,proto3" json:"meanUptimeRatio,omitempty"`
+	NumMaintenanceServers    int64                  `protobuf:"varint,5,opt,name=numMaintenanceServers,proto3" json:"numMaintenanceServers,omitempty"` // not counted in numOnServers / numOffServers
+	NumUnknownServers        int64                  `protobuf:"varint,6,opt,name=numUnknownServers,proto3" json:"numUnknownServers,omitempty"`         // never checked yet
+	NumDegradedServers       int64                  `protobuf:"varint,7,opt,name=numDegradedServers,proto3" json:"numDegradedServers,omitempty"`       // included in numOnServers
+	NumDecommissionedServers int64                  `protobuf:"varint,8,opt,name=numDecommissionedServers,proto3" json:"numDecommissionedServers,omitempty"`
+	unknownFields            protoimpl.UnknownFields
+	sizeCache                protoimpl.SizeCache
 }
 
 func (x *GetServerInformationResponse) Reset() {
 	*x = GetServerInformationResponse{}
-	mi := &file_proto_server_proto_msgTypes[4]
+	mi := &file_proto_server_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -229,7 +412,7 @@ func (x *GetServerInformationResponse) String() string {
 func (*GetServerInformationResponse) ProtoMessage() {}
 
 func (x *GetServerInformationResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_server_proto_msgTypes[4]
+	mi := &file_proto_server_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -242,7 +425,7 @@ func (x *GetServerInformationResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetServerInformationResponse.ProtoReflect.Descriptor instead.
 func (*GetServerInformationResponse) Descriptor() ([]byte, []int) {
-	return file_proto_server_proto_rawDescGZIP(), []int{4}
+	return file_proto_server_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *GetServerInformationResponse) GetNumServers() int64 {
@@ -273,30 +456,488 @@ func (x *GetServerInformationResponse) GetMeanUptimeRatio() float32 {
 	return 0
 }
 
+func (x *GetServerInformationResponse) GetNumMaintenanceServers() int64 {
+	if x != nil {
+		return x.NumMaintenanceServers
+	}
+	return 0
+}
+
+func (x *GetServerInformationResponse) GetNumUnknownServers() int64 {
+	if x != nil {
+		return x.NumUnknownServers
+	}
+	return 0
+}
+
+func (x *GetServerInformationResponse) GetNumDegradedServers() int64 {
+	if x != nil {
+		return x.NumDegradedServers
+	}
+	return 0
+}
+
+func (x *GetServerInformationResponse) GetNumDecommissionedServers() int64 {
+	if x != nil {
+		return x.NumDecommissionedServers
+	}
+	return 0
+}
+
+type GetServersUptimeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StartTime     int64                  `protobuf:"varint,1,opt,name=startTime,proto3" json:"startTime,omitempty"` // timestamp in unix format
+	EndTime       int64                  `protobuf:"varint,2,opt,name=endTime,proto3" json:"endTime,omitempty"`     // timestamp in unix format
+	Offset        int64                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit         int64                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	SortColumn    string                 `protobuf:"bytes,5,opt,name=sortColumn,proto3" json:"sortColumn,omitempty"`                                                                   // id, uptime_ratio, num_checks or num_status_changes
+	SortOrder     string                 `protobuf:"bytes,6,opt,name=sortOrder,proto3" json:"sortOrder,omitempty"`                                                                     // asc or desc
+	Labels        map[string]string      `protobuf:"bytes,7,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // only servers having all of these labels
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetServersUptimeRequest) Reset() {
+	*x = GetServersUptimeRequest{}
+	mi := &file_proto_server_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetServersUptimeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetServersUptimeRequest) ProtoMessage() {}
+
+func (x *GetServersUptimeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_server_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetServersUptimeRequest.ProtoReflect.Descriptor instead.
+func (*GetServersUptimeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_server_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetServersUptimeRequest) GetStartTime() int64 {
+	if x != nil {
+		return x.StartTime
+	}
+	return 0
+}
+
+func (x *GetServersUptimeRequest) GetEndTime() int64 {
+	if x != nil {
+		return x.EndTime
+	}
+	return 0
+}
+
+func (x *GetServersUptimeRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *GetServersUptimeRequest) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *GetServersUptimeRequest) GetSortColumn() string {
+	if x != nil {
+		return x.SortColumn
+	}
+	return ""
+}
+
+func (x *GetServersUptimeRequest) GetSortOrder() string {
+	if x != nil {
+		return x.SortOrder
+	}
+	return ""
+}
+
+func (x *GetServersUptimeRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type ServerUptime struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Id               int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	UptimeRatio      float32                `protobuf:"fixed32,2,opt,name=uptimeRatio,proto3" json:"uptimeRatio,omitempty"`
+	NumChecks        int64                  `protobuf:"varint,3,opt,name=numChecks,proto3" json:"numChecks,omitempty"`
+	NumStatusChanges int64                  `protobuf:"varint,4,opt,name=numStatusChanges,proto3" json:"numStatusChanges,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ServerUptime) Reset() {
+	*x = ServerUptime{}
+	mi := &file_proto_server_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerUptime) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerUptime) ProtoMessage() {}
+
+func (x *ServerUptime) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_server_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerUptime.ProtoReflect.Descriptor instead.
+func (*ServerUptime) Descriptor() ([]byte, []int) {
+	return file_proto_server_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ServerUptime) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ServerUptime) GetUptimeRatio() float32 {
+	if x != nil {
+		return x.UptimeRatio
+	}
+	return 0
+}
+
+func (x *ServerUptime) GetNumChecks() int64 {
+	if x != nil {
+		return x.NumChecks
+	}
+	return 0
+}
+
+func (x *ServerUptime) GetNumStatusChanges() int64 {
+	if x != nil {
+		return x.NumStatusChanges
+	}
+	return 0
+}
+
+type GetServersUptimeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Servers       []*ServerUptime        `protobuf:"bytes,1,rep,name=servers,proto3" json:"servers,omitempty"`
+	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetServersUptimeResponse) Reset() {
+	*x = GetServersUptimeResponse{}
+	mi := &file_proto_server_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetServersUptimeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetServersUptimeResponse) ProtoMessage() {}
+
+func (x *GetServersUptimeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_server_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetServersUptimeResponse.ProtoReflect.Descriptor instead.
+func (*GetServersUptimeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_server_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetServersUptimeResponse) GetServers() []*ServerUptime {
+	if x != nil {
+		return x.Servers
+	}
+	return nil
+}
+
+func (x *GetServersUptimeResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type GetDownServersRequest struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	MinConsecutiveChecks int64                  `protobuf:"varint,1,opt,name=minConsecutiveChecks,proto3" json:"minConsecutiveChecks,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *GetDownServersRequest) Reset() {
+	*x = GetDownServersRequest{}
+	mi := &file_proto_server_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDownServersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDownServersRequest) ProtoMessage() {}
+
+func (x *GetDownServersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_server_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDownServersRequest.ProtoReflect.Descriptor instead.
+func (*GetDownServersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_server_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetDownServersRequest) GetMinConsecutiveChecks() int64 {
+	if x != nil {
+		return x.MinConsecutiveChecks
+	}
+	return 0
+}
+
+type DownServer struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	Id                    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ServerName            string                 `protobuf:"bytes,2,opt,name=serverName,proto3" json:"serverName,omitempty"`
+	ConsecutiveDownChecks int64                  `protobuf:"varint,3,opt,name=consecutiveDownChecks,proto3" json:"consecutiveDownChecks,omitempty"`
+	LastCheck             int64                  `protobuf:"varint,4,opt,name=lastCheck,proto3" json:"lastCheck,omitempty"` // timestamp in unix format
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *DownServer) Reset() {
+	*x = DownServer{}
+	mi := &file_proto_server_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownServer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownServer) ProtoMessage() {}
+
+func (x *DownServer) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_server_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownServer.ProtoReflect.Descriptor instead.
+func (*DownServer) Descriptor() ([]byte, []int) {
+	return file_proto_server_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DownServer) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *DownServer) GetServerName() string {
+	if x != nil {
+		return x.ServerName
+	}
+	return ""
+}
+
+func (x *DownServer) GetConsecutiveDownChecks() int64 {
+	if x != nil {
+		return x.ConsecutiveDownChecks
+	}
+	return 0
+}
+
+func (x *DownServer) GetLastCheck() int64 {
+	if x != nil {
+		return x.LastCheck
+	}
+	return 0
+}
+
+type GetDownServersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Servers       []*DownServer          `protobuf:"bytes,1,rep,name=servers,proto3" json:"servers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDownServersResponse) Reset() {
+	*x = GetDownServersResponse{}
+	mi := &file_proto_server_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDownServersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDownServersResponse) ProtoMessage() {}
+
+func (x *GetDownServersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_server_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDownServersResponse.ProtoReflect.Descriptor instead.
+func (*GetDownServersResponse) Descriptor() ([]byte, []int) {
+	return file_proto_server_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetDownServersResponse) GetServers() []*DownServer {
+	if x != nil {
+		return x.Servers
+	}
+	return nil
+}
+
 var File_proto_server_proto protoreflect.FileDescriptor
 
 const file_proto_server_proto_rawDesc = "" +
 	"\n" +
 	"\x12proto/server.proto\x12\x1dserver_administration_service\"\x0e\n" +
-	"\fEmptyRequest\"]\n" +
+	"\fEmptyRequest\"}\n" +
 	"\x11AddressesResponse\x12H\n" +
-	"\taddresses\x18\x01 \x03(\v2*.server_administration_service.AddressInfoR\taddresses\"7\n" +
+	"\taddresses\x18\x01 \x03(\v2*.server_administration_service.AddressInfoR\taddresses\x12\x1e\n" +
+	"\n" +
+	"serverTime\x18\x02 \x01(\x03R\n" +
+	"serverTime\"@\n" +
+	"\x1aGetUpdatedAddressesRequest\x12\"\n" +
+	"\fupdatedSince\x18\x01 \x01(\x03R\fupdatedSince\"\x99\x01\n" +
+	"\x1bGetUpdatedAddressesResponse\x12H\n" +
+	"\taddresses\x18\x01 \x03(\v2*.server_administration_service.AddressInfoR\taddresses\x12\x10\n" +
+	"\x03ids\x18\x02 \x03(\x03R\x03ids\x12\x1e\n" +
+	"\n" +
+	"serverTime\x18\x03 \x01(\x03R\n" +
+	"serverTime\"\xbf\x02\n" +
 	"\vAddressInfo\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x18\n" +
-	"\aaddress\x18\x02 \x01(\tR\aaddress\"U\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x1c\n" +
+	"\tprobeType\x18\x03 \x01(\tR\tprobeType\x12\x1c\n" +
+	"\tprobePath\x18\x04 \x01(\tR\tprobePath\x120\n" +
+	"\x13probeExpectedStatus\x18\x05 \x01(\x05R\x13probeExpectedStatus\x12,\n" +
+	"\x11probeExpectedBody\x18\x06 \x01(\tR\x11probeExpectedBody\x122\n" +
+	"\x14checkIntervalSeconds\x18\a \x01(\x05R\x14checkIntervalSeconds\x12\x1c\n" +
+	"\ttimeoutMs\x18\b \x01(\x05R\ttimeoutMs\x12\x18\n" +
+	"\aretries\x18\t \x01(\x05R\aretries\"\xf0\x01\n" +
 	"\x1bGetServerInformationRequest\x12\x1c\n" +
 	"\tstartTime\x18\x01 \x01(\x03R\tstartTime\x12\x18\n" +
-	"\aendTime\x18\x02 \x01(\x03R\aendTime\"\xb2\x01\n" +
+	"\aendTime\x18\x02 \x01(\x03R\aendTime\x12^\n" +
+	"\x06labels\x18\x03 \x03(\v2F.server_administration_service.GetServerInformationRequest.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x82\x03\n" +
 	"\x1cGetServerInformationResponse\x12\x1e\n" +
 	"\n" +
 	"numServers\x18\x01 \x01(\x03R\n" +
 	"numServers\x12\"\n" +
 	"\fnumOnServers\x18\x02 \x01(\x03R\fnumOnServers\x12$\n" +
 	"\rnumOffServers\x18\x03 \x01(\x03R\rnumOffServers\x12(\n" +
-	"\x0fmeanUptimeRatio\x18\x04 \x01(\x02R\x0fmeanUptimeRatio2\xa1\x02\n" +
+	"\x0fmeanUptimeRatio\x18\x04 \x01(\x02R\x0fmeanUptimeRatio\x124\n" +
+	"\x15numMaintenanceServers\x18\x05 \x01(\x03R\x15numMaintenanceServers\x12,\n" +
+	"\x11numUnknownServers\x18\x06 \x01(\x03R\x11numUnknownServers\x12.\n" +
+	"\x12numDegradedServers\x18\a \x01(\x03R\x12numDegradedServers\x12:\n" +
+	"\x18numDecommissionedServers\x18\b \x01(\x03R\x18numDecommissionedServers\"\xd4\x02\n" +
+	"\x17GetServersUptimeRequest\x12\x1c\n" +
+	"\tstartTime\x18\x01 \x01(\x03R\tstartTime\x12\x18\n" +
+	"\aendTime\x18\x02 \x01(\x03R\aendTime\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x03R\x06offset\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x03R\x05limit\x12\x1e\n" +
+	"\n" +
+	"sortColumn\x18\x05 \x01(\tR\n" +
+	"sortColumn\x12\x1c\n" +
+	"\tsortOrder\x18\x06 \x01(\tR\tsortOrder\x12Z\n" +
+	"\x06labels\x18\a \x03(\v2B.server_administration_service.GetServersUptimeRequest.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x8a\x01\n" +
+	"\fServerUptime\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12 \n" +
+	"\vuptimeRatio\x18\x02 \x01(\x02R\vuptimeRatio\x12\x1c\n" +
+	"\tnumChecks\x18\x03 \x01(\x03R\tnumChecks\x12*\n" +
+	"\x10numStatusChanges\x18\x04 \x01(\x03R\x10numStatusChanges\"w\n" +
+	"\x18GetServersUptimeResponse\x12E\n" +
+	"\aservers\x18\x01 \x03(\v2+.server_administration_service.ServerUptimeR\aservers\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\"K\n" +
+	"\x15GetDownServersRequest\x122\n" +
+	"\x14minConsecutiveChecks\x18\x01 \x01(\x03R\x14minConsecutiveChecks\"\x90\x01\n" +
+	"\n" +
+	"DownServer\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1e\n" +
+	"\n" +
+	"serverName\x18\x02 \x01(\tR\n" +
+	"serverName\x124\n" +
+	"\x15consecutiveDownChecks\x18\x03 \x01(\x03R\x15consecutiveDownChecks\x12\x1c\n" +
+	"\tlastCheck\x18\x04 \x01(\x03R\tlastCheck\"]\n" +
+	"\x16GetDownServersResponse\x12C\n" +
+	"\aservers\x18\x01 \x03(\v2).server_administration_service.DownServerR\aservers2\xb5\x05\n" +
 	"\x1bServerAdministrationService\x12p\n" +
-	"\x0fGetAllAddresses\x12+.server_administration_service.EmptyRequest\x1a0.server_administration_service.AddressesResponse\x12\x8f\x01\n" +
-	"\x14GetServerInformation\x12:.server_administration_service.GetServerInformationRequest\x1a;.server_administration_service.GetServerInformationResponseB\x06Z\x04./pbb\x06proto3"
+	"\x0fGetAllAddresses\x12+.server_administration_service.EmptyRequest\x1a0.server_administration_service.AddressesResponse\x12\x8c\x01\n" +
+	"\x13GetUpdatedAddresses\x129.server_administration_service.GetUpdatedAddressesRequest\x1a:.server_administration_service.GetUpdatedAddressesResponse\x12\x8f\x01\n" +
+	"\x14GetServerInformation\x12:.server_administration_service.GetServerInformationRequest\x1a;.server_administration_service.GetServerInformationResponse\x12\x83\x01\n" +
+	"\x10GetServersUptime\x126.server_administration_service.GetServersUptimeRequest\x1a7.server_administration_service.GetServersUptimeResponse\x12}\n" +
+	"\x0eGetDownServers\x124.server_administration_service.GetDownServersRequest\x1a5.server_administration_service.GetDownServersResponseB\x06Z\x04./pbb\x06proto3"
 
 var (
 	file_proto_server_proto_rawDescOnce sync.Once
@@ -310,25 +951,46 @@ func file_proto_server_proto_rawDescGZIP() []byte {
 	return file_proto_server_proto_rawDescData
 }
 
-var file_proto_server_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_proto_server_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_proto_server_proto_goTypes = []any{
 	(*EmptyRequest)(nil),                 // 0: server_administration_service.EmptyRequest
 	(*AddressesResponse)(nil),            // 1: server_administration_service.AddressesResponse
-	(*AddressInfo)(nil),                  // 2: server_administration_service.AddressInfo
-	(*GetServerInformationRequest)(nil),  // 3: server_administration_service.GetServerInformationRequest
-	(*GetServerInformationResponse)(nil), // 4: server_administration_service.GetServerInformationResponse
+	(*GetUpdatedAddressesRequest)(nil),   // 2: server_administration_service.GetUpdatedAddressesRequest
+	(*GetUpdatedAddressesResponse)(nil),  // 3: server_administration_service.GetUpdatedAddressesResponse
+	(*AddressInfo)(nil),                  // 4: server_administration_service.AddressInfo
+	(*GetServerInformationRequest)(nil),  // 5: server_administration_service.GetServerInformationRequest
+	(*GetServerInformationResponse)(nil), // 6: server_administration_service.GetServerInformationResponse
+	(*GetServersUptimeRequest)(nil),      // 7: server_administration_service.GetServersUptimeRequest
+	(*ServerUptime)(nil),                 // 8: server_administration_service.ServerUptime
+	(*GetServersUptimeResponse)(nil),     // 9: server_administration_service.GetServersUptimeResponse
+	(*GetDownServersRequest)(nil),        // 10: server_administration_service.GetDownServersRequest
+	(*DownServer)(nil),                   // 11: server_administration_service.DownServer
+	(*GetDownServersResponse)(nil),       // 12: server_administration_service.GetDownServersResponse
+	nil,                                  // 13: server_administration_service.GetServerInformationRequest.LabelsEntry
+	nil,                                  // 14: server_administration_service.GetServersUptimeRequest.LabelsEntry
 }
 var file_proto_server_proto_depIdxs = []int32{
-	2, // 0: server_administration_service.AddressesResponse.addresses:type_name -> server_administration_service.AddressInfo
-	0, // 1: server_administration_service.ServerAdministrationService.GetAllAddresses:input_type -> server_administration_service.EmptyRequest
-	3, // 2: server_administration_service.ServerAdministrationService.GetServerInformation:input_type -> server_administration_service.GetServerInformationRequest
-	1, // 3: server_administration_service.ServerAdministrationService.GetAllAddresses:output_type -> server_administration_service.AddressesResponse
-	4, // 4: server_administration_service.ServerAdministrationService.GetServerInformation:output_type -> server_administration_service.GetServerInformationResponse
-	3, // [3:5] is the sub-list for method output_type
-	1, // [1:3] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	4,  // 0: server_administration_service.AddressesResponse.addresses:type_name -> server_administration_service.AddressInfo
+	4,  // 1: server_administration_service.GetUpdatedAddressesResponse.addresses:type_name -> server_administration_service.AddressInfo
+	13, // 2: server_administration_service.GetServerInformationRequest.labels:type_name -> server_administration_service.GetServerInformationRequest.LabelsEntry
+	14, // 3: server_administration_service.GetServersUptimeRequest.labels:type_name -> server_administration_service.GetServersUptimeRequest.LabelsEntry
+	8,  // 4: server_administration_service.GetServersUptimeResponse.servers:type_name -> server_administration_service.ServerUptime
+	11, // 5: server_administration_service.GetDownServersResponse.servers:type_name -> server_administration_service.DownServer
+	0,  // 6: server_administration_service.ServerAdministrationService.GetAllAddresses:input_type -> server_administration_service.EmptyRequest
+	2,  // 7: server_administration_service.ServerAdministrationService.GetUpdatedAddresses:input_type -> server_administration_service.GetUpdatedAddressesRequest
+	5,  // 8: server_administration_service.ServerAdministrationService.GetServerInformation:input_type -> server_administration_service.GetServerInformationRequest
+	7,  // 9: server_administration_service.ServerAdministrationService.GetServersUptime:input_type -> server_administration_service.GetServersUptimeRequest
+	10, // 10: server_administration_service.ServerAdministrationService.GetDownServers:input_type -> server_administration_service.GetDownServersRequest
+	1,  // 11: server_administration_service.ServerAdministrationService.GetAllAddresses:output_type -> server_administration_service.AddressesResponse
+	3,  // 12: server_administration_service.ServerAdministrationService.GetUpdatedAddresses:output_type -> server_administration_service.GetUpdatedAddressesResponse
+	6,  // 13: server_administration_service.ServerAdministrationService.GetServerInformation:output_type -> server_administration_service.GetServerInformationResponse
+	9,  // 14: server_administration_service.ServerAdministrationService.GetServersUptime:output_type -> server_administration_service.GetServersUptimeResponse
+	12, // 15: server_administration_service.ServerAdministrationService.GetDownServers:output_type -> server_administration_service.GetDownServersResponse
+	11, // [11:16] is the sub-list for method output_type
+	6,  // [6:11] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_proto_server_proto_init() }
@@ -342,7 +1004,7 @@ func file_proto_server_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_server_proto_rawDesc), len(file_proto_server_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
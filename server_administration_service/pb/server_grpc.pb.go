@@ -20,7 +20,10 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	ServerAdministrationService_GetAllAddresses_FullMethodName      = "/server_administration_service.ServerAdministrationService/GetAllAddresses"
+	ServerAdministrationService_GetUpdatedAddresses_FullMethodName  = "/server_administration_service.ServerAdministrationService/GetUpdatedAddresses"
 	ServerAdministrationService_GetServerInformation_FullMethodName = "/server_administration_service.ServerAdministrationService/GetServerInformation"
+	ServerAdministrationService_GetServersUptime_FullMethodName     = "/server_administration_service.ServerAdministrationService/GetServersUptime"
+	ServerAdministrationService_GetDownServers_FullMethodName       = "/server_administration_service.ServerAdministrationService/GetDownServers"
 )
 
 // ServerAdministrationServiceClient is the client API for ServerAdministrationService service.
@@ -28,7 +31,10 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ServerAdministrationServiceClient interface {
 	GetAllAddresses(ctx context.Context, in *EmptyRequest, opts ...grpc.CallOption) (*AddressesResponse, error)
+	GetUpdatedAddresses(ctx context.Context, in *GetUpdatedAddressesRequest, opts ...grpc.CallOption) (*GetUpdatedAddressesResponse, error)
 	GetServerInformation(ctx context.Context, in *GetServerInformationRequest, opts ...grpc.CallOption) (*GetServerInformationResponse, error)
+	GetServersUptime(ctx context.Context, in *GetServersUptimeRequest, opts ...grpc.CallOption) (*GetServersUptimeResponse, error)
+	GetDownServers(ctx context.Context, in *GetDownServersRequest, opts ...grpc.CallOption) (*GetDownServersResponse, error)
 }
 
 type serverAdministrationServiceClient struct {
@@ -49,6 +55,16 @@ func (c *serverAdministrationServiceClient) GetAllAddresses(ctx context.Context,
 	return out, nil
 }
 
+func (c *serverAdministrationServiceClient) GetUpdatedAddresses(ctx context.Context, in *GetUpdatedAddressesRequest, opts ...grpc.CallOption) (*GetUpdatedAddressesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUpdatedAddressesResponse)
+	err := c.cc.Invoke(ctx, ServerAdministrationService_GetUpdatedAddresses_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *serverAdministrationServiceClient) GetServerInformation(ctx context.Context, in *GetServerInformationRequest, opts ...grpc.CallOption) (*GetServerInformationResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetServerInformationResponse)
@@ -59,12 +75,35 @@ func (c *serverAdministrationServiceClient) GetServerInformation(ctx context.Con
 	return out, nil
 }
 
+func (c *serverAdministrationServiceClient) GetServersUptime(ctx context.Context, in *GetServersUptimeRequest, opts ...grpc.CallOption) (*GetServersUptimeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetServersUptimeResponse)
+	err := c.cc.Invoke(ctx, ServerAdministrationService_GetServersUptime_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serverAdministrationServiceClient) GetDownServers(ctx context.Context, in *GetDownServersRequest, opts ...grpc.CallOption) (*GetDownServersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDownServersResponse)
+	err := c.cc.Invoke(ctx, ServerAdministrationService_GetDownServers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ServerAdministrationServiceServer is the server API for ServerAdministrationService service.
 // All implementations must embed UnimplementedServerAdministrationServiceServer
 // for forward compatibility.
 type ServerAdministrationServiceServer interface {
 	GetAllAddresses(context.Context, *EmptyRequest) (*AddressesResponse, error)
+	GetUpdatedAddresses(context.Context, *GetUpdatedAddressesRequest) (*GetUpdatedAddressesResponse, error)
 	GetServerInformation(context.Context, *GetServerInformationRequest) (*GetServerInformationResponse, error)
+	GetServersUptime(context.Context, *GetServersUptimeRequest) (*GetServersUptimeResponse, error)
+	GetDownServers(context.Context, *GetDownServersRequest) (*GetDownServersResponse, error)
 	mustEmbedUnimplementedServerAdministrationServiceServer()
 }
 
@@ -78,9 +117,18 @@ type UnimplementedServerAdministrationServiceServer struct{}
 func (UnimplementedServerAdministrationServiceServer) GetAllAddresses(context.Context, *EmptyRequest) (*AddressesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetAllAddresses not implemented")
 }
+func (UnimplementedServerAdministrationServiceServer) GetUpdatedAddresses(context.Context, *GetUpdatedAddressesRequest) (*GetUpdatedAddressesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUpdatedAddresses not implemented")
+}
 func (UnimplementedServerAdministrationServiceServer) GetServerInformation(context.Context, *GetServerInformationRequest) (*GetServerInformationResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetServerInformation not implemented")
 }
+func (UnimplementedServerAdministrationServiceServer) GetServersUptime(context.Context, *GetServersUptimeRequest) (*GetServersUptimeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServersUptime not implemented")
+}
+func (UnimplementedServerAdministrationServiceServer) GetDownServers(context.Context, *GetDownServersRequest) (*GetDownServersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDownServers not implemented")
+}
 func (UnimplementedServerAdministrationServiceServer) mustEmbedUnimplementedServerAdministrationServiceServer() {
 }
 func (UnimplementedServerAdministrationServiceServer) testEmbeddedByValue() {}
@@ -121,6 +169,24 @@ func _ServerAdministrationService_GetAllAddresses_Handler(srv interface{}, ctx c
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ServerAdministrationService_GetUpdatedAddresses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUpdatedAddressesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServerAdministrationServiceServer).GetUpdatedAddresses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ServerAdministrationService_GetUpdatedAddresses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServerAdministrationServiceServer).GetUpdatedAddresses(ctx, req.(*GetUpdatedAddressesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ServerAdministrationService_GetServerInformation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetServerInformationRequest)
 	if err := dec(in); err != nil {
@@ -139,6 +205,42 @@ func _ServerAdministrationService_GetServerInformation_Handler(srv interface{},
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ServerAdministrationService_GetServersUptime_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetServersUptimeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServerAdministrationServiceServer).GetServersUptime(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ServerAdministrationService_GetServersUptime_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServerAdministrationServiceServer).GetServersUptime(ctx, req.(*GetServersUptimeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ServerAdministrationService_GetDownServers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDownServersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServerAdministrationServiceServer).GetDownServers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ServerAdministrationService_GetDownServers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServerAdministrationServiceServer).GetDownServers(ctx, req.(*GetDownServersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ServerAdministrationService_ServiceDesc is the grpc.ServiceDesc for ServerAdministrationService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -150,10 +252,22 @@ var ServerAdministrationService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetAllAddresses",
 			Handler:    _ServerAdministrationService_GetAllAddresses_Handler,
 		},
+		{
+			MethodName: "GetUpdatedAddresses",
+			Handler:    _ServerAdministrationService_GetUpdatedAddresses_Handler,
+		},
 		{
 			MethodName: "GetServerInformation",
 			Handler:    _ServerAdministrationService_GetServerInformation_Handler,
 		},
+		{
+			MethodName: "GetServersUptime",
+			Handler:    _ServerAdministrationService_GetServersUptime_Handler,
+		},
+		{
+			MethodName: "GetDownServers",
+			Handler:    _ServerAdministrationService_GetDownServers_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/server.proto",